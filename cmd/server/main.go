@@ -1,10 +1,16 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"cloudevents-explorer/internal/config"
+	"cloudevents-explorer/internal/gcs"
 	"cloudevents-explorer/internal/handlers"
 )
 
@@ -14,6 +20,13 @@ func main() {
 		log.Printf("Warning: Failed to load config: %v", err)
 	}
 
+	// The server runs until interrupted
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Publish the changes of GCS buckets with watched notifications
+	gcs.StartWatching(ctx, 5*time.Second)
+
 	// Register page handlers
 	http.HandleFunc("/", handlers.HandleIndex)
 	http.HandleFunc("/pubsub", handlers.HandlePubSub)
@@ -23,6 +36,7 @@ func main() {
 	http.HandleFunc("/gcs", handlers.HandleGCS)
 	http.HandleFunc("/trace-journey", handlers.HandleTraceJourney)
 	http.HandleFunc("/spanner", handlers.HandleSpanner)
+	http.HandleFunc("/spanner/change-streams", handlers.HandleSpannerChangeStreams)
 	http.HandleFunc("/config-editor", handlers.HandleConfigEditor)
 	http.HandleFunc("/flimflam-explorer", handlers.FlimFlamExplorerHandler)
 
@@ -39,18 +53,65 @@ func main() {
 	http.HandleFunc("/api/rest/collections", handlers.HandleGetCollections)
 	http.HandleFunc("/api/rest/delete", handlers.HandleDeleteRequest)
 	http.HandleFunc("/api/rest/collection/delete", handlers.HandleDeleteCollection)
+	http.HandleFunc("/api/rest/collection/variables", handlers.HandleSaveCollectionVariables)
+	http.HandleFunc("/api/rest/environments", handlers.HandleGetEnvironments)
+	http.HandleFunc("/api/rest/environments/save", handlers.HandleSaveEnvironment)
+	http.HandleFunc("/api/rest/environments/delete", handlers.HandleDeleteEnvironment)
 	http.HandleFunc("/api/docker/status", handlers.HandleDockerStatus)
 	http.HandleFunc("/api/gcloud/status", handlers.HandleGCloudStatus)
 	http.HandleFunc("/api/gcs/buckets", handlers.HandleListBuckets)
 	http.HandleFunc("/api/gcs/objects", handlers.HandleListObjects)
 	http.HandleFunc("/api/gcs/object/content", handlers.HandleGetObjectContent)
+	http.HandleFunc("/api/gcs/object/table", handlers.HandlePreviewObjectTable)
+	http.HandleFunc("/api/gcs/object/diff", handlers.HandleDiffObjects)
 	http.HandleFunc("/api/gcs/object/download", handlers.HandleDownloadObject)
+	http.HandleFunc("/api/gcs/search", handlers.HandleSearchObjects)
+	http.HandleFunc("/api/gcs/zip", handlers.HandleDownloadZip)
+	http.HandleFunc("/api/gcs/buckets/create", handlers.HandleCreateBucket)
+	http.HandleFunc("/api/gcs/upload", handlers.HandleUploadObjects)
+	http.HandleFunc("/api/gcs/object/delete", handlers.HandleDeleteObject)
+	http.HandleFunc("/api/gcs/object/copy", handlers.HandleCopyObject)
+	http.HandleFunc("/api/gcs/folder/create", handlers.HandleCreateFolder)
+	http.HandleFunc("/api/gcs/object/metadata", handlers.HandleGetObjectMetadata)
+	http.HandleFunc("/api/gcs/object/metadata/update", handlers.HandleUpdateObjectMetadata)
+	http.HandleFunc("/api/gcs/object/versions", handlers.HandleListObjectVersions)
+	http.HandleFunc("/api/gcs/notifications", handlers.HandleListGCSNotifications)
+	http.HandleFunc("/api/gcs/notifications/save", handlers.HandleSaveGCSNotification)
+	http.HandleFunc("/api/gcs/notifications/delete", handlers.HandleDeleteGCSNotification)
 	http.HandleFunc("/api/trace/search", handlers.HandleTraceSearch)
 	http.HandleFunc("/api/spanner/connect", handlers.HandleSpannerConnect)
+	http.HandleFunc("/api/spanner/instances", handlers.HandleSpannerInstances)
+	http.HandleFunc("/api/spanner/instances/create", handlers.HandleSpannerInstanceCreate)
+	http.HandleFunc("/api/spanner/instances/delete", handlers.HandleSpannerInstanceDelete)
+	http.HandleFunc("/api/spanner/databases", handlers.HandleSpannerDatabases)
+	http.HandleFunc("/api/spanner/databases/create", handlers.HandleSpannerDatabaseCreate)
+	http.HandleFunc("/api/spanner/databases/drop", handlers.HandleSpannerDatabaseDrop)
 	http.HandleFunc("/api/spanner/tables", handlers.HandleSpannerTables)
+	http.HandleFunc("/api/spanner/tables/count", handlers.HandleSpannerTableCount)
 	http.HandleFunc("/api/spanner/query", handlers.HandleSpannerQuery)
 	http.HandleFunc("/api/spanner/configs", handlers.HandleSaveSpannerConfig)
+	http.HandleFunc("/api/spanner/history", handlers.HandleSpannerHistory)
+	http.HandleFunc("/api/spanner/history/clear", handlers.HandleSpannerHistoryClear)
+	http.HandleFunc("/api/spanner/queries/save", handlers.HandleSpannerSavedQuerySave)
+	http.HandleFunc("/api/spanner/queries/delete", handlers.HandleSpannerSavedQueryDelete)
 	http.HandleFunc("/api/spanner/schema", handlers.HandleSpannerSchema)
+	http.HandleFunc("/api/spanner/database-schema", handlers.HandleSpannerDatabaseSchema)
+	http.HandleFunc("/api/spanner/rows", handlers.HandleSpannerRows)
+	http.HandleFunc("/api/spanner/mutations", handlers.HandleSpannerMutations)
+	http.HandleFunc("/api/spanner/change-streams/read", handlers.HandleSpannerChangeStreamRead)
+	http.HandleFunc("/api/spanner/export", handlers.HandleSpannerExport)
+	http.HandleFunc("/api/spanner/import", handlers.HandleSpannerImport)
+	http.HandleFunc("/api/spanner/seeds", handlers.HandleSpannerSeeds)
+	http.HandleFunc("/api/spanner/seeds/save", handlers.HandleSpannerSeedSave)
+	http.HandleFunc("/api/spanner/seeds/apply", handlers.HandleSpannerSeedApply)
+	http.HandleFunc("/api/spanner/seeds/delete", handlers.HandleSpannerSeedDelete)
+	http.HandleFunc("/api/spanner/snapshots", handlers.HandleSpannerSnapshots)
+	http.HandleFunc("/api/spanner/snapshots/capture", handlers.HandleSpannerSnapshotCapture)
+	http.HandleFunc("/api/spanner/snapshots/diff", handlers.HandleSpannerSnapshotDiff)
+	http.HandleFunc("/api/spanner/snapshots/delete", handlers.HandleSpannerSnapshotDelete)
+	http.HandleFunc("/api/spanner/transaction/begin", handlers.HandleSpannerBegin)
+	http.HandleFunc("/api/spanner/transaction/commit", handlers.HandleSpannerCommit)
+	http.HandleFunc("/api/spanner/transaction/rollback", handlers.HandleSpannerRollback)
 	http.HandleFunc("/api/flimflam/apis", handlers.FlimFlamAPIsHandler)
 	http.HandleFunc("/api/flimflam/send", handlers.FlimFlamProxyHandler)
 	http.HandleFunc("/api/flimflam/status", handlers.FlimFlamStatusHandler)
@@ -58,7 +119,18 @@ func main() {
 	port := "8888"
 	log.Printf("🚀 Testing Studio starting on http://localhost:%s", port)
 	log.Printf("📝 Configuration file: configs.json")
-	if err := http.ListenAndServe(":"+port, nil); err != nil {
+	server := &http.Server{Addr: ":" + port}
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+		gcs.ClosePublisher()
+	}()
+	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 		log.Fatal(err)
 	}
+	<-stopped
 }
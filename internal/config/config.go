@@ -24,11 +24,12 @@ type KafkaConfig struct {
 }
 
 type SpannerConfig struct {
-	Name         string `json:"name"`
-	EmulatorHost string `json:"emulatorHost"`
-	ProjectID    string `json:"projectId"`
-	InstanceID   string `json:"instanceId"`
-	DatabaseID   string `json:"databaseId"`
+	Name         string       `json:"name"`
+	EmulatorHost string       `json:"emulatorHost"`
+	ProjectID    string       `json:"projectId"`
+	InstanceID   string       `json:"instanceId"`
+	DatabaseID   string       `json:"databaseId"`
+	SavedQueries []SavedQuery `json:"savedQueries,omitempty"`
 }
 
 type GCSConfig struct {
@@ -38,27 +39,33 @@ type GCSConfig struct {
 }
 
 type SavedRequest struct {
-	Name       string                 `json:"name"`
-	Method     string                 `json:"method"`
-	URL        string                 `json:"url"`
-	Headers    map[string]string      `json:"headers"`
-	Parameters map[string]string      `json:"parameters"`
-	Body       string                 `json:"body"`
-	TLSCert    string                 `json:"tlsCert,omitempty"`
-	TLSKey     string                 `json:"tlsKey,omitempty"`
+	Name        string            `json:"name"`
+	Method      string            `json:"method"`
+	URL         string            `json:"url"`
+	Headers     map[string]string `json:"headers"`
+	Parameters  QueryParams       `json:"parameters"`
+	Body        RequestBody       `json:"body"`
+	TLSCert     string            `json:"tlsCert,omitempty"`
+	TLSKey      string            `json:"tlsKey,omitempty"`
+	Options     RequestOptions    `json:"options"`
+	Assertions  []Assertion       `json:"assertions,omitempty"`
+	Extractions []Extraction      `json:"extractions,omitempty"`
 }
 
 type RequestCollection struct {
-	Name     string          `json:"name"`
-	Requests []SavedRequest  `json:"requests"`
+	Name      string         `json:"name"`
+	Requests  []SavedRequest `json:"requests"`
+	Variables []Variable     `json:"variables,omitempty"`
 }
 
 type Config struct {
-	PubSubConfigs       []PubSubConfig       `json:"pubsubConfigs"`
-	KafkaConfigs        []KafkaConfig        `json:"kafkaConfigs"`
-	SpannerConfigs      []SpannerConfig      `json:"spannerConfigs"`
-	GCSConfigs          []GCSConfig          `json:"gcsConfigs,omitempty"`
-	RequestCollections  []RequestCollection  `json:"requestCollections,omitempty"`
+	PubSubConfigs      []PubSubConfig      `json:"pubsubConfigs"`
+	KafkaConfigs       []KafkaConfig       `json:"kafkaConfigs"`
+	SpannerConfigs     []SpannerConfig     `json:"spannerConfigs"`
+	GCSConfigs         []GCSConfig         `json:"gcsConfigs,omitempty"`
+	RequestCollections []RequestCollection `json:"requestCollections,omitempty"`
+	GCSNotifications   []GCSNotification   `json:"gcsNotifications,omitempty"`
+	Environments       []Environment       `json:"environments,omitempty"`
 }
 
 var (
@@ -170,6 +177,10 @@ func AddOrUpdateSpannerConfig(newConfig SpannerConfig) error {
 	found := false
 	for i, cfg := range config.SpannerConfigs {
 		if cfg.Name == newConfig.Name {
+			// The connection form does not send the saved queries
+			if newConfig.SavedQueries == nil {
+				newConfig.SavedQueries = cfg.SavedQueries
+			}
 			config.SpannerConfigs[i] = newConfig
 			found = true
 			break
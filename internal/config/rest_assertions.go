@@ -0,0 +1,49 @@
+package config
+
+// Assertion types
+const (
+	AssertStatus       = "status"
+	AssertHeader       = "header"
+	AssertJSONPath     = "jsonPath"
+	AssertJSONSchema   = "jsonSchema"
+	AssertResponseTime = "responseTime"
+)
+
+// Assertion operators
+const (
+	OpEquals      = "equals"
+	OpNotEquals   = "notEquals"
+	OpContains    = "contains"
+	OpMatches     = "matches"
+	OpExists      = "exists"
+	OpNotExists   = "notExists"
+	OpLessThan    = "lessThan"
+	OpGreaterThan = "greaterThan"
+)
+
+// Assertion is a check on a response. Target is the header name or the
+// JSONPath for those types; Value is what Operator compares against, the
+// schema for jsonSchema, and the limit in milliseconds for responseTime.
+type Assertion struct {
+	Type     string `json:"type"`
+	Target   string `json:"target,omitempty"`
+	Operator string `json:"operator,omitempty"`
+	Value    string `json:"value,omitempty"`
+	Disabled bool   `json:"disabled,omitempty"`
+}
+
+// Extraction sources
+const (
+	ExtractJSONPath = "jsonPath"
+	ExtractHeader   = "header"
+)
+
+// Extraction copies a value from a response into a variable of the selected
+// environment, for the requests that follow
+type Extraction struct {
+	Variable string `json:"variable"`
+	Source   string `json:"source"`
+	Path     string `json:"path"`
+	Secret   bool   `json:"secret,omitempty"`
+	Disabled bool   `json:"disabled,omitempty"`
+}
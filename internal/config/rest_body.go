@@ -0,0 +1,54 @@
+package config
+
+import "encoding/json"
+
+// Request body types
+const (
+	BodyNone      = "none"
+	BodyRaw       = "raw"
+	BodyForm      = "form"
+	BodyMultipart = "multipart"
+	BodyFile      = "file"
+)
+
+// RequestBody is the body of a REST client request. A raw body is sent as
+// written with ContentType, a form body as URL-encoded Fields, a multipart
+// body as form-data Fields, any of which may be a file, and a file body as
+// the bytes of File.
+type RequestBody struct {
+	Type        string        `json:"type"`
+	ContentType string        `json:"contentType,omitempty"`
+	Raw         string        `json:"raw,omitempty"`
+	Fields      []BodyField   `json:"fields,omitempty"`
+	File        *UploadedFile `json:"file,omitempty"`
+}
+
+// BodyField is a form field, or with File set a multipart file part
+type BodyField struct {
+	Name  string        `json:"name"`
+	Value string        `json:"value,omitempty"`
+	File  *UploadedFile `json:"file,omitempty"`
+}
+
+// UploadedFile is an uploaded file, kept with the request that sends it
+type UploadedFile struct {
+	Name        string `json:"name"`
+	ContentType string `json:"contentType,omitempty"`
+	Data        []byte `json:"data"`
+}
+
+// UnmarshalJSON also reads the bodies of older saved requests, which were
+// JSON text stored as a string
+func (b *RequestBody) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*b = RequestBody{Type: BodyNone}
+		if text != "" {
+			*b = RequestBody{Type: BodyRaw, ContentType: "application/json", Raw: text}
+		}
+		return nil
+	}
+
+	type plain RequestBody
+	return json.Unmarshal(data, (*plain)(b))
+}
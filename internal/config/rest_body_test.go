@@ -0,0 +1,48 @@
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestRequestBodyUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  RequestBody
+	}{
+		{"legacy empty string", `""`, RequestBody{Type: BodyNone}},
+		{"legacy JSON text", `"{\"a\": 1}"`, RequestBody{Type: BodyRaw, ContentType: "application/json", Raw: `{"a": 1}`}},
+		{"legacy plain text", `"hello"`, RequestBody{Type: BodyRaw, ContentType: "application/json", Raw: "hello"}},
+		{"raw", `{"type": "raw", "contentType": "text/plain", "raw": "hi"}`,
+			RequestBody{Type: BodyRaw, ContentType: "text/plain", Raw: "hi"}},
+		{"form", `{"type": "form", "fields": [{"name": "a", "value": "1"}]}`,
+			RequestBody{Type: BodyForm, Fields: []BodyField{{Name: "a", Value: "1"}}}},
+		{"file", `{"type": "file", "file": {"name": "a.txt", "data": "aGk="}}`,
+			RequestBody{Type: BodyFile, File: &UploadedFile{Name: "a.txt", Data: []byte("hi")}}},
+	}
+
+	for _, tt := range tests {
+		var got RequestBody
+		if err := json.Unmarshal([]byte(tt.input), &got); err != nil {
+			t.Errorf("%s: could not decode: %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: expected %+v, got %+v", tt.name, tt.want, got)
+		}
+	}
+
+	var req SavedRequest
+	if err := json.Unmarshal([]byte(`{"body": "{}"}`), &req); err != nil {
+		t.Fatalf("could not decode a legacy request: %v", err)
+	}
+	if req.Body.Type != BodyRaw || req.Body.Raw != "{}" {
+		t.Errorf("expected a legacy request body to be read as raw, got %+v", req.Body)
+	}
+
+	if err := json.Unmarshal([]byte(`42`), &RequestBody{}); err == nil {
+		t.Error("expected an error for a body that is neither a string nor an object")
+	}
+}
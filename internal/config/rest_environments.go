@@ -0,0 +1,157 @@
+package config
+
+import "fmt"
+
+// Variable is a value the REST client substitutes for {{Key}}. Secret
+// values are never sent back to the REST client page.
+type Variable struct {
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Secret bool   `json:"secret,omitempty"`
+}
+
+// Environment is a named set of variables, such as the hosts and tokens of
+// one deployment. Its variables override those of a collection.
+type Environment struct {
+	Name      string     `json:"name"`
+	Variables []Variable `json:"variables"`
+}
+
+// keepSecrets fills the empty values of secret variables from the variables
+// they replace, since the page never has the stored value to send back
+func keepSecrets(existing, updated []Variable) []Variable {
+	stored := map[string]string{}
+	for _, v := range existing {
+		if v.Secret {
+			stored[v.Key] = v.Value
+		}
+	}
+	for i, v := range updated {
+		if v.Secret && v.Value == "" {
+			updated[i].Value = stored[v.Key]
+		}
+	}
+	return updated
+}
+
+func GetEnvironments() []Environment {
+	mu.RLock()
+	defer mu.RUnlock()
+	return config.Environments
+}
+
+// SaveEnvironment adds an environment or replaces the one with its name.
+// Secret variables sent without a value keep their stored value.
+func SaveEnvironment(env Environment) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	for i := range config.Environments {
+		if config.Environments[i].Name == env.Name {
+			env.Variables = keepSecrets(config.Environments[i].Variables, env.Variables)
+			config.Environments[i] = env
+			return saveLocked()
+		}
+	}
+	config.Environments = append(config.Environments, env)
+
+	return saveLocked()
+}
+
+func DeleteEnvironment(name string) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	for i, env := range config.Environments {
+		if env.Name == name {
+			config.Environments = append(config.Environments[:i], config.Environments[i+1:]...)
+			return saveLocked()
+		}
+	}
+
+	return nil
+}
+
+// SetEnvironmentVariables adds variables to an environment, replacing those
+// with the same keys
+func SetEnvironmentVariables(environmentName string, vars []Variable) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	for i := range config.Environments {
+		env := &config.Environments[i]
+		if env.Name != environmentName {
+			continue
+		}
+		for _, v := range vars {
+			replaced := false
+			for j := range env.Variables {
+				if env.Variables[j].Key == v.Key {
+					env.Variables[j] = v
+					replaced = true
+					break
+				}
+			}
+			if !replaced {
+				env.Variables = append(env.Variables, v)
+			}
+		}
+		return saveLocked()
+	}
+
+	return fmt.Errorf("environment %q not found", environmentName)
+}
+
+// SetCollectionVariables replaces the variables of a collection, creating
+// the collection when it has no saved requests yet. Secret variables sent
+// without a value keep their stored value.
+func SetCollectionVariables(collectionName string, vars []Variable) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	for i := range config.RequestCollections {
+		if config.RequestCollections[i].Name == collectionName {
+			config.RequestCollections[i].Variables = keepSecrets(config.RequestCollections[i].Variables, vars)
+			return saveLocked()
+		}
+	}
+	config.RequestCollections = append(config.RequestCollections, RequestCollection{
+		Name:      collectionName,
+		Requests:  []SavedRequest{},
+		Variables: vars,
+	})
+
+	return saveLocked()
+}
+
+// RequestVariables returns the variables of a collection overridden by those
+// of an environment. Either name may be empty.
+func RequestVariables(collectionName, environmentName string) ([]Variable, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	var vars []Variable
+	if collectionName != "" {
+		for _, coll := range config.RequestCollections {
+			if coll.Name == collectionName {
+				vars = append(vars, coll.Variables...)
+				break
+			}
+		}
+	}
+	if environmentName != "" {
+		found := false
+		for _, env := range config.Environments {
+			if env.Name == environmentName {
+				vars = append(vars, env.Variables...)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("environment %q not found", environmentName)
+		}
+	}
+
+	return vars, nil
+}
@@ -0,0 +1,29 @@
+package config
+
+// Resolver modes
+const (
+	ResolverSystem = "system"
+	ResolverCustom = "custom"
+)
+
+// RequestOptions controls how the REST client sends a request. The zero
+// value resolves names with the system resolver, connects directly, follows
+// up to 10 redirects, times out after 30 seconds and does not verify TLS
+// certificates.
+type RequestOptions struct {
+	Resolver         string         `json:"resolver,omitempty"`
+	DNSServer        string         `json:"dnsServer,omitempty"`
+	HostOverrides    []HostOverride `json:"hostOverrides,omitempty"`
+	Proxy            string         `json:"proxy,omitempty"`
+	DisableRedirects bool           `json:"disableRedirects,omitempty"`
+	MaxRedirects     int            `json:"maxRedirects,omitempty"`
+	TimeoutMs        int            `json:"timeoutMs,omitempty"`
+	VerifyTLS        bool           `json:"verifyTls,omitempty"`
+}
+
+// HostOverride connects to Address, an IP or host with an optional port,
+// whenever a request dials Host, the way an /etc/hosts entry would
+type HostOverride struct {
+	Host    string `json:"host"`
+	Address string `json:"address"`
+}
@@ -0,0 +1,38 @@
+package config
+
+import (
+	"encoding/json"
+	"sort"
+)
+
+// QueryParam is a row of a request's query parameters. Keys may repeat, and
+// disabled rows are kept but not sent.
+type QueryParam struct {
+	Key      string `json:"key"`
+	Value    string `json:"value"`
+	Disabled bool   `json:"disabled,omitempty"`
+}
+
+// QueryParams are sent in order
+type QueryParams []QueryParam
+
+// UnmarshalJSON also reads the parameters of older saved requests, which
+// were a map of keys to values
+func (p *QueryParams) UnmarshalJSON(data []byte) error {
+	var legacy map[string]string
+	if err := json.Unmarshal(data, &legacy); err == nil {
+		keys := make([]string, 0, len(legacy))
+		for key := range legacy {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		*p = nil
+		for _, key := range keys {
+			*p = append(*p, QueryParam{Key: key, Value: legacy[key]})
+		}
+		return nil
+	}
+
+	return json.Unmarshal(data, (*[]QueryParam)(p))
+}
@@ -0,0 +1,49 @@
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestQueryParamsUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  QueryParams
+	}{
+		{"legacy map sorted by key", `{"b": "2", "a": "1", "c": ""}`,
+			QueryParams{{Key: "a", Value: "1"}, {Key: "b", Value: "2"}, {Key: "c", Value: ""}}},
+		{"legacy empty map", `{}`, nil},
+		{"null", `null`, nil},
+		{"list keeps order, repeats and disabled rows", `[
+			{"key": "z", "value": "1"},
+			{"key": "a", "value": "2", "disabled": true},
+			{"key": "z", "value": "3"}
+		]`, QueryParams{{Key: "z", Value: "1"}, {Key: "a", Value: "2", Disabled: true}, {Key: "z", Value: "3"}}},
+		{"empty list", `[]`, QueryParams{}},
+	}
+
+	for _, tt := range tests {
+		var got QueryParams
+		if err := json.Unmarshal([]byte(tt.input), &got); err != nil {
+			t.Errorf("%s: could not decode: %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: expected %+v, got %+v", tt.name, tt.want, got)
+		}
+	}
+
+	var req SavedRequest
+	if err := json.Unmarshal([]byte(`{"parameters": {"page": "1"}}`), &req); err != nil {
+		t.Fatalf("could not decode a legacy request: %v", err)
+	}
+	if want := (QueryParams{{Key: "page", Value: "1"}}); !reflect.DeepEqual(req.Parameters, want) {
+		t.Errorf("expected %+v, got %+v", want, req.Parameters)
+	}
+
+	if err := json.Unmarshal([]byte(`"a=1"`), &QueryParams{}); err == nil {
+		t.Error("expected an error for parameters that are neither a map nor a list")
+	}
+}
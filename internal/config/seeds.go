@@ -0,0 +1,111 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+)
+
+// Seed sets live beside configs.json rather than inside it, since they carry
+// table data that can grow well beyond the size of the connection profiles
+const seedsFile = "seeds.json"
+
+type SeedTable struct {
+	Table string                   `json:"table"`
+	Rows  []map[string]interface{} `json:"rows"`
+}
+
+type SeedSet struct {
+	Name        string      `json:"name"`
+	Description string      `json:"description,omitempty"`
+	Tables      []SeedTable `json:"tables"`
+}
+
+func loadSeedSetsLocked() ([]SeedSet, error) {
+	data, err := os.ReadFile(seedsFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []SeedSet{}, nil
+		}
+		return nil, err
+	}
+
+	// Keep numbers as json.Number so INT64 values survive the round trip
+	var seeds []SeedSet
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&seeds); err != nil {
+		return nil, err
+	}
+	return seeds, nil
+}
+
+func saveSeedSetsLocked(seeds []SeedSet) error {
+	data, err := json.MarshalIndent(seeds, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(seedsFile, data, 0644)
+}
+
+func GetSeedSets() ([]SeedSet, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+	return loadSeedSetsLocked()
+}
+
+func GetSeedSet(name string) (*SeedSet, error) {
+	seeds, err := GetSeedSets()
+	if err != nil {
+		return nil, err
+	}
+	for i := range seeds {
+		if seeds[i].Name == name {
+			return &seeds[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func AddOrUpdateSeedSet(seed SeedSet) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	seeds, err := loadSeedSetsLocked()
+	if err != nil {
+		return err
+	}
+
+	found := false
+	for i, s := range seeds {
+		if s.Name == seed.Name {
+			seeds[i] = seed
+			found = true
+			break
+		}
+	}
+	if !found {
+		seeds = append(seeds, seed)
+	}
+
+	return saveSeedSetsLocked(seeds)
+}
+
+func DeleteSeedSet(name string) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	seeds, err := loadSeedSetsLocked()
+	if err != nil {
+		return err
+	}
+
+	for i, s := range seeds {
+		if s.Name == name {
+			seeds = append(seeds[:i], seeds[i+1:]...)
+			return saveSeedSetsLocked(seeds)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,117 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+)
+
+// Snapshots are kept in their own file for the same reason as seed sets:
+// they hold table data
+const snapshotsFile = "snapshots.json"
+
+// SnapshotTable holds the rows one query returned. Name is the table name
+// for table snapshots or a label for query snapshots; KeyColumns are the
+// columns rows are matched by when two snapshots are compared.
+type SnapshotTable struct {
+	Name       string                   `json:"name"`
+	Query      string                   `json:"query"`
+	KeyColumns []string                 `json:"keyColumns,omitempty"`
+	Columns    []string                 `json:"columns"`
+	Rows       []map[string]interface{} `json:"rows"`
+}
+
+type Snapshot struct {
+	Name      string          `json:"name"`
+	Database  string          `json:"database"`
+	CreatedAt string          `json:"createdAt"`
+	Tables    []SnapshotTable `json:"tables"`
+}
+
+func loadSnapshotsLocked() ([]Snapshot, error) {
+	data, err := os.ReadFile(snapshotsFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []Snapshot{}, nil
+		}
+		return nil, err
+	}
+
+	var snapshots []Snapshot
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&snapshots); err != nil {
+		return nil, err
+	}
+	return snapshots, nil
+}
+
+func saveSnapshotsLocked(snapshots []Snapshot) error {
+	data, err := json.MarshalIndent(snapshots, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(snapshotsFile, data, 0644)
+}
+
+func GetSnapshots() ([]Snapshot, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+	return loadSnapshotsLocked()
+}
+
+func GetSnapshot(name string) (*Snapshot, error) {
+	snapshots, err := GetSnapshots()
+	if err != nil {
+		return nil, err
+	}
+	for i := range snapshots {
+		if snapshots[i].Name == name {
+			return &snapshots[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func AddOrUpdateSnapshot(snapshot Snapshot) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	snapshots, err := loadSnapshotsLocked()
+	if err != nil {
+		return err
+	}
+
+	found := false
+	for i, s := range snapshots {
+		if s.Name == snapshot.Name {
+			snapshots[i] = snapshot
+			found = true
+			break
+		}
+	}
+	if !found {
+		snapshots = append(snapshots, snapshot)
+	}
+
+	return saveSnapshotsLocked(snapshots)
+}
+
+func DeleteSnapshot(name string) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	snapshots, err := loadSnapshotsLocked()
+	if err != nil {
+		return err
+	}
+
+	for i, s := range snapshots {
+		if s.Name == name {
+			snapshots = append(snapshots[:i], snapshots[i+1:]...)
+			return saveSnapshotsLocked(snapshots)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,153 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Query history lives beside configs.json rather than inside it, since it
+// changes with every query and is not worth sharing with the profiles
+const queryHistoryFile = "spanner_history.json"
+
+// maxQueryHistory caps the history kept; the oldest entries are dropped first
+const maxQueryHistory = 200
+
+type SavedQuery struct {
+	Name      string `json:"name"`
+	SQL       string `json:"sql"`
+	UpdatedAt string `json:"updatedAt"`
+}
+
+// QueryHistoryEntry records one successful query run from the Spanner
+// explorer. Connection is the database path the query ran against;
+// ConfigName is the profile that was selected, if any. RowCount is the rows
+// returned, or the rows affected when a script was run.
+type QueryHistoryEntry struct {
+	Timestamp  string `json:"timestamp"`
+	ConfigName string `json:"configName,omitempty"`
+	Connection string `json:"connection"`
+	SQL        string `json:"sql"`
+	Duration   string `json:"duration"`
+	RowCount   int    `json:"rowCount"`
+}
+
+func findSpannerConfigLocked(name string) (*SpannerConfig, error) {
+	for i := range config.SpannerConfigs {
+		if config.SpannerConfigs[i].Name == name {
+			return &config.SpannerConfigs[i], nil
+		}
+	}
+	return nil, fmt.Errorf("spanner configuration %q not found", name)
+}
+
+// SaveSpannerQuery adds or replaces a named query of a Spanner configuration
+func SaveSpannerQuery(configName string, query SavedQuery) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	cfg, err := findSpannerConfigLocked(configName)
+	if err != nil {
+		return err
+	}
+
+	query.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
+
+	for i, q := range cfg.SavedQueries {
+		if q.Name == query.Name {
+			cfg.SavedQueries[i] = query
+			return saveLocked()
+		}
+	}
+	cfg.SavedQueries = append(cfg.SavedQueries, query)
+
+	return saveLocked()
+}
+
+func DeleteSpannerQuery(configName, queryName string) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	cfg, err := findSpannerConfigLocked(configName)
+	if err != nil {
+		return err
+	}
+
+	for i, q := range cfg.SavedQueries {
+		if q.Name == queryName {
+			cfg.SavedQueries = append(cfg.SavedQueries[:i], cfg.SavedQueries[i+1:]...)
+			return saveLocked()
+		}
+	}
+
+	return nil
+}
+
+func loadQueryHistoryLocked() ([]QueryHistoryEntry, error) {
+	data, err := os.ReadFile(queryHistoryFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var history []QueryHistoryEntry
+	if err := json.Unmarshal(data, &history); err != nil {
+		return nil, err
+	}
+	return history, nil
+}
+
+func saveQueryHistoryLocked(history []QueryHistoryEntry) error {
+	data, err := json.MarshalIndent(history, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(queryHistoryFile, data, 0644)
+}
+
+// AddQueryHistory records a query run, newest first. Running the newest
+// query again against the same database refreshes its entry instead of
+// adding another.
+func AddQueryHistory(entry QueryHistoryEntry) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if entry.Timestamp == "" {
+		entry.Timestamp = time.Now().UTC().Format(time.RFC3339)
+	}
+
+	history, err := loadQueryHistoryLocked()
+	if err != nil {
+		return err
+	}
+	if len(history) > 0 && history[0].SQL == entry.SQL &&
+		history[0].Connection == entry.Connection && history[0].ConfigName == entry.ConfigName {
+		history = history[1:]
+	}
+
+	history = append([]QueryHistoryEntry{entry}, history...)
+	if len(history) > maxQueryHistory {
+		history = history[:maxQueryHistory]
+	}
+
+	return saveQueryHistoryLocked(history)
+}
+
+func GetQueryHistory() ([]QueryHistoryEntry, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+	return loadQueryHistoryLocked()
+}
+
+func ClearQueryHistory() error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if err := os.Remove(queryHistoryFile); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
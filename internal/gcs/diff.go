@@ -0,0 +1,580 @@
+package gcs
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"cloudevents-explorer/internal/jsondiff"
+	"cloudevents-explorer/internal/types"
+)
+
+const (
+	// MaxDiffSize caps each side of a diff, after decompression
+	MaxDiffSize = 16 << 20
+	// MaxDiffEdits is the number of differing lines past which a text diff
+	// stops looking for the smallest edit and shows the rest as replaced
+	MaxDiffEdits = 2000
+	// MaxDiffChanges caps the lines, paths or rows a diff returns
+	MaxDiffChanges = 5000
+	// DefaultDiffContext is the number of unchanged lines shown around each
+	// change of a text diff
+	DefaultDiffContext = 3
+)
+
+// Diff modes
+const (
+	DiffText = "text"
+	DiffJSON = "json"
+	DiffCSV  = "csv"
+)
+
+// ObjectRef names an object, or one generation of it
+type ObjectRef struct {
+	Bucket     string
+	Object     string
+	Generation string
+}
+
+// DiffOptions tunes a diff. An empty Mode is guessed from the left object's
+// name. KeyColumns match CSV rows; without them rows are matched by their
+// whole contents. IgnoreWhitespace compares text lines with runs of
+// whitespace collapsed and trimmed.
+type DiffOptions struct {
+	Mode             string
+	KeyColumns       []string
+	IgnoreWhitespace bool
+	Context          int
+}
+
+// DiffMode guesses how to compare an object from its name, ignoring a
+// trailing .gz, or else from its content type
+func DiffMode(name, contentType string) string {
+	name = strings.TrimSuffix(strings.ToLower(name), ".gz")
+	switch {
+	case strings.HasSuffix(name, ".json"):
+		return DiffJSON
+	case strings.HasSuffix(name, ".csv"), strings.HasSuffix(name, ".tsv"):
+		return DiffCSV
+	}
+
+	switch strings.TrimSpace(strings.Split(contentType, ";")[0]) {
+	case "application/json":
+		return DiffJSON
+	case "text/csv", "text/tab-separated-values":
+		return DiffCSV
+	}
+	return DiffText
+}
+
+// readForDiff reads a whole object, decompressing gzip objects
+func (c *Client) readForDiff(ctx context.Context, ref ObjectRef) (*types.Object, []byte, error) {
+	obj, err := c.GetObject(ctx, ref.Bucket, ref.Object, ref.Generation)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !isGzip(obj) && obj.Size > MaxDiffSize {
+		return nil, nil, fmt.Errorf("%s is larger than the %d MB diff limit", ref.Object, MaxDiffSize>>20)
+	}
+
+	rc, err := c.OpenObject(ctx, ref.Bucket, ref.Object, ref.Generation)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rc.Close()
+
+	var r io.Reader = rc
+	if isGzip(obj) {
+		zr, err := gzip.NewReader(rc)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to decompress %s: %w", ref.Object, err)
+		}
+		r = zr
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r, MaxDiffSize+1))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read %s: %w", ref.Object, err)
+	}
+	if len(data) > MaxDiffSize {
+		return nil, nil, fmt.Errorf("%s is larger than the %d MB diff limit", ref.Object, MaxDiffSize>>20)
+	}
+	return obj, data, nil
+}
+
+// Diff compares two objects, which may be in different buckets or be two
+// generations of the same object
+func (c *Client) Diff(ctx context.Context, left, right ObjectRef, opts DiffOptions) (*types.ObjectDiff, error) {
+	leftObj, leftData, err := c.readForDiff(ctx, left)
+	if err != nil {
+		return nil, err
+	}
+	rightObj, rightData, err := c.readForDiff(ctx, right)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Mode == "" {
+		opts.Mode = DiffMode(leftObj.Name, leftObj.ContentType)
+	}
+	if opts.Context < 0 {
+		opts.Context = DefaultDiffContext
+	}
+
+	diff := &types.ObjectDiff{Mode: opts.Mode, Left: *leftObj, Right: *rightObj}
+	switch opts.Mode {
+	case DiffText:
+		diffText(diff, leftData, rightData, opts)
+	case DiffJSON:
+		err = diffJSONObjects(diff, leftData, rightData)
+	case DiffCSV:
+		err = diffCSV(diff, leftData, rightData, opts)
+	default:
+		return nil, fmt.Errorf("unsupported diff mode %q", opts.Mode)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	diff.Identical = diff.Added == 0 && diff.Removed == 0 && diff.Changed == 0
+	return diff, nil
+}
+
+// splitLines splits text into lines without their terminating newlines
+func splitLines(data []byte) []string {
+	if len(data) == 0 {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+}
+
+func diffText(diff *types.ObjectDiff, left, right []byte, opts DiffOptions) {
+	if bytes.Equal(left, right) {
+		return
+	}
+	if !isText(left) || !isText(right) {
+		diff.Changed = 1
+		diff.Note = "The objects hold binary data that differs"
+		return
+	}
+
+	a, b := splitLines(left), splitLines(right)
+
+	// Compare lines by number, counting lines that differ only in whitespace
+	// as the same when asked to
+	ids := map[string]int{}
+	code := func(lines []string) []int {
+		codes := make([]int, len(lines))
+		for i, line := range lines {
+			if opts.IgnoreWhitespace {
+				line = strings.Join(strings.Fields(line), " ")
+			}
+			id, ok := ids[line]
+			if !ok {
+				id = len(ids)
+				ids[line] = id
+			}
+			codes[i] = id
+		}
+		return codes
+	}
+
+	edits, exact := diffLines(code(a), code(b))
+	if !exact {
+		diff.Note = fmt.Sprintf("More than %d lines differ, so part of the diff is shown as replaced rather than matched line by line", MaxDiffEdits)
+	}
+	for _, e := range edits {
+		switch e.op {
+		case '-':
+			diff.Removed++
+		case '+':
+			diff.Added++
+		}
+	}
+	diff.Hunks, diff.Truncated = hunks(edits, a, b, opts.Context)
+}
+
+// edit is one step of an edit script: '=' keeps line a[ai], which is b[bi];
+// '-' deletes a[ai] and '+' inserts b[bi]
+type edit struct {
+	op     byte
+	ai, bi int
+}
+
+// diffLines finds the shortest edit script turning a into b with Myers'
+// algorithm. Past MaxDiffEdits differences it stops searching and replaces
+// what is left, reporting that the script is not exact.
+func diffLines(a, b []int) ([]edit, bool) {
+	// Lines common to both ends need no search
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	edits := make([]edit, 0, len(a)+len(b))
+	for i := 0; i < prefix; i++ {
+		edits = append(edits, edit{'=', i, i})
+	}
+	middle, exact := myers(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])
+	for _, e := range middle {
+		if e.op != '+' {
+			e.ai += prefix
+		}
+		if e.op != '-' {
+			e.bi += prefix
+		}
+		edits = append(edits, e)
+	}
+	for i := suffix; i > 0; i-- {
+		edits = append(edits, edit{'=', len(a) - i, len(b) - i})
+	}
+	return edits, exact
+}
+
+func myers(a, b []int) ([]edit, bool) {
+	n, m := len(a), len(b)
+	limit := n + m
+	if limit > MaxDiffEdits {
+		limit = MaxDiffEdits
+	}
+
+	// v[offset+k] is the furthest x reached on diagonal k; trace keeps v as
+	// it was before each round, over the diagonals that round can reach
+	offset := limit + 1
+	v := make([]int, 2*limit+3)
+	var trace [][]int
+	for d := 0; d <= limit; d++ {
+		trace = append(trace, append([]int(nil), v[offset-d:offset+d+1]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrack(trace, n, m), true
+			}
+		}
+	}
+
+	// Too many differences: delete all of a and insert all of b
+	edits := make([]edit, 0, n+m)
+	for i := 0; i < n; i++ {
+		edits = append(edits, edit{'-', i, -1})
+	}
+	for j := 0; j < m; j++ {
+		edits = append(edits, edit{'+', -1, j})
+	}
+	return edits, false
+}
+
+// backtrack walks the rounds of myers back from the end of both sequences to
+// recover the edit script
+func backtrack(trace [][]int, x, y int) []edit {
+	var reversed []edit
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		at := func(k int) int { return v[k+d] }
+
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := 0
+		if d > 0 {
+			prevX = at(prevK)
+		}
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			x--
+			y--
+			reversed = append(reversed, edit{'=', x, y})
+		}
+		if d > 0 {
+			if x == prevX {
+				reversed = append(reversed, edit{'+', -1, prevY})
+			} else {
+				reversed = append(reversed, edit{'-', prevX, -1})
+			}
+		}
+		x, y = prevX, prevY
+	}
+
+	edits := make([]edit, len(reversed))
+	for i, e := range reversed {
+		edits[len(reversed)-1-i] = e
+	}
+	return edits
+}
+
+// hunks groups the changes of an edit script with context unchanged lines
+// around them, merging changes whose context overlaps. Line numbers count
+// from 1.
+func hunks(edits []edit, a, b []string, context int) ([]types.DiffHunk, bool) {
+	result := []types.DiffHunk{}
+	lines := 0
+	// Line numbers of the first edit not yet passed
+	pos, leftLine, rightLine := 0, 1, 1
+	for i := 0; i < len(edits); {
+		if edits[i].op == '=' {
+			i++
+			continue
+		}
+
+		// Extend the hunk while the next change is within twice the context
+		start := i - context
+		if start < 0 {
+			start = 0
+		}
+		end := i
+		for end < len(edits) {
+			if edits[end].op != '=' {
+				end++
+				continue
+			}
+			next := end
+			for next < len(edits) && edits[next].op == '=' {
+				next++
+			}
+			if next == len(edits) || next-end > 2*context {
+				end += context
+				if end > len(edits) {
+					end = len(edits)
+				}
+				break
+			}
+			end = next
+		}
+
+		for ; pos < start; pos++ {
+			if edits[pos].op != '+' {
+				leftLine++
+			}
+			if edits[pos].op != '-' {
+				rightLine++
+			}
+		}
+		hunk := types.DiffHunk{LeftStart: leftLine, RightStart: rightLine, Lines: []types.DiffLine{}}
+		for _, e := range edits[start:end] {
+			if lines == MaxDiffChanges {
+				result = append(result, hunk)
+				return result, true
+			}
+			lines++
+			switch e.op {
+			case '=':
+				hunk.Lines = append(hunk.Lines, types.DiffLine{Op: "=", Left: e.ai + 1, Right: e.bi + 1, Text: b[e.bi]})
+			case '-':
+				hunk.Lines = append(hunk.Lines, types.DiffLine{Op: "-", Left: e.ai + 1, Text: a[e.ai]})
+			case '+':
+				hunk.Lines = append(hunk.Lines, types.DiffLine{Op: "+", Right: e.bi + 1, Text: b[e.bi]})
+			}
+		}
+		result = append(result, hunk)
+		i = end
+	}
+	return result, false
+}
+
+// parseJSON decodes a single JSON document, keeping numbers as written
+func parseJSON(name string, data []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var value interface{}
+	if err := dec.Decode(&value); err != nil {
+		return nil, fmt.Errorf("%s is not valid JSON: %w", name, err)
+	}
+	if dec.More() {
+		return nil, fmt.Errorf("%s holds more than one JSON document; compare it as text", name)
+	}
+	return value, nil
+}
+
+func diffJSONObjects(diff *types.ObjectDiff, left, right []byte) error {
+	a, err := parseJSON(diff.Left.Name, left)
+	if err != nil {
+		return err
+	}
+	b, err := parseJSON(diff.Right.Name, right)
+	if err != nil {
+		return err
+	}
+
+	diff.JSON = []types.JSONChange{}
+	diffJSON(diff, "$", a, b)
+	return nil
+}
+
+// diffJSON records where two values differ. Objects are compared key by key,
+// whatever their order, and arrays index by index.
+func diffJSON(diff *types.ObjectDiff, path string, a, b interface{}) {
+	add := func(change types.JSONChange) {
+		switch change.Op {
+		case "added":
+			diff.Added++
+		case "removed":
+			diff.Removed++
+		default:
+			diff.Changed++
+		}
+		if len(diff.JSON) == MaxDiffChanges {
+			diff.Truncated = true
+			return
+		}
+		diff.JSON = append(diff.JSON, change)
+	}
+
+	switch x := a.(type) {
+	case map[string]interface{}:
+		y, ok := b.(map[string]interface{})
+		if !ok {
+			break
+		}
+		keys := make([]string, 0, len(x)+len(y))
+		for k := range x {
+			keys = append(keys, k)
+		}
+		for k := range y {
+			if _, ok := x[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			before, inA := x[k]
+			after, inB := y[k]
+			switch {
+			case !inB:
+				add(types.JSONChange{Path: jsondiff.PathKey(path, k), Op: "removed", Before: before})
+			case !inA:
+				add(types.JSONChange{Path: jsondiff.PathKey(path, k), Op: "added", After: after})
+			default:
+				diffJSON(diff, jsondiff.PathKey(path, k), before, after)
+			}
+		}
+		return
+	case []interface{}:
+		y, ok := b.([]interface{})
+		if !ok {
+			break
+		}
+		for i := 0; i < len(x) || i < len(y); i++ {
+			itemPath := path + "[" + strconv.Itoa(i) + "]"
+			switch {
+			case i >= len(y):
+				add(types.JSONChange{Path: itemPath, Op: "removed", Before: x[i]})
+			case i >= len(x):
+				add(types.JSONChange{Path: itemPath, Op: "added", After: y[i]})
+			default:
+				diffJSON(diff, itemPath, x[i], y[i])
+			}
+		}
+		return
+	default:
+		if jsondiff.Equal(a, b) {
+			return
+		}
+	}
+	add(types.JSONChange{Path: path, Op: "changed", Before: a, After: b})
+}
+
+// readCSVRows reads a CSV or TSV object into its header and its records keyed
+// by column name
+func readCSVRows(obj types.Object, data []byte) ([]string, []map[string]interface{}, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	if strings.HasSuffix(strings.TrimSuffix(strings.ToLower(obj.Name), ".gz"), ".tsv") ||
+		strings.HasPrefix(obj.ContentType, "text/tab-separated-values") {
+		reader.Comma = '\t'
+	}
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read %s as CSV: %w", obj.Name, err)
+	}
+	if len(records) == 0 {
+		return []string{}, []map[string]interface{}{}, nil
+	}
+
+	header := records[0]
+	rows := make([]map[string]interface{}, 0, len(records)-1)
+	for _, record := range records[1:] {
+		row := make(map[string]interface{}, len(record))
+		for i, field := range record {
+			name := fmt.Sprintf("column_%d", i+1)
+			if i < len(header) {
+				name = header[i]
+			}
+			row[name] = field
+		}
+		rows = append(rows, row)
+	}
+	return header, rows, nil
+}
+
+// diffCSV compares the records of two CSV or TSV objects, matching them by
+// the key columns when there are any
+func diffCSV(diff *types.ObjectDiff, left, right []byte, opts DiffOptions) error {
+	beforeColumns, before, err := readCSVRows(diff.Left, left)
+	if err != nil {
+		return err
+	}
+	afterColumns, after, err := readCSVRows(diff.Right, right)
+	if err != nil {
+		return err
+	}
+
+	for _, k := range opts.KeyColumns {
+		if !slices.Contains(beforeColumns, k) || !slices.Contains(afterColumns, k) {
+			return fmt.Errorf("key column %q is not in both headers", k)
+		}
+	}
+
+	d := jsondiff.DiffRows(
+		jsondiff.Table{Columns: beforeColumns, Rows: before},
+		jsondiff.Table{Columns: afterColumns, Rows: after}, opts.KeyColumns, MaxDiffChanges)
+
+	rows := &types.TableDiff{
+		Name:       diff.Left.Name,
+		KeyColumns: opts.KeyColumns,
+		Added:      append([]map[string]interface{}{}, d.Added...),
+		Removed:    append([]map[string]interface{}{}, d.Removed...),
+		Changed:    []types.RowDiff{},
+		Unchanged:  d.Unchanged,
+	}
+	for _, c := range d.Changed {
+		rows.Changed = append(rows.Changed, types.RowDiff(c))
+	}
+	if len(opts.KeyColumns) == 0 {
+		rows.Note = "No key columns, so rows are matched by their full contents"
+	}
+	diff.Rows = rows
+
+	diff.Added, diff.Removed, diff.Changed = d.Counts.Added, d.Counts.Removed, d.Counts.Changed
+	diff.Truncated = diff.Added > len(rows.Added) || diff.Removed > len(rows.Removed) || diff.Changed > len(rows.Changed)
+	return nil
+}
@@ -0,0 +1,355 @@
+package gcs
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"cloudevents-explorer/internal/types"
+)
+
+// lineCodes numbers lines as diffText does, so equal lines get equal codes
+func lineCodes(a, b []string) ([]int, []int) {
+	ids := map[string]int{}
+	code := func(lines []string) []int {
+		codes := make([]int, len(lines))
+		for i, line := range lines {
+			if _, ok := ids[line]; !ok {
+				ids[line] = len(ids)
+			}
+			codes[i] = ids[line]
+		}
+		return codes
+	}
+	return code(a), code(b)
+}
+
+// applyEdits checks that an edit script walks both sequences in order and
+// returns the lines it produces
+func applyEdits(t *testing.T, edits []edit, a, b []string) []string {
+	t.Helper()
+	var out []string
+	ai, bi := 0, 0
+	for _, e := range edits {
+		switch e.op {
+		case '=':
+			if e.ai != ai || e.bi != bi || a[e.ai] != b[e.bi] {
+				t.Fatalf("keep %+v out of order or of different lines at %d,%d", e, ai, bi)
+			}
+			out = append(out, a[ai])
+			ai++
+			bi++
+		case '-':
+			if e.ai != ai {
+				t.Fatalf("delete %+v out of order at %d", e, ai)
+			}
+			ai++
+		case '+':
+			if e.bi != bi {
+				t.Fatalf("insert %+v out of order at %d", e, bi)
+			}
+			out = append(out, b[bi])
+			bi++
+		}
+	}
+	if ai != len(a) || bi != len(b) {
+		t.Fatalf("edit script stops at %d,%d of %d,%d", ai, bi, len(a), len(b))
+	}
+	return out
+}
+
+func TestDiffLines(t *testing.T) {
+	tests := []struct {
+		name  string
+		a, b  string
+		edits int
+	}{
+		{"both empty", "", "", 0},
+		{"identical", "a b c", "a b c", 0},
+		{"insert into empty", "", "a b", 2},
+		{"delete everything", "a b", "", 2},
+		{"insert in the middle", "a b c", "a x b c", 1},
+		{"delete at the end", "a b c", "a b", 1},
+		{"replace one line", "a b c", "a x c", 2},
+		{"move a line", "a b c d", "b c d a", 2},
+		{"classic example", "a b c a b b a", "c b a b a c", 5},
+		{"repeated lines", "x x x y", "y x x x", 2},
+	}
+
+	for _, tt := range tests {
+		a, b := strings.Fields(tt.a), strings.Fields(tt.b)
+		codesA, codesB := lineCodes(a, b)
+		edits, exact := diffLines(codesA, codesB)
+		if !exact {
+			t.Errorf("%s: expected an exact diff", tt.name)
+		}
+		if got := strings.Join(applyEdits(t, edits, a, b), " "); got != strings.Join(b, " ") {
+			t.Errorf("%s: edits produce %q, want %q", tt.name, got, tt.b)
+		}
+		changes := 0
+		for _, e := range edits {
+			if e.op != '=' {
+				changes++
+			}
+		}
+		if changes != tt.edits {
+			t.Errorf("%s: expected %d changes, got %d", tt.name, tt.edits, changes)
+		}
+	}
+}
+
+func TestDiffLinesPastTheEditLimit(t *testing.T) {
+	var a, b []string
+	for i := 0; i < MaxDiffEdits; i++ {
+		a = append(a, fmt.Sprintf("a%d", i))
+		b = append(b, fmt.Sprintf("b%d", i))
+	}
+	// A shared first and last line are still matched outside the search
+	a = append(append([]string{"same"}, a...), "end")
+	b = append(append([]string{"same"}, b...), "end")
+
+	codesA, codesB := lineCodes(a, b)
+	edits, exact := diffLines(codesA, codesB)
+	if exact {
+		t.Fatalf("expected the diff to give up past %d edits", MaxDiffEdits)
+	}
+	if got := applyEdits(t, edits, a, b); strings.Join(got, "\n") != strings.Join(b, "\n") {
+		t.Fatalf("edits do not produce the right side")
+	}
+	if edits[0].op != '=' || edits[len(edits)-1].op != '=' {
+		t.Errorf("expected the common first and last lines to be kept")
+	}
+}
+
+func TestHunks(t *testing.T) {
+	lines := func(n int, change map[int]string) ([]string, []string) {
+		var a, b []string
+		for i := 1; i <= n; i++ {
+			a = append(a, fmt.Sprintf("line %d", i))
+			if text, ok := change[i]; ok {
+				if text != "" {
+					b = append(b, text)
+				}
+				continue
+			}
+			b = append(b, fmt.Sprintf("line %d", i))
+		}
+		return a, b
+	}
+
+	tests := []struct {
+		name    string
+		n       int
+		change  map[int]string
+		context int
+		// starts holds the left and right start line of each hunk
+		starts [][2]int
+		sizes  []int
+	}{
+		{"no changes", 10, nil, 3, nil, nil},
+		{"one change in the middle", 20, map[int]string{10: "ten"}, 3, [][2]int{{7, 7}}, []int{8}},
+		{"change at the start", 10, map[int]string{1: "one"}, 3, [][2]int{{1, 1}}, []int{5}},
+		{"change at the end", 10, map[int]string{10: "ten"}, 3, [][2]int{{7, 7}}, []int{5}},
+		{"close changes share a hunk", 20, map[int]string{5: "five", 10: "ten"}, 3, [][2]int{{2, 2}}, []int{14}},
+		{"far changes get two hunks", 40, map[int]string{5: "five", 30: "thirty"}, 3, [][2]int{{2, 2}, {27, 27}}, []int{8, 8}},
+		{"deleted line shifts the right side", 40, map[int]string{5: "", 30: "thirty"}, 3, [][2]int{{2, 2}, {27, 26}}, []int{7, 8}},
+		{"no context", 10, map[int]string{5: "five"}, 0, [][2]int{{5, 5}}, []int{2}},
+	}
+
+	for _, tt := range tests {
+		a, b := lines(tt.n, tt.change)
+		codesA, codesB := lineCodes(a, b)
+		edits, _ := diffLines(codesA, codesB)
+		got, truncated := hunks(edits, a, b, tt.context)
+		if truncated {
+			t.Errorf("%s: unexpected truncation", tt.name)
+		}
+		if len(got) != len(tt.starts) {
+			t.Errorf("%s: expected %d hunks, got %d", tt.name, len(tt.starts), len(got))
+			continue
+		}
+		for i, h := range got {
+			if h.LeftStart != tt.starts[i][0] || h.RightStart != tt.starts[i][1] {
+				t.Errorf("%s: hunk %d starts at %d,%d, want %v", tt.name, i, h.LeftStart, h.RightStart, tt.starts[i])
+			}
+			if len(h.Lines) != tt.sizes[i] {
+				t.Errorf("%s: hunk %d has %d lines, want %d", tt.name, i, len(h.Lines), tt.sizes[i])
+			}
+			// Line numbers follow on from the hunk start
+			left, right := h.LeftStart, h.RightStart
+			for _, line := range h.Lines {
+				if line.Op != "+" {
+					if line.Left != left || a[left-1] != line.Text {
+						t.Errorf("%s: line %+v, want left line %d", tt.name, line, left)
+					}
+					left++
+				}
+				if line.Op != "-" {
+					if line.Right != right || b[right-1] != line.Text {
+						t.Errorf("%s: line %+v, want right line %d", tt.name, line, right)
+					}
+					right++
+				}
+			}
+		}
+	}
+}
+
+func TestDiffText(t *testing.T) {
+	tests := []struct {
+		name             string
+		left, right      string
+		ignoreWhitespace bool
+		added, removed   int
+		changed          int
+	}{
+		{"identical", "a\nb\n", "a\nb\n", false, 0, 0, 0},
+		{"trailing newline only", "a\nb\n", "a\nb", false, 0, 0, 0},
+		{"one line replaced", "a\nb\nc\n", "a\nB\nc\n", false, 1, 1, 0},
+		{"whitespace counts", "a  b\n", "a b\n", false, 1, 1, 0},
+		{"whitespace ignored", "a  b \n", " a b\n", true, 0, 0, 0},
+		{"binary", "a\x00b", "a\x00c", false, 0, 0, 1},
+	}
+
+	for _, tt := range tests {
+		diff := &types.ObjectDiff{}
+		diffText(diff, []byte(tt.left), []byte(tt.right), DiffOptions{IgnoreWhitespace: tt.ignoreWhitespace, Context: 3})
+		if diff.Added != tt.added || diff.Removed != tt.removed || diff.Changed != tt.changed {
+			t.Errorf("%s: expected +%d -%d ~%d, got +%d -%d ~%d", tt.name, tt.added, tt.removed, tt.changed, diff.Added, diff.Removed, diff.Changed)
+		}
+	}
+}
+
+func TestDiffJSON(t *testing.T) {
+	tests := []struct {
+		name        string
+		left, right string
+		want        []string
+	}{
+		{"key order does not matter", `{"a": 1, "b": 2}`, `{"b": 2, "a": 1}`, nil},
+		{"numbers by value", `{"a": 1.0}`, `{"a": 1}`, nil},
+		{"changed value", `{"a": {"b": 1}}`, `{"a": {"b": 2}}`, []string{"changed $.a.b"}},
+		{"added and removed keys", `{"a": 1, "b": 2}`, `{"b": 2, "c": 3}`, []string{"removed $.a", "added $.c"}},
+		{"awkward key", `{"a b": 1}`, `{"a b": 2}`, []string{`changed $["a b"]`}},
+		{"array items", `[1, 2, 3]`, `[1, 5]`, []string{"changed $[1]", "removed $[2]"}},
+		{"type change", `{"a": [1]}`, `{"a": {"0": 1}}`, []string{"changed $.a"}},
+		{"null and missing differ", `{"a": null}`, `{}`, []string{"removed $.a"}},
+	}
+
+	for _, tt := range tests {
+		diff := &types.ObjectDiff{Left: types.Object{Name: "left.json"}, Right: types.Object{Name: "right.json"}}
+		if err := diffJSONObjects(diff, []byte(tt.left), []byte(tt.right)); err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+			continue
+		}
+		var got []string
+		for _, change := range diff.JSON {
+			got = append(got, change.Op+" "+change.Path)
+		}
+		if strings.Join(got, ", ") != strings.Join(tt.want, ", ") {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+		if diff.Added+diff.Removed+diff.Changed != len(tt.want) {
+			t.Errorf("%s: counts +%d -%d ~%d do not match %v", tt.name, diff.Added, diff.Removed, diff.Changed, tt.want)
+		}
+	}
+}
+
+func TestDiffJSONRejectsInvalidDocuments(t *testing.T) {
+	for _, data := range []string{`{"a": `, `{} {}`} {
+		diff := &types.ObjectDiff{Left: types.Object{Name: "left.json"}, Right: types.Object{Name: "right.json"}}
+		if err := diffJSONObjects(diff, []byte(data), []byte(`{}`)); err == nil {
+			t.Errorf("expected an error for %q", data)
+		}
+	}
+}
+
+func TestDiffCSV(t *testing.T) {
+	tests := []struct {
+		name        string
+		left, right string
+		tsv         bool
+		keys        []string
+		added       int
+		removed     int
+		changed     int
+		unchanged   int
+		wantErr     bool
+	}{
+		{
+			name:      "keyed rows",
+			left:      "id,name\n1,a\n2,b\n3,c\n",
+			right:     "id,name\n3,c\n2,B\n4,d\n",
+			keys:      []string{"id"},
+			added:     1,
+			removed:   1,
+			changed:   1,
+			unchanged: 1,
+		},
+		{
+			name:      "rows matched by contents",
+			left:      "id,name\n1,a\n2,b\n",
+			right:     "id,name\n2,b\n1,A\n",
+			added:     1,
+			removed:   1,
+			unchanged: 1,
+		},
+		{
+			name:    "tab separated",
+			left:    "id\tname\n1\ta\n",
+			right:   "id\tname\n1\tb\n",
+			tsv:     true,
+			keys:    []string{"id"},
+			changed: 1,
+		},
+		{
+			name:    "key column missing on one side",
+			left:    "id,name\n1,a\n",
+			right:   "key,name\n1,a\n",
+			keys:    []string{"id"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		name := "data.csv"
+		if tt.tsv {
+			name = "data.tsv"
+		}
+		diff := &types.ObjectDiff{Left: types.Object{Name: name}, Right: types.Object{Name: name}}
+		err := diffCSV(diff, []byte(tt.left), []byte(tt.right), DiffOptions{KeyColumns: tt.keys})
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: expected an error", tt.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+			continue
+		}
+		if diff.Added != tt.added || diff.Removed != tt.removed || diff.Changed != tt.changed || diff.Rows.Unchanged != tt.unchanged {
+			t.Errorf("%s: expected +%d -%d ~%d =%d, got +%d -%d ~%d =%d", tt.name, tt.added, tt.removed, tt.changed, tt.unchanged,
+				diff.Added, diff.Removed, diff.Changed, diff.Rows.Unchanged)
+		}
+	}
+}
+
+func TestDiffMode(t *testing.T) {
+	tests := []struct {
+		name, contentType, want string
+	}{
+		{"data.json", "", DiffJSON},
+		{"data.JSON.gz", "application/gzip", DiffJSON},
+		{"rows.tsv", "", DiffCSV},
+		{"object", "application/json; charset=utf-8", DiffJSON},
+		{"object", "text/csv", DiffCSV},
+		{"notes.txt", "text/plain", DiffText},
+	}
+
+	for _, tt := range tests {
+		if got := DiffMode(tt.name, tt.contentType); got != tt.want {
+			t.Errorf("DiffMode(%q, %q): expected %s, got %s", tt.name, tt.contentType, tt.want, got)
+		}
+	}
+}
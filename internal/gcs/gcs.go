@@ -0,0 +1,372 @@
+package gcs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/oauth2/google"
+
+	"cloudevents-explorer/internal/types"
+)
+
+const (
+	// storageAPI is the JSON API of real GCS
+	storageAPI = "https://storage.googleapis.com"
+	// storageScope is the OAuth scope requested from Application Default
+	// Credentials
+	storageScope = "https://www.googleapis.com/auth/devstorage.full_control"
+)
+
+// Client talks to the GCS JSON API, either on fake-gcs-server or on real GCS
+type Client struct {
+	http      *http.Client
+	endpoint  string
+	projectID string
+	emulator  bool
+	onChange  ChangeFunc
+}
+
+// NewClient returns a client for the connection. With an emulator host it
+// talks plain HTTP without credentials; without one it uses Application
+// Default Credentials against real GCS.
+func NewClient(ctx context.Context, conn types.GCSConnection) (*Client, error) {
+	if conn.EmulatorHost != "" {
+		endpoint := strings.TrimRight(conn.EmulatorHost, "/")
+		if !strings.Contains(endpoint, "://") {
+			endpoint = "http://" + endpoint
+		}
+		return &Client{http: http.DefaultClient, endpoint: endpoint, projectID: conn.ProjectID, emulator: true}, nil
+	}
+
+	httpClient, err := google.DefaultClient(ctx, storageScope)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load application default credentials: %w", err)
+	}
+	return &Client{http: httpClient, endpoint: storageAPI, projectID: conn.ProjectID}, nil
+}
+
+// objectURL returns the JSON API URL of an object; object names may contain
+// slashes, which must be escaped as part of the path segment
+func (c *Client) objectURL(bucket, object string) string {
+	return fmt.Sprintf("%s/storage/v1/b/%s/o/%s", c.endpoint, url.PathEscape(bucket), url.PathEscape(object))
+}
+
+// do sends a request and fails on any non-2xx status
+func (c *Client) do(ctx context.Context, method, rawURL string, body io.Reader, contentType string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, body)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		defer resp.Body.Close()
+		return nil, apiError(resp)
+	}
+	return resp, nil
+}
+
+func (c *Client) get(ctx context.Context, rawURL string) (*http.Response, error) {
+	return c.do(ctx, http.MethodGet, rawURL, nil, "")
+}
+
+func (c *Client) getJSON(ctx context.Context, rawURL string, v interface{}) error {
+	resp, err := c.get(ctx, rawURL)
+	if err != nil {
+		return err
+	}
+	return decodeJSON(resp, v)
+}
+
+// sendJSON sends a request with an optional JSON body and decodes the JSON
+// response into v when v is not nil
+func (c *Client) sendJSON(ctx context.Context, method, rawURL string, body, v interface{}) error {
+	var reader io.Reader
+	contentType := ""
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+		contentType = "application/json"
+	}
+
+	resp, err := c.do(ctx, method, rawURL, reader, contentType)
+	if err != nil {
+		return err
+	}
+	if v == nil {
+		resp.Body.Close()
+		return nil
+	}
+	return decodeJSON(resp, v)
+}
+
+func decodeJSON(resp *http.Response, v interface{}) error {
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+	return nil
+}
+
+// apiError turns an error response into an error, using the message of the
+// JSON API error body when there is one
+func apiError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+
+	var apiErr struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(body, &apiErr) == nil && apiErr.Error.Message != "" {
+		return fmt.Errorf("%s: %s", resp.Status, apiErr.Error.Message)
+	}
+	if msg := strings.TrimSpace(string(body)); msg != "" {
+		return fmt.Errorf("%s: %s", resp.Status, msg)
+	}
+	return fmt.Errorf("%s", resp.Status)
+}
+
+// ListBuckets returns the buckets of the client's project. The emulator has
+// no projects, so there the project is optional.
+func (c *Client) ListBuckets(ctx context.Context) ([]types.Bucket, error) {
+	if c.projectID == "" && !c.emulator {
+		return nil, fmt.Errorf("a project ID is required to list buckets")
+	}
+
+	buckets := []types.Bucket{}
+	pageToken := ""
+	for {
+		params := url.Values{}
+		if c.projectID != "" {
+			params.Set("project", c.projectID)
+		}
+		if pageToken != "" {
+			params.Set("pageToken", pageToken)
+		}
+
+		var page struct {
+			Items []struct {
+				Name         string `json:"name"`
+				Location     string `json:"location"`
+				StorageClass string `json:"storageClass"`
+			} `json:"items"`
+			NextPageToken string `json:"nextPageToken"`
+		}
+		if err := c.getJSON(ctx, c.endpoint+"/storage/v1/b?"+params.Encode(), &page); err != nil {
+			return nil, err
+		}
+
+		for _, item := range page.Items {
+			buckets = append(buckets, types.Bucket{
+				Name:         item.Name,
+				Location:     item.Location,
+				StorageClass: item.StorageClass,
+			})
+		}
+
+		if page.NextPageToken == "" {
+			return buckets, nil
+		}
+		pageToken = page.NextPageToken
+	}
+}
+
+// errStopListing ends listPages early without an error
+var errStopListing = errors.New("stop listing")
+
+// listPages lists objects with the given query parameters, calling fn with
+// each page until the last one, or until fn returns errStopListing
+func (c *Client) listPages(ctx context.Context, bucket string, params url.Values, fn func(items []types.Object, prefixes []string) error) error {
+	for {
+		var page struct {
+			Items         []types.Object `json:"items"`
+			Prefixes      []string       `json:"prefixes"`
+			NextPageToken string         `json:"nextPageToken"`
+		}
+		rawURL := fmt.Sprintf("%s/storage/v1/b/%s/o?%s", c.endpoint, url.PathEscape(bucket), params.Encode())
+		if err := c.getJSON(ctx, rawURL, &page); err != nil {
+			return err
+		}
+
+		if err := fn(page.Items, page.Prefixes); err != nil {
+			if err == errStopListing {
+				return nil
+			}
+			return err
+		}
+
+		if page.NextPageToken == "" {
+			return nil
+		}
+		params.Set("pageToken", page.NextPageToken)
+	}
+}
+
+// listAll lists objects with the given query parameters, following
+// nextPageToken until the last page
+func (c *Client) listAll(ctx context.Context, bucket string, params url.Values) ([]types.Object, []string, error) {
+	items := []types.Object{}
+	prefixes := []string{}
+	seen := map[string]bool{}
+	err := c.listPages(ctx, bucket, params, func(pageItems []types.Object, pagePrefixes []string) error {
+		items = append(items, pageItems...)
+		for _, p := range pagePrefixes {
+			if !seen[p] {
+				seen[p] = true
+				prefixes = append(prefixes, p)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return items, prefixes, nil
+}
+
+// ListObjects lists one level of a bucket: the objects directly under prefix
+// and the prefixes of the "folders" below it
+func (c *Client) ListObjects(ctx context.Context, bucket, prefix string) (*types.ObjectsResponse, error) {
+	params := url.Values{"delimiter": {"/"}}
+	if prefix != "" {
+		params.Set("prefix", prefix)
+	}
+
+	items, prefixes, err := c.listAll(ctx, bucket, params)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &types.ObjectsResponse{}
+	if len(items) > 0 {
+		response.Items = items
+	}
+	if len(prefixes) > 0 {
+		response.Prefixes = prefixes
+	}
+	return response, nil
+}
+
+// listNames returns the names of every object under prefix
+func (c *Client) listNames(ctx context.Context, bucket, prefix string) ([]string, error) {
+	params := url.Values{"fields": {"items(name),nextPageToken"}}
+	if prefix != "" {
+		params.Set("prefix", prefix)
+	}
+
+	items, _, err := c.listAll(ctx, bucket, params)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, len(items))
+	for i, item := range items {
+		names[i] = item.Name
+	}
+	return names, nil
+}
+
+// GetObject returns the metadata of an object, or of one of its generations
+// when generation is not empty
+func (c *Client) GetObject(ctx context.Context, bucket, object, generation string) (*types.Object, error) {
+	rawURL := c.objectURL(bucket, object)
+	if generation != "" {
+		rawURL += "?generation=" + url.QueryEscape(generation)
+	}
+
+	var obj types.Object
+	if err := c.getJSON(ctx, rawURL, &obj); err != nil {
+		return nil, err
+	}
+	return &obj, nil
+}
+
+// UpdateMetadata replaces the custom metadata of an object. A patch only
+// merges keys, so keys missing from metadata are removed by patching them
+// to null.
+func (c *Client) UpdateMetadata(ctx context.Context, bucket, object string, metadata map[string]string) (*types.Object, error) {
+	current, err := c.GetObject(ctx, bucket, object, "")
+	if err != nil {
+		return nil, err
+	}
+
+	patch := map[string]interface{}{}
+	for k := range current.Metadata {
+		patch[k] = nil
+	}
+	for k, v := range metadata {
+		patch[k] = v
+	}
+
+	var obj types.Object
+	body := map[string]interface{}{"metadata": patch}
+	if err := c.sendJSON(ctx, http.MethodPatch, c.objectURL(bucket, object), body, &obj); err != nil {
+		return nil, err
+	}
+	c.changed(ctx, EventMetadataUpdate, bucket, &obj)
+	return &obj, nil
+}
+
+// ListVersions reports whether the bucket keeps object versions and returns
+// every generation of an object, live and noncurrent, newest first
+func (c *Client) ListVersions(ctx context.Context, bucket, object string) (*types.ObjectVersionsResponse, error) {
+	var b struct {
+		Versioning struct {
+			Enabled bool `json:"enabled"`
+		} `json:"versioning"`
+	}
+	if err := c.getJSON(ctx, fmt.Sprintf("%s/storage/v1/b/%s", c.endpoint, url.PathEscape(bucket)), &b); err != nil {
+		return nil, err
+	}
+
+	items, _, err := c.listAll(ctx, bucket, url.Values{"versions": {"true"}, "prefix": {object}})
+	if err != nil {
+		return nil, err
+	}
+
+	versions := []types.Object{}
+	for _, item := range items {
+		if item.Name == object {
+			versions = append(versions, item)
+		}
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		gi, _ := strconv.ParseInt(versions[i].Generation, 10, 64)
+		gj, _ := strconv.ParseInt(versions[j].Generation, 10, 64)
+		return gi > gj
+	})
+
+	return &types.ObjectVersionsResponse{VersioningEnabled: b.Versioning.Enabled, Versions: versions}, nil
+}
+
+// OpenObject returns the content of an object, or of one of its generations
+// when generation is not empty. The caller closes it.
+func (c *Client) OpenObject(ctx context.Context, bucket, object, generation string) (io.ReadCloser, error) {
+	params := url.Values{"alt": {"media"}}
+	if generation != "" {
+		params.Set("generation", generation)
+	}
+	resp, err := c.get(ctx, c.objectURL(bucket, object)+"?"+params.Encode())
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
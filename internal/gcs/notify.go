@@ -0,0 +1,412 @@
+package gcs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"cloudevents-explorer/internal/config"
+	"cloudevents-explorer/internal/pubsub"
+	"cloudevents-explorer/internal/types"
+)
+
+// Notification event types, as GCS names them
+const (
+	EventFinalize       = "OBJECT_FINALIZE"
+	EventDelete         = "OBJECT_DELETE"
+	EventMetadataUpdate = "OBJECT_METADATA_UPDATE"
+)
+
+// Notification payload formats
+const (
+	PayloadJSONAPIV1 = "JSON_API_V1"
+	PayloadNone      = "NONE"
+)
+
+// publishTimeout bounds a publish, which waits on an unreachable emulator
+// until its context ends
+const publishTimeout = 10 * time.Second
+
+// ChangeFunc is told about every object a client writes, deletes or updates
+// the metadata of
+type ChangeFunc func(ctx context.Context, event, bucket string, obj types.Object)
+
+// OnChange sets the function told about the client's changes
+func (c *Client) OnChange(fn ChangeFunc) {
+	c.onChange = fn
+}
+
+func (c *Client) changed(ctx context.Context, event, bucket string, obj *types.Object) {
+	if c.onChange != nil && obj != nil {
+		c.onChange(ctx, event, bucket, *obj)
+	}
+}
+
+// SameHost reports whether two emulator hosts are the same, written with or
+// without a scheme
+func SameHost(a, b string) bool {
+	return normalizeHost(a) == normalizeHost(b)
+}
+
+func normalizeHost(host string) string {
+	host = strings.TrimRight(strings.ToLower(host), "/")
+	if i := strings.Index(host, "://"); i != -1 {
+		host = host[i+3:]
+	}
+	return host
+}
+
+// notificationApplies reports whether a notification wants an event on an
+// object of the connection's bucket
+func notificationApplies(n config.GCSNotification, conn types.GCSConnection, event, bucket, name string) bool {
+	if n.Bucket != bucket || !SameHost(n.GCSEmulatorHost, conn.EmulatorHost) {
+		return false
+	}
+	if !strings.HasPrefix(name, n.ObjectNamePrefix) {
+		return false
+	}
+	if len(n.EventTypes) == 0 {
+		return true
+	}
+	for _, t := range n.EventTypes {
+		if t == event {
+			return true
+		}
+	}
+	return false
+}
+
+// NotificationMessage builds the Pub/Sub message GCS publishes for an event:
+// the attributes GCS sets, and with JSON_API_V1 the object resource as data.
+// extra holds attributes such as overwroteGeneration that only some events
+// carry.
+func NotificationMessage(n config.GCSNotification, event, bucket string, obj types.Object, extra map[string]string) ([]byte, map[string]string, error) {
+	eventTime := obj.Updated
+	if event == EventDelete || eventTime == "" {
+		eventTime = time.Now().UTC().Format(time.RFC3339Nano)
+	}
+
+	payloadFormat := n.PayloadFormat
+	if payloadFormat == "" {
+		payloadFormat = PayloadJSONAPIV1
+	}
+
+	attributes := map[string]string{}
+	for k, v := range n.CustomAttributes {
+		attributes[k] = v
+	}
+	for k, v := range extra {
+		attributes[k] = v
+	}
+	attributes["notificationConfig"] = fmt.Sprintf("projects/_/buckets/%s/notificationConfigs/%s", bucket, n.ID)
+	attributes["eventType"] = event
+	attributes["payloadFormat"] = payloadFormat
+	attributes["bucketId"] = bucket
+	attributes["objectId"] = obj.Name
+	attributes["objectGeneration"] = obj.Generation
+	attributes["eventTime"] = eventTime
+
+	if payloadFormat == PayloadNone {
+		return nil, attributes, nil
+	}
+
+	selfLink := fmt.Sprintf("https://www.googleapis.com/storage/v1/b/%s/o/%s", bucket, url.PathEscape(obj.Name))
+	payload := struct {
+		Kind      string `json:"kind"`
+		ID        string `json:"id"`
+		SelfLink  string `json:"selfLink"`
+		MediaLink string `json:"mediaLink"`
+		Bucket    string `json:"bucket"`
+		types.Object
+	}{
+		Kind:      "storage#object",
+		ID:        bucket + "/" + obj.Name + "/" + obj.Generation,
+		SelfLink:  selfLink,
+		MediaLink: "https://storage.googleapis.com/download/storage/v1/b/" + bucket + "/o/" + url.PathEscape(obj.Name) + "?generation=" + obj.Generation + "&alt=media",
+		Bucket:    bucket,
+		Object:    obj,
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, attributes, nil
+}
+
+// publisher keeps the Pub/Sub clients notifications are published with
+var publisher = pubsub.NewPublisher()
+
+// ClosePublisher closes the Pub/Sub clients notifications were published
+// with, once the server has stopped
+func ClosePublisher() {
+	publisher.Close()
+}
+
+// publishNotification publishes one event for a notification
+func publishNotification(ctx context.Context, n config.GCSNotification, event, bucket string, obj types.Object, extra map[string]string) error {
+	data, attributes, err := NotificationMessage(n, event, bucket, obj, extra)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, publishTimeout)
+	defer cancel()
+	_, err = publisher.Publish(ctx, pubsub.PublishParams{
+		EmulatorHost: n.PubSubEmulatorHost,
+		ProjectID:    n.PubSubProjectID,
+		TopicID:      n.TopicID,
+		Data:         data,
+		Attributes:   attributes,
+	})
+	if err != nil {
+		return fmt.Errorf("notification %s to topic %s: %w", n.ID, n.TopicID, err)
+	}
+	return nil
+}
+
+// Notifier publishes the notifications configured for a connection when a
+// client reports a change, and counts what it published for the response
+type Notifier struct {
+	conn types.GCSConnection
+
+	mu        sync.Mutex
+	published int
+	errors    []string
+}
+
+func NewNotifier(conn types.GCSConnection) *Notifier {
+	return &Notifier{conn: conn}
+}
+
+// Notify is a ChangeFunc
+func (n *Notifier) Notify(ctx context.Context, event, bucket string, obj types.Object) {
+	for _, notification := range config.Get().GCSNotifications {
+		if !notificationApplies(notification, n.conn, event, bucket, obj.Name) {
+			continue
+		}
+
+		// A watched bucket's poll may find the same change
+		if notification.Watch && !watchState.claim(notification.ID, event, obj) {
+			continue
+		}
+
+		err := publishNotification(ctx, notification, event, bucket, obj, nil)
+
+		n.mu.Lock()
+		if err != nil {
+			log.Printf("GCS notification failed: %v", err)
+			n.errors = append(n.errors, err.Error())
+		} else {
+			n.published++
+		}
+		n.mu.Unlock()
+	}
+}
+
+// Result returns the number of notifications published and the errors of
+// those that failed
+func (n *Notifier) Result() (int, string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.published, strings.Join(n.errors, "; ")
+}
+
+// watchedObjects holds the last listing of each watched notification's
+// bucket, by notification ID and object name, and the last change published
+// for each object
+type watchedObjects struct {
+	mu        sync.Mutex
+	snapshots map[string]map[string]types.Object
+	published map[string]map[string]string
+
+	// The poller's context and interval, and whether it is polling
+	ctx      context.Context
+	interval time.Duration
+	running  bool
+}
+
+var watchState = &watchedObjects{
+	snapshots: map[string]map[string]types.Object{},
+	published: map[string]map[string]string{},
+}
+
+// claim reports whether a change to an object has not been published for a
+// notification yet, and marks it published. Changes are told apart by the
+// object's generation and metageneration, so a change the studio made and
+// published is not published again when the next poll finds it.
+func (w *watchedObjects) claim(id, event string, obj types.Object) bool {
+	change := event + "/" + obj.Generation
+	if event == EventMetadataUpdate {
+		change += "/" + obj.Metageneration
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	published, ok := w.published[id]
+	if !ok {
+		published = map[string]string{}
+		w.published[id] = published
+	}
+	if published[obj.Name] == change {
+		return false
+	}
+	published[obj.Name] = change
+	return true
+}
+
+// watchedNotifications returns the notifications with Watch set
+func watchedNotifications() []config.GCSNotification {
+	var watched []config.GCSNotification
+	for _, n := range config.Get().GCSNotifications {
+		if n.Watch {
+			watched = append(watched, n)
+		}
+	}
+	return watched
+}
+
+// StartWatching polls the buckets of notifications with Watch set every
+// interval, and publishes the changes found between polls, until ctx is
+// done. Polling only runs while a notification is watched; Rewatch starts it
+// again after notifications change.
+func StartWatching(ctx context.Context, interval time.Duration) {
+	watchState.mu.Lock()
+	watchState.ctx = ctx
+	watchState.interval = interval
+	watchState.mu.Unlock()
+
+	Rewatch()
+}
+
+// Rewatch starts polling when a notification is watched and polling is not
+// running already
+func Rewatch() {
+	watchState.mu.Lock()
+	defer watchState.mu.Unlock()
+
+	if watchState.ctx == nil || watchState.ctx.Err() != nil || watchState.running {
+		return
+	}
+	if len(watchedNotifications()) == 0 {
+		return
+	}
+	watchState.running = true
+	go watchNotifications(watchState.ctx, watchState.interval)
+}
+
+// watchNotifications polls until ctx is done or no notification is watched.
+// The first poll of a bucket only records what is there.
+func watchNotifications(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		watched := watchedNotifications()
+		active := map[string]bool{}
+		for _, n := range watched {
+			active[n.ID] = true
+			if err := pollNotification(ctx, n); err != nil {
+				log.Printf("GCS notification %s: failed to poll bucket %s: %v", n.ID, n.Bucket, err)
+			}
+		}
+
+		// Forget notifications that were deleted or stopped watching. Once
+		// none are left polling stops; the check is repeated under the lock
+		// so a notification saved meanwhile is not missed by Rewatch.
+		watchState.mu.Lock()
+		for id := range watchState.snapshots {
+			if !active[id] {
+				delete(watchState.snapshots, id)
+			}
+		}
+		for id := range watchState.published {
+			if !active[id] {
+				delete(watchState.published, id)
+			}
+		}
+		if ctx.Err() != nil || len(watchedNotifications()) == 0 {
+			watchState.running = false
+			watchState.mu.Unlock()
+			return
+		}
+		watchState.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			watchState.mu.Lock()
+			watchState.running = false
+			watchState.mu.Unlock()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// pollNotification lists a watched bucket and publishes what changed since
+// the previous listing
+func pollNotification(ctx context.Context, n config.GCSNotification) error {
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+
+	client, err := NewClient(ctx, types.GCSConnection{EmulatorHost: n.GCSEmulatorHost, ProjectID: n.GCSProjectID})
+	if err != nil {
+		return err
+	}
+	params := url.Values{}
+	if n.ObjectNamePrefix != "" {
+		params.Set("prefix", n.ObjectNamePrefix)
+	}
+	items, _, err := client.listAll(ctx, n.Bucket, params)
+	if err != nil {
+		return err
+	}
+
+	current := make(map[string]types.Object, len(items))
+	listed := make(map[string]bool, len(items))
+	for _, item := range items {
+		current[item.Name] = item
+		listed[item.Name] = true
+	}
+
+	watchState.mu.Lock()
+	previous, ok := watchState.snapshots[n.ID]
+	watchState.snapshots[n.ID] = current
+	watchState.mu.Unlock()
+	if !ok {
+		return nil
+	}
+
+	conn := types.GCSConnection{EmulatorHost: n.GCSEmulatorHost}
+	publish := func(event string, obj types.Object, extra map[string]string) {
+		if !notificationApplies(n, conn, event, n.Bucket, obj.Name) || !watchState.claim(n.ID, event, obj) {
+			return
+		}
+		if err := publishNotification(ctx, n, event, n.Bucket, obj, extra); err != nil {
+			log.Printf("GCS notification failed: %v", err)
+		}
+	}
+
+	for _, obj := range items {
+		before, existed := previous[obj.Name]
+		switch {
+		case !existed:
+			publish(EventFinalize, obj, nil)
+		case before.Generation != obj.Generation:
+			publish(EventFinalize, obj, map[string]string{"overwroteGeneration": before.Generation})
+		case before.Metageneration != obj.Metageneration:
+			publish(EventMetadataUpdate, obj, nil)
+		}
+	}
+	for name, obj := range previous {
+		if !listed[name] {
+			publish(EventDelete, obj, nil)
+		}
+	}
+	return nil
+}
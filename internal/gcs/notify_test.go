@@ -0,0 +1,118 @@
+package gcs
+
+import (
+	"encoding/json"
+	"testing"
+
+	"cloudevents-explorer/internal/config"
+	"cloudevents-explorer/internal/types"
+)
+
+func TestClaimPublishesEachChangeOnce(t *testing.T) {
+	w := &watchedObjects{published: map[string]map[string]string{}}
+	obj := types.Object{Name: "a.txt", Generation: "1", Metageneration: "1"}
+
+	steps := []struct {
+		name  string
+		id    string
+		event string
+		obj   types.Object
+		want  bool
+	}{
+		{"first write", "1", EventFinalize, obj, true},
+		{"the poll finds the same write", "1", EventFinalize, obj, false},
+		{"another notification", "2", EventFinalize, obj, true},
+		{"metadata update", "1", EventMetadataUpdate, types.Object{Name: "a.txt", Generation: "1", Metageneration: "2"}, true},
+		{"the same metadata update", "1", EventMetadataUpdate, types.Object{Name: "a.txt", Generation: "1", Metageneration: "2"}, false},
+		{"overwrite", "1", EventFinalize, types.Object{Name: "a.txt", Generation: "2", Metageneration: "1"}, true},
+		{"delete", "1", EventDelete, types.Object{Name: "a.txt", Generation: "2", Metageneration: "1"}, true},
+		{"the poll finds the delete", "1", EventDelete, types.Object{Name: "a.txt", Generation: "2", Metageneration: "1"}, false},
+		{"other object", "1", EventFinalize, types.Object{Name: "b.txt", Generation: "1"}, true},
+	}
+
+	for _, step := range steps {
+		if got := w.claim(step.id, step.event, step.obj); got != step.want {
+			t.Errorf("%s: expected %v, got %v", step.name, step.want, got)
+		}
+	}
+}
+
+func TestNotificationApplies(t *testing.T) {
+	n := config.GCSNotification{
+		GCSEmulatorHost:  "http://localhost:4443/",
+		Bucket:           "b",
+		ObjectNamePrefix: "in/",
+		EventTypes:       []string{EventFinalize},
+	}
+	conn := types.GCSConnection{EmulatorHost: "LOCALHOST:4443"}
+
+	tests := []struct {
+		name   string
+		event  string
+		bucket string
+		object string
+		want   bool
+	}{
+		{"matching event and prefix", EventFinalize, "b", "in/a.txt", true},
+		{"other event", EventDelete, "b", "in/a.txt", false},
+		{"outside the prefix", EventFinalize, "b", "out/a.txt", false},
+		{"other bucket", EventFinalize, "c", "in/a.txt", false},
+	}
+
+	for _, tt := range tests {
+		if got := notificationApplies(n, conn, tt.event, tt.bucket, tt.object); got != tt.want {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+
+	if notificationApplies(n, types.GCSConnection{EmulatorHost: "localhost:9000"}, EventFinalize, "b", "in/a.txt") {
+		t.Errorf("expected a notification of another host not to apply")
+	}
+}
+
+func TestNotificationMessage(t *testing.T) {
+	n := config.GCSNotification{ID: "3", CustomAttributes: map[string]string{"team": "x", "eventType": "overridden"}}
+	obj := types.Object{Name: "dir/a b.txt", Generation: "7", Updated: "2024-01-02T03:04:05Z"}
+
+	data, attributes, err := NotificationMessage(n, EventFinalize, "bkt", obj, map[string]string{"overwroteGeneration": "6"})
+	if err != nil {
+		t.Fatalf("could not build message: %v", err)
+	}
+
+	want := map[string]string{
+		"notificationConfig":  "projects/_/buckets/bkt/notificationConfigs/3",
+		"eventType":           EventFinalize,
+		"payloadFormat":       PayloadJSONAPIV1,
+		"bucketId":            "bkt",
+		"objectId":            "dir/a b.txt",
+		"objectGeneration":    "7",
+		"eventTime":           "2024-01-02T03:04:05Z",
+		"overwroteGeneration": "6",
+		"team":                "x",
+	}
+	for k, v := range want {
+		if attributes[k] != v {
+			t.Errorf("expected attribute %s %q, got %q", k, v, attributes[k])
+		}
+	}
+
+	var payload map[string]interface{}
+	if err := json.Unmarshal(data, &payload); err != nil {
+		t.Fatalf("could not parse payload: %v", err)
+	}
+	if payload["id"] != "bkt/dir/a b.txt/7" || payload["kind"] != "storage#object" {
+		t.Errorf("expected the object resource, got %v", payload)
+	}
+	if payload["selfLink"] != "https://www.googleapis.com/storage/v1/b/bkt/o/dir%2Fa%20b.txt" {
+		t.Errorf("expected an escaped self link, got %v", payload["selfLink"])
+	}
+
+	n.PayloadFormat = PayloadNone
+	data, attributes, err = NotificationMessage(n, EventDelete, "bkt", obj, nil)
+	if err != nil {
+		t.Fatalf("could not build message: %v", err)
+	}
+	if data != nil || attributes["payloadFormat"] != PayloadNone {
+		t.Errorf("expected no payload, got %q with format %q", data, attributes["payloadFormat"])
+	}
+}
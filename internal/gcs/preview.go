@@ -0,0 +1,203 @@
+package gcs
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"cloudevents-explorer/internal/types"
+)
+
+const (
+	// DefaultPreviewLength is the chunk read when a preview asks for none
+	DefaultPreviewLength = 64 << 10
+	// MaxPreviewLength caps a single preview chunk
+	MaxPreviewLength = 1 << 20
+)
+
+// Preview kinds
+const (
+	PreviewText   = "text"
+	PreviewBinary = "binary"
+	PreviewImage  = "image"
+)
+
+// OpenRange returns length bytes of an object starting at offset. The caller
+// closes it.
+func (c *Client) OpenRange(ctx context.Context, bucket, object, generation string, offset, length int64) (io.ReadCloser, error) {
+	params := url.Values{"alt": {"media"}}
+	if generation != "" {
+		params.Set("generation", generation)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.objectURL(bucket, object)+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", offset, offset+length-1))
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		defer resp.Body.Close()
+		return nil, apiError(resp)
+	}
+	if resp.StatusCode == http.StatusOK && offset > 0 {
+		// The server ignored the range and sent the whole object
+		if _, err := io.CopyN(io.Discard, resp.Body, offset); err != nil {
+			resp.Body.Close()
+			return nil, err
+		}
+	}
+	return resp.Body, nil
+}
+
+// isGzip reports whether an object holds gzip data that previews decompress
+func isGzip(obj *types.Object) bool {
+	switch obj.ContentType {
+	case "application/gzip", "application/x-gzip":
+		return true
+	}
+	return strings.HasSuffix(obj.Name, ".gz")
+}
+
+// Preview reads one chunk of an object for display, starting at offset.
+// Gzip objects are decompressed, in which case offsets count decompressed
+// bytes and the object is read from its start on every chunk.
+func (c *Client) Preview(ctx context.Context, bucket, object, generation string, offset, length int64) (*types.ContentResponse, error) {
+	if length <= 0 {
+		length = DefaultPreviewLength
+	}
+	if length > MaxPreviewLength {
+		length = MaxPreviewLength
+	}
+
+	obj, err := c.GetObject(ctx, bucket, object, generation)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &types.ContentResponse{ContentType: obj.ContentType, Size: obj.Size, Offset: offset}
+
+	var data []byte
+	if isGzip(obj) {
+		data, response.EOF, err = c.readGunzipped(ctx, bucket, object, generation, offset, length)
+		if err != nil {
+			return nil, err
+		}
+		response.Gunzipped = true
+	} else if offset < obj.Size {
+		rc, err := c.OpenRange(ctx, bucket, object, generation, offset, length)
+		if err != nil {
+			return nil, err
+		}
+		data, err = io.ReadAll(io.LimitReader(rc, length))
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+		response.EOF = offset+int64(len(data)) >= obj.Size
+	} else {
+		response.EOF = true
+	}
+
+	sniffed := http.DetectContentType(data)
+	if offset == 0 && !response.Gunzipped &&
+		(strings.HasPrefix(obj.ContentType, "image/") || strings.HasPrefix(sniffed, "image/")) {
+		// The browser renders images from the download endpoint
+		response.Kind = PreviewImage
+		response.ContentType = sniffed
+		if strings.HasPrefix(obj.ContentType, "image/") {
+			response.ContentType = obj.ContentType
+		}
+		response.NextOffset = obj.Size
+		response.EOF = true
+		return response, nil
+	}
+	if response.ContentType == "" || response.Gunzipped {
+		response.ContentType = sniffed
+	}
+
+	if isText(data) {
+		// Keep a rune split by the chunk boundary for the next chunk
+		if !response.EOF {
+			data = trimPartialRune(data)
+		}
+		response.Kind = PreviewText
+		response.Content = string(data)
+	} else {
+		response.Kind = PreviewBinary
+		response.Data = data
+	}
+	response.NextOffset = offset + int64(len(data))
+	return response, nil
+}
+
+// readGunzipped decompresses an object and returns length bytes of it from
+// offset, and whether that reached its end
+func (c *Client) readGunzipped(ctx context.Context, bucket, object, generation string, offset, length int64) ([]byte, bool, error) {
+	rc, err := c.OpenObject(ctx, bucket, object, generation)
+	if err != nil {
+		return nil, false, err
+	}
+	defer rc.Close()
+
+	zr, err := gzip.NewReader(rc)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to decompress: %w", err)
+	}
+	if _, err := io.CopyN(io.Discard, zr, offset); err != nil {
+		if err == io.EOF {
+			return nil, true, nil
+		}
+		return nil, false, fmt.Errorf("failed to decompress: %w", err)
+	}
+
+	// Read one byte past the chunk to tell whether more follows
+	data, err := io.ReadAll(io.LimitReader(zr, length+1))
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to decompress: %w", err)
+	}
+	if int64(len(data)) > length {
+		return data[:length], false, nil
+	}
+	return data, true, nil
+}
+
+// isText reports whether a chunk reads as UTF-8 text. A chunk may end in the
+// middle of a rune, which does not make it binary.
+func isText(data []byte) bool {
+	for i := 0; i < len(data); {
+		r, size := utf8.DecodeRune(data[i:])
+		if r == utf8.RuneError && size <= 1 {
+			if !utf8.FullRune(data[i:]) {
+				return true
+			}
+			return false
+		}
+		if r == 0 || (r < 0x20 && r != '\n' && r != '\r' && r != '\t' && r != '\f' && r != 0x1b) {
+			return false
+		}
+		i += size
+	}
+	return true
+}
+
+func trimPartialRune(data []byte) []byte {
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(data[i]) {
+			if !utf8.FullRune(data[i:]) {
+				return data[:i]
+			}
+			break
+		}
+	}
+	return data
+}
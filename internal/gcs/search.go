@@ -0,0 +1,210 @@
+package gcs
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"cloudevents-explorer/internal/types"
+)
+
+const (
+	// DefaultSearchResults is the number of matches a search returns when it
+	// is asked for none
+	DefaultSearchResults = 500
+	// MaxSearchResults caps the matches of a search
+	MaxSearchResults = 5000
+)
+
+// SearchOptions filters a recursive search. A glob pattern matches the whole
+// object name, or only the last path segment when the pattern has no "/";
+// "**" crosses "/" and "*" does not. A regex pattern may match anywhere in
+// the name. Zero sizes and times are not filtered on.
+type SearchOptions struct {
+	Prefix        string
+	Pattern       string
+	Regex         bool
+	MinSize       int64
+	MaxSize       int64
+	UpdatedAfter  time.Time
+	UpdatedBefore time.Time
+	Limit         int
+}
+
+// globToRegexp translates a glob into an anchored regular expression
+func globToRegexp(glob string) string {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		switch ch := glob[i]; ch {
+		case '*':
+			if i+1 < len(glob) && glob[i+1] == '*' {
+				b.WriteString(".*")
+				i++
+			} else {
+				b.WriteString("[^/]*")
+			}
+		case '?':
+			b.WriteString("[^/]")
+		case '[':
+			if end := strings.IndexByte(glob[i+1:], ']'); end > 0 {
+				class := glob[i+1 : i+1+end]
+				if strings.HasPrefix(class, "!") {
+					class = "^" + class[1:]
+				}
+				b.WriteString("[" + class + "]")
+				i += end + 1
+			} else {
+				b.WriteString(`\[`)
+			}
+		default:
+			b.WriteString(regexp.QuoteMeta(string(ch)))
+		}
+	}
+	b.WriteString("$")
+	return b.String()
+}
+
+// nameMatcher compiles the pattern of a search into a test on object names
+func nameMatcher(opts SearchOptions) (func(string) bool, error) {
+	if opts.Pattern == "" {
+		return func(string) bool { return true }, nil
+	}
+
+	if opts.Regex {
+		re, err := regexp.Compile(opts.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression: %w", err)
+		}
+		return re.MatchString, nil
+	}
+
+	re, err := regexp.Compile(globToRegexp(opts.Pattern))
+	if err != nil {
+		return nil, fmt.Errorf("invalid glob: %w", err)
+	}
+	if strings.Contains(opts.Pattern, "/") {
+		return re.MatchString, nil
+	}
+	return func(name string) bool {
+		return re.MatchString(name[strings.LastIndex(name, "/")+1:])
+	}, nil
+}
+
+// Search lists every object under the prefix, at any depth, and returns those
+// matching the options, up to the limit
+func (c *Client) Search(ctx context.Context, bucket string, opts SearchOptions) (*types.ObjectSearchResponse, error) {
+	if opts.Limit <= 0 {
+		opts.Limit = DefaultSearchResults
+	}
+	if opts.Limit > MaxSearchResults {
+		opts.Limit = MaxSearchResults
+	}
+
+	match, err := nameMatcher(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	params := url.Values{}
+	if opts.Prefix != "" {
+		params.Set("prefix", opts.Prefix)
+	}
+
+	response := &types.ObjectSearchResponse{Items: []types.Object{}}
+	err = c.listPages(ctx, bucket, params, func(items []types.Object, _ []string) error {
+		for _, item := range items {
+			response.Scanned++
+			if strings.HasSuffix(item.Name, "/") || !match(item.Name) {
+				continue
+			}
+			if item.Size < opts.MinSize || (opts.MaxSize > 0 && item.Size > opts.MaxSize) {
+				continue
+			}
+			if !opts.UpdatedAfter.IsZero() || !opts.UpdatedBefore.IsZero() {
+				updated, err := time.Parse(time.RFC3339, item.Updated)
+				if err != nil ||
+					(!opts.UpdatedAfter.IsZero() && updated.Before(opts.UpdatedAfter)) ||
+					(!opts.UpdatedBefore.IsZero() && updated.After(opts.UpdatedBefore)) {
+					continue
+				}
+			}
+
+			if len(response.Items) == opts.Limit {
+				response.Truncated = true
+				return errStopListing
+			}
+			response.Items = append(response.Items, item)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// ZipObjects returns the objects a ZIP download holds: the named objects, or
+// every object under prefix when names is empty. Folder placeholders are left
+// out.
+func (c *Client) ZipObjects(ctx context.Context, bucket, prefix string, names []string) ([]types.Object, error) {
+	var objects []types.Object
+	if len(names) == 0 {
+		items, _, err := c.listAll(ctx, bucket, url.Values{"prefix": {prefix}})
+		if err != nil {
+			return nil, err
+		}
+		objects = items
+	} else {
+		for _, name := range names {
+			obj, err := c.GetObject(ctx, bucket, name, "")
+			if err != nil {
+				return nil, fmt.Errorf("failed to fetch %s: %w", name, err)
+			}
+			objects = append(objects, *obj)
+		}
+	}
+
+	files := []types.Object{}
+	for _, obj := range objects {
+		if !strings.HasSuffix(obj.Name, "/") {
+			files = append(files, obj)
+		}
+	}
+	return files, nil
+}
+
+// WriteZip streams the objects into a ZIP archive, naming each entry after
+// its object name with base trimmed off
+func (c *Client) WriteZip(ctx context.Context, w io.Writer, bucket, base string, objects []types.Object) error {
+	zw := zip.NewWriter(w)
+	for _, obj := range objects {
+		header := &zip.FileHeader{
+			Name:   strings.TrimPrefix(obj.Name, base),
+			Method: zip.Deflate,
+		}
+		if updated, err := time.Parse(time.RFC3339, obj.Updated); err == nil {
+			header.Modified = updated
+		}
+
+		entry, err := zw.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		rc, err := c.OpenObject(ctx, bucket, obj.Name, "")
+		if err != nil {
+			return fmt.Errorf("failed to fetch %s: %w", obj.Name, err)
+		}
+		_, err = io.Copy(entry, rc)
+		rc.Close()
+		if err != nil {
+			return fmt.Errorf("failed to write %s: %w", obj.Name, err)
+		}
+	}
+	return zw.Close()
+}
@@ -0,0 +1,638 @@
+package gcs
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/linkedin/goavro/v2"
+	"github.com/parquet-go/parquet-go"
+
+	"cloudevents-explorer/internal/types"
+)
+
+const (
+	// DefaultTableRows is the number of records a table preview reads when
+	// it is asked for none
+	DefaultTableRows = 100
+	// MaxTableRows caps the records of a table preview
+	MaxTableRows = 1000
+)
+
+// Structured formats read by PreviewTable
+const (
+	FormatAvro    = "avro"
+	FormatParquet = "parquet"
+	FormatCSV     = "csv"
+	FormatNDJSON  = "ndjson"
+	FormatJSON    = "json"
+)
+
+// TableFormat guesses the structured format of an object from its name,
+// ignoring a trailing .gz, or else from its content type. It returns "" for
+// anything else.
+func TableFormat(name, contentType string) string {
+	name = strings.TrimSuffix(strings.ToLower(name), ".gz")
+	switch {
+	case strings.HasSuffix(name, ".avro"):
+		return FormatAvro
+	case strings.HasSuffix(name, ".parquet"), strings.HasSuffix(name, ".parq"):
+		return FormatParquet
+	case strings.HasSuffix(name, ".csv"), strings.HasSuffix(name, ".tsv"):
+		return FormatCSV
+	case strings.HasSuffix(name, ".ndjson"), strings.HasSuffix(name, ".jsonl"):
+		return FormatNDJSON
+	case strings.HasSuffix(name, ".json"):
+		return FormatJSON
+	}
+
+	switch strings.TrimSpace(strings.Split(contentType, ";")[0]) {
+	case "avro/binary", "application/avro":
+		return FormatAvro
+	case "application/vnd.apache.parquet", "application/x-parquet":
+		return FormatParquet
+	case "text/csv", "text/tab-separated-values":
+		return FormatCSV
+	case "application/x-ndjson", "application/jsonl":
+		return FormatNDJSON
+	case "application/json":
+		return FormatJSON
+	}
+	return ""
+}
+
+// PreviewTable reads the schema and the first limit records of a structured
+// object. An empty format is guessed with TableFormat.
+func (c *Client) PreviewTable(ctx context.Context, bucket, object, generation, format string, limit int) (*types.TablePreview, error) {
+	if limit <= 0 {
+		limit = DefaultTableRows
+	}
+	if limit > MaxTableRows {
+		limit = MaxTableRows
+	}
+
+	obj, err := c.GetObject(ctx, bucket, object, generation)
+	if err != nil {
+		return nil, err
+	}
+	if format == "" {
+		if format = TableFormat(obj.Name, obj.ContentType); format == "" {
+			return nil, fmt.Errorf("cannot tell the format of %s; choose one", object)
+		}
+	}
+
+	if format == FormatParquet {
+		if isGzip(obj) {
+			return nil, fmt.Errorf("gzipped Parquet files cannot be previewed")
+		}
+		r := &objectReaderAt{ctx: ctx, client: c, bucket: bucket, object: object, generation: generation}
+		return readParquet(r, obj.Size, limit)
+	}
+
+	rc, err := c.OpenObject(ctx, bucket, object, generation)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	var r io.Reader = rc
+	if isGzip(obj) {
+		zr, err := gzip.NewReader(rc)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress: %w", err)
+		}
+		r = zr
+	}
+
+	switch format {
+	case FormatAvro:
+		return readAvro(r, limit)
+	case FormatCSV:
+		delimiter := ','
+		if strings.HasSuffix(strings.TrimSuffix(strings.ToLower(obj.Name), ".gz"), ".tsv") ||
+			strings.HasPrefix(obj.ContentType, "text/tab-separated-values") {
+			delimiter = '\t'
+		}
+		return readCSV(r, delimiter, limit)
+	case FormatNDJSON:
+		return readNDJSON(r, limit)
+	case FormatJSON:
+		return readJSON(r, limit)
+	}
+	return nil, fmt.Errorf("unknown format %q", format)
+}
+
+// objectReaderAt reads an object with range requests, so Parquet fetches its
+// footer and the pages it needs rather than the whole file
+type objectReaderAt struct {
+	ctx        context.Context
+	client     *Client
+	bucket     string
+	object     string
+	generation string
+}
+
+func (r *objectReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	rc, err := r.client.OpenRange(r.ctx, r.bucket, r.object, r.generation, off, int64(len(p)))
+	if err != nil {
+		return 0, err
+	}
+	defer rc.Close()
+
+	n, err := io.ReadFull(rc, p)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	return n, err
+}
+
+func readParquet(r io.ReaderAt, size int64, limit int) (*types.TablePreview, error) {
+	file, err := parquet.OpenFile(r, size,
+		parquet.SkipPageIndex(true),
+		parquet.SkipBloomFilters(true),
+		parquet.OptimisticRead(true),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open Parquet file: %w", err)
+	}
+
+	preview := &types.TablePreview{
+		Format:    FormatParquet,
+		RawSchema: file.Schema().String(),
+		Rows:      [][]interface{}{},
+		TotalRows: file.NumRows(),
+		Truncated: file.NumRows() > int64(limit),
+	}
+	// Leaves are numbered depth first, the order values refer to them by
+	var leaves func(col *parquet.Column, path []string)
+	leaves = func(col *parquet.Column, path []string) {
+		for _, child := range col.Columns() {
+			childPath := append(append([]string{}, path...), child.Name())
+			if child.Leaf() {
+				preview.Schema = append(preview.Schema, types.TableColumn{
+					Name: strings.Join(childPath, "."),
+					Type: child.Type().String(),
+				})
+			} else {
+				leaves(child, childPath)
+			}
+		}
+	}
+	leaves(file.Root(), nil)
+
+	buf := make([]parquet.Row, 64)
+	for _, rowGroup := range file.RowGroups() {
+		rows := rowGroup.Rows()
+		for len(preview.Rows) < limit {
+			n, err := rows.ReadRows(buf[:min(len(buf), limit-len(preview.Rows))])
+			for _, row := range buf[:n] {
+				preview.Rows = append(preview.Rows, parquetCells(row, len(preview.Schema)))
+			}
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to read Parquet rows: %w", err)
+			}
+		}
+		rows.Close()
+		if len(preview.Rows) >= limit {
+			break
+		}
+	}
+	return preview, nil
+}
+
+// parquetCells turns a row's values into one cell per leaf column. Repeated
+// columns hold several values, which are listed in brackets.
+func parquetCells(row parquet.Row, columns int) []interface{} {
+	values := make([][]string, columns)
+	for _, v := range row {
+		if v.IsNull() || v.Column() < 0 || v.Column() >= columns {
+			continue
+		}
+		values[v.Column()] = append(values[v.Column()], v.String())
+	}
+
+	cells := make([]interface{}, columns)
+	for i, vs := range values {
+		switch len(vs) {
+		case 0:
+		case 1:
+			cells[i] = vs[0]
+		default:
+			cells[i] = "[" + strings.Join(vs, ", ") + "]"
+		}
+	}
+	return cells
+}
+
+func readAvro(r io.Reader, limit int) (*types.TablePreview, error) {
+	ocf, err := goavro.NewOCFReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open Avro container: %w", err)
+	}
+
+	schema := ocf.Codec().Schema()
+	preview := &types.TablePreview{Format: FormatAvro, RawSchema: schema, Rows: [][]interface{}{}}
+
+	// A record schema gives one column per field; any other schema is shown
+	// as a single column
+	var record struct {
+		Type   interface{} `json:"type"`
+		Fields []struct {
+			Name string          `json:"name"`
+			Type json.RawMessage `json:"type"`
+		} `json:"fields"`
+	}
+	unions := map[string]bool{}
+	if json.Unmarshal([]byte(schema), &record) == nil && record.Type == "record" {
+		for _, field := range record.Fields {
+			preview.Schema = append(preview.Schema, types.TableColumn{Name: field.Name, Type: avroTypeName(field.Type)})
+			unions[field.Name] = bytes.HasPrefix(bytes.TrimSpace(field.Type), []byte("["))
+		}
+	} else {
+		preview.Schema = []types.TableColumn{{Name: "value", Type: avroTypeName(json.RawMessage(schema))}}
+	}
+
+	for ocf.Scan() {
+		if len(preview.Rows) == limit {
+			preview.Truncated = true
+			break
+		}
+		datum, err := ocf.Read()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read Avro record: %w", err)
+		}
+
+		fields, ok := datum.(map[string]interface{})
+		if !ok || len(record.Fields) == 0 {
+			preview.Rows = append(preview.Rows, []interface{}{formatCell(datum)})
+			continue
+		}
+		cells := make([]interface{}, len(preview.Schema))
+		for i, column := range preview.Schema {
+			value := fields[column.Name]
+			// goavro wraps a union value in a map keyed by its branch type
+			if branch, ok := value.(map[string]interface{}); ok && unions[column.Name] && len(branch) == 1 {
+				for _, v := range branch {
+					value = v
+				}
+			}
+			cells[i] = formatCell(value)
+		}
+		preview.Rows = append(preview.Rows, cells)
+	}
+	if err := ocf.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read Avro records: %w", err)
+	}
+	return preview, nil
+}
+
+// avroTypeName shortens an Avro type for the schema list: named and complex
+// types by their kind and name, unions by their branches
+func avroTypeName(raw json.RawMessage) string {
+	var name string
+	if json.Unmarshal(raw, &name) == nil {
+		return name
+	}
+
+	var branches []json.RawMessage
+	if json.Unmarshal(raw, &branches) == nil {
+		names := make([]string, len(branches))
+		for i, branch := range branches {
+			names[i] = avroTypeName(branch)
+		}
+		return strings.Join(names, " | ")
+	}
+
+	var complex struct {
+		Type        string          `json:"type"`
+		Name        string          `json:"name"`
+		LogicalType string          `json:"logicalType"`
+		Items       json.RawMessage `json:"items"`
+		Values      json.RawMessage `json:"values"`
+	}
+	if json.Unmarshal(raw, &complex) != nil {
+		return string(raw)
+	}
+	switch {
+	case complex.LogicalType != "":
+		return complex.Type + " (" + complex.LogicalType + ")"
+	case complex.Type == "array":
+		return "array<" + avroTypeName(complex.Items) + ">"
+	case complex.Type == "map":
+		return "map<" + avroTypeName(complex.Values) + ">"
+	case complex.Name != "":
+		return complex.Type + " " + complex.Name
+	}
+	return complex.Type
+}
+
+// formatCell renders a decoded value as a table cell
+func formatCell(v interface{}) interface{} {
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case string:
+		return v
+	case []byte:
+		return base64.StdEncoding.EncodeToString(v)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case bool, int, int32, int64, float32, float64:
+		return fmt.Sprint(v)
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+// readCSV reads a CSV file whose first line names the columns. Column types
+// are inferred from the records read.
+func readCSV(r io.Reader, delimiter rune, limit int) (*types.TablePreview, error) {
+	reader := csv.NewReader(r)
+	reader.Comma = delimiter
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return &types.TablePreview{Format: FormatCSV, Schema: []types.TableColumn{}, Rows: [][]interface{}{}}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+
+	preview := &types.TablePreview{Format: FormatCSV, Rows: [][]interface{}{}}
+	for _, name := range header {
+		preview.Schema = append(preview.Schema, types.TableColumn{Name: name})
+	}
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CSV record %d: %w", len(preview.Rows)+1, err)
+		}
+		if len(preview.Rows) == limit {
+			preview.Truncated = true
+			break
+		}
+
+		// Records wider than the header get unnamed columns
+		for len(preview.Schema) < len(record) {
+			preview.Schema = append(preview.Schema, types.TableColumn{Name: fmt.Sprintf("column_%d", len(preview.Schema)+1)})
+		}
+		cells := make([]interface{}, len(preview.Schema))
+		for i, field := range record {
+			cells[i] = field
+			preview.Schema[i].Type = mergeType(preview.Schema[i].Type, csvType(field))
+		}
+		preview.Rows = append(preview.Rows, cells)
+	}
+
+	return finishTable(preview, "string"), nil
+}
+
+// finishTable pads rows read before later columns appeared, and names the
+// type of columns that only held missing values
+func finishTable(preview *types.TablePreview, emptyType string) *types.TablePreview {
+	for i, row := range preview.Rows {
+		for len(row) < len(preview.Schema) {
+			row = append(row, nil)
+		}
+		preview.Rows[i] = row
+	}
+	for i := range preview.Schema {
+		if preview.Schema[i].Type == "" {
+			preview.Schema[i].Type = emptyType
+		}
+	}
+	return preview
+}
+
+func csvType(field string) string {
+	if field == "" {
+		return ""
+	}
+	if _, err := strconv.ParseInt(field, 10, 64); err == nil {
+		return "integer"
+	}
+	if _, err := strconv.ParseFloat(field, 64); err == nil {
+		return "float"
+	}
+	if _, err := strconv.ParseBool(field); err == nil {
+		return "boolean"
+	}
+	return "string"
+}
+
+// mergeType combines the types seen in a column; "" is a missing value and
+// integers widen to floats
+func mergeType(current, seen string) string {
+	switch {
+	case seen == "" || current == seen:
+		return current
+	case current == "":
+		return seen
+	case (current == "integer" && seen == "float") || (current == "float" && seen == "integer"):
+		return "float"
+	case current == "mixed" || seen == "mixed":
+		return "mixed"
+	}
+	if current == "string" || seen == "string" {
+		return "string"
+	}
+	return "mixed"
+}
+
+// readNDJSON reads a stream of JSON values, one record per value
+func readNDJSON(r io.Reader, limit int) (*types.TablePreview, error) {
+	decoder := json.NewDecoder(r)
+	return jsonTable(FormatNDJSON, limit, func() (json.RawMessage, error) {
+		var raw json.RawMessage
+		err := decoder.Decode(&raw)
+		return raw, err
+	})
+}
+
+// readJSON reads a JSON document. An array holds one record per item; any
+// other value is a single record.
+func readJSON(r io.Reader, limit int) (*types.TablePreview, error) {
+	br := bufio.NewReader(r)
+	for {
+		b, err := br.ReadByte()
+		if err == io.EOF {
+			return jsonTable(FormatJSON, limit, func() (json.RawMessage, error) { return nil, io.EOF })
+		}
+		if err != nil {
+			return nil, err
+		}
+		if b != ' ' && b != '\t' && b != '\n' && b != '\r' {
+			br.UnreadByte()
+			break
+		}
+	}
+	first, _ := br.Peek(1)
+	decoder := json.NewDecoder(br)
+
+	if first[0] != '[' {
+		done := false
+		return jsonTable(FormatJSON, limit, func() (json.RawMessage, error) {
+			if done {
+				return nil, io.EOF
+			}
+			done = true
+			var raw json.RawMessage
+			err := decoder.Decode(&raw)
+			return raw, err
+		})
+	}
+
+	if _, err := decoder.Token(); err != nil {
+		return nil, err
+	}
+	return jsonTable(FormatJSON, limit, func() (json.RawMessage, error) {
+		if !decoder.More() {
+			return nil, io.EOF
+		}
+		var raw json.RawMessage
+		err := decoder.Decode(&raw)
+		return raw, err
+	})
+}
+
+// jsonTable reads the records next returns until io.EOF. The keys of objects
+// become columns in the order they are first seen; other values go in a
+// "value" column.
+func jsonTable(format string, limit int, next func() (json.RawMessage, error)) (*types.TablePreview, error) {
+	preview := &types.TablePreview{Format: format, Schema: []types.TableColumn{}, Rows: [][]interface{}{}}
+	columns := map[string]int{}
+
+	column := func(name string) int {
+		i, ok := columns[name]
+		if !ok {
+			i = len(preview.Schema)
+			columns[name] = i
+			preview.Schema = append(preview.Schema, types.TableColumn{Name: name})
+		}
+		return i
+	}
+
+	var records [][2][]string
+	for {
+		raw, err := next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read JSON record %d: %w", len(records)+1, err)
+		}
+		if len(records) == limit {
+			preview.Truncated = true
+			break
+		}
+
+		keys, values, err := jsonFields(raw)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read JSON record %d: %w", len(records)+1, err)
+		}
+		records = append(records, [2][]string{keys, values})
+	}
+
+	// Rows are built once every column is known
+	for _, record := range records {
+		keys, values := record[0], record[1]
+		for _, key := range keys {
+			column(key)
+		}
+		cells := make([]interface{}, len(preview.Schema))
+		for i, key := range keys {
+			idx := columns[key]
+			cells[idx] = jsonCell(values[i])
+			preview.Schema[idx].Type = mergeType(preview.Schema[idx].Type, jsonType(values[i]))
+		}
+		preview.Rows = append(preview.Rows, cells)
+	}
+	return finishTable(preview, "null"), nil
+}
+
+// jsonFields returns the keys of a JSON object in document order with their
+// raw values, or the value itself under "value" when it is not an object
+func jsonFields(raw json.RawMessage) ([]string, []string, error) {
+	if !bytes.HasPrefix(bytes.TrimSpace(raw), []byte("{")) {
+		return []string{"value"}, []string{string(raw)}, nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	if _, err := decoder.Token(); err != nil {
+		return nil, nil, err
+	}
+	var keys, values []string
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, nil, err
+		}
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			return nil, nil, err
+		}
+		keys = append(keys, token.(string))
+		values = append(values, string(value))
+	}
+	return keys, values, nil
+}
+
+func jsonCell(raw string) interface{} {
+	switch {
+	case raw == "null":
+		return nil
+	case strings.HasPrefix(raw, `"`):
+		var s string
+		if json.Unmarshal([]byte(raw), &s) == nil {
+			return s
+		}
+	}
+	var compact bytes.Buffer
+	if json.Compact(&compact, []byte(raw)) == nil {
+		return compact.String()
+	}
+	return raw
+}
+
+func jsonType(raw string) string {
+	switch {
+	case raw == "null":
+		return ""
+	case raw == "true" || raw == "false":
+		return "boolean"
+	case strings.HasPrefix(raw, `"`):
+		return "string"
+	case strings.HasPrefix(raw, "{"):
+		return "object"
+	case strings.HasPrefix(raw, "["):
+		return "array"
+	case strings.ContainsAny(raw, ".eE"):
+		return "float"
+	}
+	return "integer"
+}
@@ -0,0 +1,283 @@
+package gcs
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/linkedin/goavro/v2"
+	"github.com/parquet-go/parquet-go"
+
+	"cloudevents-explorer/internal/types"
+)
+
+func TestTableFormat(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		want        string
+	}{
+		{"events.avro", "", FormatAvro},
+		{"part-0.parquet", "", FormatParquet},
+		{"data.CSV.gz", "", FormatCSV},
+		{"data.tsv", "", FormatCSV},
+		{"events.ndjson", "", FormatNDJSON},
+		{"events.jsonl.gz", "", FormatNDJSON},
+		{"records.json", "", FormatJSON},
+		{"blob", "application/json; charset=utf-8", FormatJSON},
+		{"blob", "application/x-ndjson", FormatNDJSON},
+		{"blob", "application/vnd.apache.parquet", FormatParquet},
+		{"blob", "text/plain", ""},
+		{"notes.txt", "", ""},
+	}
+
+	for _, tt := range tests {
+		if got := TableFormat(tt.name, tt.contentType); got != tt.want {
+			t.Errorf("%s (%s): expected %q, got %q", tt.name, tt.contentType, tt.want, got)
+		}
+	}
+}
+
+// columns builds a schema from names and types
+func columns(namesAndTypes ...string) []types.TableColumn {
+	schema := []types.TableColumn{}
+	for i := 0; i < len(namesAndTypes); i += 2 {
+		schema = append(schema, types.TableColumn{Name: namesAndTypes[i], Type: namesAndTypes[i+1]})
+	}
+	return schema
+}
+
+// checkTable compares a preview's columns, rows and truncation
+func checkTable(t *testing.T, name string, got *types.TablePreview, schema []types.TableColumn, rows [][]interface{}, truncated bool) {
+	t.Helper()
+	if !reflect.DeepEqual(got.Schema, schema) {
+		t.Errorf("%s: expected schema %v, got %v", name, schema, got.Schema)
+	}
+	if !reflect.DeepEqual(got.Rows, rows) {
+		t.Errorf("%s: expected rows %v, got %v", name, rows, got.Rows)
+	}
+	if got.Truncated != truncated {
+		t.Errorf("%s: expected truncated %v, got %v", name, truncated, got.Truncated)
+	}
+}
+
+func TestReadCSV(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		delimiter rune
+		limit     int
+		schema    []types.TableColumn
+		rows      [][]interface{}
+		truncated bool
+	}{
+		{"types", "id,price,ok,name\n1,2.5,true,a\n2,3,false,b\n", ',', 10,
+			columns("id", "integer", "price", "float", "ok", "boolean", "name", "string"),
+			[][]interface{}{{"1", "2.5", "true", "a"}, {"2", "3", "false", "b"}}, false},
+		{"tab separated", "a\tb\nx\t\n", '\t', 10,
+			columns("a", "string", "b", "string"),
+			[][]interface{}{{"x", ""}}, false},
+		{"wider records and mixed types", "a\n1\nx,2\n", ',', 10,
+			columns("a", "string", "column_2", "integer"),
+			[][]interface{}{{"1", nil}, {"x", "2"}}, false},
+		{"limit", "a\n1\n2\n3\n", ',', 2,
+			columns("a", "integer"),
+			[][]interface{}{{"1"}, {"2"}}, true},
+		{"empty", "", ',', 10, []types.TableColumn{}, [][]interface{}{}, false},
+	}
+
+	for _, tt := range tests {
+		got, err := readCSV(strings.NewReader(tt.input), tt.delimiter, tt.limit)
+		if err != nil {
+			t.Errorf("%s: could not read: %v", tt.name, err)
+			continue
+		}
+		checkTable(t, tt.name, got, tt.schema, tt.rows, tt.truncated)
+	}
+}
+
+func TestReadNDJSON(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		limit     int
+		schema    []types.TableColumn
+		rows      [][]interface{}
+		truncated bool
+	}{
+		{"objects", `{"id": 1, "name": "a"}` + "\n" + `{"name": "b", "tags": [1, 2], "id": 2.5}` + "\n", 10,
+			columns("id", "float", "name", "string", "tags", "array"),
+			[][]interface{}{{"1", "a", nil}, {"2.5", "b", "[1,2]"}}, false},
+		{"nulls and objects", `{"a": null, "b": {"c": true}}`, 10,
+			columns("a", "null", "b", "object"),
+			[][]interface{}{{nil, `{"c":true}`}}, false},
+		{"other values", "1\n\"x\"\n", 10,
+			columns("value", "string"),
+			[][]interface{}{{"1"}, {"x"}}, false},
+		{"a line holding an array is one record", "[1, 2]\n", 10,
+			columns("value", "array"),
+			[][]interface{}{{"[1,2]"}}, false},
+		{"limit", "{\"a\": 1}\n{\"a\": 2}\n", 1,
+			columns("a", "integer"),
+			[][]interface{}{{"1"}}, true},
+	}
+
+	for _, tt := range tests {
+		got, err := readNDJSON(strings.NewReader(tt.input), tt.limit)
+		if err != nil {
+			t.Errorf("%s: could not read: %v", tt.name, err)
+			continue
+		}
+		if got.Format != FormatNDJSON {
+			t.Errorf("%s: expected format %q, got %q", tt.name, FormatNDJSON, got.Format)
+		}
+		checkTable(t, tt.name, got, tt.schema, tt.rows, tt.truncated)
+	}
+
+	if _, err := readNDJSON(strings.NewReader("{\"a\": 1}\n{oops}\n"), 10); err == nil || !strings.Contains(err.Error(), "record 2") {
+		t.Errorf("expected an error about record 2, got %v", err)
+	}
+}
+
+func TestReadJSON(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		limit     int
+		schema    []types.TableColumn
+		rows      [][]interface{}
+		truncated bool
+	}{
+		{"array of records", "\n [{\"id\": 1, \"name\": \"a\"}, {\"id\": 2}]\n", 10,
+			columns("id", "integer", "name", "string"),
+			[][]interface{}{{"1", "a"}, {"2", nil}}, false},
+		{"array of values", `["x", "y"]`, 10,
+			columns("value", "string"),
+			[][]interface{}{{"x"}, {"y"}}, false},
+		{"single object", `{"id": 1}`, 10,
+			columns("id", "integer"),
+			[][]interface{}{{"1"}}, false},
+		{"empty array", `[]`, 10, []types.TableColumn{}, [][]interface{}{}, false},
+		{"empty file", "  \n", 10, []types.TableColumn{}, [][]interface{}{}, false},
+		{"limit", `[{"a": 1}, {"a": 2}, {"a": 3}]`, 2,
+			columns("a", "integer"),
+			[][]interface{}{{"1"}, {"2"}}, true},
+	}
+
+	for _, tt := range tests {
+		got, err := readJSON(strings.NewReader(tt.input), tt.limit)
+		if err != nil {
+			t.Errorf("%s: could not read: %v", tt.name, err)
+			continue
+		}
+		if got.Format != FormatJSON {
+			t.Errorf("%s: expected format %q, got %q", tt.name, FormatJSON, got.Format)
+		}
+		checkTable(t, tt.name, got, tt.schema, tt.rows, tt.truncated)
+	}
+
+	if _, err := readJSON(strings.NewReader(`[{"a": 1}, oops]`), 10); err == nil || !strings.Contains(err.Error(), "record 2") {
+		t.Errorf("expected an error about record 2, got %v", err)
+	}
+}
+
+func TestReadAvro(t *testing.T) {
+	schema := `{
+		"type": "record",
+		"name": "Event",
+		"fields": [
+			{"name": "id", "type": "long"},
+			{"name": "name", "type": ["null", "string"]},
+			{"name": "payload", "type": "bytes"},
+			{"name": "tags", "type": {"type": "array", "items": "string"}}
+		]
+	}`
+	var buf bytes.Buffer
+	w, err := goavro.NewOCFWriter(goavro.OCFConfig{W: &buf, Schema: schema})
+	if err != nil {
+		t.Fatalf("could not create writer: %v", err)
+	}
+	err = w.Append([]interface{}{
+		map[string]interface{}{"id": int64(1), "name": goavro.Union("string", "a"), "payload": []byte("hi"), "tags": []interface{}{"x"}},
+		map[string]interface{}{"id": int64(2), "name": nil, "payload": []byte{}, "tags": []interface{}{}},
+		map[string]interface{}{"id": int64(3), "name": goavro.Union("string", "c"), "payload": []byte{}, "tags": []interface{}{}},
+	})
+	if err != nil {
+		t.Fatalf("could not write records: %v", err)
+	}
+
+	got, err := readAvro(bytes.NewReader(buf.Bytes()), 2)
+	if err != nil {
+		t.Fatalf("could not read: %v", err)
+	}
+	checkTable(t, "avro", got,
+		columns("id", "long", "name", "null | string", "payload", "bytes", "tags", "array<string>"),
+		[][]interface{}{{"1", "a", "aGk=", `["x"]`}, {"2", nil, "", "[]"}}, true)
+	if got.RawSchema == "" {
+		t.Error("expected the raw schema")
+	}
+
+	buf.Reset()
+	w, err = goavro.NewOCFWriter(goavro.OCFConfig{W: &buf, Schema: `"string"`})
+	if err != nil {
+		t.Fatalf("could not create writer: %v", err)
+	}
+	if err := w.Append([]interface{}{"a"}); err != nil {
+		t.Fatalf("could not write records: %v", err)
+	}
+	got, err = readAvro(bytes.NewReader(buf.Bytes()), 10)
+	if err != nil {
+		t.Fatalf("could not read: %v", err)
+	}
+	checkTable(t, "non-record schema", got, columns("value", "string"), [][]interface{}{{"a"}}, false)
+
+	if _, err := readAvro(strings.NewReader("not avro"), 10); err == nil {
+		t.Error("expected an error for a file that is not an Avro container")
+	}
+}
+
+func TestReadParquet(t *testing.T) {
+	type address struct {
+		City string `parquet:"city"`
+	}
+	type row struct {
+		ID      int64    `parquet:"id"`
+		Name    *string  `parquet:"name,optional"`
+		Tags    []string `parquet:"tags,list"`
+		Address address  `parquet:"address"`
+	}
+	name := "a"
+	rows := []row{
+		{ID: 1, Name: &name, Tags: []string{"x", "y"}, Address: address{City: "Perth"}},
+		{ID: 2, Tags: []string{"z"}, Address: address{City: "Hobart"}},
+		{ID: 3, Address: address{City: "Darwin"}},
+	}
+	var buf bytes.Buffer
+	if err := parquet.Write(&buf, rows); err != nil {
+		t.Fatalf("could not write Parquet: %v", err)
+	}
+
+	got, err := readParquet(bytes.NewReader(buf.Bytes()), int64(buf.Len()), 2)
+	if err != nil {
+		t.Fatalf("could not read: %v", err)
+	}
+	names := make([]string, len(got.Schema))
+	for i, col := range got.Schema {
+		names[i] = col.Name
+	}
+	if want := []string{"id", "name", "tags.list.element", "address.city"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("expected columns %v, got %v", want, names)
+	}
+	want := [][]interface{}{{"1", "a", "[x, y]", "Perth"}, {"2", nil, "z", "Hobart"}}
+	if !reflect.DeepEqual(got.Rows, want) {
+		t.Errorf("expected rows %v, got %v", want, got.Rows)
+	}
+	if !got.Truncated || got.TotalRows != 3 {
+		t.Errorf("expected 3 rows in total and a truncated preview, got %d and %v", got.TotalRows, got.Truncated)
+	}
+
+	if _, err := readParquet(strings.NewReader("not parquet"), 11, 10); err == nil {
+		t.Error("expected an error for a file that is not Parquet")
+	}
+}
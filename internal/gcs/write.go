@@ -0,0 +1,334 @@
+package gcs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"cloudevents-explorer/internal/types"
+)
+
+// Upload types, as named by the JSON API's uploadType parameter
+const (
+	UploadMultipart = "multipart"
+	UploadResumable = "resumable"
+)
+
+// ResumableThreshold is the size above which UploadType picks a resumable
+// upload; it is also the chunk size of resumable uploads. Chunks must be a
+// multiple of 256 KiB.
+const ResumableThreshold = 8 << 20
+
+// UploadType returns the requested upload type, or picks one by size when
+// none was requested
+func UploadType(requested string, size int64) (string, error) {
+	switch requested {
+	case "", "auto":
+		if size > ResumableThreshold {
+			return UploadResumable, nil
+		}
+		return UploadMultipart, nil
+	case UploadMultipart, UploadResumable:
+		return requested, nil
+	}
+	return "", fmt.Errorf("unknown upload type %q", requested)
+}
+
+func (c *Client) uploadURL(bucket string, params url.Values) string {
+	return fmt.Sprintf("%s/upload/storage/v1/b/%s/o?%s", c.endpoint, url.PathEscape(bucket), params.Encode())
+}
+
+// Upload writes an object with the given upload type
+func (c *Client) Upload(ctx context.Context, uploadType, bucket, name, contentType string, size int64, r io.Reader) error {
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	if uploadType == UploadResumable {
+		return c.uploadResumable(ctx, bucket, name, contentType, size, r)
+	}
+	return c.uploadMultipart(ctx, bucket, name, contentType, r)
+}
+
+// uploadMultipart sends the object's metadata and content in one
+// multipart/related request, streaming the content
+func (c *Client) uploadMultipart(ctx context.Context, bucket, name, contentType string, r io.Reader) error {
+	meta, err := json.Marshal(map[string]string{"name": name, "contentType": contentType})
+	if err != nil {
+		return err
+	}
+
+	pr, pw := io.Pipe()
+	mw := multipart.NewWriter(pw)
+	go func() {
+		part, err := mw.CreatePart(textproto.MIMEHeader{"Content-Type": {"application/json; charset=UTF-8"}})
+		if err == nil {
+			_, err = part.Write(meta)
+		}
+		if err == nil {
+			part, err = mw.CreatePart(textproto.MIMEHeader{"Content-Type": {contentType}})
+		}
+		if err == nil {
+			_, err = io.Copy(part, r)
+		}
+		if err == nil {
+			err = mw.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+
+	rawURL := c.uploadURL(bucket, url.Values{"uploadType": {UploadMultipart}})
+	resp, err := c.do(ctx, http.MethodPost, rawURL, pr, "multipart/related; boundary="+mw.Boundary())
+	pr.Close()
+	if err != nil {
+		return err
+	}
+	return c.finalized(ctx, bucket, resp)
+}
+
+// finalized reads the object resource an upload responds with and reports
+// the new object
+func (c *Client) finalized(ctx context.Context, bucket string, resp *http.Response) error {
+	var obj types.Object
+	if err := decodeJSON(resp, &obj); err != nil {
+		return err
+	}
+	if c.onChange != nil && obj.Generation == "" {
+		// The upload response left the resource out
+		fetched, err := c.GetObject(ctx, bucket, obj.Name, "")
+		if err != nil {
+			return err
+		}
+		obj = *fetched
+	}
+	c.changed(ctx, EventFinalize, bucket, &obj)
+	return nil
+}
+
+// uploadResumable starts a resumable upload session and sends the content
+// in ResumableThreshold chunks. Each chunk but the last is acknowledged with
+// 308 and the range the server has persisted so far.
+func (c *Client) uploadResumable(ctx context.Context, bucket, name, contentType string, size int64, r io.Reader) error {
+	meta, err := json.Marshal(map[string]string{"name": name, "contentType": contentType})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		c.uploadURL(bucket, url.Values{"uploadType": {UploadResumable}, "name": {name}}), bytes.NewReader(meta))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=UTF-8")
+	req.Header.Set("X-Upload-Content-Type", contentType)
+	req.Header.Set("X-Upload-Content-Length", strconv.FormatInt(size, 10))
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode/100 != 2 {
+		defer resp.Body.Close()
+		return apiError(resp)
+	}
+	resp.Body.Close()
+
+	session := resp.Header.Get("Location")
+	if session == "" {
+		return fmt.Errorf("the server did not return a resumable upload session")
+	}
+
+	// A 308 here is not a redirect, so it must not be followed
+	chunkClient := *c.http
+	chunkClient.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	buf := make([]byte, ResumableThreshold)
+	var offset int64
+	for {
+		n, err := io.ReadFull(r, buf)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return err
+		}
+
+		contentRange := fmt.Sprintf("bytes */%d", size)
+		if n > 0 {
+			contentRange = fmt.Sprintf("bytes %d-%d/%d", offset, offset+int64(n)-1, size)
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPut, session, bytes.NewReader(buf[:n]))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Range", contentRange)
+
+		resp, err := chunkClient.Do(req)
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode/100 == 2 {
+			return c.finalized(ctx, bucket, resp)
+		}
+		resp.Body.Close()
+
+		switch {
+		case resp.StatusCode == http.StatusPermanentRedirect:
+			// "Resume Incomplete": Range holds the bytes persisted so far
+			persisted := offset + int64(n)
+			if rng := resp.Header.Get("Range"); rng != "" {
+				if i := strings.LastIndex(rng, "-"); i != -1 {
+					end, _ := strconv.ParseInt(rng[i+1:], 10, 64)
+					persisted = end + 1
+				}
+			}
+			if persisted != offset+int64(n) {
+				return fmt.Errorf("upload interrupted: server persisted %d of %d bytes", persisted, offset+int64(n))
+			}
+			offset = persisted
+			if n == 0 {
+				return fmt.Errorf("upload incomplete: server expects more than %d bytes", offset)
+			}
+		default:
+			return fmt.Errorf("upload chunk at byte %d failed: %s", offset, resp.Status)
+		}
+	}
+}
+
+// DeleteObject deletes one object
+func (c *Client) DeleteObject(ctx context.Context, bucket, object string) error {
+	// A deletion is reported with the resource of the object it removed
+	var obj *types.Object
+	if c.onChange != nil {
+		var err error
+		if obj, err = c.GetObject(ctx, bucket, object, ""); err != nil {
+			return err
+		}
+	}
+
+	resp, err := c.do(ctx, http.MethodDelete, c.objectURL(bucket, object), nil, "")
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	c.changed(ctx, EventDelete, bucket, obj)
+	return nil
+}
+
+// DeletePrefix deletes every object under prefix, including a folder
+// placeholder named after the prefix itself, and returns how many it deleted
+func (c *Client) DeletePrefix(ctx context.Context, bucket, prefix string) (int, error) {
+	names, err := c.listNames(ctx, bucket, prefix)
+	if err != nil {
+		return 0, err
+	}
+	for i, name := range names {
+		if err := c.DeleteObject(ctx, bucket, name); err != nil {
+			return i, fmt.Errorf("failed to delete %s: %w", name, err)
+		}
+	}
+	return len(names), nil
+}
+
+// CopyObject copies one object with the rewrite API, which real GCS may
+// need several calls to finish for large objects or across locations
+func (c *Client) CopyObject(ctx context.Context, srcBucket, srcObject, dstBucket, dstObject string) error {
+	token := ""
+	for {
+		rawURL := fmt.Sprintf("%s/rewriteTo/b/%s/o/%s", c.objectURL(srcBucket, srcObject), url.PathEscape(dstBucket), url.PathEscape(dstObject))
+		if token != "" {
+			rawURL += "?rewriteToken=" + url.QueryEscape(token)
+		}
+
+		var result struct {
+			Done         bool          `json:"done"`
+			RewriteToken string        `json:"rewriteToken"`
+			Resource     *types.Object `json:"resource"`
+		}
+		err := c.sendJSON(ctx, http.MethodPost, rawURL, nil, &result)
+		if err != nil {
+			return err
+		}
+		if result.Done || result.RewriteToken == "" {
+			if result.Resource == nil && c.onChange != nil {
+				// Some emulators leave the resource out of the response
+				if result.Resource, err = c.GetObject(ctx, dstBucket, dstObject, ""); err != nil {
+					return err
+				}
+			}
+			c.changed(ctx, EventFinalize, dstBucket, result.Resource)
+			return nil
+		}
+		token = result.RewriteToken
+	}
+}
+
+// Copy copies an object, or every object under a prefix when the source ends
+// with "/". Names below a source prefix are kept below the destination. With
+// move the sources are deleted once copied. It returns the objects copied.
+func (c *Client) Copy(ctx context.Context, srcBucket, src, dstBucket, dst string, move bool) (int, error) {
+	names := []string{src}
+	if strings.HasSuffix(src, "/") {
+		if dst != "" && !strings.HasSuffix(dst, "/") {
+			dst += "/"
+		}
+		var err error
+		if names, err = c.listNames(ctx, srcBucket, src); err != nil {
+			return 0, err
+		}
+	}
+
+	for i, name := range names {
+		target := dst
+		if strings.HasSuffix(src, "/") {
+			target = dst + strings.TrimPrefix(name, src)
+		}
+		if srcBucket == dstBucket && name == target {
+			return i, fmt.Errorf("cannot copy %s onto itself", name)
+		}
+
+		if err := c.CopyObject(ctx, srcBucket, name, dstBucket, target); err != nil {
+			return i, fmt.Errorf("failed to copy %s: %w", name, err)
+		}
+		if move {
+			if err := c.DeleteObject(ctx, srcBucket, name); err != nil {
+				return i, fmt.Errorf("copied %s but failed to delete it: %w", name, err)
+			}
+		}
+	}
+	return len(names), nil
+}
+
+// CreateBucket creates a bucket in the client's project. An empty location
+// leaves the choice to the server.
+func (c *Client) CreateBucket(ctx context.Context, name, location string) error {
+	if c.projectID == "" && !c.emulator {
+		return fmt.Errorf("a project ID is required to create a bucket")
+	}
+
+	params := url.Values{}
+	if c.projectID != "" {
+		params.Set("project", c.projectID)
+	}
+	body := map[string]string{"name": name}
+	if location != "" {
+		body["location"] = location
+	}
+	return c.sendJSON(ctx, http.MethodPost, c.endpoint+"/storage/v1/b?"+params.Encode(), body, nil)
+}
+
+// CreateFolder writes an empty placeholder object named prefix + "/", which
+// the browser shows as a folder even before anything is stored under it
+func (c *Client) CreateFolder(ctx context.Context, bucket, prefix string) error {
+	if !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	return c.uploadMultipart(ctx, bucket, prefix, "application/x-directory", bytes.NewReader(nil))
+}
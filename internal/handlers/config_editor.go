@@ -7,6 +7,7 @@ import (
 	"os"
 
 	"cloudevents-explorer/internal/config"
+	"cloudevents-explorer/internal/gcs"
 	"cloudevents-explorer/internal/templates"
 )
 
@@ -45,6 +46,9 @@ func HandleSaveAllConfigs(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Start polling in case the new config watches a bucket
+	gcs.Rewatch()
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{"status": "success"})
 }
@@ -4,140 +4,226 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
+	"cloudevents-explorer/internal/config"
+	"cloudevents-explorer/internal/gcs"
 	"cloudevents-explorer/internal/templates"
+	"cloudevents-explorer/internal/types"
 )
 
-const gcsBaseURL = "http://localhost:4443/storage/v1"
-
-type BucketsResponse struct {
-	Buckets []Bucket `json:"buckets"`
+func HandleGCS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprint(w, templates.GCS)
 }
 
-type Bucket struct {
-	Name string `json:"name"`
+// gcsConnection reads the connection of a GCS request: either the name of a
+// saved configuration in "config", or "emulatorHost" and "projectId". An
+// empty emulator host selects real GCS with Application Default Credentials.
+func gcsConnection(r *http.Request) (types.GCSConnection, error) {
+	q := r.URL.Query()
+	if name := q.Get("config"); name != "" {
+		for _, cfg := range config.Get().GCSConfigs {
+			if cfg.Name == name {
+				return types.GCSConnection{EmulatorHost: cfg.EmulatorHost, ProjectID: cfg.ProjectID}, nil
+			}
+		}
+		return types.GCSConnection{}, fmt.Errorf("GCS configuration %q not found", name)
+	}
+	return types.GCSConnection{
+		EmulatorHost: q.Get("emulatorHost"),
+		ProjectID:    q.Get("projectId"),
+	}, nil
 }
 
-type ObjectsResponse struct {
-	Items    []Object `json:"items,omitempty"`
-	Prefixes []string `json:"prefixes,omitempty"`
-}
+// openGCS creates a client for the request's connection, writing the error
+// response itself when it cannot
+func openGCS(w http.ResponseWriter, r *http.Request) (*gcs.Client, bool) {
+	conn, err := gcsConnection(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
 
-type Object struct {
-	Name string `json:"name"`
-	Size int64  `json:"size,string"`
+	client, err := gcs.NewClient(r.Context(), conn)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+	return client, true
 }
 
-type ContentResponse struct {
-	Content string `json:"content"`
-}
+// openGCSNotifying creates a client for a write, with the notifier that
+// publishes the bucket notifications the write triggers
+func openGCSNotifying(w http.ResponseWriter, r *http.Request) (*gcs.Client, *gcs.Notifier, bool) {
+	conn, err := gcsConnection(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, nil, false
+	}
 
-func HandleGCS(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	fmt.Fprint(w, templates.GCS)
+	client, err := gcs.NewClient(r.Context(), conn)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, nil, false
+	}
+	notifier := gcs.NewNotifier(conn)
+	client.OnChange(notifier.Notify)
+	return client, notifier, true
 }
 
 func HandleListBuckets(w http.ResponseWriter, r *http.Request) {
-	resp, err := http.Get(gcsBaseURL + "/b")
-	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to fetch buckets: %v", err), http.StatusInternalServerError)
+	client, ok := openGCS(w, r)
+	if !ok {
 		return
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	buckets, err := client.ListBuckets(r.Context())
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to read response: %v", err), http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("Failed to fetch buckets: %v", err), http.StatusInternalServerError)
 		return
 	}
 
-	var gcsResp struct {
-		Items []struct {
-			Name string `json:"name"`
-		} `json:"items"`
+	response := types.BucketsResponse{Buckets: buckets}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func HandleListObjects(w http.ResponseWriter, r *http.Request) {
+	bucket := r.URL.Query().Get("bucket")
+	if bucket == "" {
+		http.Error(w, "bucket parameter is required", http.StatusBadRequest)
+		return
 	}
 
-	if err := json.Unmarshal(body, &gcsResp); err != nil {
-		http.Error(w, fmt.Sprintf("Failed to parse response: %v", err), http.StatusInternalServerError)
+	client, ok := openGCS(w, r)
+	if !ok {
 		return
 	}
 
-	buckets := make([]Bucket, len(gcsResp.Items))
-	for i, item := range gcsResp.Items {
-		buckets[i] = Bucket{Name: item.Name}
+	response, err := client.ListObjects(r.Context(), bucket, r.URL.Query().Get("prefix"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to fetch objects: %v", err), http.StatusInternalServerError)
+		return
 	}
 
-	response := BucketsResponse{Buckets: buckets}
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
 
-func HandleListObjects(w http.ResponseWriter, r *http.Request) {
+// HandleGetObjectContent returns one chunk of an object for preview, from
+// "offset" and at most "length" bytes long
+func HandleGetObjectContent(w http.ResponseWriter, r *http.Request) {
 	bucket := r.URL.Query().Get("bucket")
-	if bucket == "" {
-		http.Error(w, "bucket parameter is required", http.StatusBadRequest)
+	object := r.URL.Query().Get("object")
+
+	if bucket == "" || object == "" {
+		http.Error(w, "bucket and object parameters are required", http.StatusBadRequest)
 		return
 	}
 
-	prefix := r.URL.Query().Get("prefix")
-
-	url := fmt.Sprintf("%s/b/%s/o", gcsBaseURL, bucket)
-	if prefix != "" {
-		url += "?prefix=" + prefix + "&delimiter=/"
-	} else {
-		url += "?delimiter=/"
+	var offset, length int64
+	var err error
+	if v := r.URL.Query().Get("offset"); v != "" {
+		if offset, err = strconv.ParseInt(v, 10, 64); err != nil || offset < 0 {
+			http.Error(w, "invalid offset", http.StatusBadRequest)
+			return
+		}
+	}
+	if v := r.URL.Query().Get("length"); v != "" {
+		if length, err = strconv.ParseInt(v, 10, 64); err != nil {
+			http.Error(w, "invalid length", http.StatusBadRequest)
+			return
+		}
 	}
 
-	resp, err := http.Get(url)
-	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to fetch objects: %v", err), http.StatusInternalServerError)
+	client, ok := openGCS(w, r)
+	if !ok {
 		return
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	response, err := client.Preview(r.Context(), bucket, object, r.URL.Query().Get("generation"), offset, length)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to read response: %v", err), http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("Failed to fetch object: %v", err), http.StatusInternalServerError)
 		return
 	}
 
-	var gcsResp struct {
-		Items []struct {
-			Name string `json:"name"`
-			Size string `json:"size"`
-		} `json:"items"`
-		Prefixes []string `json:"prefixes"`
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// HandleDiffObjects compares the object named by "bucket", "object" and
+// "generation" with the one named by "otherBucket", "otherObject" and
+// "otherGeneration". Either other name defaults to its counterpart, so
+// comparing two generations only needs "otherGeneration". "mode" is text,
+// json or csv, guessed from the object name when empty.
+func HandleDiffObjects(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	left := gcs.ObjectRef{Bucket: q.Get("bucket"), Object: q.Get("object"), Generation: q.Get("generation")}
+	right := gcs.ObjectRef{Bucket: q.Get("otherBucket"), Object: q.Get("otherObject"), Generation: q.Get("otherGeneration")}
+	if right.Bucket == "" {
+		right.Bucket = left.Bucket
+	}
+	if right.Object == "" {
+		right.Object = left.Object
 	}
 
-	if err := json.Unmarshal(body, &gcsResp); err != nil {
-		http.Error(w, fmt.Sprintf("Failed to parse response: %v", err), http.StatusInternalServerError)
+	if left.Bucket == "" || left.Object == "" {
+		http.Error(w, "bucket and object parameters are required", http.StatusBadRequest)
 		return
 	}
-
-	response := ObjectsResponse{
-		Prefixes: gcsResp.Prefixes,
+	if left == right {
+		http.Error(w, "choose a different object or generation to compare with", http.StatusBadRequest)
+		return
 	}
 
-	if len(gcsResp.Items) > 0 {
-		objects := make([]Object, 0, len(gcsResp.Items))
-		for _, item := range gcsResp.Items {
-			var size int64
-			fmt.Sscanf(item.Size, "%d", &size)
-			objects = append(objects, Object{
-				Name: item.Name,
-				Size: size,
-			})
+	opts := gcs.DiffOptions{
+		Mode:             q.Get("mode"),
+		IgnoreWhitespace: q.Get("ignoreWhitespace") == "1",
+		Context:          -1,
+	}
+	switch opts.Mode {
+	case "", gcs.DiffText, gcs.DiffJSON, gcs.DiffCSV:
+	default:
+		http.Error(w, "mode must be text, json or csv", http.StatusBadRequest)
+		return
+	}
+	for _, column := range strings.Split(q.Get("keyColumns"), ",") {
+		if column = strings.TrimSpace(column); column != "" {
+			opts.KeyColumns = append(opts.KeyColumns, column)
+		}
+	}
+	if v := q.Get("context"); v != "" {
+		var err error
+		if opts.Context, err = strconv.Atoi(v); err != nil || opts.Context < 0 {
+			http.Error(w, "invalid context", http.StatusBadRequest)
+			return
 		}
-		response.Items = objects
+	}
+
+	client, ok := openGCS(w, r)
+	if !ok {
+		return
+	}
+
+	diff, err := client.Diff(r.Context(), left, right, opts)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to compare objects: %v", err), http.StatusInternalServerError)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	json.NewEncoder(w).Encode(diff)
 }
 
-func HandleGetObjectContent(w http.ResponseWriter, r *http.Request) {
+// HandlePreviewObjectTable returns the schema and first "limit" records of
+// an Avro, Parquet, CSV, NDJSON or JSON object. "format" overrides the format
+// guessed from the object's name.
+func HandlePreviewObjectTable(w http.ResponseWriter, r *http.Request) {
 	bucket := r.URL.Query().Get("bucket")
 	object := r.URL.Query().Get("object")
 
@@ -146,24 +232,28 @@ func HandleGetObjectContent(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	url := fmt.Sprintf("%s/b/%s/o/%s?alt=media", gcsBaseURL, bucket, strings.ReplaceAll(object, "/", "%2F"))
+	limit := 0
+	if v := r.URL.Query().Get("limit"); v != "" {
+		var err error
+		if limit, err = strconv.Atoi(v); err != nil {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+	}
 
-	resp, err := http.Get(url)
-	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to fetch object: %v", err), http.StatusInternalServerError)
+	client, ok := openGCS(w, r)
+	if !ok {
 		return
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	preview, err := client.PreviewTable(r.Context(), bucket, object, r.URL.Query().Get("generation"), r.URL.Query().Get("format"), limit)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to read response: %v", err), http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("Failed to preview object: %v", err), http.StatusInternalServerError)
 		return
 	}
 
-	response := ContentResponse{Content: string(body)}
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	json.NewEncoder(w).Encode(preview)
 }
 
 func HandleDownloadObject(w http.ResponseWriter, r *http.Request) {
@@ -175,14 +265,17 @@ func HandleDownloadObject(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	url := fmt.Sprintf("%s/b/%s/o/%s?alt=media", gcsBaseURL, bucket, strings.ReplaceAll(object, "/", "%2F"))
+	client, ok := openGCS(w, r)
+	if !ok {
+		return
+	}
 
-	resp, err := http.Get(url)
+	rc, err := client.OpenObject(r.Context(), bucket, object, r.URL.Query().Get("generation"))
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to fetch object: %v", err), http.StatusInternalServerError)
 		return
 	}
-	defer resp.Body.Close()
+	defer rc.Close()
 
 	// Set headers for file download
 	filename := object
@@ -190,9 +283,511 @@ func HandleDownloadObject(w http.ResponseWriter, r *http.Request) {
 		filename = object[idx+1:]
 	}
 
-	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", filename))
-	w.Header().Set("Content-Type", "application/octet-stream")
+	// With "inline" an image is served with its content type so the preview
+	// can show it; the sandbox keeps an SVG opened directly from running
+	// scripts
+	if contentType := r.URL.Query().Get("contentType"); r.URL.Query().Get("inline") != "" && strings.HasPrefix(contentType, "image/") {
+		w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=\"%s\"", filename))
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Security-Policy", "sandbox")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+	} else {
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", filename))
+		w.Header().Set("Content-Type", "application/octet-stream")
+	}
 
 	// Copy the content to response
-	io.Copy(w, resp.Body)
+	io.Copy(w, rc)
+}
+
+// HandleGetObjectMetadata returns the full metadata of an object, or of the
+// generation named by "generation"
+func HandleGetObjectMetadata(w http.ResponseWriter, r *http.Request) {
+	bucket := r.URL.Query().Get("bucket")
+	object := r.URL.Query().Get("object")
+
+	if bucket == "" || object == "" {
+		http.Error(w, "bucket and object parameters are required", http.StatusBadRequest)
+		return
+	}
+
+	client, ok := openGCS(w, r)
+	if !ok {
+		return
+	}
+
+	obj, err := client.GetObject(r.Context(), bucket, object, r.URL.Query().Get("generation"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to fetch object metadata: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(obj)
+}
+
+// HandleUpdateObjectMetadata replaces the custom metadata of an object
+func HandleUpdateObjectMetadata(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req types.GCSMetadataRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Bucket == "" || req.Object == "" {
+		http.Error(w, "bucket and object are required", http.StatusBadRequest)
+		return
+	}
+
+	client, _, ok := openGCSNotifying(w, r)
+	if !ok {
+		return
+	}
+
+	obj, err := client.UpdateMetadata(r.Context(), req.Bucket, req.Object, req.Metadata)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to update object metadata: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(obj)
+}
+
+// HandleListObjectVersions lists the generations of an object
+func HandleListObjectVersions(w http.ResponseWriter, r *http.Request) {
+	bucket := r.URL.Query().Get("bucket")
+	object := r.URL.Query().Get("object")
+
+	if bucket == "" || object == "" {
+		http.Error(w, "bucket and object parameters are required", http.StatusBadRequest)
+		return
+	}
+
+	client, ok := openGCS(w, r)
+	if !ok {
+		return
+	}
+
+	response, err := client.ListVersions(r.Context(), bucket, object)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to fetch object versions: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// HandleSearchObjects searches a bucket recursively by object name, size and
+// updated time. "mode" is glob or regex; sizes are in bytes and times are
+// RFC 3339.
+func HandleSearchObjects(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	bucket := q.Get("bucket")
+	if bucket == "" {
+		http.Error(w, "bucket parameter is required", http.StatusBadRequest)
+		return
+	}
+
+	opts := gcs.SearchOptions{
+		Prefix:  q.Get("prefix"),
+		Pattern: q.Get("pattern"),
+		Regex:   q.Get("mode") == "regex",
+	}
+	var err error
+	for _, p := range []struct {
+		name string
+		dst  *int64
+	}{{"minSize", &opts.MinSize}, {"maxSize", &opts.MaxSize}} {
+		if v := q.Get(p.name); v != "" {
+			if *p.dst, err = strconv.ParseInt(v, 10, 64); err != nil {
+				http.Error(w, "invalid "+p.name, http.StatusBadRequest)
+				return
+			}
+		}
+	}
+	for _, p := range []struct {
+		name string
+		dst  *time.Time
+	}{{"updatedAfter", &opts.UpdatedAfter}, {"updatedBefore", &opts.UpdatedBefore}} {
+		if v := q.Get(p.name); v != "" {
+			if *p.dst, err = time.Parse(time.RFC3339, v); err != nil {
+				http.Error(w, "invalid "+p.name, http.StatusBadRequest)
+				return
+			}
+		}
+	}
+	if v := q.Get("limit"); v != "" {
+		if opts.Limit, err = strconv.Atoi(v); err != nil {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+	}
+
+	client, ok := openGCS(w, r)
+	if !ok {
+		return
+	}
+
+	response, err := client.Search(r.Context(), bucket, opts)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to search objects: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// HandleDownloadZip streams a ZIP archive of the objects named by "object",
+// which may repeat, or of every object under "prefix". The names may come
+// from the query or from a posted form.
+func HandleDownloadZip(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	bucket := r.Form.Get("bucket")
+	names := r.Form["object"]
+	prefix := r.Form.Get("prefix")
+	if bucket == "" {
+		http.Error(w, "bucket parameter is required", http.StatusBadRequest)
+		return
+	}
+
+	client, ok := openGCS(w, r)
+	if !ok {
+		return
+	}
+
+	objects, err := client.ZipObjects(r.Context(), bucket, prefix, names)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to list objects: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Entries of a prefix download are named relative to it
+	base := ""
+	filename := bucket
+	if len(names) == 0 {
+		base = prefix
+		if p := strings.Trim(prefix, "/"); p != "" {
+			filename += "-" + strings.ReplaceAll(p, "/", "-")
+		}
+	}
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s.zip\"", filename))
+	w.Header().Set("Content-Type", "application/zip")
+
+	// Once streaming starts the status is sent, so a failure can only cut
+	// the archive short
+	if err := client.WriteZip(r.Context(), w, bucket, base, objects); err != nil {
+		log.Printf("GCS ZIP download of %s failed: %v", bucket, err)
+	}
+}
+
+func writeGCSOperation(w http.ResponseWriter, notifier *gcs.Notifier, resp types.GCSOperationResponse) {
+	if notifier != nil {
+		resp.Notified, resp.NotificationError = notifier.Result()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if resp.Error != "" {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+// HandleUploadObjects stores the files of a multipart form under the form's
+// prefix. uploadType is multipart, resumable, or empty to pick by file size.
+func HandleUploadObjects(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Larger files are spooled to temporary files rather than held in memory
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	bucket := r.FormValue("bucket")
+	files := r.MultipartForm.File["files"]
+	if bucket == "" || len(files) == 0 {
+		http.Error(w, "bucket and at least one file are required", http.StatusBadRequest)
+		return
+	}
+
+	client, notifier, ok := openGCSNotifying(w, r)
+	if !ok {
+		return
+	}
+
+	prefix := r.FormValue("prefix")
+	resp := types.GCSOperationResponse{Uploaded: []types.GCSUploadedObject{}}
+	for _, fh := range files {
+		uploadType, err := gcs.UploadType(r.FormValue("uploadType"), fh.Size)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		name := prefix + fh.Filename
+		f, err := fh.Open()
+		if err == nil {
+			err = client.Upload(r.Context(), uploadType, bucket, name, fh.Header.Get("Content-Type"), fh.Size, f)
+			f.Close()
+		}
+		if err != nil {
+			resp.Error = fmt.Sprintf("Failed to upload %s: %v", name, err)
+			break
+		}
+		resp.Uploaded = append(resp.Uploaded, types.GCSUploadedObject{Name: name, Size: fh.Size, UploadType: uploadType})
+	}
+
+	resp.Count = len(resp.Uploaded)
+	resp.Message = fmt.Sprintf("Uploaded %d of %d file(s)", resp.Count, len(files))
+	writeGCSOperation(w, notifier, resp)
+}
+
+// HandleDeleteObject deletes the object named by "object", or every object
+// under "prefix"
+func HandleDeleteObject(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	bucket := r.URL.Query().Get("bucket")
+	object := r.URL.Query().Get("object")
+	prefix := r.URL.Query().Get("prefix")
+	if bucket == "" || (object == "") == (prefix == "") {
+		http.Error(w, "bucket and either object or prefix parameters are required", http.StatusBadRequest)
+		return
+	}
+
+	client, notifier, ok := openGCSNotifying(w, r)
+	if !ok {
+		return
+	}
+
+	var resp types.GCSOperationResponse
+	if object != "" {
+		if err := client.DeleteObject(r.Context(), bucket, object); err != nil {
+			resp.Error = err.Error()
+		} else {
+			resp.Count = 1
+		}
+	} else {
+		count, err := client.DeletePrefix(r.Context(), bucket, prefix)
+		resp.Count = count
+		if err != nil {
+			resp.Error = err.Error()
+		}
+	}
+
+	resp.Message = fmt.Sprintf("Deleted %d object(s)", resp.Count)
+	writeGCSOperation(w, notifier, resp)
+}
+
+// HandleCopyObject copies or moves an object or prefix, within a bucket or
+// between buckets
+func HandleCopyObject(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req types.GCSCopyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.SourceBucket == "" || req.SourceObject == "" || req.DestinationBucket == "" {
+		http.Error(w, "sourceBucket, sourceObject and destinationBucket are required", http.StatusBadRequest)
+		return
+	}
+	if req.DestinationObject == "" && !strings.HasSuffix(req.SourceObject, "/") {
+		req.DestinationObject = req.SourceObject
+	}
+
+	client, notifier, ok := openGCSNotifying(w, r)
+	if !ok {
+		return
+	}
+
+	count, err := client.Copy(r.Context(), req.SourceBucket, req.SourceObject, req.DestinationBucket, req.DestinationObject, req.Move)
+	resp := types.GCSOperationResponse{Count: count}
+	verb := "Copied"
+	if req.Move {
+		verb = "Moved"
+	}
+	resp.Message = fmt.Sprintf("%s %d object(s)", verb, count)
+	if err != nil {
+		resp.Error = err.Error()
+	}
+	writeGCSOperation(w, notifier, resp)
+}
+
+// HandleCreateBucket creates a bucket in the connection's project
+func HandleCreateBucket(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req types.GCSBucketRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Name == "" {
+		http.Error(w, "name is required", http.StatusBadRequest)
+		return
+	}
+
+	client, ok := openGCS(w, r)
+	if !ok {
+		return
+	}
+
+	resp := types.GCSOperationResponse{Message: fmt.Sprintf("Created bucket %s", req.Name)}
+	if err := client.CreateBucket(r.Context(), req.Name, req.Location); err != nil {
+		resp.Error = err.Error()
+	}
+	writeGCSOperation(w, nil, resp)
+}
+
+// HandleCreateFolder creates an empty folder placeholder object
+func HandleCreateFolder(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req types.GCSFolderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Bucket == "" || strings.Trim(req.Prefix, "/") == "" {
+		http.Error(w, "bucket and prefix are required", http.StatusBadRequest)
+		return
+	}
+
+	client, notifier, ok := openGCSNotifying(w, r)
+	if !ok {
+		return
+	}
+
+	resp := types.GCSOperationResponse{Message: fmt.Sprintf("Created folder %s", req.Prefix)}
+	if err := client.CreateFolder(r.Context(), req.Bucket, req.Prefix); err != nil {
+		resp.Error = err.Error()
+	}
+	writeGCSOperation(w, notifier, resp)
+}
+
+// HandleListGCSNotifications lists the notifications of a bucket on the
+// request's connection
+func HandleListGCSNotifications(w http.ResponseWriter, r *http.Request) {
+	bucket := r.URL.Query().Get("bucket")
+	if bucket == "" {
+		http.Error(w, "bucket parameter is required", http.StatusBadRequest)
+		return
+	}
+
+	conn, err := gcsConnection(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	notifications := []config.GCSNotification{}
+	for _, n := range config.Get().GCSNotifications {
+		if n.Bucket == bucket && gcs.SameHost(n.GCSEmulatorHost, conn.EmulatorHost) {
+			notifications = append(notifications, n)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(notifications)
+}
+
+// HandleSaveGCSNotification adds or replaces a notification of a bucket on
+// the request's connection
+func HandleSaveGCSNotification(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var n config.GCSNotification
+	if err := json.NewDecoder(r.Body).Decode(&n); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if n.Bucket == "" || n.PubSubEmulatorHost == "" || n.PubSubProjectID == "" || n.TopicID == "" {
+		http.Error(w, "bucket, pubsubEmulatorHost, pubsubProjectId and topicId are required", http.StatusBadRequest)
+		return
+	}
+	if n.PayloadFormat == "" {
+		n.PayloadFormat = gcs.PayloadJSONAPIV1
+	}
+	if n.PayloadFormat != gcs.PayloadJSONAPIV1 && n.PayloadFormat != gcs.PayloadNone {
+		http.Error(w, "payloadFormat must be JSON_API_V1 or NONE", http.StatusBadRequest)
+		return
+	}
+	for _, event := range n.EventTypes {
+		if event != gcs.EventFinalize && event != gcs.EventDelete && event != gcs.EventMetadataUpdate {
+			http.Error(w, fmt.Sprintf("unsupported event type %q", event), http.StatusBadRequest)
+			return
+		}
+	}
+
+	conn, err := gcsConnection(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	n.GCSEmulatorHost = conn.EmulatorHost
+	n.GCSProjectID = conn.ProjectID
+
+	saved, err := config.SaveGCSNotification(n)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to save notification: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Start polling in case the notification watches its bucket
+	gcs.Rewatch()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(saved)
+}
+
+func HandleDeleteGCSNotification(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		http.Error(w, "id parameter is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := config.DeleteGCSNotification(id); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to delete notification: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
 }
@@ -2,34 +2,56 @@ package handlers
 
 import (
 	"bytes"
-	"context"
 	"crypto/tls"
 	"crypto/x509"
 	"encoding/json"
 	"fmt"
 	"io"
-	"net"
 	"net/http"
+	"strings"
 	"time"
 
 	"cloudevents-explorer/internal/config"
+	"cloudevents-explorer/internal/restclient"
 	"cloudevents-explorer/internal/templates"
 )
 
+// RestRequest is a request to send. {{name}} references in the URL, params,
+// headers, body, TLS fields and the resolver and proxy options are replaced
+// with the variables of Collection, overridden by those of Environment.
 type RestRequest struct {
-	Method  string                 `json:"method"`
-	URL     string                 `json:"url"`
-	Headers map[string]string      `json:"headers"`
-	Body    map[string]interface{} `json:"body"`
-	TLSCert string                 `json:"tlsCert"`
-	TLSKey  string                 `json:"tlsKey"`
+	Method      string                `json:"method"`
+	URL         string                `json:"url"`
+	Params      config.QueryParams    `json:"params"`
+	Headers     map[string]string     `json:"headers"`
+	Body        config.RequestBody    `json:"body"`
+	TLSCert     string                `json:"tlsCert"`
+	TLSKey      string                `json:"tlsKey"`
+	Collection  string                `json:"collection,omitempty"`
+	Environment string                `json:"environment,omitempty"`
+	Options     config.RequestOptions `json:"options"`
+	Assertions  []config.Assertion    `json:"assertions,omitempty"`
+	Extractions []config.Extraction   `json:"extractions,omitempty"`
 }
 
+// RestResponse is the response to a RestRequest, with every value of each
+// header. ResolvedURL is the URL sent, with secret variables masked;
+// UnresolvedVariables names references that no variable matched and were
+// sent as written; Redirects is the chain of redirects on the way; Timing
+// breaks down the time the request took and describes the connection;
+// Assertions and Extractions report the request's checks and the variables
+// it set.
 type RestResponse struct {
-	StatusCode int                    `json:"statusCode"`
-	Headers    map[string]string      `json:"headers"`
-	Body       interface{}            `json:"body"`
-	Error      string                 `json:"error,omitempty"`
+	StatusCode          int                           `json:"statusCode"`
+	Headers             map[string][]string           `json:"headers"`
+	Body                interface{}                   `json:"body"`
+	ResolvedURL         string                        `json:"resolvedUrl,omitempty"`
+	UnresolvedVariables []string                      `json:"unresolvedVariables,omitempty"`
+	Redirects           []restclient.Redirect         `json:"redirects,omitempty"`
+	Timing              *restclient.Timing            `json:"timing,omitempty"`
+	Assertions          []restclient.AssertionResult  `json:"assertions,omitempty"`
+	Extractions         []restclient.ExtractionResult `json:"extractions,omitempty"`
+	Error               string                        `json:"error,omitempty"`
 }
 
 func HandleRestClient(w http.ResponseWriter, r *http.Request) {
@@ -50,10 +72,31 @@ func HandleRestSend(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Create HTTP client with TLS configuration
-	// Default to InsecureSkipVerify=true for testing convenience
+	variables, err := config.RequestVariables(req.Collection, req.Environment)
+	if err != nil {
+		sendRestError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	vars := restclient.NewVariables(variables)
+	req.URL = vars.Substitute(req.URL)
+	req.Headers = vars.SubstituteMap(req.Headers)
+	req.TLSCert = vars.Substitute(req.TLSCert)
+	req.TLSKey = vars.Substitute(req.TLSKey)
+	req.Options.DNSServer = vars.Substitute(req.Options.DNSServer)
+	req.Options.Proxy = vars.Substitute(req.Options.Proxy)
+	for i := range req.Options.HostOverrides {
+		req.Options.HostOverrides[i].Host = vars.Substitute(req.Options.HostOverrides[i].Host)
+		req.Options.HostOverrides[i].Address = vars.Substitute(req.Options.HostOverrides[i].Address)
+	}
+	body, contentType, err := restclient.BuildBody(req.Body, vars)
+	if err != nil {
+		sendRestError(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Certificates are only verified when the request opts in
 	tlsConfig := &tls.Config{
-		InsecureSkipVerify: true,
+		InsecureSkipVerify: !req.Options.VerifyTLS,
 	}
 
 	// Configure TLS if certificates are provided
@@ -65,7 +108,6 @@ func HandleRestSend(w http.ResponseWriter, r *http.Request) {
 		}
 
 		tlsConfig.Certificates = []tls.Certificate{cert}
-		tlsConfig.RootCAs = x509.NewCertPool()
 	} else if req.TLSCert != "" {
 		// If only cert is provided, use it as CA cert
 		tlsConfig.RootCAs = x509.NewCertPool()
@@ -75,61 +117,51 @@ func HandleRestSend(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	// Create custom dialer with Google Public DNS fallback
-	dialer := &net.Dialer{
-		Timeout:   30 * time.Second,
-		KeepAlive: 30 * time.Second,
-		Resolver: &net.Resolver{
-			PreferGo: true,
-			Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
-				// Use Google Public DNS (8.8.8.8:53) as fallback
-				d := net.Dialer{
-					Timeout: time.Second * 5,
-				}
-				return d.DialContext(ctx, network, "8.8.8.8:53")
-			},
-		},
-	}
-
-	// Use custom transport with proper DNS resolution
-	transport := &http.Transport{
-		TLSClientConfig:   tlsConfig,
-		DisableKeepAlives: false,
-		DialContext:       dialer.DialContext,
-	}
-
-	client := &http.Client{
-		Timeout:   30 * time.Second,
-		Transport: transport,
+	client, err := restclient.NewClient(req.Options, tlsConfig)
+	if err != nil {
+		sendRestError(w, vars.Mask("Invalid request options: "+err.Error()), http.StatusBadRequest)
+		return
 	}
 
 	// Prepare request body
 	var bodyReader io.Reader
-	if req.Body != nil && len(req.Body) > 0 {
-		bodyBytes, err := json.Marshal(req.Body)
-		if err != nil {
-			sendRestError(w, "Failed to marshal request body: "+err.Error(), http.StatusBadRequest)
-			return
-		}
-		bodyReader = bytes.NewReader(bodyBytes)
+	if body != nil {
+		bodyReader = bytes.NewReader(body)
 	}
 
 	// Create HTTP request
 	httpReq, err := http.NewRequest(req.Method, req.URL, bodyReader)
 	if err != nil {
-		sendRestError(w, "Failed to create request: "+err.Error(), http.StatusBadRequest)
+		sendRestError(w, vars.Mask("Failed to create request: "+err.Error()), http.StatusBadRequest)
 		return
 	}
 
+	// Add query parameters
+	httpReq.URL.RawQuery = restclient.AppendQuery(httpReq.URL.RawQuery, req.Params, vars)
+
 	// Set headers
 	for key, value := range req.Headers {
 		httpReq.Header.Set(key, value)
 	}
+	// The body decides its own content type, which for form-data carries the
+	// boundary the body was written with
+	if contentType != "" {
+		httpReq.Header.Set("Content-Type", contentType)
+	}
 
-	// Execute request
+	// Execute request, timing its phases
+	tracer := restclient.NewTracer()
+	httpReq = httpReq.WithContext(tracer.WithTrace(httpReq.Context()))
 	resp, err := client.Do(httpReq)
 	if err != nil {
-		sendRestError(w, "Request failed: "+err.Error(), http.StatusBadGateway)
+		// Keep the redirects followed before the failure
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadGateway)
+		json.NewEncoder(w).Encode(RestResponse{
+			StatusCode: http.StatusBadGateway,
+			Redirects:  maskRedirects(client.Redirects, vars),
+			Error:      vars.Mask("Request failed: " + err.Error()),
+		})
 		return
 	}
 	defer resp.Body.Close()
@@ -140,6 +172,34 @@ func HandleRestSend(w http.ResponseWriter, r *http.Request) {
 		sendRestError(w, "Failed to read response: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
+	timing := tracer.Timing(resp, time.Now())
+
+	// Check the response and extract variables from it
+	checked := &restclient.Response{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Body:       respBody,
+		Duration:   timing.Total,
+	}
+	for i := range req.Assertions {
+		req.Assertions[i].Target = vars.Substitute(req.Assertions[i].Target)
+		req.Assertions[i].Value = vars.Substitute(req.Assertions[i].Value)
+	}
+	assertions := restclient.Evaluate(req.Assertions, checked)
+	extracted, extractions := restclient.Extract(req.Extractions, checked)
+	if len(extracted) > 0 {
+		err := fmt.Errorf("select an environment to extract variables into")
+		if req.Environment != "" {
+			err = config.SetEnvironmentVariables(req.Environment, extracted)
+		}
+		if err != nil {
+			for i := range extractions {
+				if extractions[i].Error == "" {
+					extractions[i].Error = "Failed to save: " + err.Error()
+				}
+			}
+		}
+	}
 
 	// Parse response body as JSON if possible
 	var parsedBody interface{}
@@ -150,25 +210,42 @@ func HandleRestSend(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	// Extract response headers
-	headers := make(map[string]string)
-	for key, values := range resp.Header {
-		if len(values) > 0 {
-			headers[key] = values[0]
-		}
-	}
-
 	// Send successful response
 	response := RestResponse{
-		StatusCode: resp.StatusCode,
-		Headers:    headers,
-		Body:       parsedBody,
+		StatusCode:          resp.StatusCode,
+		Headers:             resp.Header,
+		Body:                parsedBody,
+		ResolvedURL:         vars.Mask(httpReq.URL.String()),
+		UnresolvedVariables: vars.Unresolved(),
+		Redirects:           maskRedirects(client.Redirects, vars),
+		Timing:              timing,
+		Assertions:          maskAssertions(assertions, vars),
+		Extractions:         extractions,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
 
+// maskRedirects hides secret variables in the URLs of a redirect chain
+func maskRedirects(redirects []restclient.Redirect, vars *restclient.Variables) []restclient.Redirect {
+	for i := range redirects {
+		redirects[i].URL = vars.Mask(redirects[i].URL)
+		redirects[i].Location = vars.Mask(redirects[i].Location)
+	}
+	return redirects
+}
+
+// maskAssertions hides secret variables substituted into assertions
+func maskAssertions(results []restclient.AssertionResult, vars *restclient.Variables) []restclient.AssertionResult {
+	for i := range results {
+		results[i].Assertion.Target = vars.Mask(results[i].Assertion.Target)
+		results[i].Assertion.Value = vars.Mask(results[i].Assertion.Value)
+		results[i].Message = vars.Mask(results[i].Message)
+	}
+	return results
+}
+
 func sendRestError(w http.ResponseWriter, message string, statusCode int) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
@@ -186,15 +263,18 @@ func HandleSaveRequest(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req struct {
-		Collection string                 `json:"collection"`
-		Name       string                 `json:"name"`
-		Method     string                 `json:"method"`
-		URL        string                 `json:"url"`
-		Headers    map[string]string      `json:"headers"`
-		Parameters map[string]string      `json:"parameters"`
-		Body       string                 `json:"body"`
-		TLSCert    string                 `json:"tlsCert"`
-		TLSKey     string                 `json:"tlsKey"`
+		Collection  string                `json:"collection"`
+		Name        string                `json:"name"`
+		Method      string                `json:"method"`
+		URL         string                `json:"url"`
+		Headers     map[string]string     `json:"headers"`
+		Parameters  config.QueryParams    `json:"parameters"`
+		Body        config.RequestBody    `json:"body"`
+		TLSCert     string                `json:"tlsCert"`
+		TLSKey      string                `json:"tlsKey"`
+		Options     config.RequestOptions `json:"options"`
+		Assertions  []config.Assertion    `json:"assertions"`
+		Extractions []config.Extraction   `json:"extractions"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -212,14 +292,17 @@ func HandleSaveRequest(w http.ResponseWriter, r *http.Request) {
 	}
 
 	savedReq := config.SavedRequest{
-		Name:       req.Name,
-		Method:     req.Method,
-		URL:        req.URL,
-		Headers:    req.Headers,
-		Parameters: req.Parameters,
-		Body:       req.Body,
-		TLSCert:    req.TLSCert,
-		TLSKey:     req.TLSKey,
+		Name:        req.Name,
+		Method:      req.Method,
+		URL:         req.URL,
+		Headers:     req.Headers,
+		Parameters:  req.Parameters,
+		Body:        req.Body,
+		TLSCert:     req.TLSCert,
+		TLSKey:      req.TLSKey,
+		Options:     req.Options,
+		Assertions:  req.Assertions,
+		Extractions: req.Extractions,
 	}
 
 	if err := config.SaveRequestToCollection(req.Collection, savedReq); err != nil {
@@ -238,7 +321,11 @@ func HandleGetCollections(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	collections := config.GetRequestCollections()
+	// Secret collection variables stay on the server
+	collections := append([]config.RequestCollection(nil), config.GetRequestCollections()...)
+	for i := range collections {
+		collections[i].Variables = restclient.MaskVariables(collections[i].Variables)
+	}
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(collections)
 }
@@ -293,3 +380,115 @@ func HandleDeleteCollection(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{"status": "success", "message": "Collection deleted successfully"})
 }
+
+// HandleGetEnvironments returns the REST client environments, without the
+// values of secret variables
+func HandleGetEnvironments(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	environments := append([]config.Environment{}, config.GetEnvironments()...)
+	for i := range environments {
+		environments[i].Variables = restclient.MaskVariables(environments[i].Variables)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(environments)
+}
+
+// HandleSaveEnvironment adds or replaces an environment
+func HandleSaveEnvironment(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var env config.Environment
+	if err := json.NewDecoder(r.Body).Decode(&env); err != nil {
+		http.Error(w, "Invalid request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if env.Name == "" {
+		http.Error(w, "Environment name is required", http.StatusBadRequest)
+		return
+	}
+	env.Variables = cleanVariables(env.Variables)
+
+	if err := config.SaveEnvironment(env); err != nil {
+		http.Error(w, "Failed to save environment: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "success", "message": "Environment saved successfully"})
+}
+
+// HandleDeleteEnvironment deletes an environment
+func HandleDeleteEnvironment(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Name string `json:"name"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := config.DeleteEnvironment(req.Name); err != nil {
+		http.Error(w, "Failed to delete environment: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "success", "message": "Environment deleted successfully"})
+}
+
+// HandleSaveCollectionVariables replaces the variables of a collection
+func HandleSaveCollectionVariables(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Collection string            `json:"collection"`
+		Variables  []config.Variable `json:"variables"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if req.Collection == "" {
+		http.Error(w, "Collection name is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := config.SetCollectionVariables(req.Collection, cleanVariables(req.Variables)); err != nil {
+		http.Error(w, "Failed to save collection variables: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "success", "message": "Collection variables saved successfully"})
+}
+
+// cleanVariables drops variables without a key and trims the rest
+func cleanVariables(vars []config.Variable) []config.Variable {
+	cleaned := []config.Variable{}
+	for _, v := range vars {
+		v.Key = strings.TrimSpace(v.Key)
+		if v.Key != "" {
+			cleaned = append(cleaned, v)
+		}
+	}
+	return cleaned
+}
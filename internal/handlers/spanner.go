@@ -3,8 +3,11 @@ package handlers
 import (
 	"encoding/json"
 	"fmt"
+	"log"
 	"net/http"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"cloudevents-explorer/internal/config"
 	"cloudevents-explorer/internal/spanner"
@@ -50,6 +53,119 @@ func HandleSpannerConnect(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(resp)
 }
 
+// HandleSpannerInstances lists the instances of the connection's project
+func HandleSpannerInstances(w http.ResponseWriter, r *http.Request) {
+	var req types.ConnectionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	instances, err := spanner.ListInstances(req)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(instances)
+}
+
+// HandleSpannerInstanceCreate creates the connection's instance on the emulator
+func HandleSpannerInstanceCreate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req types.ConnectionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	writeAdminResponse(w, spanner.CreateInstance(req))
+}
+
+// HandleSpannerInstanceDelete deletes the connection's instance from the emulator
+func HandleSpannerInstanceDelete(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req types.ConnectionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	writeAdminResponse(w, spanner.DeleteInstance(req))
+}
+
+// HandleSpannerDatabases lists the databases of the connection's instance
+func HandleSpannerDatabases(w http.ResponseWriter, r *http.Request) {
+	var req types.ConnectionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	databases, err := spanner.ListDatabases(req)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(databases)
+}
+
+// HandleSpannerDatabaseCreate creates the connection's database on the
+// emulator, applying the DDL sent with it
+func HandleSpannerDatabaseCreate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req types.DatabaseRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	writeAdminResponse(w, spanner.CreateDatabase(req))
+}
+
+// HandleSpannerDatabaseDrop drops the connection's database from the emulator
+func HandleSpannerDatabaseDrop(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req types.ConnectionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	writeAdminResponse(w, spanner.DropDatabase(req))
+}
+
+func writeAdminResponse(w http.ResponseWriter, resp types.ConnectionResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	if !resp.Success {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
 // HandleSpannerTables returns list of tables
 func HandleSpannerTables(w http.ResponseWriter, r *http.Request) {
 	var req types.ConnectionRequest
@@ -70,6 +186,31 @@ func HandleSpannerTables(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(tables)
 }
 
+// HandleSpannerTableCount counts the rows of one table, which the table list
+// leaves out because it scans the table
+func HandleSpannerTableCount(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		types.ConnectionRequest
+		TableName string `json:"tableName"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	count, err := spanner.CountRows(req.ConnectionRequest, req.TableName)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int64{"rowCount": count})
+}
+
 // HandleSpannerQuery executes a SQL query
 func HandleSpannerQuery(w http.ResponseWriter, r *http.Request) {
 	var req types.QueryRequest
@@ -79,6 +220,7 @@ func HandleSpannerQuery(w http.ResponseWriter, r *http.Request) {
 	}
 
 	resp := spanner.ExecuteQuery(req)
+	recordQueryHistory(req, resp)
 
 	w.Header().Set("Content-Type", "application/json")
 	if resp.Error != "" {
@@ -87,6 +229,120 @@ func HandleSpannerQuery(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(resp)
 }
 
+// recordQueryHistory adds a successful query run to the history. Failing to
+// save the history does not fail the query.
+func recordQueryHistory(req types.QueryRequest, resp types.QueryResponse) {
+	if resp.Error != "" {
+		return
+	}
+
+	rowCount := resp.RowCount
+	if len(resp.Statements) > 0 {
+		rowCount = 0
+		for _, st := range resp.Statements {
+			rowCount += int(st.RowsAffected)
+		}
+	}
+
+	entry := config.QueryHistoryEntry{
+		ConfigName: req.ConfigName,
+		Connection: fmt.Sprintf("projects/%s/instances/%s/databases/%s", req.ProjectID, req.InstanceID, req.DatabaseID),
+		SQL:        req.Query,
+		Duration:   resp.ExecutionTime,
+		RowCount:   rowCount,
+	}
+	if err := config.AddQueryHistory(entry); err != nil {
+		log.Printf("Failed to save query history: %v", err)
+	}
+}
+
+// HandleSpannerHistory returns the query history, newest first
+func HandleSpannerHistory(w http.ResponseWriter, r *http.Request) {
+	history, err := config.GetQueryHistory()
+	if err != nil {
+		http.Error(w, "Failed to load history: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if history == nil {
+		history = []config.QueryHistoryEntry{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(history)
+}
+
+// HandleSpannerHistoryClear removes every query history entry
+func HandleSpannerHistoryClear(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := config.ClearQueryHistory(); err != nil {
+		http.Error(w, "Failed to clear history: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "success"})
+}
+
+// HandleSpannerSavedQuerySave saves a named query under a configuration profile
+func HandleSpannerSavedQuerySave(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		ConfigName string `json:"configName"`
+		Name       string `json:"name"`
+		SQL        string `json:"sql"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if req.ConfigName == "" || req.Name == "" || strings.TrimSpace(req.SQL) == "" {
+		http.Error(w, "configName, name and sql are required", http.StatusBadRequest)
+		return
+	}
+
+	if err := config.SaveSpannerQuery(req.ConfigName, config.SavedQuery{Name: req.Name, SQL: req.SQL}); err != nil {
+		http.Error(w, "Failed to save query: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "success", "message": "Query saved successfully"})
+}
+
+// HandleSpannerSavedQueryDelete removes a saved query from a configuration profile
+func HandleSpannerSavedQueryDelete(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		ConfigName string `json:"configName"`
+		Name       string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := config.DeleteSpannerQuery(req.ConfigName, req.Name); err != nil {
+		http.Error(w, "Failed to delete query: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "success"})
+}
+
 // HandleSaveSpannerConfig saves a Spanner configuration
 func HandleSaveSpannerConfig(w http.ResponseWriter, r *http.Request) {
 	var newConfig config.SpannerConfig
@@ -127,3 +383,496 @@ func HandleSpannerSchema(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(schema)
 }
+
+// HandleSpannerDatabaseSchema returns the schema of every table in the
+// database, used to draw the ER diagram
+func HandleSpannerDatabaseSchema(w http.ResponseWriter, r *http.Request) {
+	var req types.ConnectionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	schema, err := spanner.GetDatabaseSchema(req)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(schema)
+}
+
+// HandleSpannerRows returns a page of table rows for the row editor
+func HandleSpannerRows(w http.ResponseWriter, r *http.Request) {
+	var req types.TableRowsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp := spanner.ReadTableRows(req)
+
+	w.Header().Set("Content-Type", "application/json")
+	if resp.Error != "" {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+// HandleSpannerMutations previews or applies the row editor's changes
+func HandleSpannerMutations(w http.ResponseWriter, r *http.Request) {
+	var req types.RowChangesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp := spanner.ApplyRowChanges(req)
+
+	w.Header().Set("Content-Type", "application/json")
+	if resp.Error != "" {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+// HandleSpannerChangeStreams renders the change stream reader, which shows
+// mods in the same event viewer as Pub/Sub messages
+func HandleSpannerChangeStreams(w http.ResponseWriter, r *http.Request) {
+	html := templates.GetBaseHTML("Spanner Change Streams", templates.ChangeStreamContent, templates.ChangeStreamJS)
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprint(w, html)
+}
+
+// HandleSpannerChangeStreamRead reads the data change records of a change stream
+func HandleSpannerChangeStreamRead(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req types.ChangeStreamRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp := spanner.ReadChangeStream(req)
+
+	w.Header().Set("Content-Type", "application/json")
+	if resp.Error != "" {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+// HandleSpannerSnapshots lists the saved snapshots with the row count of each table
+func HandleSpannerSnapshots(w http.ResponseWriter, r *http.Request) {
+	snapshots, err := config.GetSnapshots()
+	if err != nil {
+		http.Error(w, "Failed to load snapshots: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	type snapshotTableSummary struct {
+		Name     string `json:"name"`
+		RowCount int    `json:"rowCount"`
+	}
+	type snapshotSummary struct {
+		Name      string                 `json:"name"`
+		Database  string                 `json:"database"`
+		CreatedAt string                 `json:"createdAt"`
+		Tables    []snapshotTableSummary `json:"tables"`
+	}
+
+	summaries := make([]snapshotSummary, 0, len(snapshots))
+	for _, s := range snapshots {
+		summary := snapshotSummary{Name: s.Name, Database: s.Database, CreatedAt: s.CreatedAt, Tables: []snapshotTableSummary{}}
+		for _, t := range s.Tables {
+			summary.Tables = append(summary.Tables, snapshotTableSummary{Name: t.Name, RowCount: len(t.Rows)})
+		}
+		summaries = append(summaries, summary)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(summaries)
+}
+
+// HandleSpannerSnapshotCapture captures the selected tables and queries as a named snapshot
+func HandleSpannerSnapshotCapture(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req types.SnapshotRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if req.Name == "" || len(req.Tables)+len(req.Queries) == 0 {
+		http.Error(w, "name and at least one table or query are required", http.StatusBadRequest)
+		return
+	}
+
+	snapshot, err := spanner.CaptureSnapshot(req)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	if err := config.AddOrUpdateSnapshot(*snapshot); err != nil {
+		http.Error(w, "Failed to save snapshot: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "success", "message": "Snapshot saved successfully"})
+}
+
+// HandleSpannerSnapshotDiff compares two saved snapshots, or a saved
+// snapshot with the current contents of the database
+func HandleSpannerSnapshotDiff(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req types.SnapshotDiffRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	before, err := config.GetSnapshot(req.Before)
+	if err != nil {
+		http.Error(w, "Failed to load snapshots: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if before == nil {
+		http.Error(w, fmt.Sprintf("snapshot %q not found", req.Before), http.StatusNotFound)
+		return
+	}
+
+	var after *config.Snapshot
+	if req.After == "" {
+		after, err = spanner.RecaptureSnapshot(req.ConnectionRequest, before)
+	} else {
+		after, err = config.GetSnapshot(req.After)
+		if err == nil && after == nil {
+			http.Error(w, fmt.Sprintf("snapshot %q not found", req.After), http.StatusNotFound)
+			return
+		}
+	}
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(types.SnapshotDiff{Before: req.Before, After: req.After, Error: err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(spanner.DiffSnapshots(before, after))
+}
+
+// HandleSpannerSnapshotDelete removes a saved snapshot
+func HandleSpannerSnapshotDelete(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := config.DeleteSnapshot(req.Name); err != nil {
+		http.Error(w, "Failed to delete snapshot: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "success", "message": "Snapshot deleted successfully"})
+}
+
+// exportWriter holds back the download headers until the export writes its
+// first byte, so a query that fails up front can still be reported as JSON
+type exportWriter struct {
+	w           http.ResponseWriter
+	contentType string
+	filename    string
+	started     bool
+}
+
+func (e *exportWriter) Write(p []byte) (int, error) {
+	if !e.started {
+		e.started = true
+		e.w.Header().Set("Content-Type", e.contentType)
+		e.w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", e.filename))
+	}
+	return e.w.Write(p)
+}
+
+// exportFilename names an export after its target table, keeping only the
+// characters that are safe in a Content-Disposition header
+func exportFilename(table []string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '.', r == '-':
+			return r
+		}
+		return '_'
+	}, strings.Join(table, "."))
+	if strings.Trim(name, "._-") == "" {
+		return "query-results"
+	}
+	return name
+}
+
+// HandleSpannerExport runs a query and streams the results as a file download
+func HandleSpannerExport(w http.ResponseWriter, r *http.Request) {
+	var req types.ExportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	contentType, ext, err := spanner.ExportContentType(req.Format)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	out := &exportWriter{w: w, contentType: contentType, filename: exportFilename(req.Table) + "." + ext}
+	if err := spanner.ExportQuery(req, out); err != nil {
+		if out.started {
+			// Headers are already sent; all we can do is log and cut the stream short
+			log.Printf("Spanner export aborted: %v", err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+	}
+}
+
+// HandleSpannerImport loads an uploaded CSV, JSON or NDJSON file into a table.
+// The file comes in the "file" form field and the import options as JSON in
+// the "options" field.
+func HandleSpannerImport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		http.Error(w, "Invalid upload: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var req types.ImportRequest
+	if err := json.Unmarshal([]byte(r.FormValue("options")), &req); err != nil {
+		http.Error(w, "Invalid import options: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "file is required: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	if req.Format == "" {
+		switch strings.ToLower(filepath.Ext(header.Filename)) {
+		case ".csv":
+			req.Format = spanner.ExportCSV
+		case ".ndjson", ".jsonl":
+			req.Format = spanner.ExportNDJSON
+		default:
+			req.Format = spanner.ExportJSON
+		}
+	}
+
+	resp := spanner.ImportFile(req, file)
+
+	w.Header().Set("Content-Type", "application/json")
+	if resp.Error != "" {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+// HandleSpannerSeeds lists the saved seed sets with the row count of each table
+func HandleSpannerSeeds(w http.ResponseWriter, r *http.Request) {
+	seeds, err := config.GetSeedSets()
+	if err != nil {
+		http.Error(w, "Failed to load seed sets: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	type seedTableSummary struct {
+		Table    string `json:"table"`
+		RowCount int    `json:"rowCount"`
+	}
+	type seedSummary struct {
+		Name        string             `json:"name"`
+		Description string             `json:"description,omitempty"`
+		Tables      []seedTableSummary `json:"tables"`
+	}
+
+	summaries := make([]seedSummary, 0, len(seeds))
+	for _, seed := range seeds {
+		summary := seedSummary{Name: seed.Name, Description: seed.Description, Tables: []seedTableSummary{}}
+		for _, t := range seed.Tables {
+			summary.Tables = append(summary.Tables, seedTableSummary{Table: t.Table, RowCount: len(t.Rows)})
+		}
+		summaries = append(summaries, summary)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(summaries)
+}
+
+// HandleSpannerSeedSave captures the current rows of the selected tables as a named seed set
+func HandleSpannerSeedSave(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req types.SeedRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if req.Name == "" || len(req.Tables) == 0 {
+		http.Error(w, "name and at least one table are required", http.StatusBadRequest)
+		return
+	}
+
+	seed, err := spanner.CaptureSeedSet(req)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	if err := config.AddOrUpdateSeedSet(*seed); err != nil {
+		http.Error(w, "Failed to save seed set: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "success", "message": "Seed set saved successfully"})
+}
+
+// HandleSpannerSeedApply writes a seed set into the database, optionally
+// clearing the seeded tables first
+func HandleSpannerSeedApply(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req types.SeedRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	seed, err := config.GetSeedSet(req.Name)
+	if err != nil {
+		http.Error(w, "Failed to load seed sets: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if seed == nil {
+		http.Error(w, fmt.Sprintf("seed set %q not found", req.Name), http.StatusNotFound)
+		return
+	}
+
+	resp := spanner.ApplySeedSet(req.ConnectionRequest, seed, req.Reset)
+
+	w.Header().Set("Content-Type", "application/json")
+	if resp.Error != "" {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+// HandleSpannerSeedDelete removes a saved seed set
+func HandleSpannerSeedDelete(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := config.DeleteSeedSet(req.Name); err != nil {
+		http.Error(w, "Failed to delete seed set: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "success", "message": "Seed set deleted successfully"})
+}
+
+// HandleSpannerBegin opens a read-write transaction for subsequent queries
+func HandleSpannerBegin(w http.ResponseWriter, r *http.Request) {
+	var req types.ConnectionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	writeTransactionResponse(w, spanner.BeginTransaction(req))
+}
+
+// HandleSpannerCommit commits an open read-write transaction
+func HandleSpannerCommit(w http.ResponseWriter, r *http.Request) {
+	var req types.TransactionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	writeTransactionResponse(w, spanner.CommitTransaction(req.TransactionID))
+}
+
+// HandleSpannerRollback rolls back an open read-write transaction
+func HandleSpannerRollback(w http.ResponseWriter, r *http.Request) {
+	var req types.TransactionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	writeTransactionResponse(w, spanner.RollbackTransaction(req.TransactionID))
+}
+
+func writeTransactionResponse(w http.ResponseWriter, resp types.TransactionResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	if resp.Error != "" {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,258 @@
+// Package jsondiff compares JSON-like values: decoded JSON documents, and
+// rows of named columns such as query results and CSV records
+package jsondiff
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+var identifierPattern = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+// PathKey extends a JSONPath with an object key, as .key when the key is an
+// identifier and as ["key"] otherwise
+func PathKey(parent, key string) string {
+	if identifierPattern.MatchString(key) {
+		return parent + "." + key
+	}
+	quoted, _ := json.Marshal(key)
+	return parent + "[" + string(quoted) + "]"
+}
+
+// Equal reports whether two values are the same JSON value. Objects are
+// compared key by key and arrays item by item. Numbers are equal when they
+// are the same number however they are written, so 1 equals 1.0, and an
+// int64 read from a database equals the json.Number it becomes once saved
+// and loaded. Values that are not decoded JSON are compared by their JSON
+// encoding.
+func Equal(a, b interface{}) bool {
+	a, b = normalize(a), normalize(b)
+	switch x := a.(type) {
+	case json.Number:
+		y, ok := b.(json.Number)
+		return ok && sameNumber(x, y)
+	case map[string]interface{}:
+		y, ok := b.(map[string]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for key, value := range x {
+			other, ok := y[key]
+			if !ok || !Equal(value, other) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		y, ok := b.([]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !Equal(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return a == b
+}
+
+// normalize turns a value into the types encoding/json decodes to with
+// UseNumber, leaving the items of objects and arrays for Equal to visit
+func normalize(v interface{}) interface{} {
+	switch x := v.(type) {
+	case nil, bool, string, json.Number, map[string]interface{}, []interface{}:
+		return v
+	case float64:
+		data, err := json.Marshal(x)
+		if err != nil {
+			return v
+		}
+		return json.Number(data)
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var decoded interface{}
+	if err := dec.Decode(&decoded); err != nil {
+		return v
+	}
+	return decoded
+}
+
+// sameNumber compares numbers exactly, so large integers that round to the
+// same float64 still differ
+func sameNumber(x, y json.Number) bool {
+	if x == y {
+		return true
+	}
+	a, okA := new(big.Rat).SetString(string(x))
+	b, okB := new(big.Rat).SetString(string(y))
+	return okA && okB && a.Cmp(b) == 0
+}
+
+// Table is a set of rows and their column names in order
+type Table struct {
+	Columns []string
+	Rows    []map[string]interface{}
+}
+
+// Change is a row in both tables, matched by its key, with different values
+type Change struct {
+	Key            map[string]interface{}
+	Before         map[string]interface{}
+	After          map[string]interface{}
+	ChangedColumns []string
+}
+
+// Counts are the numbers of rows added, removed and changed, which exceed
+// the rows a capped Rows lists
+type Counts struct {
+	Added   int
+	Removed int
+	Changed int
+}
+
+// Rows is the difference between two tables
+type Rows struct {
+	Added     []map[string]interface{}
+	Removed   []map[string]interface{}
+	Changed   []Change
+	Unchanged int
+	Counts    Counts
+}
+
+// DiffRows returns the rows added to, removed from and changed between two
+// tables, matching rows by the key columns. Rows with the same key are
+// changed when any value differs. Without key columns, rows are matched by
+// their whole contents, so duplicates are counted rather than collapsed.
+// When limit is positive, at most that many rows of each kind are listed.
+func DiffRows(before, after Table, keys []string, limit int) Rows {
+	var d Rows
+	fits := func(n int) bool { return limit <= 0 || n < limit }
+
+	columns := append([]string{}, before.Columns...)
+	for _, c := range after.Columns {
+		if !slices.Contains(columns, c) {
+			columns = append(columns, c)
+		}
+	}
+
+	// Queue the before rows under each key; every after row takes the first
+	// unmatched before row with its key
+	pending := map[string][]int{}
+	for i, row := range before.Rows {
+		k := rowKey(row, keys)
+		pending[k] = append(pending[k], i)
+	}
+	matched := make([]bool, len(before.Rows))
+
+	for _, row := range after.Rows {
+		k := rowKey(row, keys)
+		queue := pending[k]
+		if len(queue) == 0 {
+			d.Counts.Added++
+			if fits(len(d.Added)) {
+				d.Added = append(d.Added, row)
+			}
+			continue
+		}
+		i := queue[0]
+		pending[k] = queue[1:]
+		matched[i] = true
+
+		var changed []string
+		for _, c := range columns {
+			if !Equal(before.Rows[i][c], row[c]) {
+				changed = append(changed, c)
+			}
+		}
+		if len(changed) == 0 {
+			d.Unchanged++
+			continue
+		}
+
+		d.Counts.Changed++
+		if fits(len(d.Changed)) {
+			key := make(map[string]interface{}, len(keys))
+			for _, c := range keys {
+				key[c] = row[c]
+			}
+			d.Changed = append(d.Changed, Change{
+				Key:            key,
+				Before:         before.Rows[i],
+				After:          row,
+				ChangedColumns: changed,
+			})
+		}
+	}
+
+	for i, row := range before.Rows {
+		if !matched[i] {
+			d.Counts.Removed++
+			if fits(len(d.Removed)) {
+				d.Removed = append(d.Removed, row)
+			}
+		}
+	}
+
+	return d
+}
+
+// rowKey renders the key columns of a row, or the whole row when there are
+// none, as a string to match rows on. Values are normalized first, so keys
+// that Equal holds the same, such as 1 and 1.0, match.
+func rowKey(row map[string]interface{}, keys []string) string {
+	if len(keys) == 0 {
+		data, _ := json.Marshal(canonical(row))
+		return string(data)
+	}
+	values := make([]interface{}, len(keys))
+	for i, k := range keys {
+		values[i] = canonical(row[k])
+	}
+	data, _ := json.Marshal(values)
+	return string(data)
+}
+
+// canonical normalizes a value and everything in it, writing each number in
+// one form, so values Equal holds the same encode the same
+func canonical(v interface{}) interface{} {
+	switch x := normalize(v).(type) {
+	case json.Number:
+		r, ok := new(big.Rat).SetString(string(x))
+		if !ok {
+			return x
+		}
+		if r.IsInt() {
+			return json.Number(r.Num().String())
+		}
+		// The denominator of a decimal is 2^a*5^b, so as many places as it
+		// has bits write the number exactly
+		text := r.FloatString(r.Denom().BitLen())
+		return json.Number(strings.TrimRight(text, "0"))
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(x))
+		for key, value := range x {
+			out[key] = canonical(value)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(x))
+		for i, value := range x {
+			out[i] = canonical(value)
+		}
+		return out
+	default:
+		return x
+	}
+}
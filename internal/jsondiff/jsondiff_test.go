@@ -0,0 +1,181 @@
+package jsondiff
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+func TestPathKey(t *testing.T) {
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{"name", "$.name"},
+		{"_id$", "$._id$"},
+		{"first name", `$["first name"]`},
+		{"1st", `$["1st"]`},
+		{`say "hi"`, `$["say \"hi\""]`},
+		{"", `$[""]`},
+	}
+
+	for _, tt := range tests {
+		if got := PathKey("$", tt.key); got != tt.want {
+			t.Errorf("PathKey(%q): expected %s, got %s", tt.key, tt.want, got)
+		}
+	}
+}
+
+func TestEqual(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b interface{}
+		want bool
+	}{
+		{"same strings", "a", "a", true},
+		{"different strings", "a", "b", false},
+		{"number written two ways", json.Number("1"), json.Number("1.0"), true},
+		{"exponent", json.Number("1e3"), json.Number("1000"), true},
+		{"different numbers", json.Number("1"), json.Number("2"), false},
+		{"large integers past float64", json.Number("9007199254740993"), json.Number("9007199254740992"), false},
+		{"int64 and json.Number", int64(42), json.Number("42"), true},
+		{"float64 and json.Number", 1.5, json.Number("1.50"), true},
+		{"number and string", json.Number("1"), "1", false},
+		{"nil and nil", nil, nil, true},
+		{"nil and false", nil, false, false},
+		{"objects in any order",
+			map[string]interface{}{"a": json.Number("1"), "b": []interface{}{"x"}},
+			map[string]interface{}{"b": []interface{}{"x"}, "a": json.Number("1.0")}, true},
+		{"object with an extra key",
+			map[string]interface{}{"a": nil},
+			map[string]interface{}{"a": nil, "b": nil}, false},
+		{"arrays in a different order",
+			[]interface{}{"x", "y"}, []interface{}{"y", "x"}, false},
+		{"nested database values",
+			map[string]interface{}{"id": int64(7), "tags": []string{"a"}},
+			map[string]interface{}{"id": json.Number("7"), "tags": []interface{}{"a"}}, true},
+	}
+
+	for _, tt := range tests {
+		if got := Equal(tt.a, tt.b); got != tt.want {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+		if got := Equal(tt.b, tt.a); got != tt.want {
+			t.Errorf("%s (swapped): expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
+
+func row(values ...interface{}) map[string]interface{} {
+	r := map[string]interface{}{}
+	for i := 0; i < len(values); i += 2 {
+		r[values[i].(string)] = values[i+1]
+	}
+	return r
+}
+
+func TestDiffRows(t *testing.T) {
+	columns := []string{"id", "name"}
+	tests := []struct {
+		name      string
+		keys      []string
+		before    []map[string]interface{}
+		after     []map[string]interface{}
+		limit     int
+		want      Counts
+		unchanged int
+		changed   [][]string
+	}{
+		{
+			name:      "keyed rows",
+			keys:      []string{"id"},
+			before:    []map[string]interface{}{row("id", int64(1), "name", "a"), row("id", int64(2), "name", "b"), row("id", int64(3), "name", "c")},
+			after:     []map[string]interface{}{row("id", json.Number("1"), "name", "a"), row("id", json.Number("2"), "name", "B"), row("id", json.Number("4"), "name", "d")},
+			want:      Counts{Added: 1, Removed: 1, Changed: 1},
+			unchanged: 1,
+			changed:   [][]string{{"name"}},
+		},
+		{
+			name:      "keys match by value however the numbers are written",
+			keys:      []string{"id"},
+			before:    []map[string]interface{}{row("id", json.Number("1"), "name", "a"), row("id", 2.5, "name", "b"), row("id", json.Number("30"), "name", "c")},
+			after:     []map[string]interface{}{row("id", json.Number("1.0"), "name", "a"), row("id", json.Number("25e-1"), "name", "B"), row("id", int64(30), "name", "c")},
+			want:      Counts{Changed: 1},
+			unchanged: 2,
+			changed:   [][]string{{"name"}},
+		},
+		{
+			name:      "without keys whole rows match by value",
+			before:    []map[string]interface{}{row("id", json.Number("1"), "name", "a")},
+			after:     []map[string]interface{}{row("id", json.Number("1.00"), "name", "a")},
+			unchanged: 1,
+		},
+		{
+			name:      "without keys duplicates are counted",
+			before:    []map[string]interface{}{row("id", "1", "name", "a"), row("id", "1", "name", "a")},
+			after:     []map[string]interface{}{row("id", "1", "name", "a")},
+			want:      Counts{Removed: 1},
+			unchanged: 1,
+		},
+		{
+			name:   "without keys a change is a removal and an addition",
+			before: []map[string]interface{}{row("id", "1", "name", "a")},
+			after:  []map[string]interface{}{row("id", "1", "name", "b")},
+			want:   Counts{Added: 1, Removed: 1},
+		},
+		{
+			name:      "a repeated key matches in order",
+			keys:      []string{"id"},
+			before:    []map[string]interface{}{row("id", "1", "name", "a"), row("id", "1", "name", "b")},
+			after:     []map[string]interface{}{row("id", "1", "name", "b"), row("id", "1", "name", "b")},
+			want:      Counts{Changed: 1},
+			unchanged: 1,
+			changed:   [][]string{{"name"}},
+		},
+		{
+			name:  "limit caps the rows listed but not the counts",
+			keys:  []string{"id"},
+			after: []map[string]interface{}{row("id", "1"), row("id", "2"), row("id", "3")},
+			limit: 2,
+			want:  Counts{Added: 3},
+		},
+	}
+
+	for _, tt := range tests {
+		d := DiffRows(Table{Columns: columns, Rows: tt.before}, Table{Columns: columns, Rows: tt.after}, tt.keys, tt.limit)
+		got := d.Counts
+		if got != tt.want {
+			t.Errorf("%s: expected counts %+v, got %+v", tt.name, tt.want, got)
+		}
+		if d.Unchanged != tt.unchanged {
+			t.Errorf("%s: expected %d unchanged, got %d", tt.name, tt.unchanged, d.Unchanged)
+		}
+		listed := func(n int) int {
+			if tt.limit > 0 && n > tt.limit {
+				return tt.limit
+			}
+			return n
+		}
+		if len(d.Added) != listed(got.Added) || len(d.Removed) != listed(got.Removed) || len(d.Changed) != listed(got.Changed) {
+			t.Errorf("%s: listed %d added, %d removed, %d changed for counts %+v", tt.name, len(d.Added), len(d.Removed), len(d.Changed), got)
+		}
+		for i, change := range d.Changed {
+			if i < len(tt.changed) && !slices.Equal(change.ChangedColumns, tt.changed[i]) {
+				t.Errorf("%s: expected changed columns %v, got %v", tt.name, tt.changed[i], change.ChangedColumns)
+			}
+		}
+	}
+}
+
+func TestDiffRowsColumnOnlyOnOneSide(t *testing.T) {
+	d := DiffRows(
+		Table{Columns: []string{"id"}, Rows: []map[string]interface{}{row("id", "1")}},
+		Table{Columns: []string{"id", "extra"}, Rows: []map[string]interface{}{row("id", "1", "extra", "x")}}, []string{"id"}, 0)
+
+	if len(d.Changed) != 1 || !slices.Equal(d.Changed[0].ChangedColumns, []string{"extra"}) {
+		t.Fatalf("expected the new column to be the change, got %+v", d.Changed)
+	}
+	if d.Changed[0].Key["id"] != "1" {
+		t.Errorf("expected the key of the changed row, got %v", d.Changed[0].Key)
+	}
+}
@@ -5,11 +5,16 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"strings"
 	"sync"
 	"time"
 
 	"cloud.google.com/go/pubsub"
 	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
 
 	"cloudevents-explorer/internal/types"
 )
@@ -87,3 +92,226 @@ func Pull(params PullParams) (*PullResult, error) {
 		Count:    len(messages),
 	}, nil
 }
+
+type PublishParams struct {
+	EmulatorHost string            `json:"emulatorHost"`
+	ProjectID    string            `json:"projectId"`
+	TopicID      string            `json:"topicId"`
+	Data         []byte            `json:"data"`
+	Attributes   map[string]string `json:"attributes"`
+}
+
+// Publisher publishes messages to topics on emulators. It keeps one client
+// per emulator host and project, and one handle per topic, so publishing
+// many messages does not dial the emulator or check the topic each time.
+type Publisher struct {
+	mu      sync.Mutex
+	clients map[string]*publisherClient
+	topics  map[string]*pubsub.Topic
+}
+
+// publisherClient is a client with the publishes using it. A dropped client,
+// and the topics forgotten while publishes were using them, are closed once
+// the last of those publishes is done.
+type publisherClient struct {
+	client  *pubsub.Client
+	refs    int
+	dropped bool
+	retired []*pubsub.Topic
+}
+
+func NewPublisher() *Publisher {
+	return &Publisher{
+		clients: map[string]*publisherClient{},
+		topics:  map[string]*pubsub.Topic{},
+	}
+}
+
+// Publish sends one message to a topic on the emulator, creating the topic
+// first when it does not exist yet, and returns the message ID
+func (p *Publisher) Publish(ctx context.Context, params PublishParams) (string, error) {
+	c, err := p.acquire(params)
+	if err != nil {
+		return "", err
+	}
+	defer p.release(c)
+
+	topic, err := p.topic(ctx, c, params)
+	if err != nil {
+		return "", err
+	}
+
+	id, err := topic.Publish(ctx, &pubsub.Message{Data: params.Data, Attributes: params.Attributes}).Get(ctx)
+	if err != nil {
+		// A deleted topic is created again on the next publish; any other
+		// failure, such as a restarted emulator, also redials it
+		p.mu.Lock()
+		p.forget(c, params, topic, status.Code(err) != codes.NotFound)
+		p.mu.Unlock()
+		return "", fmt.Errorf("failed to publish: %w", err)
+	}
+	return id, nil
+}
+
+func publisherKeys(params PublishParams) (clientKey, topicKey string) {
+	clientKey = params.EmulatorHost + "|" + params.ProjectID
+	return clientKey, clientKey + "|" + params.TopicID
+}
+
+// acquire returns the client for a publish's emulator and project, opening
+// it the first time, and holds it open until release
+func (p *Publisher) acquire(params PublishParams) (*publisherClient, error) {
+	clientKey, _ := publisherKeys(params)
+
+	p.mu.Lock()
+	if c, ok := p.clients[clientKey]; ok {
+		c.refs++
+		p.mu.Unlock()
+		return c, nil
+	}
+	p.mu.Unlock()
+
+	// The client outlives this publish, so it is not tied to its context.
+	// The emulator is plaintext, which the client library only assumes when
+	// PUBSUB_EMULATOR_HOST is set.
+	client, err := pubsub.NewClient(context.Background(), params.ProjectID,
+		option.WithEndpoint(params.EmulatorHost),
+		option.WithGRPCDialOption(grpc.WithTransportCredentials(insecure.NewCredentials())),
+		option.WithoutAuthentication(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client: %w", err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if c, ok := p.clients[clientKey]; ok {
+		// Another publish opened one first
+		client.Close()
+		c.refs++
+		return c, nil
+	}
+	c := &publisherClient{client: client, refs: 1}
+	p.clients[clientKey] = c
+	return c, nil
+}
+
+// release ends a publish's use of its client, closing what was dropped
+// while it was in use once no other publish is using it
+func (p *Publisher) release(c *publisherClient) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	c.refs--
+	if c.refs > 0 {
+		return
+	}
+	for _, topic := range c.retired {
+		topic.Stop()
+	}
+	c.retired = nil
+	if c.dropped {
+		c.client.Close()
+	}
+}
+
+// topic returns the handle of a topic, creating the topic the first time it
+// is asked for. The emulator is called without holding p.mu, so a slow one
+// does not hold up publishes to others.
+func (p *Publisher) topic(ctx context.Context, c *publisherClient, params PublishParams) (*pubsub.Topic, error) {
+	clientKey, topicKey := publisherKeys(params)
+
+	p.mu.Lock()
+	topic, ok := p.topics[topicKey]
+	current := p.clients[clientKey] == c
+	p.mu.Unlock()
+	if ok && current {
+		return topic, nil
+	}
+
+	topic = c.client.Topic(params.TopicID)
+	exists, err := topic.Exists(ctx)
+	if err != nil {
+		topic.Stop()
+		p.mu.Lock()
+		p.forget(c, params, nil, true)
+		p.mu.Unlock()
+		return nil, fmt.Errorf("failed to check topic: %w", err)
+	}
+	if !exists {
+		topic.Stop()
+		if topic, err = c.client.CreateTopic(ctx, params.TopicID); err != nil {
+			if status.Code(err) != codes.AlreadyExists {
+				return nil, fmt.Errorf("failed to create topic: %w", err)
+			}
+			// Another publish created it first
+			topic = c.client.Topic(params.TopicID)
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.clients[clientKey] != c {
+		// The client was dropped meanwhile, so the handle serves only this
+		// publish
+		c.retired = append(c.retired, topic)
+		return topic, nil
+	}
+	if cached, ok := p.topics[topicKey]; ok {
+		c.retired = append(c.retired, topic)
+		return cached, nil
+	}
+	p.topics[topicKey] = topic
+	return topic, nil
+}
+
+// forget drops the handle of a topic a call failed on, so the next publish
+// checks and creates the topic again. A handle another publish has already
+// dropped or replaced is left alone. With dropClient it also drops the
+// topic's client and every other topic opened with it. Handles are stopped,
+// and the client closed, once no publish is using them. p.mu must be held.
+func (p *Publisher) forget(c *publisherClient, params PublishParams, failed *pubsub.Topic, dropClient bool) {
+	clientKey, topicKey := publisherKeys(params)
+	if p.clients[clientKey] != c {
+		return
+	}
+	topic, ok := p.topics[topicKey]
+	if failed != nil && (!ok || topic != failed) {
+		return
+	}
+	if ok {
+		c.retired = append(c.retired, topic)
+		delete(p.topics, topicKey)
+	}
+	if !dropClient {
+		return
+	}
+
+	for key, topic := range p.topics {
+		if strings.HasPrefix(key, clientKey+"|") {
+			c.retired = append(c.retired, topic)
+			delete(p.topics, key)
+		}
+	}
+	c.dropped = true
+	delete(p.clients, clientKey)
+}
+
+// Close stops every topic and closes every client of the publisher
+func (p *Publisher) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for key, topic := range p.topics {
+		topic.Stop()
+		delete(p.topics, key)
+	}
+	for key, c := range p.clients {
+		for _, topic := range c.retired {
+			topic.Stop()
+		}
+		c.retired = nil
+		c.client.Close()
+		delete(p.clients, key)
+	}
+}
@@ -0,0 +1,96 @@
+package pubsub
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	"cloud.google.com/go/pubsub/pstest"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+func TestPublisher(t *testing.T) {
+	// The publisher must reach the emulator without the variable Pull sets
+	t.Setenv("PUBSUB_EMULATOR_HOST", "")
+
+	srv := pstest.NewServer()
+	defer srv.Close()
+
+	p := NewPublisher()
+	defer p.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	params := PublishParams{
+		EmulatorHost: srv.Addr,
+		ProjectID:    "test-project",
+		TopicID:      "events",
+		Data:         []byte(`{"a":1}`),
+		Attributes:   map[string]string{"eventType": "OBJECT_FINALIZE"},
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := p.Publish(ctx, params); err != nil {
+			t.Fatalf("publish %d failed: %v", i, err)
+		}
+	}
+
+	messages := srv.Messages()
+	if len(messages) != 2 {
+		t.Fatalf("expected 2 messages, got %d", len(messages))
+	}
+	if string(messages[0].Data) != `{"a":1}` || messages[0].Attributes["eventType"] != "OBJECT_FINALIZE" {
+		t.Errorf("unexpected message %+v", messages[0])
+	}
+	if len(p.clients) != 1 || len(p.topics) != 1 {
+		t.Errorf("expected one cached client and topic, got %d and %d", len(p.clients), len(p.topics))
+	}
+	for _, c := range p.clients {
+		if c.refs != 0 {
+			t.Errorf("expected no publishes to hold the client, got %d", c.refs)
+		}
+	}
+
+	// A topic deleted behind the publisher's back is created again
+	admin, err := pubsub.NewClient(ctx, params.ProjectID,
+		option.WithEndpoint(srv.Addr),
+		option.WithGRPCDialOption(grpc.WithTransportCredentials(insecure.NewCredentials())),
+		option.WithoutAuthentication(),
+	)
+	if err != nil {
+		t.Fatalf("could not create client: %v", err)
+	}
+	defer admin.Close()
+	if err := admin.Topic(params.TopicID).Delete(ctx); err != nil {
+		t.Fatalf("could not delete topic: %v", err)
+	}
+
+	if _, err := p.Publish(ctx, params); err == nil {
+		t.Error("expected publishing to the deleted topic to fail")
+	}
+	if _, err := p.Publish(ctx, params); err != nil {
+		t.Errorf("expected the topic to be created again, got %v", err)
+	}
+	if len(p.clients) != 1 {
+		t.Errorf("expected a missing topic to keep the client, got %d clients", len(p.clients))
+	}
+}
+
+func TestPublisherUnreachable(t *testing.T) {
+	p := NewPublisher()
+	defer p.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	_, err := p.Publish(ctx, PublishParams{EmulatorHost: "127.0.0.1:1", ProjectID: "p", TopicID: "t"})
+	if err == nil {
+		t.Fatal("expected an error for an unreachable emulator")
+	}
+	if len(p.clients) != 0 || len(p.topics) != 0 {
+		t.Errorf("expected the failed client to be dropped, got %d clients and %d topics", len(p.clients), len(p.topics))
+	}
+}
@@ -0,0 +1,327 @@
+package restclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"cloudevents-explorer/internal/config"
+	"cloudevents-explorer/internal/jsondiff"
+)
+
+// maxActualLength bounds the actual values shown beside failed assertions
+const maxActualLength = 200
+
+// defaultOperators apply to assertions saved without an operator
+var defaultOperators = map[string]string{
+	config.AssertStatus:       config.OpEquals,
+	config.AssertHeader:       config.OpExists,
+	config.AssertJSONPath:     config.OpExists,
+	config.AssertResponseTime: config.OpLessThan,
+}
+
+// Response is what assertions and extractions look at. Duration is the
+// total time of the request in milliseconds.
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+	Duration   float64
+
+	parsed   interface{}
+	parseErr error
+	isParsed bool
+}
+
+// json decodes the body once, keeping numbers exact
+func (r *Response) json() (interface{}, error) {
+	if !r.isParsed {
+		r.isParsed = true
+		dec := json.NewDecoder(bytes.NewReader(r.Body))
+		dec.UseNumber()
+		if err := dec.Decode(&r.parsed); err != nil {
+			r.parseErr = fmt.Errorf("response body is not JSON: %w", err)
+		}
+	}
+	return r.parsed, r.parseErr
+}
+
+// AssertionResult is the outcome of one assertion. Actual is the value it
+// looked at; Message explains a failure.
+type AssertionResult struct {
+	Assertion config.Assertion `json:"assertion"`
+	Passed    bool             `json:"passed"`
+	Actual    string           `json:"actual,omitempty"`
+	Message   string           `json:"message,omitempty"`
+}
+
+// Evaluate checks a response against the enabled assertions
+func Evaluate(assertions []config.Assertion, resp *Response) []AssertionResult {
+	results := []AssertionResult{}
+	for _, a := range assertions {
+		if a.Disabled {
+			continue
+		}
+		if a.Operator == "" {
+			a.Operator = defaultOperators[a.Type]
+		}
+		result := AssertionResult{Assertion: a}
+		actual, passed, err := evaluate(a, resp)
+		result.Passed = passed && err == nil
+		if actual != nil {
+			result.Actual = truncate(textOf(actual), maxActualLength)
+		}
+		if err != nil {
+			result.Message = err.Error()
+		} else if !passed {
+			result.Message = describeFailure(a)
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// evaluate returns the value an assertion looked at and whether it held
+func evaluate(a config.Assertion, resp *Response) (interface{}, bool, error) {
+	switch a.Type {
+	case config.AssertStatus:
+		actual := json.Number(strconv.Itoa(resp.StatusCode))
+		op := a.Operator
+		// 2xx and the like match a class of status codes
+		if class := strings.ToLower(strings.TrimSpace(a.Value)); len(class) == 3 && strings.HasSuffix(class, "xx") &&
+			(op == config.OpEquals || op == config.OpNotEquals) {
+			same := class[0] == strconv.Itoa(resp.StatusCode)[0]
+			return actual, same == (op == config.OpEquals), nil
+		}
+		passed, err := compare(op, actual, true, a.Value)
+		return actual, passed, err
+
+	case config.AssertHeader:
+		if a.Target == "" {
+			return nil, false, fmt.Errorf("no header name")
+		}
+		values := resp.Header.Values(a.Target)
+		if len(values) == 0 {
+			passed, err := compare(a.Operator, nil, false, a.Value)
+			return nil, passed, err
+		}
+		actual := strings.Join(values, ", ")
+		passed, err := compare(a.Operator, actual, true, a.Value)
+		return actual, passed, err
+
+	case config.AssertJSONPath:
+		doc, err := resp.json()
+		if err != nil {
+			return nil, false, err
+		}
+		values, err := EvalJSONPath(doc, a.Target)
+		if err != nil {
+			return nil, false, err
+		}
+		var actual interface{}
+		switch len(values) {
+		case 0:
+		case 1:
+			actual = values[0]
+		default:
+			actual = values
+		}
+		passed, err := compare(a.Operator, actual, len(values) > 0, a.Value)
+		return actual, passed, err
+
+	case config.AssertJSONSchema:
+		doc, err := resp.json()
+		if err != nil {
+			return nil, false, err
+		}
+		schema, err := decodeJSON(a.Value)
+		if err != nil {
+			return nil, false, fmt.Errorf("invalid schema: %w", err)
+		}
+		problems, err := ValidateSchema(schema, doc)
+		if err != nil {
+			return nil, false, err
+		}
+		if len(problems) > 0 {
+			return nil, false, fmt.Errorf("%s", strings.Join(problems, "; "))
+		}
+		return nil, true, nil
+
+	case config.AssertResponseTime:
+		actual := json.Number(strconv.FormatFloat(resp.Duration, 'f', 2, 64))
+		passed, err := compare(a.Operator, actual, true, a.Value)
+		return actual, passed, err
+	}
+
+	return nil, false, fmt.Errorf("unknown assertion type %q", a.Type)
+}
+
+// compare applies an operator to an actual value, which exists says was
+// found, and the expected value as written
+func compare(op string, actual interface{}, exists bool, expected string) (bool, error) {
+	switch op {
+	case config.OpExists:
+		return exists, nil
+	case config.OpNotExists:
+		return !exists, nil
+	}
+	if !exists {
+		return false, nil
+	}
+
+	switch op {
+	case config.OpEquals, config.OpNotEquals:
+		// Expected values are read as JSON when they parse, so 1 and 1.0
+		// match the same number; strings may be written with or without
+		// quotes
+		same := false
+		if value, err := decodeJSON(expected); err == nil {
+			same = jsondiff.Equal(actual, value)
+		}
+		if s, ok := actual.(string); ok && s == expected {
+			same = true
+		}
+		return same == (op == config.OpEquals), nil
+
+	case config.OpContains:
+		return strings.Contains(textOf(actual), expected), nil
+
+	case config.OpMatches:
+		re, err := regexp.Compile(expected)
+		if err != nil {
+			return false, fmt.Errorf("invalid regular expression: %w", err)
+		}
+		return re.MatchString(textOf(actual)), nil
+
+	case config.OpLessThan, config.OpGreaterThan:
+		x, err := strconv.ParseFloat(textOf(actual), 64)
+		if err != nil {
+			return false, fmt.Errorf("%s is not a number", truncate(textOf(actual), maxActualLength))
+		}
+		limit, err := strconv.ParseFloat(strings.TrimSpace(expected), 64)
+		if err != nil {
+			return false, fmt.Errorf("%q is not a number", expected)
+		}
+		if op == config.OpLessThan {
+			return x < limit, nil
+		}
+		return x > limit, nil
+	}
+
+	return false, fmt.Errorf("unknown operator %q", op)
+}
+
+func describeFailure(a config.Assertion) string {
+	switch a.Operator {
+	case config.OpExists:
+		return "not found"
+	case config.OpNotExists:
+		return "found"
+	}
+	return fmt.Sprintf("expected %s %s", a.Operator, a.Value)
+}
+
+// ExtractionResult reports a variable set from a response, with secret
+// values masked
+type ExtractionResult struct {
+	Variable string `json:"variable"`
+	Value    string `json:"value,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// Extract reads the enabled extractions from a response. Strings are taken
+// as they are and other JSON values as JSON text.
+func Extract(extractions []config.Extraction, resp *Response) ([]config.Variable, []ExtractionResult) {
+	vars := []config.Variable{}
+	results := []ExtractionResult{}
+	for _, e := range extractions {
+		if e.Disabled || e.Variable == "" {
+			continue
+		}
+		result := ExtractionResult{Variable: e.Variable}
+		value, err := extract(e, resp)
+		if err != nil {
+			result.Error = err.Error()
+		} else {
+			vars = append(vars, config.Variable{Key: e.Variable, Value: value, Secret: e.Secret})
+			result.Value = value
+			if e.Secret {
+				result.Value = "••••••"
+			}
+		}
+		results = append(results, result)
+	}
+	return vars, results
+}
+
+func extract(e config.Extraction, resp *Response) (string, error) {
+	switch e.Source {
+	case config.ExtractHeader:
+		values := resp.Header.Values(e.Path)
+		if len(values) == 0 {
+			return "", fmt.Errorf("no %s header", e.Path)
+		}
+		return values[0], nil
+
+	case config.ExtractJSONPath:
+		doc, err := resp.json()
+		if err != nil {
+			return "", err
+		}
+		values, err := EvalJSONPath(doc, e.Path)
+		if err != nil {
+			return "", err
+		}
+		switch len(values) {
+		case 0:
+			return "", fmt.Errorf("nothing at %s", e.Path)
+		case 1:
+			return textOf(values[0]), nil
+		}
+		return textOf(values), nil
+	}
+
+	return "", fmt.Errorf("unknown extraction source %q", e.Source)
+}
+
+// decodeJSON decodes one JSON document, keeping numbers exact
+func decodeJSON(text string) (interface{}, error) {
+	dec := json.NewDecoder(strings.NewReader(text))
+	dec.UseNumber()
+	var value interface{}
+	if err := dec.Decode(&value); err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, fmt.Errorf("more than one JSON value")
+	}
+	return value, nil
+}
+
+// textOf returns a string as it is and any other value as JSON text
+func textOf(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+	return compactJSON(value)
+}
+
+func compactJSON(value interface{}) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
+
+func truncate(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return string(runes[:max]) + "…"
+}
@@ -0,0 +1,202 @@
+package restclient
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"cloudevents-explorer/internal/config"
+)
+
+func testResponse() *Response {
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	header.Add("Set-Cookie", "a=1")
+	header.Add("Set-Cookie", "b=2")
+	header.Set("X-Request-Id", "req-42")
+	return &Response{
+		StatusCode: 201,
+		Header:     header,
+		Body: []byte(`{
+			"id": 9007199254740993,
+			"price": 1.0,
+			"name": "Ann",
+			"token": "t0k",
+			"active": true,
+			"tags": ["a", "b"],
+			"owner": {"id": 7},
+			"empty": null
+		}`),
+		Duration: 120.5,
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	tests := []struct {
+		name      string
+		assertion config.Assertion
+		passed    bool
+		actual    string
+		message   string
+	}{
+		{"status equals", config.Assertion{Type: config.AssertStatus, Value: "201"}, true, "201", ""},
+		{"status differs", config.Assertion{Type: config.AssertStatus, Value: "200"}, false, "201", "expected equals 200"},
+		{"status class", config.Assertion{Type: config.AssertStatus, Value: "2xx"}, true, "201", ""},
+		{"status class in upper case", config.Assertion{Type: config.AssertStatus, Value: " 2XX "}, true, "201", ""},
+		{"other status class", config.Assertion{Type: config.AssertStatus, Value: "4xx"}, false, "201", ""},
+		{"not a status class", config.Assertion{Type: config.AssertStatus, Operator: config.OpNotEquals, Value: "5xx"}, true, "201", ""},
+		{"status below", config.Assertion{Type: config.AssertStatus, Operator: config.OpLessThan, Value: "300"}, true, "201", ""},
+
+		{"header exists by default", config.Assertion{Type: config.AssertHeader, Target: "x-request-id"}, true, "req-42", ""},
+		{"missing header", config.Assertion{Type: config.AssertHeader, Target: "X-Missing"}, false, "", "not found"},
+		{"header not exists", config.Assertion{Type: config.AssertHeader, Target: "X-Missing", Operator: config.OpNotExists}, true, "", ""},
+		{"present header not exists", config.Assertion{Type: config.AssertHeader, Target: "X-Request-Id", Operator: config.OpNotExists}, false, "req-42", "found"},
+		{"missing header never equals", config.Assertion{Type: config.AssertHeader, Target: "X-Missing", Operator: config.OpNotEquals, Value: "x"}, false, "", ""},
+		{"repeated header joined", config.Assertion{Type: config.AssertHeader, Target: "Set-Cookie", Operator: config.OpEquals, Value: "a=1, b=2"}, true, "a=1, b=2", ""},
+		{"header contains", config.Assertion{Type: config.AssertHeader, Target: "Content-Type", Operator: config.OpContains, Value: "json"}, true, "application/json", ""},
+		{"header matches", config.Assertion{Type: config.AssertHeader, Target: "X-Request-Id", Operator: config.OpMatches, Value: `^req-\d+$`}, true, "req-42", ""},
+		{"no header name", config.Assertion{Type: config.AssertHeader}, false, "", "no header name"},
+
+		{"large number equals exactly", config.Assertion{Type: config.AssertJSONPath, Target: "$.id", Operator: config.OpEquals, Value: "9007199254740993"}, true, "9007199254740993", ""},
+		{"large number differs in the last digit", config.Assertion{Type: config.AssertJSONPath, Target: "$.id", Operator: config.OpEquals, Value: "9007199254740992"}, false, "9007199254740993", ""},
+		{"1.0 equals 1", config.Assertion{Type: config.AssertJSONPath, Target: "$.price", Operator: config.OpEquals, Value: "1"}, true, "1.0", ""},
+		{"string without quotes", config.Assertion{Type: config.AssertJSONPath, Target: "$.name", Operator: config.OpEquals, Value: "Ann"}, true, "Ann", ""},
+		{"string with quotes", config.Assertion{Type: config.AssertJSONPath, Target: "$.name", Operator: config.OpEquals, Value: `"Ann"`}, true, "Ann", ""},
+		{"string is not a number", config.Assertion{Type: config.AssertJSONPath, Target: "$.owner.id", Operator: config.OpEquals, Value: `"7"`}, false, "7", ""},
+		{"boolean", config.Assertion{Type: config.AssertJSONPath, Target: "$.active", Operator: config.OpEquals, Value: "true"}, true, "true", ""},
+		{"array", config.Assertion{Type: config.AssertJSONPath, Target: "$.tags", Operator: config.OpEquals, Value: `["a","b"]`}, true, `["a","b"]`, ""},
+		{"several matches", config.Assertion{Type: config.AssertJSONPath, Target: "$.tags[*]", Operator: config.OpContains, Value: `"b"`}, true, `["a","b"]`, ""},
+		{"null exists", config.Assertion{Type: config.AssertJSONPath, Target: "$.empty"}, true, "", ""},
+		{"path not found", config.Assertion{Type: config.AssertJSONPath, Target: "$.missing"}, false, "", "not found"},
+		{"path not exists", config.Assertion{Type: config.AssertJSONPath, Target: "$.missing", Operator: config.OpNotExists}, true, "", ""},
+		{"number greater than", config.Assertion{Type: config.AssertJSONPath, Target: "$.owner.id", Operator: config.OpGreaterThan, Value: "5"}, true, "7", ""},
+		{"string compared as a number", config.Assertion{Type: config.AssertJSONPath, Target: "$.name", Operator: config.OpLessThan, Value: "5"}, false, "Ann", "Ann is not a number"},
+		{"limit not a number", config.Assertion{Type: config.AssertJSONPath, Target: "$.owner.id", Operator: config.OpLessThan, Value: "ten"}, false, "7", `"ten" is not a number`},
+		{"invalid regular expression", config.Assertion{Type: config.AssertJSONPath, Target: "$.name", Operator: config.OpMatches, Value: "("}, false, "Ann", "invalid regular expression"},
+
+		{"schema holds", config.Assertion{Type: config.AssertJSONSchema, Value: `{"type": "object", "required": ["id"]}`}, true, "", ""},
+		{"schema fails", config.Assertion{Type: config.AssertJSONSchema, Value: `{"properties": {"name": {"type": "integer"}}}`}, false, "", "$.name: "},
+		{"schema not JSON", config.Assertion{Type: config.AssertJSONSchema, Value: `{`}, false, "", "invalid schema"},
+
+		{"response time below the limit by default", config.Assertion{Type: config.AssertResponseTime, Value: "200"}, true, "120.50", ""},
+		{"response time over the limit", config.Assertion{Type: config.AssertResponseTime, Value: "100"}, false, "120.50", "expected lessThan 100"},
+		{"response time above", config.Assertion{Type: config.AssertResponseTime, Operator: config.OpGreaterThan, Value: "120.4"}, true, "120.50", ""},
+		{"response time limit not a number", config.Assertion{Type: config.AssertResponseTime, Value: "fast"}, false, "120.50", `"fast" is not a number`},
+
+		{"unknown type", config.Assertion{Type: "body"}, false, "", `unknown assertion type "body"`},
+		{"unknown operator", config.Assertion{Type: config.AssertStatus, Operator: "between", Value: "200"}, false, "201", `unknown operator "between"`},
+	}
+
+	for _, tt := range tests {
+		results := Evaluate([]config.Assertion{tt.assertion}, testResponse())
+		if len(results) != 1 {
+			t.Errorf("%s: expected one result, got %d", tt.name, len(results))
+			continue
+		}
+		got := results[0]
+		if got.Passed != tt.passed {
+			t.Errorf("%s: expected passed %v, got %v (%s)", tt.name, tt.passed, got.Passed, got.Message)
+		}
+		if got.Actual != tt.actual {
+			t.Errorf("%s: expected actual %q, got %q", tt.name, tt.actual, got.Actual)
+		}
+		if tt.message != "" && !strings.Contains(got.Message, tt.message) {
+			t.Errorf("%s: expected a message about %q, got %q", tt.name, tt.message, got.Message)
+		}
+		if tt.passed && got.Message != "" {
+			t.Errorf("%s: expected no message, got %q", tt.name, got.Message)
+		}
+	}
+}
+
+func TestEvaluateSkipsDisabledAndDefaultsOperator(t *testing.T) {
+	results := Evaluate([]config.Assertion{
+		{Type: config.AssertStatus, Value: "500", Disabled: true},
+		{Type: config.AssertStatus, Value: "201"},
+	}, testResponse())
+	if len(results) != 1 {
+		t.Fatalf("expected one result, got %d", len(results))
+	}
+	if results[0].Assertion.Operator != config.OpEquals {
+		t.Errorf("expected the default operator to be recorded, got %q", results[0].Assertion.Operator)
+	}
+}
+
+func TestEvaluateBodyNotJSON(t *testing.T) {
+	resp := &Response{StatusCode: 200, Header: http.Header{}, Body: []byte("<html>")}
+	results := Evaluate([]config.Assertion{{Type: config.AssertJSONPath, Target: "$.a"}}, resp)
+	if len(results) != 1 || results[0].Passed || !strings.Contains(results[0].Message, "not JSON") {
+		t.Errorf("expected a body that is not JSON to fail, got %+v", results)
+	}
+}
+
+func TestEvaluateTruncatesActual(t *testing.T) {
+	resp := &Response{StatusCode: 200, Header: http.Header{}, Body: []byte(`{"a": "` + strings.Repeat("é", 300) + `"}`)}
+	results := Evaluate([]config.Assertion{{Type: config.AssertJSONPath, Target: "$.a"}}, resp)
+	if want := strings.Repeat("é", maxActualLength) + "…"; results[0].Actual != want {
+		t.Errorf("expected the actual value cut to %d characters, got %d", maxActualLength, len([]rune(results[0].Actual)))
+	}
+}
+
+func TestExtract(t *testing.T) {
+	extractions := []config.Extraction{
+		{Variable: "name", Source: config.ExtractJSONPath, Path: "$.name"},
+		{Variable: "id", Source: config.ExtractJSONPath, Path: "$.id"},
+		{Variable: "active", Source: config.ExtractJSONPath, Path: "$.active"},
+		{Variable: "owner", Source: config.ExtractJSONPath, Path: "$.owner"},
+		{Variable: "tags", Source: config.ExtractJSONPath, Path: "$.tags[*]"},
+		{Variable: "empty", Source: config.ExtractJSONPath, Path: "$.empty"},
+		{Variable: "token", Source: config.ExtractJSONPath, Path: "$.token", Secret: true},
+		{Variable: "requestId", Source: config.ExtractHeader, Path: "x-request-id"},
+		{Variable: "cookie", Source: config.ExtractHeader, Path: "Set-Cookie"},
+		{Variable: "missing", Source: config.ExtractJSONPath, Path: "$.missing"},
+		{Variable: "noHeader", Source: config.ExtractHeader, Path: "X-Missing"},
+		{Variable: "badPath", Source: config.ExtractJSONPath, Path: "$.tags[x"},
+		{Variable: "unknown", Source: "cookie", Path: "a"},
+		{Variable: "disabled", Source: config.ExtractJSONPath, Path: "$.name", Disabled: true},
+		{Variable: "", Source: config.ExtractJSONPath, Path: "$.name"},
+	}
+
+	vars, results := Extract(extractions, testResponse())
+
+	wantVars := []config.Variable{
+		{Key: "name", Value: "Ann"},
+		{Key: "id", Value: "9007199254740993"},
+		{Key: "active", Value: "true"},
+		{Key: "owner", Value: `{"id":7}`},
+		{Key: "tags", Value: `["a","b"]`},
+		{Key: "empty", Value: "null"},
+		{Key: "token", Value: "t0k", Secret: true},
+		{Key: "requestId", Value: "req-42"},
+		{Key: "cookie", Value: "a=1"},
+	}
+	if !reflect.DeepEqual(vars, wantVars) {
+		t.Errorf("expected variables %+v, got %+v", wantVars, vars)
+	}
+
+	wantErrors := map[string]string{
+		"missing":  "nothing at $.missing",
+		"noHeader": "no X-Missing header",
+		"badPath":  "",
+		"unknown":  `unknown extraction source "cookie"`,
+	}
+	if len(results) != len(wantVars)+len(wantErrors) {
+		t.Fatalf("expected %d results, got %d", len(wantVars)+len(wantErrors), len(results))
+	}
+	for _, result := range results {
+		want, failed := wantErrors[result.Variable]
+		switch {
+		case failed && result.Error == "":
+			t.Errorf("%s: expected an error", result.Variable)
+		case failed && !strings.Contains(result.Error, want):
+			t.Errorf("%s: expected an error about %q, got %q", result.Variable, want, result.Error)
+		case !failed && result.Error != "":
+			t.Errorf("%s: unexpected error %q", result.Variable, result.Error)
+		case result.Variable == "token" && result.Value != "••••••":
+			t.Errorf("expected the secret to be masked, got %q", result.Value)
+		case result.Variable == "name" && result.Value != "Ann":
+			t.Errorf("expected the value to be reported, got %q", result.Value)
+		}
+	}
+}
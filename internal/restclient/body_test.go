@@ -0,0 +1,130 @@
+package restclient
+
+import (
+	"io"
+	"mime"
+	"mime/multipart"
+	"strings"
+	"testing"
+
+	"cloudevents-explorer/internal/config"
+)
+
+func TestBuildBody(t *testing.T) {
+	vars := NewVariables([]config.Variable{
+		{Key: "id", Value: "42"},
+		{Key: "type", Value: "application/xml"},
+		{Key: "field", Value: "user name"},
+	})
+
+	tests := []struct {
+		name        string
+		body        config.RequestBody
+		wantBody    string
+		contentType string
+	}{
+		{"no type", config.RequestBody{}, "", ""},
+		{"none ignores raw text", config.RequestBody{Type: config.BodyNone, Raw: "x"}, "", ""},
+		{"empty raw", config.RequestBody{Type: config.BodyRaw, ContentType: "text/plain"}, "", ""},
+		{"raw", config.RequestBody{Type: config.BodyRaw, ContentType: "application/json", Raw: `{"id": {{id}}}`},
+			`{"id": 42}`, "application/json"},
+		{"raw content type from a variable", config.RequestBody{Type: config.BodyRaw, ContentType: "{{type}}", Raw: "<a/>"},
+			"<a/>", "application/xml"},
+		{"raw without content type", config.RequestBody{Type: config.BodyRaw, Raw: "text"}, "text", ""},
+		{"form keeps field order", config.RequestBody{Type: config.BodyForm, Fields: []config.BodyField{
+			{Name: "z", Value: "1"}, {Name: "a", Value: "2"},
+		}}, "z=1&a=2", "application/x-www-form-urlencoded"},
+		{"form escapes and substitutes", config.RequestBody{Type: config.BodyForm, Fields: []config.BodyField{
+			{Name: "{{field}}", Value: "a&b={{id}}"}, {Name: "", Value: "skipped"}, {Name: "empty"},
+		}}, "user+name=a%26b%3D42&empty=", "application/x-www-form-urlencoded"},
+		{"file", config.RequestBody{Type: config.BodyFile, File: &config.UploadedFile{Name: "a.png", ContentType: "image/png", Data: []byte("{{id}}")}},
+			"{{id}}", "image/png"},
+		{"file with its content type overridden", config.RequestBody{Type: config.BodyFile, ContentType: "{{type}}", File: &config.UploadedFile{Data: []byte("<a/>")}},
+			"<a/>", "application/xml"},
+		{"file without a content type", config.RequestBody{Type: config.BodyFile, File: &config.UploadedFile{Data: []byte{1}}},
+			"\x01", "application/octet-stream"},
+	}
+
+	for _, tt := range tests {
+		body, contentType, err := BuildBody(tt.body, vars)
+		if err != nil {
+			t.Errorf("%s: could not build body: %v", tt.name, err)
+			continue
+		}
+		if string(body) != tt.wantBody {
+			t.Errorf("%s: expected body %q, got %q", tt.name, tt.wantBody, body)
+		}
+		if contentType != tt.contentType {
+			t.Errorf("%s: expected content type %q, got %q", tt.name, tt.contentType, contentType)
+		}
+	}
+}
+
+func TestBuildBodyErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		body config.RequestBody
+		want string
+	}{
+		{"file body without a file", config.RequestBody{Type: config.BodyFile}, "no file chosen"},
+		{"unknown type", config.RequestBody{Type: "graphql"}, `unknown body type "graphql"`},
+	}
+
+	for _, tt := range tests {
+		_, _, err := BuildBody(tt.body, NewVariables(nil))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected an error about %q, got %v", tt.name, tt.want, err)
+		}
+	}
+}
+
+func TestBuildBodyMultipart(t *testing.T) {
+	vars := NewVariables([]config.Variable{{Key: "id", Value: "42"}, {Key: "name", Value: "upload"}})
+	body := config.RequestBody{Type: config.BodyMultipart, Fields: []config.BodyField{
+		{Name: "id", Value: "{{id}}"},
+		{Name: "", Value: "skipped"},
+		{Name: "{{name}}", File: &config.UploadedFile{Name: `my "file".txt`, ContentType: "text/plain", Data: []byte("{{id}}")}},
+		{Name: "raw", File: &config.UploadedFile{Name: "b.bin", Data: []byte{0, 1}}},
+	}}
+
+	data, contentType, err := BuildBody(body, vars)
+	if err != nil {
+		t.Fatalf("could not build body: %v", err)
+	}
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType != "multipart/form-data" {
+		t.Fatalf("expected a multipart/form-data content type, got %q", contentType)
+	}
+
+	type part struct {
+		name, filename, contentType, data string
+	}
+	want := []part{
+		{"id", "", "", "42"},
+		{"upload", `my "file".txt`, "text/plain", "{{id}}"},
+		{"raw", "b.bin", "application/octet-stream", "\x00\x01"},
+	}
+
+	reader := multipart.NewReader(strings.NewReader(string(data)), params["boundary"])
+	for i := 0; ; i++ {
+		p, err := reader.NextPart()
+		if err == io.EOF {
+			if i != len(want) {
+				t.Errorf("expected %d parts, got %d", len(want), i)
+			}
+			break
+		}
+		if err != nil {
+			t.Fatalf("could not read part %d: %v", i, err)
+		}
+		if i >= len(want) {
+			t.Errorf("unexpected part %q", p.FormName())
+			continue
+		}
+		content, _ := io.ReadAll(p)
+		got := part{p.FormName(), p.FileName(), p.Header.Get("Content-Type"), string(content)}
+		if got != want[i] {
+			t.Errorf("part %d: expected %+v, got %+v", i, want[i], got)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package restclient
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// pathStep is one selector of a JSONPath: a key, an index (negative counts
+// from the end) or a wildcard, applied to every descendant when recursive
+type pathStep struct {
+	key       string
+	index     int
+	isIndex   bool
+	wildcard  bool
+	recursive bool
+}
+
+// parseJSONPath parses the common subset of JSONPath: $, .key, ..key, .*,
+// [n], [*] and ['key']. A path without the leading $ is taken as relative to
+// the root. Filters, slices, unions and functions are errors rather than
+// keys, so an assertion on them fails instead of selecting nothing.
+func parseJSONPath(path string) ([]pathStep, error) {
+	p := strings.TrimSpace(path)
+	if p == "" {
+		return nil, fmt.Errorf("empty JSONPath")
+	}
+	if strings.HasPrefix(p, "$") {
+		p = p[1:]
+	} else if !strings.HasPrefix(p, ".") && !strings.HasPrefix(p, "[") {
+		p = "." + p
+	}
+
+	var steps []pathStep
+	for p != "" {
+		var step pathStep
+		switch {
+		case strings.HasPrefix(p, ".."):
+			step.recursive = true
+			p = p[2:]
+			if strings.HasPrefix(p, "[") {
+				break
+			}
+			fallthrough
+		case strings.HasPrefix(p, "."):
+			p = strings.TrimPrefix(p, ".")
+			end := strings.IndexAny(p, ".[")
+			if end == -1 {
+				end = len(p)
+			}
+			name := p[:end]
+			p = p[end:]
+			if name == "" {
+				return nil, fmt.Errorf("invalid JSONPath %q: empty key", path)
+			}
+			if strings.ContainsAny(name, "()?@,:'\" ") {
+				return nil, fmt.Errorf("invalid JSONPath %q: unsupported selector .%s; functions such as length() are not supported", path, name)
+			}
+			if name == "*" {
+				step.wildcard = true
+			} else {
+				step.key = name
+			}
+			steps = append(steps, step)
+			continue
+		}
+
+		if !strings.HasPrefix(p, "[") {
+			return nil, fmt.Errorf("invalid JSONPath %q at %q", path, p)
+		}
+		end := strings.Index(p, "]")
+		if end == -1 {
+			return nil, fmt.Errorf("invalid JSONPath %q: unclosed [", path)
+		}
+		// A quoted key may itself hold ]
+		if p[1] == '\'' || p[1] == '"' {
+			closing := strings.IndexByte(p[2:], p[1])
+			if closing == -1 {
+				return nil, fmt.Errorf("invalid JSONPath %q: unclosed quote", path)
+			}
+			step.key = p[2 : 2+closing]
+			p = p[2+closing+1:]
+			if !strings.HasPrefix(p, "]") {
+				return nil, fmt.Errorf("invalid JSONPath %q: unions of keys are not supported", path)
+			}
+			p = p[1:]
+			steps = append(steps, step)
+			continue
+		}
+		selector := strings.TrimSpace(p[1:end])
+		p = p[end+1:]
+
+		switch {
+		case selector == "*":
+			step.wildcard = true
+		case strings.HasPrefix(selector, "?"):
+			return nil, fmt.Errorf("invalid JSONPath %q: filters such as [%s] are not supported", path, selector)
+		case strings.Contains(selector, ":"):
+			return nil, fmt.Errorf("invalid JSONPath %q: slices such as [%s] are not supported", path, selector)
+		case strings.Contains(selector, ","):
+			return nil, fmt.Errorf("invalid JSONPath %q: unions such as [%s] are not supported", path, selector)
+		default:
+			index, err := strconv.Atoi(selector)
+			if err != nil {
+				return nil, fmt.Errorf("invalid JSONPath %q: unsupported selector [%s]", path, selector)
+			}
+			step.index = index
+			step.isIndex = true
+		}
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
+// EvalJSONPath returns the values a JSONPath selects in a decoded JSON
+// document, in document order with object keys sorted
+func EvalJSONPath(doc interface{}, path string) ([]interface{}, error) {
+	steps, err := parseJSONPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := []interface{}{doc}
+	for _, step := range steps {
+		if step.recursive {
+			var all []interface{}
+			for _, node := range nodes {
+				all = appendDescendants(all, node)
+			}
+			nodes = all
+		}
+
+		var next []interface{}
+		for _, node := range nodes {
+			// Some JSONPath dialects read .length as the size of an array
+			if _, isArray := node.([]interface{}); isArray && step.key == "length" && !step.recursive {
+				return nil, fmt.Errorf("JSONPath %q: .length of an array is not supported; check its size with minItems or maxItems in a JSON Schema assertion", path)
+			}
+			next = append(next, selectStep(node, step)...)
+		}
+		nodes = next
+	}
+	return nodes, nil
+}
+
+func selectStep(node interface{}, step pathStep) []interface{} {
+	switch v := node.(type) {
+	case map[string]interface{}:
+		if step.wildcard {
+			var values []interface{}
+			for _, key := range sortedKeys(v) {
+				values = append(values, v[key])
+			}
+			return values
+		}
+		if value, ok := v[step.key]; ok && !step.isIndex {
+			return []interface{}{value}
+		}
+	case []interface{}:
+		if step.wildcard {
+			return append([]interface{}{}, v...)
+		}
+		if step.isIndex {
+			index := step.index
+			if index < 0 {
+				index += len(v)
+			}
+			if index >= 0 && index < len(v) {
+				return []interface{}{v[index]}
+			}
+		}
+	}
+	return nil
+}
+
+// appendDescendants appends a node and everything under it
+func appendDescendants(all []interface{}, node interface{}) []interface{} {
+	all = append(all, node)
+	switch v := node.(type) {
+	case map[string]interface{}:
+		for _, key := range sortedKeys(v) {
+			all = appendDescendants(all, v[key])
+		}
+	case []interface{}:
+		for _, item := range v {
+			all = appendDescendants(all, item)
+		}
+	}
+	return all
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,107 @@
+package restclient
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEvalJSONPath(t *testing.T) {
+	doc, err := decodeJSON(`{
+		"store": {
+			"books": [
+				{"title": "A", "price": 8, "tags": ["x"]},
+				{"title": "B", "price": 12}
+			],
+			"owner": {"name": "Ann", "first name": "Ann"},
+			"odd]key": 1,
+			"length": 3
+		}
+	}`)
+	if err != nil {
+		t.Fatalf("could not decode document: %v", err)
+	}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"$.store.owner.name", `["Ann"]`},
+		{"store.owner.name", `["Ann"]`},
+		{"$.store.books[0].title", `["A"]`},
+		{"$.store.books[-1].title", `["B"]`},
+		{"$.store.books[5]", `null`},
+		{"$.store.books[*].price", `[8,12]`},
+		{"$.store.books.*.title", `["A","B"]`},
+		{"$..title", `["A","B"]`},
+		{"$..tags[0]", `["x"]`},
+		{"$.store.owner['first name']", `["Ann"]`},
+		{`$.store["odd]key"]`, `[1]`},
+		{"$.store.length", `[3]`},
+		{"$.store.missing.deeper", `null`},
+		{"$.store.owner", `[{"first name":"Ann","name":"Ann"}]`},
+	}
+
+	for _, tt := range tests {
+		values, err := EvalJSONPath(doc, tt.path)
+		if err != nil {
+			t.Errorf("%s: could not evaluate: %v", tt.path, err)
+			continue
+		}
+		if got := compactJSON(values); got != tt.want {
+			t.Errorf("%s: expected %s, got %s", tt.path, tt.want, got)
+		}
+	}
+}
+
+func TestEvalJSONPathUnsupported(t *testing.T) {
+	doc, err := decodeJSON(`{"items": [{"id": 1}, {"id": 2}]}`)
+	if err != nil {
+		t.Fatalf("could not decode document: %v", err)
+	}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"", "empty JSONPath"},
+		{"$.items[?(@.id > 1)]", "filters"},
+		{"$.items[0:1]", "slices"},
+		{"$.items[0,1]", "unions"},
+		{"$['items','other']", "unions of keys"},
+		{"$.items.length()", "unsupported selector"},
+		{"$.items.length", ".length of an array"},
+		{"$.items[first]", "unsupported selector"},
+		{"$.items[0", "unclosed ["},
+		{"$['it]ems", "unclosed quote"},
+		{"$.items..", "empty key"},
+		{"$items", "invalid JSONPath"},
+	}
+
+	for _, tt := range tests {
+		values, err := EvalJSONPath(doc, tt.path)
+		if err == nil {
+			t.Errorf("%q: expected an error, got %v", tt.path, values)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%q: expected an error about %q, got %v", tt.path, tt.want, err)
+		}
+	}
+}
+
+func TestParseJSONPath(t *testing.T) {
+	steps, err := parseJSONPath("$..a[2]['b c'][*]")
+	if err != nil {
+		t.Fatalf("could not parse: %v", err)
+	}
+	want := []pathStep{
+		{key: "a", recursive: true},
+		{index: 2, isIndex: true},
+		{key: "b c"},
+		{wildcard: true},
+	}
+	if !reflect.DeepEqual(steps, want) {
+		t.Errorf("expected %+v, got %+v", want, steps)
+	}
+}
@@ -0,0 +1,24 @@
+package restclient
+
+import (
+	"net/url"
+	"strings"
+
+	"cloudevents-explorer/internal/config"
+)
+
+// AppendQuery adds the enabled parameters to a raw query, in order and
+// keeping repeated keys, substituting variables before encoding
+func AppendQuery(rawQuery string, params config.QueryParams, vars *Variables) string {
+	pairs := []string{}
+	if rawQuery != "" {
+		pairs = append(pairs, rawQuery)
+	}
+	for _, param := range params {
+		if param.Disabled || param.Key == "" {
+			continue
+		}
+		pairs = append(pairs, url.QueryEscape(vars.Substitute(param.Key))+"="+url.QueryEscape(vars.Substitute(param.Value)))
+	}
+	return strings.Join(pairs, "&")
+}
@@ -0,0 +1,42 @@
+package restclient
+
+import (
+	"testing"
+
+	"cloudevents-explorer/internal/config"
+)
+
+func TestAppendQuery(t *testing.T) {
+	vars := NewVariables([]config.Variable{{Key: "page", Value: "2"}, {Key: "q", Value: "a b"}})
+
+	tests := []struct {
+		name     string
+		rawQuery string
+		params   config.QueryParams
+		want     string
+	}{
+		{"nothing", "", nil, ""},
+		{"existing query only", "a=1&b=2", nil, "a=1&b=2"},
+		{"appended after the existing query", "a=1", config.QueryParams{{Key: "b", Value: "2"}}, "a=1&b=2"},
+		{"existing key is kept and repeated", "a=1", config.QueryParams{{Key: "a", Value: "2"}}, "a=1&a=2"},
+		{"repeated keys in order", "", config.QueryParams{
+			{Key: "tag", Value: "z"}, {Key: "id", Value: "1"}, {Key: "tag", Value: "a"},
+		}, "tag=z&id=1&tag=a"},
+		{"disabled and empty keys are skipped", "", config.QueryParams{
+			{Key: "a", Value: "1", Disabled: true}, {Key: "", Value: "x"}, {Key: "b", Value: "2"},
+		}, "b=2"},
+		{"empty value", "", config.QueryParams{{Key: "flag"}}, "flag="},
+		{"escaping", "", config.QueryParams{{Key: "a&b", Value: "c=d e/ü+"}}, "a%26b=c%3Dd+e%2F%C3%BC%2B"},
+		{"existing query is not re-escaped", "q=a%20b", config.QueryParams{{Key: "x", Value: "%"}}, "q=a%20b&x=%25"},
+		{"variables are substituted before escaping", "", config.QueryParams{
+			{Key: "page", Value: "{{page}}"}, {Key: "{{q}}", Value: "{{q}}&"},
+		}, "page=2&a+b=a+b%26"},
+		{"unknown variables are escaped as written", "", config.QueryParams{{Key: "v", Value: "{{nope}}"}}, "v=%7B%7Bnope%7D%7D"},
+	}
+
+	for _, tt := range tests {
+		if got := AppendQuery(tt.rawQuery, tt.params, vars); got != tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package restclient
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+
+	"cloudevents-explorer/internal/jsondiff"
+)
+
+// schemaURL names an assertion's schema, so references within it resolve
+// and references to anything else go to noLoader
+const schemaURL = "mem:///assertion-schema.json"
+
+// schemaPrinter words the validator's messages
+var schemaPrinter = message.NewPrinter(language.English)
+
+// noLoader refuses every schema the validator asks to load, so a $ref
+// cannot read local files or reach the network. The drafts' meta-schemas
+// are built into the validator and need no loading.
+type noLoader struct{}
+
+func (noLoader) Load(url string) (any, error) {
+	return nil, fmt.Errorf("only references within the schema are resolved, not %s", url)
+}
+
+// ValidateSchema checks a decoded JSON value against a JSON Schema and returns
+// where it does not conform, each problem prefixed with the JSONPath of the
+// value. Drafts 4 to 2020-12 are supported, chosen by $schema and 2020-12
+// without it, and formats are asserted. The error is for a schema that is
+// itself invalid. Both must be decoded with UseNumber.
+func ValidateSchema(schema, value interface{}) ([]string, error) {
+	c := jsonschema.NewCompiler()
+	c.AssertFormat()
+	c.UseLoader(noLoader{})
+	if err := c.AddResource(schemaURL, schema); err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	compiled, err := c.Compile(schemaURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+
+	err = compiled.Validate(value)
+	var invalid *jsonschema.ValidationError
+	if errors.As(err, &invalid) {
+		var problems []string
+		collectProblems(invalid, value, &problems)
+		return problems, nil
+	}
+	return nil, err
+}
+
+// collectProblems lists the innermost errors of a validation error, which
+// name the keyword that failed rather than the schemas that hold it
+func collectProblems(e *jsonschema.ValidationError, value interface{}, problems *[]string) {
+	if len(e.Causes) == 0 {
+		*problems = append(*problems, instancePath(value, e.InstanceLocation)+": "+e.ErrorKind.LocalizedString(schemaPrinter))
+		return
+	}
+	for _, cause := range e.Causes {
+		collectProblems(cause, value, problems)
+	}
+}
+
+// instancePath renders the location of a value as a JSONPath, looking at the
+// document to tell array indexes from object keys
+func instancePath(doc interface{}, location []string) string {
+	path := "$"
+	for _, token := range location {
+		if arr, ok := doc.([]interface{}); ok {
+			if i, err := strconv.Atoi(token); err == nil && i >= 0 && i < len(arr) {
+				path += "[" + token + "]"
+				doc = arr[i]
+				continue
+			}
+		}
+		path = jsondiff.PathKey(path, token)
+		if obj, ok := doc.(map[string]interface{}); ok {
+			doc = obj[token]
+		} else {
+			doc = nil
+		}
+	}
+	return path
+}
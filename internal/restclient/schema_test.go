@@ -0,0 +1,94 @@
+package restclient
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateSchema(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		value  string
+		want   []string
+	}{
+		{"type", `{"type": "string"}`, `"a"`, nil},
+		{"wrong type", `{"type": "string"}`, `1`, []string{"$: got number, want string"}},
+		{"integer written with a fraction", `{"type": "integer"}`, `1.0`, nil},
+		{"not in enum", `{"enum": [1, "a"]}`, `"b"`, []string{"$: value must be one of 1, 'a'"}},
+		{"false schema", `false`, `1`, []string{"$: false schema"}},
+		{"required and properties",
+			`{"type": "object", "required": ["id"], "properties": {"id": {"type": "integer"}, "first name": {"type": "string"}}}`,
+			`{"first name": 1}`,
+			[]string{"$: missing property 'id'", `$["first name"]: got number, want string`}},
+		{"additional properties", `{"properties": {"a": true}, "additionalProperties": false}`, `{"a": 1, "b": 2}`,
+			[]string{"$: additional properties 'b' not allowed"}},
+		{"nested array item", `{"properties": {"a": {"items": {"type": "string"}}}}`, `{"a": ["x", 1]}`,
+			[]string{"$.a[1]: got number, want string"}},
+		{"numeric object key", `{"properties": {"0": {"type": "string"}}}`, `{"0": 1}`,
+			[]string{`$["0"]: got number, want string`}},
+		{"anyOf lists every branch", `{"anyOf": [{"type": "string"}, {"type": "null"}]}`, `1`,
+			[]string{"$: got number, want string", "$: got number, want null"}},
+		{"oneOf", `{"oneOf": [{"type": "number"}, {"type": "integer"}]}`, `1`,
+			[]string{"$: 'oneOf' failed, subschemas 0, 1 matched"}},
+		{"conditional", `{"if": {"type": "string"}, "then": {"minLength": 1}}`, `""`,
+			[]string{"$: minLength: got 0, want 1"}},
+		{"pattern properties", `{"patternProperties": {"^x": {"type": "string"}}}`, `{"xa": 1}`,
+			[]string{"$.xa: got number, want string"}},
+		{"ref", `{"$defs": {"id": {"type": "integer"}}, "properties": {"id": {"$ref": "#/$defs/id"}}}`, `{"id": "x"}`,
+			[]string{"$.id: got string, want integer"}},
+		{"draft 4", `{"$schema": "http://json-schema.org/draft-04/schema#", "minimum": 1, "exclusiveMinimum": true}`, `1`,
+			[]string{"$: exclusiveMinimum: got 1, want 1"}},
+		{"format", `{"format": "date-time"}`, `"2024-01-02T03:04:05.5+01:00"`, nil},
+		{"invalid format", `{"format": "uuid"}`, `"nope"`, []string{"$: 'nope' is not valid uuid: must have 5 elements"}},
+		{"length counts characters", `{"maxLength": 2}`, `"éé"`, nil},
+	}
+
+	for _, tt := range tests {
+		schema, err := decodeJSON(tt.schema)
+		if err != nil {
+			t.Fatalf("%s: could not decode schema: %v", tt.name, err)
+		}
+		value, err := decodeJSON(tt.value)
+		if err != nil {
+			t.Fatalf("%s: could not decode value: %v", tt.name, err)
+		}
+		got, err := ValidateSchema(schema, value)
+		if err != nil {
+			t.Errorf("%s: could not validate: %v", tt.name, err)
+			continue
+		}
+		if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestValidateSchemaInvalidSchema(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		want   string
+	}{
+		{"not a schema", `{"type": 5}`, "is not valid against metaschema"},
+		{"missing ref", `{"$ref": "#/$defs/none"}`, "not found"},
+		{"remote ref", `{"$ref": "https://example.com/s.json"}`, "only references within the schema are resolved"},
+		{"relative ref", `{"$ref": "other.json"}`, "only references within the schema are resolved"},
+		{"file ref", `{"$ref": "file:///etc/passwd"}`, "only references within the schema are resolved"},
+	}
+
+	for _, tt := range tests {
+		schema, err := decodeJSON(tt.schema)
+		if err != nil {
+			t.Fatalf("%s: could not decode schema: %v", tt.name, err)
+		}
+		problems, err := ValidateSchema(schema, 1)
+		if err == nil {
+			t.Errorf("%s: expected an error, got %q", tt.name, problems)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected an error about %q, got %v", tt.name, tt.want, err)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package restclient
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptrace"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Timing breaks down where the time of a request went, in milliseconds.
+// Phases a reused connection skips are zero. TTFB runs from the request being
+// written to the first byte of the response, and Transfer from there to the
+// end of the body. After redirects the phases are those of the last request,
+// while Total covers them all.
+type Timing struct {
+	DNS          float64  `json:"dns"`
+	Connect      float64  `json:"connect"`
+	TLSHandshake float64  `json:"tlsHandshake"`
+	TTFB         float64  `json:"ttfb"`
+	Transfer     float64  `json:"transfer"`
+	Total        float64  `json:"total"`
+	ReusedConn   bool     `json:"reusedConnection"`
+	RemoteAddr   string   `json:"remoteAddr,omitempty"`
+	Protocol     string   `json:"protocol,omitempty"`
+	TLS          *TLSInfo `json:"tls,omitempty"`
+}
+
+// TLSInfo describes the TLS connection a response came over. Certificates
+// is the chain the server sent, leaf first.
+type TLSInfo struct {
+	Version            string            `json:"version"`
+	CipherSuite        string            `json:"cipherSuite"`
+	ServerName         string            `json:"serverName,omitempty"`
+	NegotiatedProtocol string            `json:"negotiatedProtocol,omitempty"`
+	Certificates       []CertificateInfo `json:"certificates"`
+}
+
+type CertificateInfo struct {
+	Subject      string   `json:"subject"`
+	Issuer       string   `json:"issuer"`
+	SerialNumber string   `json:"serialNumber"`
+	NotBefore    string   `json:"notBefore"`
+	NotAfter     string   `json:"notAfter"`
+	DNSNames     []string `json:"dnsNames,omitempty"`
+	IsCA         bool     `json:"isCA,omitempty"`
+	SHA256       string   `json:"sha256"`
+}
+
+// Tracer records the moments of a request through httptrace. Its hooks may
+// run on the transport's goroutines, and for dual-stack hosts more than one
+// connect at a time.
+type Tracer struct {
+	mu           sync.Mutex
+	start        time.Time
+	dnsStart     time.Time
+	dnsDone      time.Time
+	connectStart time.Time
+	connectDone  time.Time
+	tlsStart     time.Time
+	tlsDone      time.Time
+	wroteRequest time.Time
+	firstByte    time.Time
+	reused       bool
+	remoteAddr   string
+}
+
+// NewTracer starts timing a request
+func NewTracer() *Tracer {
+	return &Tracer{start: time.Now()}
+}
+
+// WithTrace returns a context that reports the requests made with it to t
+func (t *Tracer) WithTrace(ctx context.Context) context.Context {
+	record := func(at *time.Time) {
+		t.mu.Lock()
+		*at = time.Now()
+		t.mu.Unlock()
+	}
+
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GetConn: func(string) {
+			// A redirect starts over
+			t.mu.Lock()
+			t.dnsStart, t.dnsDone = time.Time{}, time.Time{}
+			t.connectStart, t.connectDone = time.Time{}, time.Time{}
+			t.tlsStart, t.tlsDone = time.Time{}, time.Time{}
+			t.wroteRequest, t.firstByte = time.Time{}, time.Time{}
+			t.mu.Unlock()
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mu.Lock()
+			t.reused = info.Reused
+			t.remoteAddr = info.Conn.RemoteAddr().String()
+			t.mu.Unlock()
+		},
+		DNSStart: func(httptrace.DNSStartInfo) { record(&t.dnsStart) },
+		DNSDone:  func(httptrace.DNSDoneInfo) { record(&t.dnsDone) },
+		ConnectStart: func(string, string) {
+			t.mu.Lock()
+			if t.connectStart.IsZero() {
+				t.connectStart = time.Now()
+			}
+			t.mu.Unlock()
+		},
+		ConnectDone: func(_, _ string, err error) {
+			if err == nil {
+				record(&t.connectDone)
+			}
+		},
+		TLSHandshakeStart:    func() { record(&t.tlsStart) },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { record(&t.tlsDone) },
+		WroteRequest:         func(httptrace.WroteRequestInfo) { record(&t.wroteRequest) },
+		GotFirstResponseByte: func() { record(&t.firstByte) },
+	})
+}
+
+// Timing returns the breakdown of a request whose body was read by done
+func (t *Tracer) Timing(resp *http.Response, done time.Time) *Timing {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	timing := &Timing{
+		DNS:          milliseconds(t.dnsStart, t.dnsDone),
+		Connect:      milliseconds(t.connectStart, t.connectDone),
+		TLSHandshake: milliseconds(t.tlsStart, t.tlsDone),
+		TTFB:         milliseconds(t.wroteRequest, t.firstByte),
+		Transfer:     milliseconds(t.firstByte, done),
+		Total:        milliseconds(t.start, done),
+		ReusedConn:   t.reused,
+		RemoteAddr:   t.remoteAddr,
+		Protocol:     resp.Proto,
+	}
+	if resp.TLS != nil {
+		timing.TLS = tlsInfo(resp.TLS)
+	}
+	return timing
+}
+
+// milliseconds returns the time between two moments, or zero when either
+// did not happen
+func milliseconds(from, to time.Time) float64 {
+	if from.IsZero() || to.IsZero() {
+		return 0
+	}
+	return float64(to.Sub(from).Microseconds()) / 1000
+}
+
+func tlsInfo(state *tls.ConnectionState) *TLSInfo {
+	info := &TLSInfo{
+		Version:            tls.VersionName(state.Version),
+		CipherSuite:        tls.CipherSuiteName(state.CipherSuite),
+		ServerName:         state.ServerName,
+		NegotiatedProtocol: state.NegotiatedProtocol,
+		Certificates:       []CertificateInfo{},
+	}
+	for _, cert := range state.PeerCertificates {
+		fingerprint := sha256.Sum256(cert.Raw)
+		info.Certificates = append(info.Certificates, CertificateInfo{
+			Subject:      cert.Subject.String(),
+			Issuer:       cert.Issuer.String(),
+			SerialNumber: strings.ToUpper(cert.SerialNumber.Text(16)),
+			NotBefore:    cert.NotBefore.UTC().Format(time.RFC3339),
+			NotAfter:     cert.NotAfter.UTC().Format(time.RFC3339),
+			DNSNames:     cert.DNSNames,
+			IsCA:         cert.IsCA,
+			SHA256:       strings.ToUpper(hex.EncodeToString(fingerprint[:])),
+		})
+	}
+	return info
+}
@@ -0,0 +1,140 @@
+package restclient
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"cloudevents-explorer/internal/config"
+)
+
+const (
+	defaultTimeout      = 30 * time.Second
+	defaultMaxRedirects = 10
+	dnsTimeout          = 5 * time.Second
+)
+
+// Redirect is a redirect response the client followed, or with redirects
+// disabled the one it returned
+type Redirect struct {
+	URL        string `json:"url"`
+	StatusCode int    `json:"statusCode"`
+	Location   string `json:"location"`
+}
+
+// Client sends one request with the options it was made with, recording the
+// redirects on the way
+type Client struct {
+	*http.Client
+	Redirects []Redirect
+}
+
+// NewClient builds the client for a request's options, whose fields must
+// already have their variables substituted
+func NewClient(opts config.RequestOptions, tlsConfig *tls.Config) (*Client, error) {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+
+	switch opts.Resolver {
+	case "", config.ResolverSystem:
+	case config.ResolverCustom:
+		server := opts.DNSServer
+		if server == "" {
+			return nil, fmt.Errorf("a custom resolver needs a DNS server")
+		}
+		if _, _, err := net.SplitHostPort(server); err != nil {
+			server = net.JoinHostPort(server, "53")
+		}
+		dialer.Resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+				d := net.Dialer{Timeout: dnsTimeout}
+				return d.DialContext(ctx, network, server)
+			},
+		}
+	default:
+		return nil, fmt.Errorf("unknown resolver %q", opts.Resolver)
+	}
+
+	overrides := map[string]string{}
+	for _, o := range opts.HostOverrides {
+		if o.Host != "" && o.Address != "" {
+			overrides[strings.ToLower(o.Host)] = o.Address
+		}
+	}
+
+	transport := &http.Transport{
+		TLSClientConfig:   tlsConfig,
+		DisableKeepAlives: false,
+		DialContext: func(ctx context.Context, network, address string) (net.Conn, error) {
+			return dialer.DialContext(ctx, network, overrideAddress(address, overrides))
+		},
+	}
+
+	if opts.Proxy != "" {
+		proxyURL, err := url.Parse(opts.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL: %w", err)
+		}
+		switch proxyURL.Scheme {
+		case "http", "https", "socks5", "socks5h":
+		default:
+			return nil, fmt.Errorf("unsupported proxy scheme %q, want http, https, socks5 or socks5h", proxyURL.Scheme)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	timeout := defaultTimeout
+	if opts.TimeoutMs > 0 {
+		timeout = time.Duration(opts.TimeoutMs) * time.Millisecond
+	}
+	maxRedirects := defaultMaxRedirects
+	if opts.MaxRedirects > 0 {
+		maxRedirects = opts.MaxRedirects
+	}
+
+	c := &Client{}
+	c.Client = &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			c.Redirects = append(c.Redirects, Redirect{
+				URL:        via[len(via)-1].URL.String(),
+				StatusCode: req.Response.StatusCode,
+				Location:   req.URL.String(),
+			})
+			if opts.DisableRedirects {
+				return http.ErrUseLastResponse
+			}
+			if len(via) > maxRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxRedirects)
+			}
+			return nil
+		},
+	}
+	return c, nil
+}
+
+// overrideAddress swaps the host of a host:port for its override, keeping
+// the port unless the override has its own
+func overrideAddress(address string, overrides map[string]string) string {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return address
+	}
+	target, ok := overrides[strings.ToLower(host)]
+	if !ok {
+		return address
+	}
+	if _, _, err := net.SplitHostPort(target); err == nil {
+		return target
+	}
+	return net.JoinHostPort(strings.Trim(target, "[]"), port)
+}
@@ -0,0 +1,182 @@
+package restclient
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"cloudevents-explorer/internal/config"
+)
+
+func TestOverrideAddress(t *testing.T) {
+	overrides := map[string]string{
+		"api.example.com": "127.0.0.1",
+		"ported.test":     "10.0.0.1:8443",
+		"v6.test":         "[::1]",
+		"v6port.test":     "[::1]:9000",
+	}
+
+	tests := []struct {
+		address string
+		want    string
+	}{
+		{"api.example.com:443", "127.0.0.1:443"},
+		{"API.Example.com:80", "127.0.0.1:80"},
+		{"ported.test:443", "10.0.0.1:8443"},
+		{"v6.test:443", "[::1]:443"},
+		{"v6port.test:443", "[::1]:9000"},
+		{"other.test:443", "other.test:443"},
+		{"api.example.com", "api.example.com"},
+	}
+
+	for _, tt := range tests {
+		if got := overrideAddress(tt.address, overrides); got != tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.address, tt.want, got)
+		}
+	}
+}
+
+func TestNewClientOptionErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		opts config.RequestOptions
+		want string
+	}{
+		{"unknown resolver", config.RequestOptions{Resolver: "doh"}, `unknown resolver "doh"`},
+		{"custom resolver without a server", config.RequestOptions{Resolver: config.ResolverCustom}, "needs a DNS server"},
+		{"unsupported proxy scheme", config.RequestOptions{Proxy: "ftp://proxy:21"}, `unsupported proxy scheme "ftp"`},
+		{"proxy without a scheme", config.RequestOptions{Proxy: "proxy:3128"}, "unsupported proxy scheme"},
+		{"invalid proxy URL", config.RequestOptions{Proxy: "http://[::1"}, "invalid proxy URL"},
+	}
+
+	for _, tt := range tests {
+		_, err := NewClient(tt.opts, nil)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected an error about %q, got %v", tt.name, tt.want, err)
+		}
+	}
+
+	for _, proxy := range []string{"http://proxy:3128", "https://proxy", "socks5://proxy:1080", "socks5h://proxy:1080"} {
+		if _, err := NewClient(config.RequestOptions{Proxy: proxy}, nil); err != nil {
+			t.Errorf("%s: expected the proxy to be accepted, got %v", proxy, err)
+		}
+	}
+}
+
+func TestClientHostOverride(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.Host)
+	}))
+	defer server.Close()
+
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+	client, err := NewClient(config.RequestOptions{
+		HostOverrides: []config.HostOverride{{Host: "api.example.test", Address: "127.0.0.1"}},
+	}, nil)
+	if err != nil {
+		t.Fatalf("could not build client: %v", err)
+	}
+
+	resp, err := client.Get("http://api.example.test:" + port + "/")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if want := "api.example.test:" + port; string(body) != want {
+		t.Errorf("expected the request to keep Host %q, got %q", want, body)
+	}
+}
+
+func TestClientProxy(t *testing.T) {
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// A proxied request carries the absolute URL it is for
+		fmt.Fprint(w, "proxied "+r.URL.String())
+	}))
+	defer proxy.Close()
+
+	client, err := NewClient(config.RequestOptions{Proxy: proxy.URL}, nil)
+	if err != nil {
+		t.Fatalf("could not build client: %v", err)
+	}
+	resp, err := client.Get("http://unreachable.test/path")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if want := "proxied http://unreachable.test/path"; string(body) != want {
+		t.Errorf("expected %q, got %q", want, body)
+	}
+}
+
+// redirectServer redirects /n to /n-1 until /0, which answers "done"
+func redirectServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/"))
+		if n <= 0 {
+			fmt.Fprint(w, "done")
+			return
+		}
+		http.Redirect(w, r, "/"+strconv.Itoa(n-1), http.StatusFound)
+	}))
+}
+
+func TestClientRedirects(t *testing.T) {
+	server := redirectServer()
+	defer server.Close()
+
+	tests := []struct {
+		name      string
+		opts      config.RequestOptions
+		path      string
+		wantErr   string
+		status    int
+		redirects int
+	}{
+		{"within the limit", config.RequestOptions{MaxRedirects: 3}, "/3", "", http.StatusOK, 3},
+		{"over the limit", config.RequestOptions{MaxRedirects: 3}, "/4", "stopped after 3 redirects", 0, 4},
+		{"default limit", config.RequestOptions{}, "/10", "", http.StatusOK, 10},
+		{"over the default limit", config.RequestOptions{}, "/11", "stopped after 10 redirects", 0, 11},
+		{"disabled", config.RequestOptions{DisableRedirects: true}, "/2", "", http.StatusFound, 1},
+	}
+
+	for _, tt := range tests {
+		client, err := NewClient(tt.opts, nil)
+		if err != nil {
+			t.Fatalf("%s: could not build client: %v", tt.name, err)
+		}
+		resp, err := client.Get(server.URL + tt.path)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: expected an error about %q, got %v", tt.name, tt.wantErr, err)
+			}
+		} else if err != nil {
+			t.Errorf("%s: request failed: %v", tt.name, err)
+		} else {
+			resp.Body.Close()
+			if resp.StatusCode != tt.status {
+				t.Errorf("%s: expected status %d, got %d", tt.name, tt.status, resp.StatusCode)
+			}
+		}
+		if len(client.Redirects) != tt.redirects {
+			t.Errorf("%s: expected %d recorded redirects, got %d", tt.name, tt.redirects, len(client.Redirects))
+		}
+	}
+
+	client, _ := NewClient(config.RequestOptions{}, nil)
+	resp, err := client.Get(server.URL + "/1")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+	want := Redirect{URL: server.URL + "/1", StatusCode: http.StatusFound, Location: server.URL + "/0"}
+	if len(client.Redirects) != 1 || client.Redirects[0] != want {
+		t.Errorf("expected %+v, got %+v", want, client.Redirects)
+	}
+}
@@ -0,0 +1,125 @@
+// Package restclient holds the request logic of the REST client page
+package restclient
+
+import (
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"cloudevents-explorer/internal/config"
+)
+
+// maxVariableDepth bounds how deeply variables may refer to each other, which
+// also stops variables that refer to themselves
+const maxVariableDepth = 10
+
+var variablePattern = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_.\-]+)\s*\}\}`)
+
+// Variables substitutes {{name}} references. Values may themselves refer to
+// other variables. References to unknown variables are left as written and
+// recorded in Unresolved.
+type Variables struct {
+	values     map[string]string
+	secrets    []string
+	unresolved map[string]bool
+}
+
+// NewVariables resolves a list of variables in which later entries override
+// earlier ones with the same key
+func NewVariables(vars []config.Variable) *Variables {
+	v := &Variables{values: map[string]string{}, unresolved: map[string]bool{}}
+	secret := map[string]bool{}
+	for _, variable := range vars {
+		if variable.Key == "" {
+			continue
+		}
+		v.values[variable.Key] = variable.Value
+		secret[variable.Key] = variable.Secret
+	}
+
+	for key, value := range v.values {
+		v.values[key] = v.expand(value, 1)
+	}
+	for key, isSecret := range secret {
+		value := v.values[key]
+		if !isSecret || value == "" {
+			continue
+		}
+		// Secrets substituted into query parameters are sent escaped
+		v.secrets = append(v.secrets, value)
+		if escaped := url.QueryEscape(value); escaped != value {
+			v.secrets = append(v.secrets, escaped)
+		}
+	}
+	// Mask longer secrets first, so one that contains another is masked whole
+	sort.Slice(v.secrets, func(i, j int) bool { return len(v.secrets[i]) > len(v.secrets[j]) })
+
+	// Unknown references inside variables only matter once used
+	v.unresolved = map[string]bool{}
+	return v
+}
+
+func (v *Variables) expand(s string, depth int) string {
+	return variablePattern.ReplaceAllStringFunc(s, func(ref string) string {
+		name := variablePattern.FindStringSubmatch(ref)[1]
+		value, ok := v.values[name]
+		if !ok || depth > maxVariableDepth {
+			v.unresolved[name] = true
+			return ref
+		}
+		return v.expand(value, depth+1)
+	})
+}
+
+// Substitute replaces the variable references in s
+func (v *Variables) Substitute(s string) string {
+	if !strings.Contains(s, "{{") {
+		return s
+	}
+	return v.expand(s, 1)
+}
+
+// SubstituteMap replaces the variable references in the keys and values of
+// a map
+func (v *Variables) SubstituteMap(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	result := make(map[string]string, len(m))
+	for key, value := range m {
+		result[v.Substitute(key)] = v.Substitute(value)
+	}
+	return result
+}
+
+// Unresolved returns the names of the unknown variables substituted so far
+func (v *Variables) Unresolved() []string {
+	names := make([]string, 0, len(v.unresolved))
+	for name := range v.unresolved {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Mask hides the values of secret variables in s, for text shown on the page
+func (v *Variables) Mask(s string) string {
+	for _, secret := range v.secrets {
+		s = strings.ReplaceAll(s, secret, "••••••")
+	}
+	return s
+}
+
+// MaskVariables returns a copy of vars with the values of secrets removed,
+// for sending to the page
+func MaskVariables(vars []config.Variable) []config.Variable {
+	masked := make([]config.Variable, len(vars))
+	for i, variable := range vars {
+		masked[i] = variable
+		if variable.Secret {
+			masked[i].Value = ""
+		}
+	}
+	return masked
+}
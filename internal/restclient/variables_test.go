@@ -0,0 +1,128 @@
+package restclient
+
+import (
+	"reflect"
+	"testing"
+
+	"cloudevents-explorer/internal/config"
+)
+
+func TestVariablesSubstitute(t *testing.T) {
+	v := NewVariables([]config.Variable{
+		{Key: "host", Value: "example.com"},
+		{Key: "base", Value: "https://{{host}}/api"},
+		{Key: "users", Value: "{{ base }}/users"},
+		{Key: "env", Value: "dev"},
+		{Key: "env", Value: "prod"},
+		{Key: "self", Value: "{{self}}"},
+		{Key: "loop.a", Value: "{{loop.b}}"},
+		{Key: "loop.b", Value: "{{loop.a}}"},
+		{Key: "", Value: "ignored"},
+	})
+
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"plain text", "no references", "no references"},
+		{"simple", "{{host}}", "example.com"},
+		{"spaces inside braces", "{{  host }}", "example.com"},
+		{"nested", "{{base}}", "https://example.com/api"},
+		{"nested twice", "{{users}}/1", "https://example.com/api/users/1"},
+		{"later entry wins", "{{env}}", "prod"},
+		{"undefined is left as written", "{{missing}}/x", "{{missing}}/x"},
+		{"self reference stops", "{{self}}", "{{self}}"},
+		{"cycle stops", "{{loop.a}}", "{{loop.a}}"},
+		{"single braces", "{host}", "{host}"},
+		{"spaced braces", "{ {host} }", "{ {host} }"},
+		{"extra braces around a reference", "{{{host}}}", "{example.com}"},
+		{"invalid name", "{{a b}}", "{{a b}}"},
+	}
+
+	for _, tt := range tests {
+		if got := v.Substitute(tt.input); got != tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestVariablesUnresolved(t *testing.T) {
+	v := NewVariables([]config.Variable{
+		{Key: "unused", Value: "{{nowhere}}"},
+		{Key: "url", Value: "https://{{host}}"},
+	})
+	if got := v.Unresolved(); len(got) != 0 {
+		t.Errorf("expected no unresolved variables before substituting, got %v", got)
+	}
+
+	v.Substitute("{{url}}/{{missing}}")
+	v.SubstituteMap(map[string]string{"{{header}}": "{{host}}"})
+
+	want := []string{"header", "host", "missing"}
+	if got := v.Unresolved(); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestVariablesSubstituteMap(t *testing.T) {
+	v := NewVariables([]config.Variable{{Key: "name", Value: "X-Token"}, {Key: "token", Value: "abc"}})
+
+	got := v.SubstituteMap(map[string]string{"{{name}}": "Bearer {{token}}", "Accept": "*/*"})
+	want := map[string]string{"X-Token": "Bearer abc", "Accept": "*/*"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if v.SubstituteMap(nil) != nil {
+		t.Error("expected a nil map to stay nil")
+	}
+}
+
+func TestVariablesMask(t *testing.T) {
+	v := NewVariables([]config.Variable{
+		{Key: "token", Value: "s3cret", Secret: true},
+		{Key: "long", Value: "s3cret-and-more", Secret: true},
+		{Key: "query", Value: "a b&c", Secret: true},
+		{Key: "derived", Value: "Bearer {{token}}"},
+		{Key: "empty", Value: "", Secret: true},
+		{Key: "host", Value: "example.com"},
+	})
+
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"secret", "token=s3cret", "token=••••••"},
+		{"longer secret masked whole", "s3cret-and-more", "••••••"},
+		{"query escaped secret", "https://example.com/?q=a+b%26c", "https://example.com/?q=••••••"},
+		{"raw secret with spaces", "a b&c", "••••••"},
+		{"secret used by another variable", v.Substitute("{{derived}}"), "Bearer ••••••"},
+		{"plain variables are shown", "example.com", "example.com"},
+	}
+
+	for _, tt := range tests {
+		if got := v.Mask(tt.input); got != tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestMaskVariables(t *testing.T) {
+	vars := []config.Variable{
+		{Key: "token", Value: "s3cret", Secret: true},
+		{Key: "host", Value: "example.com"},
+	}
+
+	got := MaskVariables(vars)
+	want := []config.Variable{
+		{Key: "token", Value: "", Secret: true},
+		{Key: "host", Value: "example.com"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if vars[0].Value != "s3cret" {
+		t.Error("expected the original variables to be left alone")
+	}
+}
@@ -0,0 +1,265 @@
+package spanner
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	database "cloud.google.com/go/spanner/admin/database/apiv1"
+	"cloud.google.com/go/spanner/admin/database/apiv1/databasepb"
+	instance "cloud.google.com/go/spanner/admin/instance/apiv1"
+	"cloud.google.com/go/spanner/admin/instance/apiv1/instancepb"
+	"cloudevents-explorer/internal/types"
+	"google.golang.org/api/iterator"
+)
+
+// emulatorInstanceConfig is the only instance configuration the emulator offers
+const emulatorInstanceConfig = "emulator-config"
+
+// requireEmulator points the admin clients at the emulator. Creating and
+// dropping are refused without one, so a mistyped connection can never
+// drop a real database.
+func requireEmulator(req types.ConnectionRequest) error {
+	if req.EmulatorHost == "" {
+		return fmt.Errorf("provisioning is only available against the Spanner emulator")
+	}
+	os.Setenv("SPANNER_EMULATOR_HOST", req.EmulatorHost)
+	return nil
+}
+
+func instancePath(req types.ConnectionRequest) string {
+	return fmt.Sprintf("projects/%s/instances/%s", req.ProjectID, req.InstanceID)
+}
+
+// ListInstances returns the instances of the project
+func ListInstances(req types.ConnectionRequest) ([]types.InstanceInfo, error) {
+	if req.EmulatorHost != "" {
+		os.Setenv("SPANNER_EMULATOR_HOST", req.EmulatorHost)
+	}
+
+	ctx := context.Background()
+	admin, err := instance.NewInstanceAdminClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create instance admin client: %w", err)
+	}
+	defer admin.Close()
+
+	instances := []types.InstanceInfo{}
+	it := admin.ListInstances(ctx, &instancepb.ListInstancesRequest{Parent: "projects/" + req.ProjectID})
+	for {
+		inst, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		instances = append(instances, types.InstanceInfo{
+			InstanceID:  inst.Name[strings.LastIndex(inst.Name, "/")+1:],
+			DisplayName: inst.DisplayName,
+			Config:      inst.Config[strings.LastIndex(inst.Config, "/")+1:],
+			NodeCount:   inst.NodeCount,
+			State:       inst.State.String(),
+		})
+	}
+	return instances, nil
+}
+
+// CreateInstance creates an emulator instance and waits until it is ready
+func CreateInstance(req types.ConnectionRequest) types.ConnectionResponse {
+	if err := requireEmulator(req); err != nil {
+		return adminFailure("Failed to create instance", err)
+	}
+
+	ctx := context.Background()
+	admin, err := instance.NewInstanceAdminClient(ctx)
+	if err != nil {
+		return adminFailure("Failed to create instance", err)
+	}
+	defer admin.Close()
+
+	op, err := admin.CreateInstance(ctx, &instancepb.CreateInstanceRequest{
+		Parent:     "projects/" + req.ProjectID,
+		InstanceId: req.InstanceID,
+		Instance: &instancepb.Instance{
+			Config:      fmt.Sprintf("projects/%s/instanceConfigs/%s", req.ProjectID, emulatorInstanceConfig),
+			DisplayName: req.InstanceID,
+			NodeCount:   1,
+		},
+	})
+	if err == nil {
+		_, err = op.Wait(ctx)
+	}
+	if err != nil {
+		return adminFailure("Failed to create instance", err)
+	}
+
+	return types.ConnectionResponse{
+		Success: true,
+		Message: fmt.Sprintf("Created %s", instancePath(req)),
+	}
+}
+
+// DeleteInstance drops an emulator instance along with all of its databases
+func DeleteInstance(req types.ConnectionRequest) types.ConnectionResponse {
+	if err := requireEmulator(req); err != nil {
+		return adminFailure("Failed to delete instance", err)
+	}
+
+	ctx := context.Background()
+	admin, err := instance.NewInstanceAdminClient(ctx)
+	if err != nil {
+		return adminFailure("Failed to delete instance", err)
+	}
+	defer admin.Close()
+
+	path := instancePath(req)
+	if err := admin.DeleteInstance(ctx, &instancepb.DeleteInstanceRequest{Name: path}); err != nil {
+		return adminFailure("Failed to delete instance", err)
+	}
+
+	forgetDialect(path)
+
+	return types.ConnectionResponse{
+		Success: true,
+		Message: fmt.Sprintf("Deleted %s", path),
+	}
+}
+
+// ListDatabases returns the databases of an instance with their dialects
+func ListDatabases(req types.ConnectionRequest) ([]types.DatabaseInfo, error) {
+	if req.EmulatorHost != "" {
+		os.Setenv("SPANNER_EMULATOR_HOST", req.EmulatorHost)
+	}
+
+	ctx := context.Background()
+	admin, err := database.NewDatabaseAdminClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create database admin client: %w", err)
+	}
+	defer admin.Close()
+
+	databases := []types.DatabaseInfo{}
+	it := admin.ListDatabases(ctx, &databasepb.ListDatabasesRequest{Parent: instancePath(req)})
+	for {
+		db, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		dbDialect := db.DatabaseDialect.String()
+		if db.DatabaseDialect == databasepb.DatabaseDialect_DATABASE_DIALECT_UNSPECIFIED {
+			dbDialect = DialectGoogleSQL
+		}
+		databases = append(databases, types.DatabaseInfo{
+			DatabaseID: db.Name[strings.LastIndex(db.Name, "/")+1:],
+			State:      db.State.String(),
+			Dialect:    dbDialect,
+		})
+	}
+	return databases, nil
+}
+
+// CreateDatabase creates an emulator database in the requested dialect and
+// applies the optional DDL. GoogleSQL statements are applied as part of the
+// create, so a bad statement leaves no database behind; PostgreSQL databases
+// do not accept DDL on create, so theirs is applied straight afterwards.
+func CreateDatabase(req types.DatabaseRequest) types.ConnectionResponse {
+	if err := requireEmulator(req.ConnectionRequest); err != nil {
+		return adminFailure("Failed to create database", err)
+	}
+
+	d := dialect(DialectGoogleSQL)
+	if req.Dialect != "" {
+		d = dialect(strings.ToUpper(req.Dialect))
+	}
+	var dbDialect databasepb.DatabaseDialect
+	switch d {
+	case DialectGoogleSQL:
+		dbDialect = databasepb.DatabaseDialect_GOOGLE_STANDARD_SQL
+	case DialectPostgreSQL:
+		dbDialect = databasepb.DatabaseDialect_POSTGRESQL
+	default:
+		return adminFailure("Failed to create database", fmt.Errorf("unknown dialect %q", req.Dialect))
+	}
+
+	statements := d.splitStatements(req.DDL)
+
+	ctx := context.Background()
+	admin, err := database.NewDatabaseAdminClient(ctx)
+	if err != nil {
+		return adminFailure("Failed to create database", err)
+	}
+	defer admin.Close()
+
+	create := &databasepb.CreateDatabaseRequest{
+		Parent:          instancePath(req.ConnectionRequest),
+		CreateStatement: "CREATE DATABASE " + d.quoteIdentifier(req.DatabaseID),
+		DatabaseDialect: dbDialect,
+	}
+	if !d.postgres() {
+		create.ExtraStatements = statements
+	}
+
+	op, err := admin.CreateDatabase(ctx, create)
+	if err == nil {
+		_, err = op.Wait(ctx)
+	}
+	if err != nil {
+		return adminFailure("Failed to create database", err)
+	}
+
+	path := databasePath(req.ConnectionRequest)
+	forgetDialect(path)
+
+	if d.postgres() && len(statements) > 0 {
+		ddl, err := admin.UpdateDatabaseDdl(ctx, &databasepb.UpdateDatabaseDdlRequest{
+			Database:   path,
+			Statements: statements,
+		})
+		if err == nil {
+			err = ddl.Wait(ctx)
+		}
+		if err != nil {
+			return adminFailure("Database was created but its DDL failed", err)
+		}
+	}
+
+	return types.ConnectionResponse{
+		Success: true,
+		Message: fmt.Sprintf("Created %s with %d DDL statement(s)", path, len(statements)),
+		Dialect: string(d),
+	}
+}
+
+// DropDatabase drops an emulator database
+func DropDatabase(req types.ConnectionRequest) types.ConnectionResponse {
+	if err := requireEmulator(req); err != nil {
+		return adminFailure("Failed to drop database", err)
+	}
+
+	ctx := context.Background()
+	admin, err := database.NewDatabaseAdminClient(ctx)
+	if err != nil {
+		return adminFailure("Failed to drop database", err)
+	}
+	defer admin.Close()
+
+	path := databasePath(req)
+	if err := admin.DropDatabase(ctx, &databasepb.DropDatabaseRequest{Database: path}); err != nil {
+		return adminFailure("Failed to drop database", err)
+	}
+	forgetDialect(path)
+
+	return types.ConnectionResponse{
+		Success: true,
+		Message: fmt.Sprintf("Dropped %s", path),
+	}
+}
+
+func adminFailure(message string, err error) types.ConnectionResponse {
+	return types.ConnectionResponse{Success: false, Message: message, Error: err.Error()}
+}
@@ -0,0 +1,348 @@
+package spanner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/spanner"
+	"cloudevents-explorer/internal/types"
+)
+
+const (
+	defaultChangeStreamWindow    = time.Hour
+	defaultHeartbeatMilliseconds = 10000
+	defaultMaxPartitions         = 100
+)
+
+var streamNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// changeRecord is one record of a change stream query. GoogleSQL returns
+// each kind as an array, PostgreSQL as a single JSON object per row; both
+// are normalised to this shape.
+type changeRecord struct {
+	DataChangeRecord      []dataChangeRecord      `json:"data_change_record"`
+	HeartbeatRecord       []heartbeatRecord       `json:"heartbeat_record"`
+	ChildPartitionsRecord []childPartitionsRecord `json:"child_partitions_record"`
+}
+
+type pgChangeRecord struct {
+	DataChangeRecord      *dataChangeRecord      `json:"data_change_record"`
+	HeartbeatRecord       *heartbeatRecord       `json:"heartbeat_record"`
+	ChildPartitionsRecord *childPartitionsRecord `json:"child_partitions_record"`
+}
+
+type dataChangeRecord struct {
+	CommitTimestamp     string      `json:"commit_timestamp"`
+	RecordSequence      string      `json:"record_sequence"`
+	ServerTransactionID string      `json:"server_transaction_id"`
+	TableName           string      `json:"table_name"`
+	Mods                []modRecord `json:"mods"`
+	ModType             string      `json:"mod_type"`
+	ValueCaptureType    string      `json:"value_capture_type"`
+	TransactionTag      string      `json:"transaction_tag"`
+	IsSystemTransaction bool        `json:"is_system_transaction"`
+}
+
+type modRecord struct {
+	Keys      json.RawMessage `json:"keys"`
+	NewValues json.RawMessage `json:"new_values"`
+	OldValues json.RawMessage `json:"old_values"`
+}
+
+// changeEvent is a mod's event with its parsed commit time to order by
+type changeEvent struct {
+	committed time.Time
+	event     types.CloudEvent
+}
+
+type heartbeatRecord struct {
+	Timestamp string `json:"timestamp"`
+}
+
+type childPartitionsRecord struct {
+	StartTimestamp  string `json:"start_timestamp"`
+	RecordSequence  string `json:"record_sequence"`
+	ChildPartitions []struct {
+		Token                 string   `json:"token"`
+		ParentPartitionTokens []string `json:"parent_partition_tokens"`
+	} `json:"child_partitions"`
+}
+
+// ReadChangeStream reads the data change records of a change stream between
+// two timestamps. Without a partition token it starts from the initial
+// partition query, which only returns the first partitions; with
+// FollowChildren every child partition found is read in turn up to the end
+// timestamp, otherwise they are returned for the caller to pick from. Each
+// mod becomes one event, ordered by commit timestamp.
+func ReadChangeStream(req types.ChangeStreamRequest) types.ChangeStreamResponse {
+	startTime := time.Now()
+
+	if !streamNamePattern.MatchString(req.Stream) {
+		return types.ChangeStreamResponse{Error: fmt.Sprintf("invalid change stream name %q", req.Stream)}
+	}
+
+	end := time.Now().UTC()
+	if req.EndTimestamp != "" {
+		t, err := time.Parse(time.RFC3339Nano, req.EndTimestamp)
+		if err != nil {
+			return types.ChangeStreamResponse{Error: fmt.Sprintf("invalid end timestamp: %v", err)}
+		}
+		end = t
+	}
+	start := end.Add(-defaultChangeStreamWindow)
+	if req.StartTimestamp != "" {
+		t, err := time.Parse(time.RFC3339Nano, req.StartTimestamp)
+		if err != nil {
+			return types.ChangeStreamResponse{Error: fmt.Sprintf("invalid start timestamp: %v", err)}
+		}
+		start = t
+	}
+	if !start.Before(end) {
+		return types.ChangeStreamResponse{Error: "the start timestamp must be before the end timestamp"}
+	}
+
+	heartbeat := req.HeartbeatMilliseconds
+	if heartbeat <= 0 {
+		heartbeat = defaultHeartbeatMilliseconds
+	}
+	maxPartitions := req.MaxPartitions
+	if maxPartitions <= 0 {
+		maxPartitions = defaultMaxPartitions
+	}
+
+	ctx := context.Background()
+	client, d, err := openDatabase(ctx, req.ConnectionRequest)
+	if err != nil {
+		return types.ChangeStreamResponse{Error: err.Error()}
+	}
+	defer client.Close()
+
+	resp := types.ChangeStreamResponse{
+		Messages:   []types.CloudEvent{},
+		Partitions: []types.ChangeStreamPartition{},
+	}
+
+	// Partitions are read one after another; a child with several parents
+	// is queued only once
+	queue := []types.ChangeStreamPartition{{Token: req.PartitionToken, StartTimestamp: start.Format(time.RFC3339Nano)}}
+	known := map[string]bool{req.PartitionToken: true}
+	var events []changeEvent
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+
+		if len(resp.Partitions) >= maxPartitions {
+			resp.Partitions = append(resp.Partitions, p)
+			continue
+		}
+
+		from := start
+		if t, err := time.Parse(time.RFC3339Nano, p.StartTimestamp); err == nil && t.After(start) {
+			from = t
+		}
+
+		records, err := queryChangeStream(ctx, client, d, req.Stream, p.Token, from, end, heartbeat)
+		if err != nil {
+			resp.Error = fmt.Sprintf("partition %s: %v", partitionLabel(p.Token), err)
+			resp.Partitions = append(resp.Partitions, p)
+			break
+		}
+		p.Read = true
+
+		for _, rec := range records {
+			resp.Heartbeats += len(rec.HeartbeatRecord)
+			for _, dc := range rec.DataChangeRecord {
+				committed, _ := time.Parse(time.RFC3339Nano, dc.CommitTimestamp)
+				for i, mod := range dc.Mods {
+					events = append(events, changeEvent{committed, modEvent(req.Stream, p.Token, dc, committed, i, mod)})
+					p.Records++
+				}
+			}
+			for _, cp := range rec.ChildPartitionsRecord {
+				for _, child := range cp.ChildPartitions {
+					if known[child.Token] {
+						continue
+					}
+					known[child.Token] = true
+					next := types.ChangeStreamPartition{
+						Token:          child.Token,
+						ParentTokens:   child.ParentPartitionTokens,
+						StartTimestamp: cp.StartTimestamp,
+					}
+					if req.FollowChildren {
+						queue = append(queue, next)
+					} else {
+						resp.Partitions = append(resp.Partitions, next)
+					}
+				}
+			}
+		}
+
+		resp.Partitions = append(resp.Partitions, p)
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].committed.Before(events[j].committed)
+	})
+	for _, e := range events {
+		resp.Messages = append(resp.Messages, e.event)
+	}
+
+	resp.ExecutionTime = time.Since(startTime).String()
+	return resp
+}
+
+// queryChangeStream runs the change stream read function for one partition,
+// or the initial partition query when token is empty
+func queryChangeStream(ctx context.Context, client *spanner.Client, d dialect, stream, token string, start, end time.Time, heartbeat int64) ([]changeRecord, error) {
+	var sql string
+	if d.postgres() {
+		sql = fmt.Sprintf(`SELECT * FROM "spanner"."read_json_%s"(@start, @end, @token, @heartbeat, NULL)`, stream)
+	} else {
+		sql = fmt.Sprintf(`SELECT ChangeRecord FROM READ_%s(start_timestamp => @start, end_timestamp => @end, partition_token => @token, heartbeat_milliseconds => @heartbeat)`, stream)
+	}
+
+	params := map[string]interface{}{
+		"start":     start,
+		"end":       end,
+		"token":     spanner.NullString{StringVal: token, Valid: token != ""},
+		"heartbeat": heartbeat,
+	}
+
+	var records []changeRecord
+	iter := client.Single().Query(ctx, d.statement(sql, params))
+	err := iter.Do(func(row *spanner.Row) error {
+		v := decodeValue(row.ColumnType(0), row.ColumnValue(0).AsInterface())
+
+		if d.postgres() {
+			raw, ok := v.(json.RawMessage)
+			if !ok {
+				return nil
+			}
+			var pg pgChangeRecord
+			if err := json.Unmarshal(raw, &pg); err != nil {
+				return fmt.Errorf("failed to decode change record: %w", err)
+			}
+			var rec changeRecord
+			if pg.DataChangeRecord != nil {
+				rec.DataChangeRecord = append(rec.DataChangeRecord, *pg.DataChangeRecord)
+			}
+			if pg.HeartbeatRecord != nil {
+				rec.HeartbeatRecord = append(rec.HeartbeatRecord, *pg.HeartbeatRecord)
+			}
+			if pg.ChildPartitionsRecord != nil {
+				rec.ChildPartitionsRecord = append(rec.ChildPartitionsRecord, *pg.ChildPartitionsRecord)
+			}
+			records = append(records, rec)
+			return nil
+		}
+
+		// GoogleSQL returns ARRAY<STRUCT<...>>, decoded here into maps and
+		// round-tripped through JSON into the record types
+		items, _ := v.([]interface{})
+		for _, item := range items {
+			data, err := json.Marshal(jsonValue(item))
+			if err != nil {
+				return err
+			}
+			var rec changeRecord
+			if err := json.Unmarshal(data, &rec); err != nil {
+				return fmt.Errorf("failed to decode change record: %w", err)
+			}
+			records = append(records, rec)
+		}
+		return nil
+	})
+	return records, err
+}
+
+// modEvent presents one mod of a data change record as an event for the
+// message viewer. Keys or values that cannot be decoded are shown as
+// received and the event is marked with the error.
+func modEvent(stream, token string, dc dataChangeRecord, committed time.Time, index int, mod modRecord) types.CloudEvent {
+	var published int64
+	if !committed.IsZero() {
+		published = committed.Unix()
+	}
+
+	subject := dc.TableName
+	if len(mod.Keys) > 0 {
+		var compact bytes.Buffer
+		if json.Compact(&compact, mod.Keys) == nil {
+			subject += " " + compact.String()
+		}
+	}
+
+	data := map[string]interface{}{
+		"modType":             dc.ModType,
+		"table":               dc.TableName,
+		"commitTimestamp":     dc.CommitTimestamp,
+		"serverTransactionId": dc.ServerTransactionID,
+		"recordSequence":      dc.RecordSequence,
+		"transactionTag":      dc.TransactionTag,
+		"valueCaptureType":    dc.ValueCaptureType,
+		"isSystemTransaction": dc.IsSystemTransaction,
+		"partitionToken":      partitionLabel(token),
+	}
+	var decodeErrors []string
+	for _, field := range []struct {
+		name string
+		raw  json.RawMessage
+	}{{"keys", mod.Keys}, {"oldValues", mod.OldValues}, {"newValues", mod.NewValues}} {
+		values, err := modValues(field.raw)
+		if err != nil {
+			data[field.name] = string(field.raw)
+			decodeErrors = append(decodeErrors, fmt.Sprintf("%s: %v", field.name, err))
+			continue
+		}
+		data[field.name] = values
+	}
+	if len(decodeErrors) > 0 {
+		data["_error"] = "Failed to decode mod"
+		data["_errorDetails"] = strings.Join(decodeErrors, "; ")
+	}
+
+	return types.CloudEvent{
+		ID:        fmt.Sprintf("%s/%s/%d", dc.ServerTransactionID, dc.RecordSequence, index),
+		Type:      "spanner.changestream." + dc.ModType,
+		Subject:   subject,
+		Source:    stream,
+		Published: dc.CommitTimestamp,
+		Timestamp: published,
+		Data:      data,
+	}
+}
+
+// modValues decodes the keys or values of a mod, keeping numbers exact.
+// GoogleSQL can return them as a JSON string holding the object.
+func modValues(raw json.RawMessage) (map[string]interface{}, error) {
+	values := map[string]interface{}{}
+	if len(raw) == 0 {
+		return values, nil
+	}
+
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		raw = json.RawMessage(s)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	if err := dec.Decode(&values); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+func partitionLabel(token string) string {
+	if token == "" {
+		return "initial"
+	}
+	return token
+}
@@ -0,0 +1,76 @@
+package spanner
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestModValues(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want map[string]interface{}
+	}{
+		{"empty", ``, map[string]interface{}{}},
+		{"object", `{"id": 1, "name": "a"}`, map[string]interface{}{"id": json.Number("1"), "name": "a"}},
+		{"large numbers stay exact", `{"id": 9007199254740993}`, map[string]interface{}{"id": json.Number("9007199254740993")}},
+		{"object in a string", `"{\"id\": \"x\"}"`, map[string]interface{}{"id": "x"}},
+	}
+
+	for _, tt := range tests {
+		got, err := modValues(json.RawMessage(tt.raw))
+		if err != nil {
+			t.Errorf("%s: could not decode: %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+
+	for _, raw := range []string{`{"id":`, `[1, 2]`, `"not an object"`} {
+		if got, err := modValues(json.RawMessage(raw)); err == nil {
+			t.Errorf("%s: expected an error, got %v", raw, got)
+		}
+	}
+}
+
+func TestModEvent(t *testing.T) {
+	dc := dataChangeRecord{
+		CommitTimestamp:     "2024-01-02T03:04:05.123456789Z",
+		RecordSequence:      "00000001",
+		ServerTransactionID: "tx",
+		TableName:           "Users",
+		ModType:             "UPDATE",
+	}
+	committed, _ := time.Parse(time.RFC3339Nano, dc.CommitTimestamp)
+
+	event := modEvent("Stream", "", dc, committed, 2, modRecord{
+		Keys:      json.RawMessage(`{"id": 1}`),
+		NewValues: json.RawMessage(`{"name": "b"}`),
+	})
+	if event.ID != "tx/00000001/2" || event.Subject != `Users {"id":1}` || event.Timestamp != committed.Unix() {
+		t.Errorf("unexpected event %+v", event)
+	}
+	if _, ok := event.Data["_error"]; ok {
+		t.Errorf("expected no decode error, got %v", event.Data["_errorDetails"])
+	}
+	if want := map[string]interface{}{"name": "b"}; !reflect.DeepEqual(event.Data["newValues"], want) {
+		t.Errorf("expected new values %v, got %v", want, event.Data["newValues"])
+	}
+
+	event = modEvent("Stream", "", dc, committed, 0, modRecord{
+		Keys:      json.RawMessage(`{"id": 1}`),
+		NewValues: json.RawMessage(`{"name": `),
+	})
+	if event.Data["newValues"] != `{"name": ` {
+		t.Errorf("expected malformed values to be shown as received, got %v", event.Data["newValues"])
+	}
+	details, _ := event.Data["_errorDetails"].(string)
+	if event.Data["_error"] == nil || !strings.HasPrefix(details, "newValues: ") {
+		t.Errorf("expected the event to be marked with the decode error, got %v", event.Data)
+	}
+}
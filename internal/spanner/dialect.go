@@ -0,0 +1,457 @@
+package spanner
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"cloud.google.com/go/spanner"
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+	"cloudevents-explorer/internal/types"
+	"google.golang.org/api/iterator"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// Database dialects, as reported by information_schema.database_options
+const (
+	DialectGoogleSQL  = "GOOGLE_STANDARD_SQL"
+	DialectPostgreSQL = "POSTGRESQL"
+)
+
+// dialect is the SQL dialect of a database. The queries in this package are
+// written in GoogleSQL and adapted to PostgreSQL through its methods.
+type dialect string
+
+var (
+	dialectMu sync.Mutex
+	// dialects caches the dialect of each database path, since a database's
+	// dialect is fixed when it is created
+	dialects = map[string]dialect{}
+
+	paramPattern     = regexp.MustCompile(`@(\w+)`)
+	returningPattern = regexp.MustCompile(`(?i)\bRETURNING\b`)
+)
+
+func databasePath(req types.ConnectionRequest) string {
+	return fmt.Sprintf("projects/%s/instances/%s/databases/%s",
+		req.ProjectID, req.InstanceID, req.DatabaseID)
+}
+
+// openDatabase opens a client like openClient and also looks up the
+// database's dialect
+func openDatabase(ctx context.Context, req types.ConnectionRequest) (*spanner.Client, dialect, error) {
+	client, err := openClient(ctx, req)
+	if err != nil {
+		return nil, "", err
+	}
+
+	d, err := detectDialect(ctx, client, databasePath(req))
+	if err != nil {
+		client.Close()
+		return nil, "", err
+	}
+	return client, d, nil
+}
+
+// lookupDialect returns the dialect of a database, connecting only when it
+// has not been seen before
+func lookupDialect(req types.ConnectionRequest) (dialect, error) {
+	dialectMu.Lock()
+	d, ok := dialects[databasePath(req)]
+	dialectMu.Unlock()
+	if ok {
+		return d, nil
+	}
+
+	client, d, err := openDatabase(context.Background(), req)
+	if err != nil {
+		return "", err
+	}
+	client.Close()
+	return d, nil
+}
+
+// forgetDialect drops the cached dialect of a dropped database, or of every
+// database of a deleted instance, in case they are created again with the
+// other dialect
+func forgetDialect(path string) {
+	dialectMu.Lock()
+	for db := range dialects {
+		if db == path || strings.HasPrefix(db, path+"/") {
+			delete(dialects, db)
+		}
+	}
+	dialectMu.Unlock()
+}
+
+func detectDialect(ctx context.Context, client *spanner.Client, path string) (dialect, error) {
+	dialectMu.Lock()
+	d, ok := dialects[path]
+	dialectMu.Unlock()
+	if ok {
+		return d, nil
+	}
+
+	// This query is valid in both dialects
+	stmt := spanner.Statement{SQL: "SELECT option_value FROM information_schema.database_options WHERE option_name = 'database_dialect'"}
+	iter := client.Single().Query(ctx, stmt)
+	defer iter.Stop()
+
+	d = DialectGoogleSQL
+	row, err := iter.Next()
+	switch {
+	case err == iterator.Done:
+		// Databases that predate the option are GoogleSQL
+	case err != nil:
+		return "", fmt.Errorf("failed to detect database dialect: %w", err)
+	default:
+		var option string
+		if err := row.Columns(&option); err != nil {
+			return "", err
+		}
+		d = dialect(option)
+	}
+
+	dialectMu.Lock()
+	dialects[path] = d
+	dialectMu.Unlock()
+	return d, nil
+}
+
+func (d dialect) postgres() bool {
+	return d == DialectPostgreSQL
+}
+
+// schema is the information_schema table_schema of user tables
+func (d dialect) schema() string {
+	if d.postgres() {
+		return "public"
+	}
+	return ""
+}
+
+// statement builds a statement from SQL using GoogleSQL @name parameters.
+// For PostgreSQL they are renumbered as $1, $2, ... in order of first use,
+// with the matching p1, p2, ... parameter names the client expects. Only
+// names in params are parameters, and only outside string literals, quoted
+// identifiers and comments; anything else is left as written. Parameters
+// the SQL does not use are left out in either dialect.
+func (d dialect) statement(sql string, params map[string]interface{}) spanner.Statement {
+	numbers := map[string]int{}
+	used := make(map[string]interface{}, len(params))
+	var b strings.Builder
+	for _, span := range d.lex(sql) {
+		if !span.code {
+			b.WriteString(span.text)
+			continue
+		}
+		b.WriteString(paramPattern.ReplaceAllStringFunc(span.text, func(match string) string {
+			name := match[1:]
+			value, ok := params[name]
+			if !ok {
+				return match
+			}
+			if !d.postgres() {
+				used[name] = value
+				return match
+			}
+			n, ok := numbers[name]
+			if !ok {
+				n = len(numbers) + 1
+				numbers[name] = n
+				used[fmt.Sprintf("p%d", n)] = value
+			}
+			return fmt.Sprintf("$%d", n)
+		}))
+	}
+	return spanner.Statement{SQL: b.String(), Params: used}
+}
+
+// sqlSpan is a run of SQL: code, or else a string literal, quoted
+// identifier or comment
+type sqlSpan struct {
+	text string
+	code bool
+}
+
+// lex splits SQL into code and the literals, quoted identifiers and comments
+// between it, following the lexical rules of the dialect. Unterminated
+// literals and comments run to the end.
+func (d dialect) lex(sql string) []sqlSpan {
+	var spans []sqlSpan
+	start := 0
+	for i := 0; i < len(sql); {
+		end := d.literalEnd(sql, i)
+		if end == i {
+			i++
+			continue
+		}
+		if start < i {
+			spans = append(spans, sqlSpan{text: sql[start:i], code: true})
+		}
+		spans = append(spans, sqlSpan{text: sql[i:end]})
+		start, i = end, end
+	}
+	if start < len(sql) {
+		spans = append(spans, sqlSpan{text: sql[start:], code: true})
+	}
+	return spans
+}
+
+// literalEnd returns the end of the literal, quoted identifier or comment
+// starting at sql[i], or i when none starts there. Line comments end before
+// their newline.
+func (d dialect) literalEnd(sql string, i int) int {
+	rest := sql[i:]
+	switch {
+	case strings.HasPrefix(rest, "--"), rest[0] == '#' && !d.postgres():
+		if end := strings.IndexByte(rest, '\n'); end != -1 {
+			return i + end
+		}
+		return len(sql)
+
+	case strings.HasPrefix(rest, "/*"):
+		// PostgreSQL block comments nest
+		depth := 0
+		for j := i; j < len(sql)-1; j++ {
+			switch {
+			case sql[j] == '/' && sql[j+1] == '*' && (depth == 0 || d.postgres()):
+				depth++
+				j++
+			case sql[j] == '*' && sql[j+1] == '/':
+				depth--
+				j++
+				if depth == 0 {
+					return j + 1
+				}
+			}
+		}
+		return len(sql)
+
+	case d.postgres() && (rest[0] == '\'' || rest[0] == '"'):
+		// Quotes are escaped by doubling them; backslashes only escape in
+		// E'...' strings
+		escapes := rest[0] == '\'' && strings.EqualFold(literalPrefix(sql, i), "e")
+		for j := i + 1; j < len(sql); j++ {
+			switch {
+			case escapes && sql[j] == '\\':
+				j++
+			case sql[j] == rest[0]:
+				if j+1 < len(sql) && sql[j+1] == rest[0] {
+					j++
+					continue
+				}
+				return j + 1
+			}
+		}
+		return len(sql)
+
+	case d.postgres() && rest[0] == '$':
+		tag := dollarTag(sql, i)
+		if tag == "" {
+			return i
+		}
+		end := strings.Index(sql[i+len(tag):], tag)
+		if end == -1 {
+			return len(sql)
+		}
+		return i + len(tag) + end + len(tag)
+
+	case !d.postgres() && (rest[0] == '\'' || rest[0] == '"' || rest[0] == '`'):
+		// Triple-quoted strings may contain unescaped quotes; backslashes
+		// escape except in raw strings
+		quote := rest[:1]
+		if quote != "`" && strings.HasPrefix(rest, strings.Repeat(quote, 3)) {
+			quote = strings.Repeat(quote, 3)
+		}
+		raw := quote != "`" && strings.ContainsAny(literalPrefix(sql, i), "rR")
+		for j := i + len(quote); j < len(sql); j++ {
+			if sql[j] == '\\' && !raw {
+				j++
+				continue
+			}
+			if strings.HasPrefix(sql[j:], quote) {
+				return j + len(quote)
+			}
+		}
+		return len(sql)
+	}
+	return i
+}
+
+// literalPrefix returns the letters directly before a quote, such as the r
+// of a GoogleSQL raw string or the E of a PostgreSQL escape string, when they
+// are not the end of a longer word
+func literalPrefix(sql string, i int) string {
+	start := i
+	for start > 0 && i-start < 2 && strings.IndexByte("rRbBeE", sql[start-1]) != -1 {
+		start--
+	}
+	if start > 0 && isIdentifierByte(sql[start-1]) {
+		return ""
+	}
+	return sql[start:i]
+}
+
+// dollarTag returns the $tag$ or $$ opening a PostgreSQL dollar-quoted string
+// at sql[i], or "" for anything else, such as a $1 parameter
+func dollarTag(sql string, i int) string {
+	if i > 0 && (isIdentifierByte(sql[i-1]) || sql[i-1] == '$') {
+		return ""
+	}
+	j := i + 1
+	for j < len(sql) && isIdentifierByte(sql[j]) {
+		if j == i+1 && sql[j] >= '0' && sql[j] <= '9' {
+			return ""
+		}
+		j++
+	}
+	if j < len(sql) && sql[j] == '$' {
+		return sql[i : j+1]
+	}
+	return ""
+}
+
+func isIdentifierByte(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+func (d dialect) quoteIdentifier(name string) string {
+	if d.postgres() {
+		return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+	}
+	return "`" + strings.ReplaceAll(name, "`", "\\`") + "`"
+}
+
+func (d dialect) quoteString(s string) string {
+	if d.postgres() {
+		return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+	}
+	r := strings.NewReplacer(`\`, `\\`, `'`, `\'`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+	return "'" + r.Replace(s) + "'"
+}
+
+// returnsRows reports whether a DML statement returns the rows it changed:
+// THEN RETURN in GoogleSQL, RETURNING in PostgreSQL
+func (d dialect) returnsRows(stmt string) bool {
+	if d.postgres() {
+		return returningPattern.MatchString(stmt)
+	}
+	return thenReturnPattern.MatchString(stmt)
+}
+
+// columnType translates a column type as information_schema reports it into
+// the GoogleSQL spelling used throughout this package, e.g. "character
+// varying(64)" becomes STRING(64) and "bigint[]" becomes ARRAY<INT64>
+func (d dialect) columnType(spannerType string) string {
+	t := strings.TrimSpace(spannerType)
+	if !d.postgres() {
+		return strings.ToUpper(t)
+	}
+
+	lower := strings.ToLower(t)
+	if strings.HasSuffix(lower, "[]") {
+		return "ARRAY<" + d.columnType(t[:len(t)-2]) + ">"
+	}
+
+	length := ""
+	if i := strings.IndexByte(lower, '('); i != -1 {
+		length = lower[i:]
+		lower = strings.TrimSpace(lower[:i])
+	}
+
+	switch lower {
+	case "bigint", "int8":
+		return "INT64"
+	case "double precision", "float8":
+		return "FLOAT64"
+	case "real", "float4":
+		return "FLOAT32"
+	case "boolean", "bool":
+		return "BOOL"
+	case "character varying", "varchar", "text":
+		if length == "" {
+			return "STRING(MAX)"
+		}
+		return "STRING" + length
+	case "bytea":
+		return "BYTES(MAX)"
+	case "timestamp with time zone", "timestamptz", "spanner.commit_timestamp":
+		return "TIMESTAMP"
+	case "date":
+		return "DATE"
+	case "numeric":
+		return "NUMERIC"
+	case "jsonb":
+		return "JSON"
+	}
+	return strings.ToUpper(t)
+}
+
+// pgTypeName renders a result type the way PostgreSQL casts spell it
+func pgTypeName(t *sppb.Type) string {
+	if t == nil {
+		return "varchar"
+	}
+	switch t.Code {
+	case sppb.TypeCode_ARRAY:
+		return pgTypeName(t.ArrayElementType) + "[]"
+	case sppb.TypeCode_INT64:
+		return "bigint"
+	case sppb.TypeCode_FLOAT64:
+		return "float8"
+	case sppb.TypeCode_FLOAT32:
+		return "float4"
+	case sppb.TypeCode_BOOL:
+		return "boolean"
+	case sppb.TypeCode_BYTES:
+		return "bytea"
+	case sppb.TypeCode_TIMESTAMP:
+		return "timestamptz"
+	case sppb.TypeCode_DATE:
+		return "date"
+	case sppb.TypeCode_NUMERIC:
+		return "numeric"
+	case sppb.TypeCode_JSON:
+		return "jsonb"
+	}
+	return "varchar"
+}
+
+// columnText reads a nullable text column of an information_schema view.
+// Some views report text as BYTES in one dialect and STRING in the other.
+func columnText(row *spanner.Row, i int) (string, error) {
+	var v spanner.GenericColumnValue
+	if err := row.Column(i, &v); err != nil {
+		return "", err
+	}
+	s, ok := v.Value.GetKind().(*structpb.Value_StringValue)
+	if !ok {
+		return "", nil
+	}
+	if v.Type.GetCode() == sppb.TypeCode_BYTES {
+		b, err := base64.StdEncoding.DecodeString(s.StringValue)
+		return string(b), err
+	}
+	return s.StringValue, nil
+}
+
+// columnFlag reads a nullable yes/no column of an information_schema view,
+// which is a BOOL in GoogleSQL and often a YES/NO string in PostgreSQL
+func columnFlag(row *spanner.Row, i int) (bool, error) {
+	var v spanner.GenericColumnValue
+	if err := row.Column(i, &v); err != nil {
+		return false, err
+	}
+	switch k := v.Value.GetKind().(type) {
+	case *structpb.Value_BoolValue:
+		return k.BoolValue, nil
+	case *structpb.Value_StringValue:
+		s := strings.ToUpper(k.StringValue)
+		return s == "YES" || s == "TRUE", nil
+	}
+	return false, nil
+}
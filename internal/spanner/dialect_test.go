@@ -0,0 +1,213 @@
+package spanner
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestStatementParameters(t *testing.T) {
+	params := map[string]interface{}{"table": "Users", "limit": int64(10)}
+	tests := []struct {
+		name    string
+		dialect dialect
+		sql     string
+		want    string
+		params  map[string]interface{}
+	}{
+		{
+			name:    "GoogleSQL keeps names",
+			dialect: DialectGoogleSQL,
+			sql:     "SELECT * FROM t WHERE name = @table LIMIT @limit",
+			want:    "SELECT * FROM t WHERE name = @table LIMIT @limit",
+			params:  map[string]interface{}{"table": "Users", "limit": int64(10)},
+		},
+		{
+			name:    "PostgreSQL numbers in order of first use",
+			dialect: DialectPostgreSQL,
+			sql:     "SELECT * FROM t WHERE a = @limit OR b = @table OR c = @limit",
+			want:    "SELECT * FROM t WHERE a = $1 OR b = $2 OR c = $1",
+			params:  map[string]interface{}{"p1": int64(10), "p2": "Users"},
+		},
+		{
+			name:    "unused parameters are left out",
+			dialect: DialectPostgreSQL,
+			sql:     "SELECT 1 LIMIT @limit",
+			want:    "SELECT 1 LIMIT $1",
+			params:  map[string]interface{}{"p1": int64(10)},
+		},
+		{
+			name:    "unknown names are not parameters",
+			dialect: DialectPostgreSQL,
+			sql:     "SELECT @other, @table",
+			want:    "SELECT @other, $1",
+			params:  map[string]interface{}{"p1": "Users"},
+		},
+		{
+			name:    "PostgreSQL string literal",
+			dialect: DialectPostgreSQL,
+			sql:     "SELECT '@table', 'it''s @limit' WHERE x = @table",
+			want:    "SELECT '@table', 'it''s @limit' WHERE x = $1",
+			params:  map[string]interface{}{"p1": "Users"},
+		},
+		{
+			name:    "GoogleSQL string literal",
+			dialect: DialectGoogleSQL,
+			sql:     `SELECT '@table', "it\"s @limit" WHERE x = @table`,
+			want:    `SELECT '@table', "it\"s @limit" WHERE x = @table`,
+			params:  map[string]interface{}{"table": "Users"},
+		},
+		{
+			name:    "comments",
+			dialect: DialectPostgreSQL,
+			sql:     "SELECT @table -- not @limit\n/* nor @limit */ FROM t",
+			want:    "SELECT $1 -- not @limit\n/* nor @limit */ FROM t",
+			params:  map[string]interface{}{"p1": "Users"},
+		},
+		{
+			name:    "quoted identifiers",
+			dialect: DialectGoogleSQL,
+			sql:     "SELECT `@limit` FROM t WHERE a = @limit",
+			want:    "SELECT `@limit` FROM t WHERE a = @limit",
+			params:  map[string]interface{}{"limit": int64(10)},
+		},
+		{
+			name:    "dollar-quoted string",
+			dialect: DialectPostgreSQL,
+			sql:     "SELECT $$@table$$, $q$ @limit $q$, @limit",
+			want:    "SELECT $$@table$$, $q$ @limit $q$, $1",
+			params:  map[string]interface{}{"p1": int64(10)},
+		},
+	}
+
+	for _, tt := range tests {
+		stmt := tt.dialect.statement(tt.sql, params)
+		if stmt.SQL != tt.want {
+			t.Errorf("%s: expected SQL %q, got %q", tt.name, tt.want, stmt.SQL)
+		}
+		if !reflect.DeepEqual(stmt.Params, tt.params) {
+			t.Errorf("%s: expected params %v, got %v", tt.name, tt.params, stmt.Params)
+		}
+	}
+}
+
+func TestLexCoversTheInput(t *testing.T) {
+	inputs := []string{
+		"SELECT 'a;b' -- c\n; /* d */ x",
+		"SELECT r'\\' || '''x'''",
+		"SELECT $tag$ a $$ b $tag$, E'\\'', 'unterminated",
+		"/* outer /* inner */ still */ SELECT 1",
+	}
+	for _, d := range []dialect{DialectGoogleSQL, DialectPostgreSQL} {
+		for _, sql := range inputs {
+			var b strings.Builder
+			for _, span := range d.lex(sql) {
+				b.WriteString(span.text)
+			}
+			if b.String() != sql {
+				t.Errorf("%s: spans of %q join to %q", d, sql, b.String())
+			}
+		}
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	tests := []struct {
+		name    string
+		dialect dialect
+		script  string
+		want    []string
+	}{
+		{
+			name:    "plain statements",
+			dialect: DialectGoogleSQL,
+			script:  "SELECT 1; SELECT 2;\n\nSELECT 3",
+			want:    []string{"SELECT 1", "SELECT 2", "SELECT 3"},
+		},
+		{
+			name:    "semicolons in strings and identifiers",
+			dialect: DialectGoogleSQL,
+			script:  "INSERT INTO t VALUES ('a;b', \"c;d\", `e;f`); SELECT 1",
+			want:    []string{"INSERT INTO t VALUES ('a;b', \"c;d\", `e;f`)", "SELECT 1"},
+		},
+		{
+			name:    "GoogleSQL escapes and triple quotes",
+			dialect: DialectGoogleSQL,
+			script:  `SELECT 'it\'s;'; SELECT '''a ' ; b'''; SELECT 1`,
+			want:    []string{`SELECT 'it\'s;'`, `SELECT '''a ' ; b'''`, "SELECT 1"},
+		},
+		{
+			name:    "GoogleSQL raw string ends at the first quote",
+			dialect: DialectGoogleSQL,
+			script:  `SELECT r'\'; SELECT 2`,
+			want:    []string{`SELECT r'\'`, "SELECT 2"},
+		},
+		{
+			name:    "GoogleSQL hash comments",
+			dialect: DialectGoogleSQL,
+			script:  "# setup; really\nSELECT 1; # trailing; comment",
+			want:    []string{"# setup; really\nSELECT 1"},
+		},
+		{
+			name:    "comment-only fragments are dropped",
+			dialect: DialectGoogleSQL,
+			script:  "-- only a comment;\n;; /* and; another */",
+			want:    nil,
+		},
+		{
+			name:    "PostgreSQL doubled quotes",
+			dialect: DialectPostgreSQL,
+			script:  "SELECT 'it''s; fine'; SELECT \"a\"\"; b\" FROM t",
+			want:    []string{"SELECT 'it''s; fine'", "SELECT \"a\"\"; b\" FROM t"},
+		},
+		{
+			name:    "PostgreSQL backslashes do not escape",
+			dialect: DialectPostgreSQL,
+			script:  `SELECT 'C:\'; SELECT 2`,
+			want:    []string{`SELECT 'C:\'`, "SELECT 2"},
+		},
+		{
+			name:    "PostgreSQL escape strings",
+			dialect: DialectPostgreSQL,
+			script:  `SELECT E'it\'s; fine'; SELECT 2`,
+			want:    []string{`SELECT E'it\'s; fine'`, "SELECT 2"},
+		},
+		{
+			name:    "PostgreSQL dollar quotes",
+			dialect: DialectPostgreSQL,
+			script:  "SELECT $$a; b$$; SELECT $fn$ x; $$ y; $fn$; SELECT $1",
+			want:    []string{"SELECT $$a; b$$", "SELECT $fn$ x; $$ y; $fn$", "SELECT $1"},
+		},
+		{
+			name:    "PostgreSQL hash is not a comment",
+			dialect: DialectPostgreSQL,
+			script:  "SELECT 5 # 3; SELECT 2",
+			want:    []string{"SELECT 5 # 3", "SELECT 2"},
+		},
+		{
+			name:    "PostgreSQL nested block comments",
+			dialect: DialectPostgreSQL,
+			script:  "/* a /* b; */ c; */ SELECT 1; SELECT 2",
+			want:    []string{"/* a /* b; */ c; */ SELECT 1", "SELECT 2"},
+		},
+		{
+			name:    "GoogleSQL block comments do not nest",
+			dialect: DialectGoogleSQL,
+			script:  "/* a /* b */ SELECT 1; SELECT 2",
+			want:    []string{"/* a /* b */ SELECT 1", "SELECT 2"},
+		},
+		{
+			name:    "unterminated string runs to the end",
+			dialect: DialectGoogleSQL,
+			script:  "SELECT 1; SELECT 'a; b",
+			want:    []string{"SELECT 1", "SELECT 'a; b"},
+		},
+	}
+
+	for _, tt := range tests {
+		got := tt.dialect.splitStatements(tt.script)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.want, got)
+		}
+	}
+}
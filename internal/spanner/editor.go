@@ -0,0 +1,248 @@
+package spanner
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/spanner"
+	"cloudevents-explorer/internal/types"
+)
+
+const defaultEditorPageSize = 100
+
+// Row change operations
+const (
+	RowInsert = "insert"
+	RowUpdate = "update"
+	RowDelete = "delete"
+)
+
+// editableTable loads the schema of one table, such as the one the row editor
+// works on, without counting its rows
+func editableTable(ctx context.Context, client *spanner.Client, d dialect, table string) (*types.TableInfo, error) {
+	schema, err := loadSchema(ctx, client, d, table)
+	if err != nil {
+		return nil, err
+	}
+	if len(schema.Tables) == 0 {
+		return nil, fmt.Errorf("table %s not found", table)
+	}
+	return &schema.Tables[0], nil
+}
+
+// ReadTableRows loads a page of rows in primary key order, and counts the
+// table's rows when asked. Rows are only editable when the table has a
+// primary key to address them by.
+func ReadTableRows(req types.TableRowsRequest) types.TableRowsResponse {
+	ctx := context.Background()
+	client, d, err := openDatabase(ctx, req.ConnectionRequest)
+	if err != nil {
+		return types.TableRowsResponse{Error: err.Error()}
+	}
+	defer client.Close()
+
+	table, err := editableTable(ctx, client, d, req.Table)
+	if err != nil {
+		return types.TableRowsResponse{Error: err.Error()}
+	}
+	if req.Count {
+		if err := countRows(ctx, client, d, table); err != nil {
+			return types.TableRowsResponse{Error: err.Error()}
+		}
+	}
+
+	limit := req.Limit
+	if limit <= 0 {
+		limit = defaultEditorPageSize
+	}
+
+	names := make([]string, len(table.Columns))
+	for i, col := range table.Columns {
+		names[i] = d.quoteIdentifier(col.Name)
+	}
+	sql := fmt.Sprintf("SELECT %s FROM %s", strings.Join(names, ", "), d.quoteIdentifier(table.Name))
+	if len(table.PrimaryKey) > 0 {
+		keys := make([]string, len(table.PrimaryKey))
+		for i, k := range table.PrimaryKey {
+			keys[i] = d.quoteIdentifier(k)
+		}
+		sql += " ORDER BY " + strings.Join(keys, ", ")
+	}
+	sql += " LIMIT @limit OFFSET @offset"
+
+	stmt := d.statement(sql, map[string]interface{}{"limit": int64(limit), "offset": int64(req.Offset)})
+
+	rows := []map[string]interface{}{}
+	iter := client.Single().Query(ctx, stmt)
+	err = iter.Do(func(row *spanner.Row) error {
+		values := make(map[string]interface{}, len(table.Columns))
+		for i, col := range table.Columns {
+			v := decodeValue(row.ColumnType(i), row.ColumnValue(i).AsInterface())
+			if v == nil {
+				values[col.Name] = nil
+			} else {
+				values[col.Name] = textValue(v)
+			}
+		}
+		rows = append(rows, values)
+		return nil
+	})
+	if err != nil {
+		return types.TableRowsResponse{Error: err.Error()}
+	}
+
+	return types.TableRowsResponse{
+		Columns:    table.Columns,
+		PrimaryKey: table.PrimaryKey,
+		Rows:       rows,
+		RowCount:   table.RowCount,
+		Editable:   len(table.PrimaryKey) > 0 && table.Type != "VIEW",
+	}
+}
+
+// ApplyRowChanges turns row edits into mutations and applies them together
+// in one transaction, so either every change is written or none is. A dry
+// run only builds and describes the mutations.
+func ApplyRowChanges(req types.RowChangesRequest) types.RowChangesResponse {
+	startTime := time.Now()
+
+	ctx := context.Background()
+	client, d, err := openDatabase(ctx, req.ConnectionRequest)
+	if err != nil {
+		return types.RowChangesResponse{Error: err.Error()}
+	}
+	defer client.Close()
+
+	table, err := editableTable(ctx, client, d, req.Table)
+	if err != nil {
+		return types.RowChangesResponse{Error: err.Error()}
+	}
+	if len(table.PrimaryKey) == 0 || table.Type == "VIEW" {
+		return types.RowChangesResponse{Error: fmt.Sprintf("%s has no primary key, so its rows cannot be edited", table.Name)}
+	}
+
+	mutations, infos, err := buildMutations(d, table, req.Changes)
+	resp := types.RowChangesResponse{Mutations: infos}
+	if err != nil {
+		resp.Error = err.Error()
+		return resp
+	}
+
+	if !req.DryRun && len(mutations) > 0 {
+		ts, err := client.Apply(ctx, mutations)
+		if err != nil {
+			resp.Error = err.Error()
+		} else {
+			resp.Applied = true
+			resp.CommitTimestamp = ts.UTC().Format(time.RFC3339Nano)
+		}
+	}
+
+	resp.ExecutionTime = time.Since(startTime).String()
+	return resp
+}
+
+// buildMutations converts row changes into mutations, in the order given.
+// Updates write only the changed columns, so concurrent edits to other
+// columns of the same row are kept. Primary key and generated columns of
+// existing rows cannot be changed.
+func buildMutations(d dialect, table *types.TableInfo, changes []types.RowChange) ([]*spanner.Mutation, []types.MutationInfo, error) {
+	columns := make(map[string]types.ColumnInfo, len(table.Columns))
+	for _, col := range table.Columns {
+		columns[strings.ToLower(col.Name)] = col
+	}
+
+	// coerce resolves a column name case-insensitively and converts the
+	// editor's value, typed as text, to the column's type
+	coerce := func(name string, raw interface{}) (string, interface{}, error) {
+		col, ok := columns[strings.ToLower(name)]
+		if !ok {
+			return "", nil, fmt.Errorf("%s has no column %s", table.Name, name)
+		}
+		if col.GenerationExpression != "" {
+			return "", nil, fmt.Errorf("column %s is generated and cannot be written", col.Name)
+		}
+		v, err := coerceValue(d, col.Type, raw, true)
+		if err != nil {
+			return "", nil, fmt.Errorf("column %s: %w", col.Name, err)
+		}
+		return col.Name, v, nil
+	}
+
+	var mutations []*spanner.Mutation
+	var infos []types.MutationInfo
+	for i, change := range changes {
+		info := types.MutationInfo{
+			Op:     change.Op,
+			Table:  table.Name,
+			Key:    map[string]interface{}{},
+			Values: map[string]interface{}{},
+		}
+
+		keyRaw := make(map[string]interface{}, len(change.Key))
+		for name, v := range change.Key {
+			keyRaw[strings.ToLower(name)] = v
+		}
+
+		var key spanner.Key
+		if change.Op != RowInsert {
+			for _, name := range table.PrimaryKey {
+				raw, ok := keyRaw[strings.ToLower(name)]
+				if !ok {
+					return nil, infos, fmt.Errorf("change %d: key column %s is missing", i+1, name)
+				}
+				_, v, err := coerce(name, raw)
+				if err != nil {
+					return nil, infos, fmt.Errorf("change %d: %w", i+1, err)
+				}
+				key = append(key, v)
+				info.Key[name] = raw
+			}
+		}
+
+		values := map[string]interface{}{}
+		for name, raw := range change.Values {
+			colName, v, err := coerce(name, raw)
+			if err != nil {
+				return nil, infos, fmt.Errorf("change %d: %w", i+1, err)
+			}
+			if change.Op == RowUpdate && columns[strings.ToLower(name)].IsPrimaryKey {
+				return nil, infos, fmt.Errorf("change %d: primary key column %s cannot be updated; delete the row and insert it again", i+1, colName)
+			}
+			values[colName] = v
+			info.Values[colName] = raw
+			if change.Op == RowInsert && columns[strings.ToLower(name)].IsPrimaryKey {
+				info.Key[colName] = raw
+			}
+		}
+
+		switch change.Op {
+		case RowInsert:
+			mutations = append(mutations, spanner.InsertMap(table.Name, values))
+		case RowUpdate:
+			if len(values) == 0 {
+				return nil, infos, fmt.Errorf("change %d: no columns were changed", i+1)
+			}
+			for j, name := range table.PrimaryKey {
+				values[name] = key[j]
+			}
+			mutations = append(mutations, spanner.UpdateMap(table.Name, values))
+		case RowDelete:
+			info.Values = nil
+			mutations = append(mutations, spanner.Delete(table.Name, key))
+		default:
+			return nil, infos, fmt.Errorf("change %d: unknown operation %q", i+1, change.Op)
+		}
+
+		for name := range info.Values {
+			info.Columns = append(info.Columns, name)
+		}
+		sort.Strings(info.Columns)
+		infos = append(infos, info)
+	}
+
+	return mutations, infos, nil
+}
@@ -0,0 +1,668 @@
+package spanner
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/spanner"
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+	"cloudevents-explorer/internal/types"
+	"google.golang.org/api/iterator"
+)
+
+// Supported export formats
+const (
+	ExportCSV    = "csv"
+	ExportJSON   = "json"
+	ExportNDJSON = "ndjson"
+	ExportSQL    = "sql"
+)
+
+// ExportContentType returns the MIME type and file extension for an export format
+func ExportContentType(format string) (string, string, error) {
+	switch format {
+	case ExportCSV:
+		return "text/csv; charset=utf-8", "csv", nil
+	case ExportJSON:
+		return "application/json", "json", nil
+	case ExportNDJSON:
+		return "application/x-ndjson", "ndjson", nil
+	case ExportSQL:
+		return "application/sql", "sql", nil
+	}
+	return "", "", fmt.Errorf("unsupported export format %q", format)
+}
+
+// column describes a result column and its Spanner type
+type column struct {
+	Name string
+	Type *sppb.Type
+}
+
+// rowWriter encodes result rows in one export format
+type rowWriter interface {
+	begin(columns []column) error
+	write(values []interface{}) error
+	end() error
+}
+
+// sqlToken is a word, quoted identifier, literal or punctuation character of
+// a query, as queryTable reads it
+type sqlToken struct {
+	text   string
+	word   bool // a keyword or bare identifier
+	quoted bool // a quoted identifier, text holding the name
+}
+
+// tokens splits SQL into tokens, dropping comments. String literals become
+// a single token of their own, so nothing inside them is read as SQL.
+func (d dialect) tokens(sql string) []sqlToken {
+	var out []sqlToken
+	for _, span := range d.lex(sql) {
+		if !span.code {
+			text := span.text
+			switch {
+			case strings.HasPrefix(text, "--"), strings.HasPrefix(text, "/*"), strings.HasPrefix(text, "#"):
+			case len(text) >= 2 && (text[0] == '`' && !d.postgres() || text[0] == '"' && d.postgres()):
+				quote := text[:1]
+				name := strings.ReplaceAll(text[1:len(text)-1], quote+quote, quote)
+				out = append(out, sqlToken{text: name, quoted: true})
+			default:
+				out = append(out, sqlToken{text: text})
+			}
+			continue
+		}
+
+		code := span.text
+		for i := 0; i < len(code); {
+			switch c := code[i]; {
+			case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+				i++
+			case isIdentifierByte(c):
+				j := i
+				for j < len(code) && isIdentifierByte(code[j]) {
+					j++
+				}
+				out = append(out, sqlToken{text: code[i:j], word: true})
+				i = j
+			default:
+				out = append(out, sqlToken{text: code[i : i+1]})
+				i++
+			}
+		}
+	}
+	return out
+}
+
+// clauseEnds are the keywords that end the FROM clause of a query
+var clauseEnds = map[string]bool{
+	"WHERE": true, "GROUP": true, "HAVING": true, "QUALIFY": true, "WINDOW": true,
+	"ORDER": true, "LIMIT": true, "OFFSET": true, "FOR": true,
+}
+
+// queryTable returns the name parts of the table a query reads from, such as
+// the schema and the table, when it reads from just
+// one: a SELECT whose top-level FROM clause names a single table, without
+// joins, set operations or a WITH clause. Literals, comments and anything
+// in brackets, such as subqueries and EXTRACT(YEAR FROM ts), are skipped.
+// PostgreSQL folds bare identifiers to lower case, so they are matched the
+// same way.
+func queryTable(d dialect, query string) ([]string, bool) {
+	var top []sqlToken
+	depth := 0
+	for _, tok := range d.tokens(query) {
+		if !tok.word && !tok.quoted {
+			switch tok.text {
+			case "(", "[", "{":
+				// Only the opening bracket shows at the top level
+				if depth == 0 {
+					top = append(top, tok)
+				}
+				depth++
+				continue
+			case ")", "]", "}":
+				depth--
+				continue
+			}
+		}
+		if depth == 0 {
+			top = append(top, tok)
+		}
+	}
+
+	keyword := func(i int, words ...string) bool {
+		if i >= len(top) || !top[i].word {
+			return false
+		}
+		for _, w := range words {
+			if strings.EqualFold(top[i].text, w) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if !keyword(0, "SELECT") {
+		return nil, false
+	}
+	from := -1
+	for i := range top {
+		if keyword(i, "UNION", "INTERSECT", "EXCEPT") {
+			return nil, false
+		}
+		if from == -1 && keyword(i, "FROM") {
+			from = i
+		}
+	}
+	if from == -1 {
+		return nil, false
+	}
+
+	// The table name, possibly qualified with dots
+	var parts []string
+	i := from + 1
+	for {
+		if i >= len(top) || !top[i].word && !top[i].quoted {
+			return nil, false
+		}
+		name := top[i].text
+		if top[i].word && d.postgres() {
+			name = strings.ToLower(name)
+		}
+		parts = append(parts, name)
+		i++
+		if i >= len(top) || top[i].text != "." || top[i].word {
+			break
+		}
+		i++
+	}
+
+	// A name followed by brackets is a call such as UNNEST(...), not a
+	// table. Joins and comma-separated tables read more than one table.
+	if i < len(top) && top[i].text == "(" && !top[i].word {
+		return nil, false
+	}
+	for ; i < len(top); i++ {
+		if top[i].word && clauseEnds[strings.ToUpper(top[i].text)] {
+			break
+		}
+		if top[i].text == "," || keyword(i, "JOIN") {
+			return nil, false
+		}
+	}
+	return parts, true
+}
+
+// ExportQuery runs a query and streams its results to w. Nothing is written
+// to w until the query has returned its first row (or finished empty), so a
+// failing query leaves w untouched and the caller can still report the error.
+func ExportQuery(req types.ExportRequest, w io.Writer) error {
+	if _, _, err := ExportContentType(req.Format); err != nil {
+		return err
+	}
+
+	bound, err := timestampBound(req.TimestampBound)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	client, d, err := openDatabase(ctx, req.Connection())
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	table := req.Table
+	if req.Format == ExportSQL && len(table) == 0 {
+		var ok bool
+		if table, ok = queryTable(d, req.Query); !ok {
+			return fmt.Errorf("a target table is required for SQL exports of queries that do not read from a single table")
+		}
+	}
+
+	iter := client.Single().WithTimestampBound(bound).Query(ctx, spanner.Statement{SQL: req.Query})
+	defer iter.Stop()
+
+	// Read ahead one row so query errors surface before any output
+	first, err := iter.Next()
+	if err != nil && err != iterator.Done {
+		return err
+	}
+
+	var columns []column
+	if iter.Metadata != nil && iter.Metadata.RowType != nil {
+		for _, f := range iter.Metadata.RowType.Fields {
+			columns = append(columns, column{Name: f.Name, Type: f.Type})
+		}
+	}
+
+	buf := bufio.NewWriter(w)
+	var rw rowWriter
+	switch req.Format {
+	case ExportCSV:
+		rw = &csvRowWriter{w: csv.NewWriter(buf)}
+	case ExportJSON:
+		rw = &jsonRowWriter{w: buf}
+	case ExportNDJSON:
+		rw = &jsonRowWriter{w: buf, lines: true}
+	case ExportSQL:
+		rw = &sqlRowWriter{w: buf, d: d, table: table}
+	}
+
+	if err := rw.begin(columns); err != nil {
+		return err
+	}
+
+	row := first
+	for row != nil {
+		values := make([]interface{}, len(columns))
+		for i, col := range columns {
+			values[i] = decodeValue(col.Type, row.ColumnValue(i).AsInterface())
+		}
+		if err := rw.write(values); err != nil {
+			return err
+		}
+
+		row, err = iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := rw.end(); err != nil {
+		return err
+	}
+	return buf.Flush()
+}
+
+// decodeValue converts a value in Spanner's wire encoding into a Go value
+// that keeps its column type: INT64 becomes int64, FLOAT64 float64, BYTES
+// []byte, TIMESTAMP time.Time, JSON json.RawMessage and ARRAY []interface{}.
+// DATE, NUMERIC and the remaining scalar types keep their canonical strings.
+func decodeValue(t *sppb.Type, v interface{}) interface{} {
+	if v == nil || t == nil {
+		return v
+	}
+
+	switch t.Code {
+	case sppb.TypeCode_INT64, sppb.TypeCode_ENUM:
+		if s, ok := v.(string); ok {
+			if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+				return n
+			}
+		}
+	case sppb.TypeCode_FLOAT64, sppb.TypeCode_FLOAT32:
+		switch f := v.(type) {
+		case float64:
+			return f
+		case string:
+			switch f {
+			case "NaN":
+				return math.NaN()
+			case "Infinity":
+				return math.Inf(1)
+			case "-Infinity":
+				return math.Inf(-1)
+			}
+		}
+	case sppb.TypeCode_BYTES, sppb.TypeCode_PROTO:
+		if s, ok := v.(string); ok {
+			if b, err := base64.StdEncoding.DecodeString(s); err == nil {
+				return b
+			}
+		}
+	case sppb.TypeCode_TIMESTAMP:
+		if s, ok := v.(string); ok {
+			if ts, err := time.Parse(time.RFC3339Nano, s); err == nil {
+				return ts.UTC()
+			}
+		}
+	case sppb.TypeCode_JSON:
+		if s, ok := v.(string); ok {
+			return json.RawMessage(s)
+		}
+	case sppb.TypeCode_ARRAY:
+		if items, ok := v.([]interface{}); ok {
+			out := make([]interface{}, len(items))
+			for i, item := range items {
+				out[i] = decodeValue(t.ArrayElementType, item)
+			}
+			return out
+		}
+	case sppb.TypeCode_STRUCT:
+		if items, ok := v.([]interface{}); ok && t.StructType != nil {
+			out := make(map[string]interface{}, len(items))
+			for i, item := range items {
+				if i < len(t.StructType.Fields) {
+					f := t.StructType.Fields[i]
+					out[f.Name] = decodeValue(f.Type, item)
+				}
+			}
+			return out
+		}
+	}
+	return v
+}
+
+// typeName renders a Spanner type the way it appears in DDL
+func typeName(t *sppb.Type) string {
+	if t == nil {
+		return ""
+	}
+	if t.Code == sppb.TypeCode_ARRAY {
+		return "ARRAY<" + typeName(t.ArrayElementType) + ">"
+	}
+	return t.Code.String()
+}
+
+// textValue renders a decoded value as plain text for CSV cells
+func textValue(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case int64:
+		return strconv.FormatInt(val, 10)
+	case float64:
+		return formatFloat(val)
+	case bool:
+		return strconv.FormatBool(val)
+	case []byte:
+		return base64.StdEncoding.EncodeToString(val)
+	case time.Time:
+		return val.Format(time.RFC3339Nano)
+	case json.RawMessage:
+		return string(val)
+	default:
+		data, err := json.Marshal(jsonValue(val))
+		if err != nil {
+			return fmt.Sprintf("%v", val)
+		}
+		return string(data)
+	}
+}
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsNaN(f):
+		return "NaN"
+	case math.IsInf(f, 1):
+		return "Infinity"
+	case math.IsInf(f, -1):
+		return "-Infinity"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// jsonValue prepares a decoded value for encoding/json without losing
+// precision: INT64 is written as an exact number and non-finite floats,
+// which JSON cannot represent, as strings.
+func jsonValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case int64:
+		return json.Number(strconv.FormatInt(val, 10))
+	case float64:
+		if math.IsNaN(val) || math.IsInf(val, 0) {
+			return formatFloat(val)
+		}
+		return val
+	case time.Time:
+		return val.Format(time.RFC3339Nano)
+	case []interface{}:
+		out := make([]interface{}, len(val))
+		for i, item := range val {
+			out[i] = jsonValue(item)
+		}
+		return out
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			out[k] = jsonValue(item)
+		}
+		return out
+	}
+	return v
+}
+
+type csvRowWriter struct {
+	w *csv.Writer
+}
+
+func (c *csvRowWriter) begin(columns []column) error {
+	header := make([]string, len(columns))
+	for i, col := range columns {
+		header[i] = col.Name
+	}
+	return c.w.Write(header)
+}
+
+func (c *csvRowWriter) write(values []interface{}) error {
+	record := make([]string, len(values))
+	for i, v := range values {
+		record[i] = textValue(v)
+	}
+	return c.w.Write(record)
+}
+
+func (c *csvRowWriter) end() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// jsonRowWriter writes rows as objects with keys in column order, either as
+// a single JSON array or one object per line (NDJSON)
+type jsonRowWriter struct {
+	w       io.Writer
+	lines   bool
+	columns []column
+	count   int
+}
+
+func (j *jsonRowWriter) begin(columns []column) error {
+	j.columns = columns
+	if j.lines {
+		return nil
+	}
+	_, err := io.WriteString(j.w, "[")
+	return err
+}
+
+func (j *jsonRowWriter) write(values []interface{}) error {
+	var sb strings.Builder
+	if !j.lines && j.count > 0 {
+		sb.WriteString(",")
+	}
+	if !j.lines {
+		sb.WriteString("\n  ")
+	}
+	sb.WriteString("{")
+	for i, col := range j.columns {
+		if i > 0 {
+			sb.WriteString(",")
+		}
+		key, _ := json.Marshal(col.Name)
+		val, err := json.Marshal(jsonValue(values[i]))
+		if err != nil {
+			return fmt.Errorf("failed to encode column %s: %w", col.Name, err)
+		}
+		sb.Write(key)
+		sb.WriteString(":")
+		sb.Write(val)
+	}
+	sb.WriteString("}")
+	if j.lines {
+		sb.WriteString("\n")
+	}
+	j.count++
+	_, err := io.WriteString(j.w, sb.String())
+	return err
+}
+
+func (j *jsonRowWriter) end() error {
+	if j.lines {
+		return nil
+	}
+	closing := "]\n"
+	if j.count > 0 {
+		closing = "\n]\n"
+	}
+	_, err := io.WriteString(j.w, closing)
+	return err
+}
+
+// sqlRowWriter writes one INSERT statement per row, in the database's dialect
+type sqlRowWriter struct {
+	w       io.Writer
+	d       dialect
+	table   []string
+	columns []column
+	prefix  string
+}
+
+func (s *sqlRowWriter) begin(columns []column) error {
+	s.columns = columns
+	names := make([]string, len(columns))
+	for i, col := range columns {
+		names[i] = s.d.quoteIdentifier(col.Name)
+	}
+	parts := make([]string, len(s.table))
+	for i, part := range s.table {
+		parts[i] = s.d.quoteIdentifier(part)
+	}
+	s.prefix = fmt.Sprintf("INSERT INTO %s (%s) VALUES (", strings.Join(parts, "."), strings.Join(names, ", "))
+	return nil
+}
+
+func (s *sqlRowWriter) write(values []interface{}) error {
+	literals := make([]string, len(values))
+	for i, v := range values {
+		literals[i] = sqlLiteral(s.d, s.columns[i].Type, v)
+	}
+	_, err := io.WriteString(s.w, s.prefix+strings.Join(literals, ", ")+");\n")
+	return err
+}
+
+func (s *sqlRowWriter) end() error {
+	return nil
+}
+
+// sqlLiteral renders a decoded value as a literal of the column type
+func sqlLiteral(d dialect, t *sppb.Type, v interface{}) string {
+	if v == nil {
+		return "NULL"
+	}
+	if d.postgres() {
+		return pgLiteral(t, v)
+	}
+
+	code := sppb.TypeCode_STRING
+	if t != nil {
+		code = t.Code
+	}
+
+	switch code {
+	case sppb.TypeCode_BOOL:
+		if b, ok := v.(bool); ok && b {
+			return "TRUE"
+		}
+		return "FALSE"
+	case sppb.TypeCode_INT64:
+		return textValue(v)
+	case sppb.TypeCode_FLOAT64, sppb.TypeCode_FLOAT32:
+		f, _ := v.(float64)
+		var lit string
+		switch {
+		case math.IsNaN(f):
+			lit = "CAST('nan' AS FLOAT64)"
+		case math.IsInf(f, 1):
+			lit = "CAST('inf' AS FLOAT64)"
+		case math.IsInf(f, -1):
+			lit = "CAST('-inf' AS FLOAT64)"
+		default:
+			lit = formatFloat(f)
+		}
+		if code == sppb.TypeCode_FLOAT32 {
+			return "CAST(" + lit + " AS FLOAT32)"
+		}
+		return lit
+	case sppb.TypeCode_BYTES:
+		return "FROM_BASE64(" + d.quoteString(textValue(v)) + ")"
+	case sppb.TypeCode_TIMESTAMP:
+		return "TIMESTAMP " + d.quoteString(textValue(v))
+	case sppb.TypeCode_DATE:
+		return "DATE " + d.quoteString(textValue(v))
+	case sppb.TypeCode_NUMERIC:
+		return "NUMERIC " + d.quoteString(textValue(v))
+	case sppb.TypeCode_JSON:
+		return "JSON " + d.quoteString(textValue(v))
+	case sppb.TypeCode_ARRAY:
+		items, _ := v.([]interface{})
+		literals := make([]string, len(items))
+		for i, item := range items {
+			literals[i] = sqlLiteral(d, t.ArrayElementType, item)
+		}
+		return typeName(t) + "[" + strings.Join(literals, ", ") + "]"
+	}
+	return d.quoteString(textValue(v))
+}
+
+// pgLiteral renders a decoded, non-NULL value as a PostgreSQL literal. Typed
+// values are written as casts of their text form.
+func pgLiteral(t *sppb.Type, v interface{}) string {
+	d := dialect(DialectPostgreSQL)
+	if t == nil {
+		return d.quoteString(textValue(v))
+	}
+
+	switch t.Code {
+	case sppb.TypeCode_BOOL:
+		if b, ok := v.(bool); ok && b {
+			return "TRUE"
+		}
+		return "FALSE"
+	case sppb.TypeCode_INT64:
+		return textValue(v)
+	case sppb.TypeCode_FLOAT64, sppb.TypeCode_FLOAT32:
+		f, _ := v.(float64)
+		lit := formatFloat(f)
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			lit = d.quoteString(lit) + "::float8"
+		}
+		if t.Code == sppb.TypeCode_FLOAT32 {
+			return "CAST(" + lit + " AS float4)"
+		}
+		return lit
+	case sppb.TypeCode_BYTES:
+		b, _ := v.([]byte)
+		return `'\x` + hex.EncodeToString(b) + "'::bytea"
+	case sppb.TypeCode_TIMESTAMP, sppb.TypeCode_DATE, sppb.TypeCode_NUMERIC, sppb.TypeCode_JSON:
+		return d.quoteString(textValue(v)) + "::" + pgTypeName(t)
+	case sppb.TypeCode_ARRAY:
+		items, _ := v.([]interface{})
+		literals := make([]string, len(items))
+		for i, item := range items {
+			if item == nil {
+				literals[i] = "NULL"
+			} else {
+				literals[i] = pgLiteral(t.ArrayElementType, item)
+			}
+		}
+		return "ARRAY[" + strings.Join(literals, ", ") + "]::" + pgTypeName(t)
+	}
+	return d.quoteString(textValue(v))
+}
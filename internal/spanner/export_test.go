@@ -0,0 +1,149 @@
+package spanner
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+)
+
+func TestQueryTable(t *testing.T) {
+	tests := []struct {
+		name    string
+		dialect dialect
+		query   string
+		want    []string
+		ok      bool
+	}{
+		{"bare name", DialectGoogleSQL, "SELECT * FROM Users WHERE id = 1", []string{"Users"}, true},
+		{"lower-case keywords", DialectGoogleSQL, "select id from Users order by id", []string{"Users"}, true},
+		{"alias", DialectGoogleSQL, "SELECT u.id FROM Users AS u", []string{"Users"}, true},
+		{"quoted", DialectGoogleSQL, "SELECT * FROM `Order Items`", []string{"Order Items"}, true},
+		{"qualified", DialectGoogleSQL, "SELECT * FROM sales.Orders LIMIT 5", []string{"sales", "Orders"}, true},
+		{"table hint", DialectGoogleSQL, "SELECT * FROM Users@{FORCE_INDEX=ByEmail, GROUPBY_SCAN_OPTIMIZATION=TRUE} WHERE a = 1", []string{"Users"}, true},
+		{"FROM inside a function", DialectGoogleSQL, "SELECT EXTRACT(YEAR FROM ts) AS y FROM Events", []string{"Events"}, true},
+		{"FROM inside a string", DialectGoogleSQL, "SELECT 'copied FROM Archive' AS note FROM Users", []string{"Users"}, true},
+		{"FROM inside a comment", DialectGoogleSQL, "SELECT 1 -- FROM Archive\nFROM Users", []string{"Users"}, true},
+		{"FROM inside a subquery", DialectGoogleSQL, "SELECT (SELECT COUNT(*) FROM Orders) AS n FROM Users", []string{"Users"}, true},
+		{"subquery in WHERE", DialectGoogleSQL, "SELECT * FROM Users WHERE id IN (SELECT user_id FROM Orders JOIN Items ON true)", []string{"Users"}, true},
+		{"GROUP BY list", DialectGoogleSQL, "SELECT a, b FROM Users GROUP BY a, b", []string{"Users"}, true},
+		{"PostgreSQL folds bare names", DialectPostgreSQL, "SELECT * FROM Users", []string{"users"}, true},
+		{"PostgreSQL quoted", DialectPostgreSQL, `SELECT * FROM "Order ""Items"""`, []string{`Order "Items"`}, true},
+		{"PostgreSQL dollar string", DialectPostgreSQL, "SELECT $$ FROM Archive $$ FROM Users", []string{"users"}, true},
+
+		{"no FROM", DialectGoogleSQL, "SELECT 1", nil, false},
+		{"only FROM in a function", DialectGoogleSQL, "SELECT EXTRACT(YEAR FROM CURRENT_TIMESTAMP())", nil, false},
+		{"join", DialectGoogleSQL, "SELECT * FROM Users u JOIN Orders o ON u.id = o.user_id", nil, false},
+		{"comma join", DialectGoogleSQL, "SELECT * FROM Users, Orders", nil, false},
+		{"union", DialectGoogleSQL, "SELECT id FROM Users UNION ALL SELECT id FROM Admins", nil, false},
+		{"WITH", DialectGoogleSQL, "WITH recent AS (SELECT * FROM Orders) SELECT * FROM recent", nil, false},
+		{"subquery as the table", DialectGoogleSQL, "SELECT * FROM (SELECT * FROM Users)", nil, false},
+		{"table function", DialectGoogleSQL, "SELECT * FROM UNNEST([1, 2]) AS n", nil, false},
+		{"PostgreSQL table function", DialectPostgreSQL, "SELECT * FROM generate_series(1, 3)", nil, false},
+		{"not a query", DialectGoogleSQL, "DELETE FROM Users WHERE true", nil, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := queryTable(tt.dialect, tt.query)
+		if !reflect.DeepEqual(got, tt.want) || ok != tt.ok {
+			t.Errorf("%s: expected %q, %v, got %q, %v", tt.name, tt.want, tt.ok, got, ok)
+		}
+	}
+}
+
+func TestSQLRowWriterQualifiedTable(t *testing.T) {
+	tests := []struct {
+		name    string
+		dialect dialect
+		query   string
+		insert  string
+		parts   []string
+	}{
+		{"schema and table", DialectGoogleSQL, "SELECT * FROM sales.Orders",
+			"INSERT INTO `sales`.`Orders` (`id`) VALUES (1);\n", []string{"sales", "Orders"}},
+		{"quoted names with dots", DialectGoogleSQL, "SELECT * FROM `my.schema`.`Order.Items`",
+			"INSERT INTO `my.schema`.`Order.Items` (`id`) VALUES (1);\n", []string{"my.schema", "Order.Items"}},
+		{"PostgreSQL", DialectPostgreSQL, `SELECT * FROM Sales."Order ""Items"""`,
+			`INSERT INTO "sales"."Order ""Items""" ("id") VALUES (1);` + "\n", []string{"sales", `Order "Items"`}},
+	}
+
+	for _, tt := range tests {
+		table, ok := queryTable(tt.dialect, tt.query)
+		if !ok {
+			t.Errorf("%s: expected a table", tt.name)
+			continue
+		}
+
+		var out strings.Builder
+		w := &sqlRowWriter{w: &out, d: tt.dialect, table: table}
+		w.begin([]column{{Name: "id", Type: &sppb.Type{Code: sppb.TypeCode_INT64}}})
+		if err := w.write([]interface{}{int64(1)}); err != nil {
+			t.Errorf("%s: could not write: %v", tt.name, err)
+			continue
+		}
+		if out.String() != tt.insert {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.insert, out.String())
+		}
+
+		// The written name reads back as the same table
+		name := strings.TrimPrefix(out.String(), "INSERT INTO ")
+		name = name[:strings.Index(name, " (")]
+		again, ok := queryTable(tt.dialect, "SELECT * FROM "+name)
+		if !ok || !reflect.DeepEqual(again, tt.parts) {
+			t.Errorf("%s: expected %q to read back as %q, got %q", tt.name, name, tt.parts, again)
+		}
+	}
+}
+
+func TestSQLLiteral(t *testing.T) {
+	scalar := func(code sppb.TypeCode) *sppb.Type { return &sppb.Type{Code: code} }
+	array := func(code sppb.TypeCode) *sppb.Type {
+		return &sppb.Type{Code: sppb.TypeCode_ARRAY, ArrayElementType: scalar(code)}
+	}
+	ts := time.Date(2024, 1, 2, 3, 4, 5, 600000000, time.UTC)
+
+	tests := []struct {
+		name       string
+		typ        *sppb.Type
+		value      interface{}
+		googleSQL  string
+		postgreSQL string
+	}{
+		{"NULL", scalar(sppb.TypeCode_STRING), nil, "NULL", "NULL"},
+		{"quotes and escapes", scalar(sppb.TypeCode_STRING), "it's a \\ \"q\"\n",
+			`'it\'s a \\ "q"\n'`, "'it''s a \\ \"q\"\n'"},
+		{"untyped", nil, "x", "'x'", "'x'"},
+		{"BOOL", scalar(sppb.TypeCode_BOOL), true, "TRUE", "TRUE"},
+		{"INT64", scalar(sppb.TypeCode_INT64), int64(-9007199254740993), "-9007199254740993", "-9007199254740993"},
+		{"FLOAT64", scalar(sppb.TypeCode_FLOAT64), 0.1, "0.1", "0.1"},
+		{"NaN", scalar(sppb.TypeCode_FLOAT64), math.NaN(), "CAST('nan' AS FLOAT64)", "'NaN'::float8"},
+		{"Infinity", scalar(sppb.TypeCode_FLOAT64), math.Inf(1), "CAST('inf' AS FLOAT64)", "'Infinity'::float8"},
+		{"-Infinity", scalar(sppb.TypeCode_FLOAT64), math.Inf(-1), "CAST('-inf' AS FLOAT64)", "'-Infinity'::float8"},
+		{"FLOAT32", scalar(sppb.TypeCode_FLOAT32), 1.5, "CAST(1.5 AS FLOAT32)", "CAST(1.5 AS float4)"},
+		{"BYTES", scalar(sppb.TypeCode_BYTES), []byte("hi'"), "FROM_BASE64('aGkn')", `'\x686927'::bytea`},
+		{"TIMESTAMP", scalar(sppb.TypeCode_TIMESTAMP), ts,
+			"TIMESTAMP '2024-01-02T03:04:05.6Z'", "'2024-01-02T03:04:05.6Z'::timestamptz"},
+		{"DATE", scalar(sppb.TypeCode_DATE), "2024-01-02", "DATE '2024-01-02'", "'2024-01-02'::date"},
+		{"NUMERIC", scalar(sppb.TypeCode_NUMERIC), "1.25", "NUMERIC '1.25'", "'1.25'::numeric"},
+		{"JSON", scalar(sppb.TypeCode_JSON), json.RawMessage(`{"a":"it's"}`),
+			`JSON '{"a":"it\'s"}'`, `'{"a":"it''s"}'::jsonb`},
+		{"ARRAY with NULL", array(sppb.TypeCode_INT64), []interface{}{int64(1), nil},
+			"ARRAY<INT64>[1, NULL]", "ARRAY[1, NULL]::bigint[]"},
+		{"ARRAY of strings", array(sppb.TypeCode_STRING), []interface{}{"a'b"},
+			`ARRAY<STRING>['a\'b']`, "ARRAY['a''b']::varchar[]"},
+		{"empty ARRAY", array(sppb.TypeCode_DATE), []interface{}{}, "ARRAY<DATE>[]", "ARRAY[]::date[]"},
+	}
+
+	for _, tt := range tests {
+		if got := sqlLiteral(DialectGoogleSQL, tt.typ, tt.value); got != tt.googleSQL {
+			t.Errorf("%s: expected GoogleSQL %s, got %s", tt.name, tt.googleSQL, got)
+		}
+		if got := sqlLiteral(DialectPostgreSQL, tt.typ, tt.value); got != tt.postgreSQL {
+			t.Errorf("%s: expected PostgreSQL %s, got %s", tt.name, tt.postgreSQL, got)
+		}
+	}
+}
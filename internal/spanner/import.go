@@ -0,0 +1,648 @@
+package spanner
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/civil"
+	"cloud.google.com/go/spanner"
+	"cloudevents-explorer/internal/config"
+	"cloudevents-explorer/internal/types"
+	"google.golang.org/api/iterator"
+)
+
+// Supported import modes
+const (
+	ImportInsert  = "insert"
+	ImportUpsert  = "upsert"
+	ImportReplace = "replace"
+)
+
+const defaultImportBatchSize = 500
+
+// recordReader returns the next record of an import file, or io.EOF
+type recordReader func() (map[string]interface{}, error)
+
+// newRecordReader reads CSV files by header, and JSON files either as a
+// top-level array of objects or as one object per line (NDJSON)
+func newRecordReader(format string, r io.Reader) (recordReader, error) {
+	if format == ExportCSV {
+		cr := csv.NewReader(r)
+		header, err := cr.Read()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CSV header: %w", err)
+		}
+		return func() (map[string]interface{}, error) {
+			record, err := cr.Read()
+			if err != nil {
+				return nil, err
+			}
+			row := make(map[string]interface{}, len(header))
+			for i, name := range header {
+				if i < len(record) {
+					row[name] = record[i]
+				}
+			}
+			return row, nil
+		}, nil
+	}
+
+	if format != ExportJSON && format != ExportNDJSON {
+		return nil, fmt.Errorf("unsupported import format %q", format)
+	}
+
+	br := bufio.NewReader(r)
+	dec := json.NewDecoder(br)
+	dec.UseNumber()
+
+	// Peek past leading whitespace to tell an array from a stream of objects
+	inArray := false
+	for {
+		b, err := br.Peek(1)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if b[0] == ' ' || b[0] == '\t' || b[0] == '\r' || b[0] == '\n' {
+			br.ReadByte()
+			continue
+		}
+		if b[0] == '[' {
+			if _, err := dec.Token(); err != nil {
+				return nil, err
+			}
+			inArray = true
+		}
+		break
+	}
+
+	return func() (map[string]interface{}, error) {
+		if inArray && !dec.More() {
+			return nil, io.EOF
+		}
+		var row map[string]interface{}
+		if err := dec.Decode(&row); err != nil {
+			return nil, err
+		}
+		return row, nil
+	}, nil
+}
+
+// sliceReader feeds in-memory rows, such as a seed table, to applyRecords
+func sliceReader(rows []map[string]interface{}) recordReader {
+	i := 0
+	return func() (map[string]interface{}, error) {
+		if i >= len(rows) {
+			return nil, io.EOF
+		}
+		i++
+		return rows[i-1], nil
+	}
+}
+
+// tableColumn is a column name and its Spanner type in GoogleSQL spelling,
+// e.g. STRING(MAX)
+type tableColumn struct {
+	Name string
+	Type string
+}
+
+// writableColumns returns the columns of a table in ordinal order, leaving
+// out generated columns that cannot be written, along with a lookup keyed by
+// lower-cased name since Spanner column names are case-insensitive
+func writableColumns(ctx context.Context, client *spanner.Client, d dialect, table string) ([]tableColumn, map[string]tableColumn, error) {
+	stmt := d.statement(`
+		SELECT column_name, spanner_type
+		FROM information_schema.columns
+		WHERE table_schema = @schema AND table_name = @tableName AND is_generated = 'NEVER'
+		ORDER BY ordinal_position
+	`, map[string]interface{}{"schema": d.schema(), "tableName": table})
+
+	iter := client.Single().Query(ctx, stmt)
+	defer iter.Stop()
+
+	var columns []tableColumn
+	lookup := make(map[string]tableColumn)
+	for {
+		row, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		var name, colType string
+		if err := row.Columns(&name, &colType); err != nil {
+			return nil, nil, err
+		}
+		col := tableColumn{Name: name, Type: d.columnType(colType)}
+		columns = append(columns, col)
+		lookup[strings.ToLower(name)] = col
+	}
+
+	if len(columns) == 0 {
+		return nil, nil, fmt.Errorf("table %s not found", table)
+	}
+	return columns, lookup, nil
+}
+
+// mutationFunc builds the mutation that writes one record
+type mutationFunc func(table string, values map[string]interface{}) *spanner.Mutation
+
+// importMutation returns the mutation an import mode writes records with.
+// Only an empty mode defaults to upsert, so a mistyped mode writes nothing.
+func importMutation(mode string) (mutationFunc, error) {
+	switch mode {
+	case ImportInsert:
+		return spanner.InsertMap, nil
+	case ImportUpsert, "":
+		return spanner.InsertOrUpdateMap, nil
+	case ImportReplace:
+		return spanner.ReplaceMap, nil
+	}
+	return nil, fmt.Errorf("unknown import mode %q: use %s, %s or %s", mode, ImportInsert, ImportUpsert, ImportReplace)
+}
+
+// recordImport says how applyRecords writes the records of one table
+type recordImport struct {
+	table     string
+	mutation  mutationFunc
+	mapping   map[string]string
+	batchSize int
+
+	// text is set when records hold every value as text, as CSV cells do,
+	// rather than as decoded JSON values
+	text bool
+
+	// apply writes one batch of mutations
+	apply func([]*spanner.Mutation) error
+}
+
+// applyRecords coerces each record to the table's column types and hands
+// the mutations to imp.apply in batches of imp.batchSize
+func applyRecords(ctx context.Context, client *spanner.Client, d dialect, imp recordImport, next recordReader, resp *types.ImportResponse) error {
+	_, columns, err := writableColumns(ctx, client, d, imp.table)
+	if err != nil {
+		return err
+	}
+
+	batchSize := imp.batchSize
+	if batchSize <= 0 {
+		batchSize = defaultImportBatchSize
+	}
+
+	var batch []*spanner.Mutation
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := imp.apply(batch); err != nil {
+			return fmt.Errorf("batch %d failed: %w", resp.Batches+1, err)
+		}
+		resp.RowsImported += len(batch)
+		resp.Batches++
+		batch = batch[:0]
+		return nil
+	}
+
+	line := 0
+	for {
+		record, err := next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("record %d: %w", line+1, err)
+		}
+		line++
+
+		values := make(map[string]interface{}, len(record))
+		for field, raw := range record {
+			target := field
+			if mapped, ok := imp.mapping[field]; ok {
+				target = mapped
+			}
+			if target == "" {
+				continue
+			}
+
+			col, ok := columns[strings.ToLower(target)]
+			if !ok {
+				return fmt.Errorf("record %d: column %s is not a writable column of %s", line, target, imp.table)
+			}
+
+			v, err := coerceValue(d, col.Type, raw, imp.text)
+			if err != nil {
+				return fmt.Errorf("record %d, column %s: %w", line, col.Name, err)
+			}
+			values[col.Name] = v
+		}
+
+		batch = append(batch, imp.mutation(imp.table, values))
+		if len(batch) >= batchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+
+	return flush()
+}
+
+// ImportFile loads the records of a CSV, JSON or NDJSON file into a table.
+// Batches are applied one at a time, so on failure the rows of earlier
+// batches stay written and are reported in RowsImported.
+func ImportFile(req types.ImportRequest, data io.Reader) types.ImportResponse {
+	startTime := time.Now()
+
+	mutation, err := importMutation(req.Mode)
+	if err != nil {
+		return types.ImportResponse{Error: err.Error()}
+	}
+	next, err := newRecordReader(req.Format, data)
+	if err != nil {
+		return types.ImportResponse{Error: err.Error()}
+	}
+
+	ctx := context.Background()
+	client, d, err := openDatabase(ctx, req.ConnectionRequest)
+	if err != nil {
+		return types.ImportResponse{Error: err.Error()}
+	}
+	defer client.Close()
+
+	imp := recordImport{
+		table:     req.Table,
+		mutation:  mutation,
+		mapping:   req.Mapping,
+		batchSize: req.BatchSize,
+		text:      req.Format == ExportCSV,
+		apply: func(batch []*spanner.Mutation) error {
+			_, err := client.Apply(ctx, batch)
+			return err
+		},
+	}
+
+	var resp types.ImportResponse
+	if err := applyRecords(ctx, client, d, imp, next, &resp); err != nil {
+		resp.Error = err.Error()
+	}
+	resp.ExecutionTime = time.Since(startTime).String()
+	return resp
+}
+
+// CaptureSeedSet reads the current rows of the requested tables into a seed
+// set. Generated columns are skipped since they cannot be written back.
+func CaptureSeedSet(req types.SeedRequest) (*config.SeedSet, error) {
+	ctx := context.Background()
+	client, d, err := openDatabase(ctx, req.ConnectionRequest)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	seed := &config.SeedSet{Name: req.Name, Description: req.Description}
+	for _, table := range req.Tables {
+		columns, _, err := writableColumns(ctx, client, d, table)
+		if err != nil {
+			return nil, err
+		}
+
+		names := make([]string, len(columns))
+		for i, col := range columns {
+			names[i] = d.quoteIdentifier(col.Name)
+		}
+		stmt := spanner.Statement{
+			SQL: fmt.Sprintf("SELECT %s FROM %s", strings.Join(names, ", "), d.quoteIdentifier(table)),
+		}
+
+		rows := []map[string]interface{}{}
+		iter := client.Single().Query(ctx, stmt)
+		err = iter.Do(func(row *spanner.Row) error {
+			values := make(map[string]interface{}, len(columns))
+			for i, col := range columns {
+				values[col.Name] = jsonValue(decodeValue(row.ColumnType(i), row.ColumnValue(i).AsInterface()))
+			}
+			rows = append(rows, values)
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", table, err)
+		}
+
+		seed.Tables = append(seed.Tables, config.SeedTable{Table: table, Rows: rows})
+	}
+
+	return seed, nil
+}
+
+// ApplySeedSet upserts the rows of a seed set, table by table in the order
+// they were captured. With reset, the seeded tables are emptied first, in
+// reverse order so child tables are cleared before their parents. The whole
+// seed set is applied in one commit, so a failure writes none of it; Spanner
+// caps the mutations of a commit, which very large seed sets can exceed.
+func ApplySeedSet(conn types.ConnectionRequest, seed *config.SeedSet, reset bool) types.ImportResponse {
+	startTime := time.Now()
+
+	ctx := context.Background()
+	client, d, err := openDatabase(ctx, conn)
+	if err != nil {
+		return types.ImportResponse{Error: err.Error()}
+	}
+	defer client.Close()
+
+	var mutations []*spanner.Mutation
+	if reset {
+		for i := len(seed.Tables) - 1; i >= 0; i-- {
+			mutations = append(mutations, spanner.Delete(seed.Tables[i].Table, spanner.AllKeys()))
+		}
+	}
+
+	var resp types.ImportResponse
+	for _, t := range seed.Tables {
+		imp := recordImport{
+			table:    t.Table,
+			mutation: spanner.InsertOrUpdateMap,
+			apply: func(batch []*spanner.Mutation) error {
+				mutations = append(mutations, batch...)
+				return nil
+			},
+		}
+		if err := applyRecords(ctx, client, d, imp, sliceReader(t.Rows), &resp); err != nil {
+			return types.ImportResponse{
+				Error:         fmt.Sprintf("%s: %v", t.Table, err),
+				ExecutionTime: time.Since(startTime).String(),
+			}
+		}
+	}
+
+	resp.Batches = 0
+	if len(mutations) > 0 {
+		if _, err := client.Apply(ctx, mutations); err != nil {
+			return types.ImportResponse{
+				Error:         fmt.Sprintf("failed to apply seed set: %v", err),
+				ExecutionTime: time.Since(startTime).String(),
+			}
+		}
+		resp.Batches = 1
+	}
+
+	resp.ExecutionTime = time.Since(startTime).String()
+	return resp
+}
+
+// baseType strips the length from a column type, e.g. STRING(MAX) -> STRING
+func baseType(spannerType string) string {
+	t := strings.ToUpper(strings.TrimSpace(spannerType))
+	if i := strings.Index(t, "("); i != -1 {
+		t = t[:i]
+	}
+	return t
+}
+
+// coerceValue converts a value read from an import file, either a CSV cell
+// or a decoded JSON value, into the Go type the Spanner client encodes for a
+// column of the given type, e.g. "INT64", "STRING(MAX)" or "ARRAY<DATE>".
+// With text, v is a cell holding the value as text, and arrays and JSON
+// documents in their JSON form; otherwise a string bound for a JSON column
+// is a JSON string. Empty strings are NULL for every type except STRING.
+// PostgreSQL type names are accepted too, and NUMERIC and JSON values use
+// the PostgreSQL encodings in that dialect.
+func coerceValue(d dialect, spannerType string, v interface{}, text bool) (interface{}, error) {
+	t := d.columnType(spannerType)
+
+	if strings.HasPrefix(t, "ARRAY<") && strings.HasSuffix(t, ">") {
+		elem := t[len("ARRAY<") : len(t)-1]
+		switch val := v.(type) {
+		case nil:
+			return nil, nil
+		case []interface{}:
+			return coerceArray(d, elem, val)
+		case string:
+			// CSV cells hold arrays in their JSON form
+			if val == "" {
+				return nil, nil
+			}
+			var items []interface{}
+			dec := json.NewDecoder(strings.NewReader(val))
+			dec.UseNumber()
+			if err := dec.Decode(&items); err != nil {
+				return nil, fmt.Errorf("expected a JSON array: %w", err)
+			}
+			return coerceArray(d, elem, items)
+		}
+		return nil, fmt.Errorf("expected an array, got %T", v)
+	}
+
+	t = baseType(t)
+
+	var s string
+	switch val := v.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		s = val
+	case json.Number:
+		s = val.String()
+	case float64:
+		s = strconv.FormatFloat(val, 'f', -1, 64)
+	case bool:
+		s = strconv.FormatBool(val)
+	case map[string]interface{}, []interface{}:
+		if t != "JSON" {
+			return nil, fmt.Errorf("cannot store %T in a %s column", v, t)
+		}
+		data, err := json.Marshal(val)
+		if err != nil {
+			return nil, err
+		}
+		if d.postgres() {
+			return spanner.PGJsonB{Value: json.RawMessage(data), Valid: true}, nil
+		}
+		return spanner.NullJSON{Value: json.RawMessage(data), Valid: true}, nil
+	default:
+		return nil, fmt.Errorf("unsupported value %T", v)
+	}
+
+	if t == "STRING" {
+		return s, nil
+	}
+	if _, isString := v.(string); isString && t == "JSON" && !text {
+		data, err := json.Marshal(s)
+		if err != nil {
+			return nil, err
+		}
+		s = string(data)
+	}
+	if s == "" {
+		return nil, nil
+	}
+
+	switch t {
+	case "INT64":
+		return strconv.ParseInt(s, 10, 64)
+	case "FLOAT64":
+		return strconv.ParseFloat(s, 64)
+	case "FLOAT32":
+		f, err := strconv.ParseFloat(s, 32)
+		return float32(f), err
+	case "BOOL":
+		return strconv.ParseBool(s)
+	case "BYTES":
+		return base64.StdEncoding.DecodeString(s)
+	case "TIMESTAMP":
+		return time.Parse(time.RFC3339Nano, s)
+	case "DATE":
+		return civil.ParseDate(s)
+	case "NUMERIC":
+		if d.postgres() {
+			// PostgreSQL NUMERIC also stores NaN, so it travels as text
+			if _, ok := new(big.Rat).SetString(s); !ok && s != "NaN" {
+				return nil, fmt.Errorf("invalid NUMERIC %q", s)
+			}
+			return spanner.PGNumeric{Numeric: s, Valid: true}, nil
+		}
+		r, ok := new(big.Rat).SetString(s)
+		if !ok {
+			return nil, fmt.Errorf("invalid NUMERIC %q", s)
+		}
+		return r, nil
+	case "JSON":
+		if text && !json.Valid([]byte(s)) {
+			// Plain text destined for a JSON column becomes a JSON string
+			data, _ := json.Marshal(s)
+			s = string(data)
+		}
+		if d.postgres() {
+			return spanner.PGJsonB{Value: json.RawMessage(s), Valid: true}, nil
+		}
+		return spanner.NullJSON{Value: json.RawMessage(s), Valid: true}, nil
+	}
+	return nil, fmt.Errorf("unsupported column type %s", spannerType)
+}
+
+// coerceArray builds a typed slice so the client can encode NULL elements.
+// The items are decoded JSON values, even when the array came from a cell.
+func coerceArray(d dialect, elem string, items []interface{}) (interface{}, error) {
+	values := make([]interface{}, len(items))
+	for i, item := range items {
+		v, err := coerceValue(d, elem, item, false)
+		if err != nil {
+			return nil, fmt.Errorf("element %d: %w", i, err)
+		}
+		values[i] = v
+	}
+
+	switch baseType(elem) {
+	case "STRING":
+		out := make([]spanner.NullString, len(values))
+		for i, v := range values {
+			if v != nil {
+				out[i] = spanner.NullString{StringVal: v.(string), Valid: true}
+			}
+		}
+		return out, nil
+	case "INT64":
+		out := make([]spanner.NullInt64, len(values))
+		for i, v := range values {
+			if v != nil {
+				out[i] = spanner.NullInt64{Int64: v.(int64), Valid: true}
+			}
+		}
+		return out, nil
+	case "FLOAT64":
+		out := make([]spanner.NullFloat64, len(values))
+		for i, v := range values {
+			if v != nil {
+				out[i] = spanner.NullFloat64{Float64: v.(float64), Valid: true}
+			}
+		}
+		return out, nil
+	case "FLOAT32":
+		out := make([]spanner.NullFloat32, len(values))
+		for i, v := range values {
+			if v != nil {
+				out[i] = spanner.NullFloat32{Float32: v.(float32), Valid: true}
+			}
+		}
+		return out, nil
+	case "BOOL":
+		out := make([]spanner.NullBool, len(values))
+		for i, v := range values {
+			if v != nil {
+				out[i] = spanner.NullBool{Bool: v.(bool), Valid: true}
+			}
+		}
+		return out, nil
+	case "BYTES":
+		out := make([][]byte, len(values))
+		for i, v := range values {
+			if v != nil {
+				out[i] = v.([]byte)
+			}
+		}
+		return out, nil
+	case "TIMESTAMP":
+		out := make([]spanner.NullTime, len(values))
+		for i, v := range values {
+			if v != nil {
+				out[i] = spanner.NullTime{Time: v.(time.Time), Valid: true}
+			}
+		}
+		return out, nil
+	case "DATE":
+		out := make([]spanner.NullDate, len(values))
+		for i, v := range values {
+			if v != nil {
+				out[i] = spanner.NullDate{Date: v.(civil.Date), Valid: true}
+			}
+		}
+		return out, nil
+	case "NUMERIC":
+		if d.postgres() {
+			out := make([]spanner.PGNumeric, len(values))
+			for i, v := range values {
+				if v != nil {
+					out[i] = v.(spanner.PGNumeric)
+				}
+			}
+			return out, nil
+		}
+		out := make([]spanner.NullNumeric, len(values))
+		for i, v := range values {
+			if v != nil {
+				out[i] = spanner.NullNumeric{Numeric: *v.(*big.Rat), Valid: true}
+			}
+		}
+		return out, nil
+	case "JSON":
+		if d.postgres() {
+			out := make([]spanner.PGJsonB, len(values))
+			for i, v := range values {
+				if v != nil {
+					out[i] = v.(spanner.PGJsonB)
+				}
+			}
+			return out, nil
+		}
+		out := make([]spanner.NullJSON, len(values))
+		for i, v := range values {
+			if v != nil {
+				out[i] = v.(spanner.NullJSON)
+			}
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("unsupported array element type %s", elem)
+}
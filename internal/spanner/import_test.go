@@ -0,0 +1,155 @@
+package spanner
+
+import (
+	"encoding/json"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/civil"
+	"cloud.google.com/go/spanner"
+)
+
+func TestCoerceValue(t *testing.T) {
+	ts := time.Date(2024, 1, 2, 3, 4, 5, 600000000, time.UTC)
+	tests := []struct {
+		name       string
+		dialect    dialect
+		columnType string
+		value      interface{}
+		text       bool
+		want       interface{}
+	}{
+		{"empty STRING stays empty", DialectGoogleSQL, "STRING(MAX)", "", true, ""},
+		{"STRING from a number", DialectGoogleSQL, "STRING(10)", json.Number("5"), false, "5"},
+		{"NULL", DialectGoogleSQL, "STRING(MAX)", nil, false, nil},
+		{"INT64 cell", DialectGoogleSQL, "INT64", "42", true, int64(42)},
+		{"INT64 number", DialectGoogleSQL, "INT64", json.Number("-7"), false, int64(-7)},
+		{"empty INT64 is NULL", DialectGoogleSQL, "INT64", "", true, nil},
+		{"FLOAT64", DialectGoogleSQL, "FLOAT64", json.Number("1.5"), false, 1.5},
+		{"FLOAT32", DialectGoogleSQL, "FLOAT32", "0.25", true, float32(0.25)},
+		{"BOOL cell", DialectGoogleSQL, "BOOL", "true", true, true},
+		{"BOOL value", DialectGoogleSQL, "BOOL", false, false, false},
+		{"BYTES are base64", DialectGoogleSQL, "BYTES(MAX)", "aGk=", true, []byte("hi")},
+		{"TIMESTAMP", DialectGoogleSQL, "TIMESTAMP", "2024-01-02T03:04:05.6Z", true, ts},
+		{"DATE", DialectGoogleSQL, "DATE", "2024-01-02", true, civil.Date{Year: 2024, Month: 1, Day: 2}},
+		{"NUMERIC", DialectGoogleSQL, "NUMERIC", "1.25", true, big.NewRat(5, 4)},
+		{"JSON document cell", DialectGoogleSQL, "JSON", `{"a":1}`, true,
+			spanner.NullJSON{Value: json.RawMessage(`{"a":1}`), Valid: true}},
+		{"JSON cell of plain text", DialectGoogleSQL, "JSON", "hello", true,
+			spanner.NullJSON{Value: json.RawMessage(`"hello"`), Valid: true}},
+		{"JSON cell of a number", DialectGoogleSQL, "JSON", "123", true,
+			spanner.NullJSON{Value: json.RawMessage(`123`), Valid: true}},
+		{"JSON string value stays a string", DialectGoogleSQL, "JSON", "123", false,
+			spanner.NullJSON{Value: json.RawMessage(`"123"`), Valid: true}},
+		{"JSON empty string value", DialectGoogleSQL, "JSON", "", false,
+			spanner.NullJSON{Value: json.RawMessage(`""`), Valid: true}},
+		{"JSON number value", DialectGoogleSQL, "JSON", json.Number("123"), false,
+			spanner.NullJSON{Value: json.RawMessage(`123`), Valid: true}},
+		{"JSON object value", DialectGoogleSQL, "JSON", map[string]interface{}{"a": json.Number("1")}, false,
+			spanner.NullJSON{Value: json.RawMessage(`{"a":1}`), Valid: true}},
+		{"ARRAY cell", DialectGoogleSQL, "ARRAY<INT64>", "[1, null]", true,
+			[]spanner.NullInt64{{Int64: 1, Valid: true}, {}}},
+		{"ARRAY value", DialectGoogleSQL, "ARRAY<STRING(MAX)>", []interface{}{"a", nil}, false,
+			[]spanner.NullString{{StringVal: "a", Valid: true}, {}}},
+		{"ARRAY<JSON> items are JSON values", DialectGoogleSQL, "ARRAY<JSON>", `["123", 4]`, true,
+			[]spanner.NullJSON{{Value: json.RawMessage(`"123"`), Valid: true}, {Value: json.RawMessage(`4`), Valid: true}}},
+		{"ARRAY<DATE>", DialectGoogleSQL, "ARRAY<DATE>", []interface{}{"2024-01-02"}, false,
+			[]spanner.NullDate{{Date: civil.Date{Year: 2024, Month: 1, Day: 2}, Valid: true}}},
+		{"empty ARRAY cell is NULL", DialectGoogleSQL, "ARRAY<INT64>", "", true, nil},
+
+		{"bigint", DialectPostgreSQL, "bigint", "42", true, int64(42)},
+		{"varchar keeps empty", DialectPostgreSQL, "character varying(64)", "", true, ""},
+		{"double precision", DialectPostgreSQL, "double precision", json.Number("2.5"), false, 2.5},
+		{"boolean", DialectPostgreSQL, "boolean", "false", true, false},
+		{"bytea", DialectPostgreSQL, "bytea", "aGk=", true, []byte("hi")},
+		{"timestamptz", DialectPostgreSQL, "timestamp with time zone", "2024-01-02T03:04:05.6Z", true, ts},
+		{"numeric", DialectPostgreSQL, "numeric", json.Number("1.25"), false, spanner.PGNumeric{Numeric: "1.25", Valid: true}},
+		{"numeric NaN", DialectPostgreSQL, "numeric", "NaN", true, spanner.PGNumeric{Numeric: "NaN", Valid: true}},
+		{"jsonb cell", DialectPostgreSQL, "jsonb", `[1]`, true, spanner.PGJsonB{Value: json.RawMessage(`[1]`), Valid: true}},
+		{"jsonb string value stays a string", DialectPostgreSQL, "jsonb", "true", false,
+			spanner.PGJsonB{Value: json.RawMessage(`"true"`), Valid: true}},
+		{"bigint[]", DialectPostgreSQL, "bigint[]", "[3]", true, []spanner.NullInt64{{Int64: 3, Valid: true}}},
+		{"numeric[]", DialectPostgreSQL, "numeric[]", []interface{}{"NaN", nil}, false,
+			[]spanner.PGNumeric{{Numeric: "NaN", Valid: true}, {}}},
+		{"jsonb[]", DialectPostgreSQL, "jsonb[]", []interface{}{"x"}, false,
+			[]spanner.PGJsonB{{Value: json.RawMessage(`"x"`), Valid: true}}},
+	}
+
+	for _, tt := range tests {
+		got, err := coerceValue(tt.dialect, tt.columnType, tt.value, tt.text)
+		if err != nil {
+			t.Errorf("%s: could not coerce: %v", tt.name, err)
+			continue
+		}
+		if want, ok := tt.want.(*big.Rat); ok {
+			if r, isRat := got.(*big.Rat); !isRat || r.Cmp(want) != 0 {
+				t.Errorf("%s: expected %v, got %#v", tt.name, want, got)
+			}
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: expected %#v, got %#v", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestCoerceValueErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		dialect    dialect
+		columnType string
+		value      interface{}
+		want       string
+	}{
+		{"not an integer", DialectGoogleSQL, "INT64", "x", "invalid syntax"},
+		{"object in a scalar column", DialectGoogleSQL, "STRING(MAX)", map[string]interface{}{}, "cannot store"},
+		{"scalar in an array column", DialectGoogleSQL, "ARRAY<INT64>", json.Number("1"), "expected an array"},
+		{"array cell that is not JSON", DialectGoogleSQL, "ARRAY<INT64>", "1,2", "expected a JSON array"},
+		{"bad element", DialectGoogleSQL, "ARRAY<DATE>", []interface{}{"2024-13-01"}, "element 0"},
+		{"bad NUMERIC", DialectGoogleSQL, "NUMERIC", "NaN", "invalid NUMERIC"},
+		{"bad numeric", DialectPostgreSQL, "numeric", "abc", "invalid NUMERIC"},
+		{"unknown type", DialectGoogleSQL, "PROTO<x.Y>", "a", "unsupported column type"},
+	}
+
+	for _, tt := range tests {
+		got, err := coerceValue(tt.dialect, tt.columnType, tt.value, true)
+		if err == nil {
+			t.Errorf("%s: expected an error, got %#v", tt.name, got)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected an error about %q, got %v", tt.name, tt.want, err)
+		}
+	}
+}
+
+func TestImportMutation(t *testing.T) {
+	tests := []struct {
+		mode string
+		want mutationFunc
+	}{
+		{"", spanner.InsertOrUpdateMap},
+		{ImportUpsert, spanner.InsertOrUpdateMap},
+		{ImportInsert, spanner.InsertMap},
+		{ImportReplace, spanner.ReplaceMap},
+	}
+	for _, tt := range tests {
+		got, err := importMutation(tt.mode)
+		if err != nil {
+			t.Errorf("%q: %v", tt.mode, err)
+			continue
+		}
+		if reflect.ValueOf(got).Pointer() != reflect.ValueOf(tt.want).Pointer() {
+			t.Errorf("%q: wrong mutation", tt.mode)
+		}
+	}
+
+	for _, mode := range []string{"update", "Upsert", "insert "} {
+		if _, err := importMutation(mode); err == nil || !strings.Contains(err.Error(), "unknown import mode") {
+			t.Errorf("%q: expected an unknown mode error, got %v", mode, err)
+		}
+	}
+}
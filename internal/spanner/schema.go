@@ -0,0 +1,395 @@
+package spanner
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+	"cloudevents-explorer/internal/types"
+	"google.golang.org/api/iterator"
+)
+
+// GetDatabaseSchema returns every table with its keys, indexes and
+// constraints, along with the database's change streams
+func GetDatabaseSchema(req types.ConnectionRequest) (*types.DatabaseSchema, error) {
+	ctx := context.Background()
+	client, d, err := openDatabase(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	return loadSchema(ctx, client, d, "")
+}
+
+// loadSchema describes one table, or all tables when table is empty. Each
+// part of the schema comes from one information_schema query filtered by the
+// @table parameter, so describing the whole database costs the same number
+// of round trips as describing a single table.
+func loadSchema(ctx context.Context, client *spanner.Client, d dialect, table string) (*types.DatabaseSchema, error) {
+	tables, err := loadTables(ctx, client, d, table)
+	if err != nil {
+		return nil, err
+	}
+
+	byName := make(map[string]*types.TableInfo, len(tables))
+	for i := range tables {
+		byName[tables[i].Name] = &tables[i]
+	}
+
+	loaders := []func(context.Context, *spanner.Client, dialect, string, map[string]*types.TableInfo) error{
+		loadColumns,
+		loadIndexes,
+		loadForeignKeys,
+		loadCheckConstraints,
+	}
+	for _, load := range loaders {
+		if err := load(ctx, client, d, table, byName); err != nil {
+			return nil, err
+		}
+	}
+
+	streams, err := loadChangeStreams(ctx, client, d, byName)
+	if err != nil {
+		return nil, err
+	}
+
+	schema := &types.DatabaseSchema{Dialect: string(d), Tables: tables}
+	for _, cs := range streams {
+		if table == "" || cs.All || watchesTable(cs, table) {
+			schema.ChangeStreams = append(schema.ChangeStreams, cs)
+		}
+	}
+	return schema, nil
+}
+
+// queryEach runs an information_schema query with the @schema and @table
+// filters and calls fn for every row
+func queryEach(ctx context.Context, client *spanner.Client, d dialect, sql, table string, fn func(*spanner.Row) error) error {
+	stmt := d.statement(sql, map[string]interface{}{"schema": d.schema(), "table": table})
+
+	iter := client.Single().Query(ctx, stmt)
+	defer iter.Stop()
+
+	for {
+		row, err := iter.Next()
+		if err == iterator.Done {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+}
+
+// loadTables returns the tables and views in the default schema, with the
+// parent of each interleaved table
+func loadTables(ctx context.Context, client *spanner.Client, d dialect, table string) ([]types.TableInfo, error) {
+	query := `
+		SELECT table_name, table_type, parent_table_name, on_delete_action
+		FROM information_schema.tables
+		WHERE table_schema = @schema AND (@table = '' OR table_name = @table)
+		ORDER BY table_name
+	`
+
+	var tables []types.TableInfo
+	err := queryEach(ctx, client, d, query, table, func(row *spanner.Row) error {
+		var name, tableType string
+		var parent, onDelete spanner.NullString
+		if err := row.Columns(&name, &tableType, &parent, &onDelete); err != nil {
+			return err
+		}
+		tables = append(tables, types.TableInfo{
+			Name:           name,
+			Type:           tableType,
+			ParentTable:    parent.StringVal,
+			OnDeleteAction: onDelete.StringVal,
+		})
+		return nil
+	})
+	return tables, err
+}
+
+func loadColumns(ctx context.Context, client *spanner.Client, d dialect, table string, tables map[string]*types.TableInfo) error {
+	query := `
+		SELECT table_name, column_name, spanner_type, is_nullable,
+			generation_expression, is_stored, column_default
+		FROM information_schema.columns
+		WHERE table_schema = @schema AND (@table = '' OR table_name = @table)
+		ORDER BY table_name, ordinal_position
+	`
+
+	return queryEach(ctx, client, d, query, table, func(row *spanner.Row) error {
+		var tableName, name, colType string
+		var generated spanner.NullString
+		if err := row.Columns(&tableName, &name, &colType, nil, &generated, nil, nil); err != nil {
+			return err
+		}
+		t := tables[tableName]
+		if t == nil {
+			return nil
+		}
+		nullable, err := columnFlag(row, 3)
+		if err != nil {
+			return err
+		}
+		stored, err := columnFlag(row, 5)
+		if err != nil {
+			return err
+		}
+		// column_default is BYTES in GoogleSQL and text in PostgreSQL
+		defaultValue, err := columnText(row, 6)
+		if err != nil {
+			return err
+		}
+		t.Columns = append(t.Columns, types.ColumnInfo{
+			Name:                 name,
+			Type:                 colType,
+			IsNullable:           nullable,
+			GenerationExpression: generated.StringVal,
+			IsStored:             stored,
+			DefaultValue:         defaultValue,
+		})
+		return nil
+	})
+}
+
+// loadIndexes fills in each table's primary key and secondary indexes.
+// STORING columns have no ordinal position, so they sort first within their
+// index.
+func loadIndexes(ctx context.Context, client *spanner.Client, d dialect, table string, tables map[string]*types.TableInfo) error {
+	query := `
+		SELECT i.table_name, i.index_name, i.index_type, i.parent_table_name,
+			i.is_unique, i.is_null_filtered,
+			ic.column_name, ic.ordinal_position, ic.column_ordering
+		FROM information_schema.indexes AS i
+		JOIN information_schema.index_columns AS ic
+			ON ic.table_schema = i.table_schema AND ic.table_name = i.table_name
+			AND ic.index_name = i.index_name AND ic.index_type = i.index_type
+		WHERE i.table_schema = @schema AND i.index_type IN ('PRIMARY_KEY', 'INDEX')
+			AND (@table = '' OR i.table_name = @table)
+		ORDER BY i.table_name, i.index_type, i.index_name, ic.ordinal_position
+	`
+
+	return queryEach(ctx, client, d, query, table, func(row *spanner.Row) error {
+		var tableName, indexName, indexType, column string
+		var parent, ordering spanner.NullString
+		var position spanner.NullInt64
+		if err := row.Columns(&tableName, &indexName, &indexType, &parent,
+			nil, nil, &column, &position, &ordering); err != nil {
+			return err
+		}
+		unique, err := columnFlag(row, 4)
+		if err != nil {
+			return err
+		}
+		nullFiltered, err := columnFlag(row, 5)
+		if err != nil {
+			return err
+		}
+		t := tables[tableName]
+		if t == nil {
+			return nil
+		}
+
+		if indexType == "PRIMARY_KEY" {
+			t.PrimaryKey = append(t.PrimaryKey, column)
+			for i := range t.Columns {
+				if t.Columns[i].Name == column {
+					t.Columns[i].IsPrimaryKey = true
+				}
+			}
+			return nil
+		}
+
+		if n := len(t.Indexes); n == 0 || t.Indexes[n-1].Name != indexName {
+			t.Indexes = append(t.Indexes, types.IndexInfo{
+				Name:           indexName,
+				Columns:        []types.IndexColumnInfo{},
+				IsUnique:       unique,
+				IsNullFiltered: nullFiltered,
+				ParentTable:    parent.StringVal,
+			})
+		}
+		index := &t.Indexes[len(t.Indexes)-1]
+		if position.Valid {
+			index.Columns = append(index.Columns, types.IndexColumnInfo{Name: column, Ordering: ordering.StringVal})
+		} else {
+			index.Storing = append(index.Storing, column)
+		}
+		return nil
+	})
+}
+
+// loadForeignKeys fills in the foreign keys declared on each table. The
+// referenced columns come from the unique constraint the foreign key points
+// at, matched position by position.
+func loadForeignKeys(ctx context.Context, client *spanner.Client, d dialect, table string, tables map[string]*types.TableInfo) error {
+	query := `
+		SELECT kcu.table_name, rc.constraint_name, kcu.column_name,
+			ref.table_name, ref.column_name, rc.delete_rule
+		FROM information_schema.referential_constraints AS rc
+		JOIN information_schema.key_column_usage AS kcu
+			ON kcu.constraint_schema = rc.constraint_schema AND kcu.constraint_name = rc.constraint_name
+		JOIN information_schema.key_column_usage AS ref
+			ON ref.constraint_schema = rc.unique_constraint_schema AND ref.constraint_name = rc.unique_constraint_name
+			AND ref.ordinal_position = kcu.position_in_unique_constraint
+		WHERE rc.constraint_schema = @schema AND (@table = '' OR kcu.table_name = @table)
+		ORDER BY kcu.table_name, rc.constraint_name, kcu.ordinal_position
+	`
+
+	return queryEach(ctx, client, d, query, table, func(row *spanner.Row) error {
+		var tableName, name, column, refTable, refColumn string
+		var deleteRule spanner.NullString
+		if err := row.Columns(&tableName, &name, &column, &refTable, &refColumn, &deleteRule); err != nil {
+			return err
+		}
+		t := tables[tableName]
+		if t == nil {
+			return nil
+		}
+
+		if n := len(t.ForeignKeys); n == 0 || t.ForeignKeys[n-1].Name != name {
+			t.ForeignKeys = append(t.ForeignKeys, types.ForeignKeyInfo{
+				Name:            name,
+				ReferencedTable: refTable,
+				OnDelete:        deleteRule.StringVal,
+			})
+		}
+		fk := &t.ForeignKeys[len(t.ForeignKeys)-1]
+		fk.Columns = append(fk.Columns, column)
+		fk.ReferencedColumns = append(fk.ReferencedColumns, refColumn)
+		return nil
+	})
+}
+
+// loadCheckConstraints fills in each table's CHECK constraints, leaving out
+// the ones Spanner creates for NOT NULL columns
+func loadCheckConstraints(ctx context.Context, client *spanner.Client, d dialect, table string, tables map[string]*types.TableInfo) error {
+	query := `
+		SELECT tc.table_name, cc.constraint_name, cc.check_clause
+		FROM information_schema.table_constraints AS tc
+		JOIN information_schema.check_constraints AS cc
+			ON cc.constraint_schema = tc.constraint_schema AND cc.constraint_name = tc.constraint_name
+		WHERE tc.table_schema = @schema AND tc.constraint_type = 'CHECK'
+			AND cc.constraint_name NOT LIKE 'CK_IS_NOT_NULL_%'
+			AND (@table = '' OR tc.table_name = @table)
+		ORDER BY tc.table_name, cc.constraint_name
+	`
+
+	return queryEach(ctx, client, d, query, table, func(row *spanner.Row) error {
+		var tableName, name, clause string
+		if err := row.Columns(&tableName, &name, &clause); err != nil {
+			return err
+		}
+		if t := tables[tableName]; t != nil {
+			t.CheckConstraints = append(t.CheckConstraints, types.CheckConstraintInfo{Name: name, Expression: clause})
+		}
+		return nil
+	})
+}
+
+// countRows fills in the row count of a base table. It scans the whole
+// table, so it is only done when a single table is asked for.
+func countRows(ctx context.Context, client *spanner.Client, d dialect, table *types.TableInfo) error {
+	if table.Type == "VIEW" {
+		return nil
+	}
+
+	stmt := spanner.Statement{SQL: "SELECT COUNT(*) FROM " + d.quoteIdentifier(table.Name)}
+	iter := client.Single().Query(ctx, stmt)
+	defer iter.Stop()
+
+	row, err := iter.Next()
+	if err != nil {
+		return err
+	}
+	var count int64
+	if err := row.Columns(&count); err != nil {
+		return err
+	}
+	table.RowCount = &count
+	return nil
+}
+
+// loadChangeStreams returns every change stream in the database and records
+// on each watched table which streams cover it
+func loadChangeStreams(ctx context.Context, client *spanner.Client, d dialect, tables map[string]*types.TableInfo) ([]types.ChangeStreamInfo, error) {
+	// ALL is a reserved word, so the column needs quoting
+	query := "SELECT change_stream_name, " + d.quoteIdentifier("all") +
+		" FROM information_schema.change_streams ORDER BY change_stream_name"
+
+	var streams []types.ChangeStreamInfo
+	index := map[string]int{}
+	err := queryEach(ctx, client, d, query, "", func(row *spanner.Row) error {
+		var name string
+		if err := row.Columns(&name, nil); err != nil {
+			return err
+		}
+		all, err := columnFlag(row, 1)
+		if err != nil {
+			return err
+		}
+		index[name] = len(streams)
+		streams = append(streams, types.ChangeStreamInfo{Name: name, All: all})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	query = `
+		SELECT t.change_stream_name, t.table_name, t.all_columns, c.column_name
+		FROM information_schema.change_stream_tables AS t
+		LEFT JOIN information_schema.change_stream_columns AS c
+			ON c.change_stream_name = t.change_stream_name AND c.table_name = t.table_name
+		ORDER BY t.change_stream_name, t.table_name, c.column_name
+	`
+	err = queryEach(ctx, client, d, query, "", func(row *spanner.Row) error {
+		var streamName, tableName string
+		var column spanner.NullString
+		if err := row.Columns(&streamName, &tableName, nil, &column); err != nil {
+			return err
+		}
+		allColumns, err := columnFlag(row, 2)
+		if err != nil {
+			return err
+		}
+		i, ok := index[streamName]
+		if !ok {
+			return nil
+		}
+		cs := &streams[i]
+		if n := len(cs.Tables); n == 0 || cs.Tables[n-1].Table != tableName {
+			cs.Tables = append(cs.Tables, types.ChangeStreamTableInfo{Table: tableName, AllColumns: allColumns})
+		}
+		if column.Valid {
+			watched := &cs.Tables[len(cs.Tables)-1]
+			watched.Columns = append(watched.Columns, column.StringVal)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, cs := range streams {
+		for name, t := range tables {
+			if t.Type != "VIEW" && (cs.All || watchesTable(cs, name)) {
+				t.ChangeStreams = append(t.ChangeStreams, cs.Name)
+			}
+		}
+	}
+	return streams, nil
+}
+
+func watchesTable(cs types.ChangeStreamInfo, table string) bool {
+	for _, t := range cs.Tables {
+		if t.Table == table {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,185 @@
+package spanner
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/spanner"
+	"cloudevents-explorer/internal/types"
+)
+
+var thenReturnPattern = regexp.MustCompile(`(?i)\bTHEN\s+RETURN\b`)
+
+// statementRunner is implemented by both kinds of read-write transaction:
+// the retried closure form and the explicit begin/commit form
+type statementRunner interface {
+	Query(ctx context.Context, statement spanner.Statement) *spanner.RowIterator
+	Update(ctx context.Context, stmt spanner.Statement) (int64, error)
+}
+
+// splitStatements splits a script on semicolons, ignoring those inside
+// string literals, quoted identifiers and comments as the dialect writes
+// them. Empty statements and comment-only fragments are dropped.
+func (d dialect) splitStatements(script string) []string {
+	var statements []string
+	var current strings.Builder
+
+	flush := func() {
+		stmt := strings.TrimSpace(current.String())
+		if leadingKeyword(stmt) != "" {
+			statements = append(statements, stmt)
+		}
+		current.Reset()
+	}
+
+	for _, span := range d.lex(script) {
+		if !span.code {
+			current.WriteString(span.text)
+			continue
+		}
+		parts := strings.Split(span.text, ";")
+		for i, part := range parts {
+			if i > 0 {
+				flush()
+			}
+			current.WriteString(part)
+		}
+	}
+	flush()
+
+	return statements
+}
+
+// leadingKeyword returns the first keyword of a statement in upper case,
+// skipping whitespace, comments and statement hints. It is empty only for
+// statements with no SQL at all.
+func leadingKeyword(stmt string) string {
+	s := stmt
+	for {
+		s = strings.TrimSpace(s)
+		switch {
+		case s == "":
+			return ""
+		case strings.HasPrefix(s, "@{"):
+			end := strings.IndexByte(s, '}')
+			if end == -1 {
+				return "@"
+			}
+			s = s[end+1:]
+		case strings.HasPrefix(s, "--"), strings.HasPrefix(s, "#"):
+			end := strings.IndexByte(s, '\n')
+			if end == -1 {
+				return ""
+			}
+			s = s[end+1:]
+		case strings.HasPrefix(s, "/*"):
+			end := strings.Index(s, "*/")
+			if end == -1 {
+				return ""
+			}
+			s = s[end+2:]
+		default:
+			end := strings.IndexFunc(s, func(r rune) bool {
+				return !(r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z')
+			})
+			if end == -1 {
+				end = len(s)
+			}
+			if end == 0 {
+				// Not a keyword, e.g. a parenthesized query
+				return s[:1]
+			}
+			return strings.ToUpper(s[:end])
+		}
+	}
+}
+
+// isDML reports whether a statement modifies data
+func isDML(stmt string) bool {
+	switch leadingKeyword(stmt) {
+	case "INSERT", "UPDATE", "DELETE":
+		return true
+	}
+	return false
+}
+
+// runStatements executes statements in order inside txn. DML reports its
+// affected row count; queries and DML with THEN RETURN (RETURNING in
+// PostgreSQL) also return rows. On
+// error the results so far are returned, so the failing statement is the one
+// after the last result.
+func runStatements(ctx context.Context, txn statementRunner, d dialect, statements []string) ([]types.StatementResult, error) {
+	results := make([]types.StatementResult, 0, len(statements))
+
+	for _, sql := range statements {
+		result := types.StatementResult{SQL: sql}
+		stmt := spanner.Statement{SQL: sql}
+
+		if isDML(sql) && !d.returnsRows(sql) {
+			count, err := txn.Update(ctx, stmt)
+			if err != nil {
+				return results, err
+			}
+			result.RowsAffected = count
+		} else {
+			iter := txn.Query(ctx, stmt)
+			columns, rows, err := readRows(iter)
+			iter.Stop()
+			if err != nil {
+				return results, err
+			}
+			result.Columns = columns
+			result.Rows = rows
+			if isDML(sql) {
+				result.RowsAffected = iter.RowCount
+			} else {
+				result.RowsAffected = int64(len(rows))
+			}
+		}
+
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// scriptResponse builds the query response for a script. The last statement
+// that returned rows fills Columns and Rows; when none did, they hold a
+// summary of rows affected per statement.
+func scriptResponse(results []types.StatementResult, err error, elapsed time.Duration) types.QueryResponse {
+	if err != nil {
+		return types.QueryResponse{
+			Error:         fmt.Sprintf("Statement %d failed: %v", len(results)+1, err),
+			ExecutionTime: elapsed.String(),
+		}
+	}
+
+	resp := types.QueryResponse{
+		Statements:    results,
+		ExecutionTime: elapsed.String(),
+	}
+
+	for i := len(results) - 1; i >= 0; i-- {
+		if results[i].Columns != nil {
+			resp.Columns = results[i].Columns
+			resp.Rows = results[i].Rows
+			break
+		}
+	}
+
+	if resp.Columns == nil {
+		resp.Columns = []string{"Statement", "Rows Affected"}
+		for _, r := range results {
+			resp.Rows = append(resp.Rows, map[string]interface{}{
+				"Statement":     r.SQL,
+				"Rows Affected": r.RowsAffected,
+			})
+		}
+	}
+
+	resp.RowCount = len(resp.Rows)
+	return resp
+}
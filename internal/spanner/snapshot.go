@@ -0,0 +1,185 @@
+package spanner
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"cloud.google.com/go/spanner"
+
+	"cloudevents-explorer/internal/config"
+	"cloudevents-explorer/internal/jsondiff"
+	"cloudevents-explorer/internal/types"
+)
+
+// CaptureSnapshot reads the requested tables and queries into a snapshot,
+// over one client. Tables are keyed by their primary key; queries by the key
+// columns given with them.
+func CaptureSnapshot(req types.SnapshotRequest) (*config.Snapshot, error) {
+	snapshot := &config.Snapshot{
+		Name:      req.Name,
+		Database:  databasePath(req.ConnectionRequest),
+		CreatedAt: time.Now().UTC().Format(time.RFC3339),
+	}
+
+	ctx := context.Background()
+	client, d, err := openDatabase(ctx, req.ConnectionRequest)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	for _, table := range req.Tables {
+		info, err := editableTable(ctx, client, d, table)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", table, err)
+		}
+		captured, err := captureQuery(ctx, client, info.Name, "SELECT * FROM "+d.quoteIdentifier(info.Name), info.PrimaryKey)
+		if err != nil {
+			return nil, err
+		}
+		snapshot.Tables = append(snapshot.Tables, captured)
+	}
+
+	for i, q := range req.Queries {
+		name := q.Name
+		if name == "" {
+			name = fmt.Sprintf("Query %d", i+1)
+		}
+		statements := d.splitStatements(q.SQL)
+		if len(statements) != 1 || isDML(statements[0]) {
+			return nil, fmt.Errorf("%s: snapshot queries must be a single read-only query", name)
+		}
+		captured, err := captureQuery(ctx, client, name, statements[0], q.KeyColumns)
+		if err != nil {
+			return nil, err
+		}
+		snapshot.Tables = append(snapshot.Tables, captured)
+	}
+
+	return snapshot, nil
+}
+
+// RecaptureSnapshot runs the queries of a saved snapshot again, giving the
+// current state of the same tables to compare it with
+func RecaptureSnapshot(conn types.ConnectionRequest, saved *config.Snapshot) (*config.Snapshot, error) {
+	current := &config.Snapshot{
+		Name:      "current",
+		Database:  saved.Database,
+		CreatedAt: time.Now().UTC().Format(time.RFC3339),
+	}
+
+	ctx := context.Background()
+	client, err := openClient(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	for _, t := range saved.Tables {
+		captured, err := captureQuery(ctx, client, t.Name, t.Query, t.KeyColumns)
+		if err != nil {
+			return nil, err
+		}
+		current.Tables = append(current.Tables, captured)
+	}
+
+	return current, nil
+}
+
+// captureQuery runs one read-only query of a snapshot as a strong read
+func captureQuery(ctx context.Context, client *spanner.Client, name, sql string, keyColumns []string) (config.SnapshotTable, error) {
+	iter := client.Single().Query(ctx, spanner.Statement{SQL: sql})
+	defer iter.Stop()
+
+	columns, rows, err := readRows(iter)
+	if err != nil {
+		return config.SnapshotTable{}, fmt.Errorf("%s: %w", name, err)
+	}
+
+	for _, key := range keyColumns {
+		if !slices.Contains(columns, key) {
+			return config.SnapshotTable{}, fmt.Errorf("%s: key column %s is not in the result", name, key)
+		}
+	}
+
+	if rows == nil {
+		rows = []map[string]interface{}{}
+	}
+
+	return config.SnapshotTable{
+		Name:       name,
+		Query:      sql,
+		KeyColumns: keyColumns,
+		Columns:    columns,
+		Rows:       rows,
+	}, nil
+}
+
+// DiffSnapshots compares two snapshots table by table, in the order the
+// tables appear in before followed by any only in after
+func DiffSnapshots(before, after *config.Snapshot) types.SnapshotDiff {
+	diff := types.SnapshotDiff{Before: before.Name, After: after.Name, Tables: []types.TableDiff{}}
+
+	afterTables := make(map[string]*config.SnapshotTable, len(after.Tables))
+	for i := range after.Tables {
+		afterTables[after.Tables[i].Name] = &after.Tables[i]
+	}
+
+	seen := map[string]bool{}
+	for i := range before.Tables {
+		b := &before.Tables[i]
+		seen[b.Name] = true
+		diff.Tables = append(diff.Tables, diffTable(b.Name, b, afterTables[b.Name]))
+	}
+	for i := range after.Tables {
+		a := &after.Tables[i]
+		if !seen[a.Name] {
+			diff.Tables = append(diff.Tables, diffTable(a.Name, nil, a))
+		}
+	}
+
+	return diff
+}
+
+// diffTable compares a table or query result of two snapshots. Tables in
+// only one of them are all added or all removed.
+func diffTable(name string, before, after *config.SnapshotTable) types.TableDiff {
+	d := types.TableDiff{
+		Name:    name,
+		Added:   []map[string]interface{}{},
+		Removed: []map[string]interface{}{},
+		Changed: []types.RowDiff{},
+	}
+
+	switch {
+	case before == nil:
+		d.Note = "Only in the after snapshot"
+		d.Added = append(d.Added, after.Rows...)
+		return d
+	case after == nil:
+		d.Note = "Only in the before snapshot"
+		d.Removed = append(d.Removed, before.Rows...)
+		return d
+	}
+
+	d.KeyColumns = before.KeyColumns
+	if !slices.Equal(before.KeyColumns, after.KeyColumns) {
+		d.KeyColumns = nil
+		d.Note = "Key columns differ between the snapshots, so rows are matched by their full contents"
+	} else if len(d.KeyColumns) == 0 {
+		d.Note = "No key columns, so rows are matched by their full contents"
+	}
+
+	rows := jsondiff.DiffRows(
+		jsondiff.Table{Columns: before.Columns, Rows: before.Rows},
+		jsondiff.Table{Columns: after.Columns, Rows: after.Rows}, d.KeyColumns, 0)
+	d.Added = append(d.Added, rows.Added...)
+	d.Removed = append(d.Removed, rows.Removed...)
+	for _, c := range rows.Changed {
+		d.Changed = append(d.Changed, types.RowDiff(c))
+	}
+	d.Unchanged = rows.Unchanged
+	return d
+}
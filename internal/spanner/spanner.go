@@ -5,7 +5,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
-	"strings"
 	"time"
 
 	"cloud.google.com/go/spanner"
@@ -13,18 +12,24 @@ import (
 	"google.golang.org/api/iterator"
 )
 
-// TestConnection tests the connection to Spanner emulator
-func TestConnection(req types.ConnectionRequest) types.ConnectionResponse {
-	// Set emulator host environment variable
+// openClient points the client library at the emulator, when one is set,
+// and opens a client for the database described by req
+func openClient(ctx context.Context, req types.ConnectionRequest) (*spanner.Client, error) {
 	if req.EmulatorHost != "" {
 		os.Setenv("SPANNER_EMULATOR_HOST", req.EmulatorHost)
 	}
 
-	ctx := context.Background()
-	dbPath := fmt.Sprintf("projects/%s/instances/%s/databases/%s",
-		req.ProjectID, req.InstanceID, req.DatabaseID)
+	client, err := spanner.NewClient(ctx, databasePath(req))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client: %w", err)
+	}
+	return client, nil
+}
 
-	client, err := spanner.NewClient(ctx, dbPath)
+// TestConnection tests the connection to Spanner emulator
+func TestConnection(req types.ConnectionRequest) types.ConnectionResponse {
+	ctx := context.Background()
+	client, err := openClient(ctx, req)
 	if err != nil {
 		return types.ConnectionResponse{
 			Success: false,
@@ -48,180 +53,155 @@ func TestConnection(req types.ConnectionRequest) types.ConnectionResponse {
 		}
 	}
 
+	d, err := detectDialect(ctx, client, databasePath(req))
+	if err != nil {
+		return types.ConnectionResponse{
+			Success: false,
+			Message: "Connection failed",
+			Error:   err.Error(),
+		}
+	}
+
 	return types.ConnectionResponse{
 		Success: true,
-		Message: fmt.Sprintf("Successfully connected to %s", dbPath),
+		Message: fmt.Sprintf("Successfully connected to %s", databasePath(req)),
+		Dialect: string(d),
 	}
 }
 
-// ListTables returns all tables in the database
+// ListTables returns all tables in the database. Rows are not counted,
+// since that scans every table; CountRows counts one on request.
 func ListTables(req types.ConnectionRequest) ([]types.TableInfo, error) {
-	if req.EmulatorHost != "" {
-		os.Setenv("SPANNER_EMULATOR_HOST", req.EmulatorHost)
-	}
-
 	ctx := context.Background()
-	dbPath := fmt.Sprintf("projects/%s/instances/%s/databases/%s",
-		req.ProjectID, req.InstanceID, req.DatabaseID)
-
-	client, err := spanner.NewClient(ctx, dbPath)
+	client, d, err := openDatabase(ctx, req)
 	if err != nil {
 		return nil, err
 	}
 	defer client.Close()
 
-	query := `
-		SELECT table_name
-		FROM information_schema.tables
-		WHERE table_schema = ''
-		ORDER BY table_name
-	`
-
-	stmt := spanner.Statement{SQL: query}
-	iter := client.Single().Query(ctx, stmt)
-	defer iter.Stop()
-
-	var tables []types.TableInfo
-	for {
-		row, err := iter.Next()
-		if err == iterator.Done {
-			break
-		}
-		if err != nil {
-			return nil, err
-		}
-
-		var tableName string
-		if err := row.Columns(&tableName); err != nil {
-			return nil, err
-		}
+	return loadTables(ctx, client, d, "")
+}
 
-		tables = append(tables, types.TableInfo{Name: tableName})
+// CountRows returns the number of rows in a table
+func CountRows(req types.ConnectionRequest, tableName string) (int64, error) {
+	ctx := context.Background()
+	client, d, err := openDatabase(ctx, req)
+	if err != nil {
+		return 0, err
 	}
+	defer client.Close()
 
-	return tables, nil
+	table := &types.TableInfo{Name: tableName}
+	if err := countRows(ctx, client, d, table); err != nil {
+		return 0, err
+	}
+	return *table.RowCount, nil
 }
 
-// GetTableSchema returns the schema for a specific table
+// GetTableSchema returns the schema for a specific table: columns, primary
+// key, interleaving parent, indexes, foreign keys, check constraints, the
+// change streams watching it and its row count
 func GetTableSchema(req types.ConnectionRequest, tableName string) (*types.TableInfo, error) {
-	if req.EmulatorHost != "" {
-		os.Setenv("SPANNER_EMULATOR_HOST", req.EmulatorHost)
-	}
-
 	ctx := context.Background()
-	dbPath := fmt.Sprintf("projects/%s/instances/%s/databases/%s",
-		req.ProjectID, req.InstanceID, req.DatabaseID)
-
-	client, err := spanner.NewClient(ctx, dbPath)
+	client, d, err := openDatabase(ctx, req)
 	if err != nil {
 		return nil, err
 	}
 	defer client.Close()
 
-	query := `
-		SELECT
-			column_name,
-			spanner_type,
-			is_nullable
-		FROM information_schema.columns
-		WHERE table_name = @tableName
-		ORDER BY ordinal_position
-	`
-
-	stmt := spanner.Statement{
-		SQL: query,
-		Params: map[string]interface{}{
-			"tableName": tableName,
-		},
+	schema, err := loadSchema(ctx, client, d, tableName)
+	if err != nil {
+		return nil, err
+	}
+	if len(schema.Tables) == 0 {
+		return nil, fmt.Errorf("table %s not found", tableName)
+	}
+	if err := countRows(ctx, client, d, &schema.Tables[0]); err != nil {
+		return nil, err
 	}
 
-	iter := client.Single().Query(ctx, stmt)
-	defer iter.Stop()
-
-	var columns []types.ColumnInfo
-	for {
-		row, err := iter.Next()
-		if err == iterator.Done {
-			break
-		}
-		if err != nil {
-			return nil, err
-		}
+	return &schema.Tables[0], nil
+}
 
-		var colName, colType, nullable string
-		if err := row.Columns(&colName, &colType, &nullable); err != nil {
-			return nil, err
-		}
+// ExecuteQuery executes a SQL query and returns results. A single SELECT runs
+// as a strong read; scripts with several statements or any DML run together
+// in one read-write transaction, or in the open transaction named by
+// req.TransactionID.
+func ExecuteQuery(req types.QueryRequest) types.QueryResponse {
+	d, err := lookupDialect(req.Connection())
+	if err != nil {
+		return types.QueryResponse{Error: err.Error()}
+	}
+	statements := d.splitStatements(req.Query)
+	if len(statements) == 0 {
+		return types.QueryResponse{Error: "No SQL statements to execute"}
+	}
 
-		columns = append(columns, types.ColumnInfo{
-			Name:       colName,
-			Type:       colType,
-			IsNullable: nullable == "YES",
-		})
+	readOnly := len(statements) == 1 && !isDML(statements[0])
+	if !isStrong(req.TimestampBound) && (!readOnly || req.TransactionID != "") {
+		return types.QueryResponse{Error: "Stale and point-in-time reads only apply to a single read-only query outside a transaction"}
 	}
 
-	return &types.TableInfo{
-		Name:    tableName,
-		Columns: columns,
-	}, nil
-}
+	bound, err := timestampBound(req.TimestampBound)
+	if err != nil {
+		return types.QueryResponse{Error: err.Error()}
+	}
 
-// ExecuteQuery executes a SQL query and returns results
-func ExecuteQuery(req types.QueryRequest) types.QueryResponse {
-	if req.EmulatorHost != "" {
-		os.Setenv("SPANNER_EMULATOR_HOST", req.EmulatorHost)
+	if req.TransactionID != "" {
+		return executeInTransaction(req.TransactionID, statements)
 	}
 
 	ctx := context.Background()
-	dbPath := fmt.Sprintf("projects/%s/instances/%s/databases/%s",
-		req.ProjectID, req.InstanceID, req.DatabaseID)
-
-	client, err := spanner.NewClient(ctx, dbPath)
+	client, err := openClient(ctx, req.Connection())
 	if err != nil {
-		return types.QueryResponse{
-			Error: fmt.Sprintf("Failed to create client: %v", err),
-		}
+		return types.QueryResponse{Error: err.Error()}
 	}
 	defer client.Close()
 
 	startTime := time.Now()
 
-	// Detect if this is a DML statement
-	queryUpper := strings.ToUpper(strings.TrimSpace(req.Query))
-	isDML := strings.HasPrefix(queryUpper, "INSERT") ||
-		strings.HasPrefix(queryUpper, "UPDATE") ||
-		strings.HasPrefix(queryUpper, "DELETE")
-
-	if isDML {
-		// Execute DML
+	if !readOnly {
+		var results []types.StatementResult
 		_, err := client.ReadWriteTransaction(ctx, func(ctx context.Context, txn *spanner.ReadWriteTransaction) error {
-			stmt := spanner.Statement{SQL: req.Query}
-			_, err := txn.Update(ctx, stmt)
+			// The function is retried if Spanner aborts the transaction
+			var err error
+			results, err = runStatements(ctx, txn, d, statements)
 			return err
 		})
 
-		executionTime := time.Since(startTime).String()
+		return scriptResponse(results, err, time.Since(startTime))
+	}
 
-		if err != nil {
-			return types.QueryResponse{
-				Error:         err.Error(),
-				ExecutionTime: executionTime,
-			}
-		}
+	// Execute SELECT query
+	stmt := spanner.Statement{SQL: statements[0]}
+	ro := client.Single().WithTimestampBound(bound)
+	iter := ro.Query(ctx, stmt)
+	defer iter.Stop()
 
+	columns, rows, err := readRows(iter)
+	if err != nil {
 		return types.QueryResponse{
-			Columns:       []string{"Status"},
-			Rows:          []map[string]interface{}{{"Status": "DML executed successfully"}},
-			RowCount:      1,
-			ExecutionTime: executionTime,
+			Error:         err.Error(),
+			ExecutionTime: time.Since(startTime).String(),
 		}
 	}
 
-	// Execute SELECT query
-	stmt := spanner.Statement{SQL: req.Query}
-	iter := client.Single().Query(ctx, stmt)
-	defer iter.Stop()
+	executionTime := time.Since(startTime).String()
+
+	resp := types.QueryResponse{
+		Columns:       columns,
+		Rows:          rows,
+		RowCount:      len(rows),
+		ExecutionTime: executionTime,
+	}
+	if ts, err := ro.Timestamp(); err == nil {
+		resp.ReadTimestamp = ts.UTC().Format(time.RFC3339Nano)
+	}
+	return resp
+}
 
+// readRows drains a query iterator into column names and row maps
+func readRows(iter *spanner.RowIterator) ([]string, []map[string]interface{}, error) {
 	var columns []string
 	var rows []map[string]interface{}
 
@@ -231,10 +211,7 @@ func ExecuteQuery(req types.QueryRequest) types.QueryResponse {
 			break
 		}
 		if err != nil {
-			return types.QueryResponse{
-				Error:         err.Error(),
-				ExecutionTime: time.Since(startTime).String(),
-			}
+			return nil, nil, err
 		}
 
 		// Get column names from first row
@@ -242,91 +219,119 @@ func ExecuteQuery(req types.QueryRequest) types.QueryResponse {
 			columns = row.ColumnNames()
 		}
 
-		// Convert row to map - try multiple type decodings
-		rowMap := make(map[string]interface{})
-
-		for i, col := range columns {
-			// Try to decode as different Spanner types, falling back to string
-			var stringVal spanner.NullString
-			if err := row.Column(i, &stringVal); err == nil {
-				if stringVal.Valid {
-					rowMap[col] = stringVal.StringVal
-				} else {
-					rowMap[col] = nil
-				}
-				continue
-			}
+		rows = append(rows, rowToMap(row, columns))
+	}
+
+	// Empty results still carry their column names in the metadata
+	if columns == nil && iter.Metadata != nil && iter.Metadata.RowType != nil {
+		columns = []string{}
+		for _, f := range iter.Metadata.RowType.Fields {
+			columns = append(columns, f.Name)
+		}
+	}
+
+	return columns, rows, nil
+}
+
+// rowToMap converts a row to a map for display, trying multiple type decodings
+func rowToMap(row *spanner.Row, columns []string) map[string]interface{} {
+	rowMap := make(map[string]interface{})
 
-			var intVal spanner.NullInt64
-			if err := row.Column(i, &intVal); err == nil {
-				if intVal.Valid {
-					rowMap[col] = intVal.Int64
-				} else {
-					rowMap[col] = nil
-				}
-				continue
+	for i, col := range columns {
+		// Try to decode as different Spanner types, falling back to string
+		var stringVal spanner.NullString
+		if err := row.Column(i, &stringVal); err == nil {
+			if stringVal.Valid {
+				rowMap[col] = stringVal.StringVal
+			} else {
+				rowMap[col] = nil
 			}
+			continue
+		}
 
-			var floatVal spanner.NullFloat64
-			if err := row.Column(i, &floatVal); err == nil {
-				if floatVal.Valid {
-					rowMap[col] = floatVal.Float64
-				} else {
-					rowMap[col] = nil
-				}
-				continue
+		var intVal spanner.NullInt64
+		if err := row.Column(i, &intVal); err == nil {
+			if intVal.Valid {
+				rowMap[col] = intVal.Int64
+			} else {
+				rowMap[col] = nil
 			}
+			continue
+		}
 
-			var boolVal spanner.NullBool
-			if err := row.Column(i, &boolVal); err == nil {
-				if boolVal.Valid {
-					rowMap[col] = boolVal.Bool
-				} else {
-					rowMap[col] = nil
-				}
-				continue
+		var floatVal spanner.NullFloat64
+		if err := row.Column(i, &floatVal); err == nil {
+			if floatVal.Valid {
+				rowMap[col] = floatVal.Float64
+			} else {
+				rowMap[col] = nil
 			}
+			continue
+		}
 
-			var timeVal spanner.NullTime
-			if err := row.Column(i, &timeVal); err == nil {
-				if timeVal.Valid {
-					rowMap[col] = timeVal.Time.Format(time.RFC3339)
-				} else {
-					rowMap[col] = nil
-				}
-				continue
+		var boolVal spanner.NullBool
+		if err := row.Column(i, &boolVal); err == nil {
+			if boolVal.Valid {
+				rowMap[col] = boolVal.Bool
+			} else {
+				rowMap[col] = nil
 			}
+			continue
+		}
 
-			var jsonVal spanner.NullJSON
-			if err := row.Column(i, &jsonVal); err == nil {
-				if jsonVal.Valid {
-					// Convert JSON value to string representation
-					jsonBytes, _ := json.Marshal(jsonVal.Value)
-					rowMap[col] = string(jsonBytes)
-				} else {
-					rowMap[col] = nil
-				}
-				continue
+		var timeVal spanner.NullTime
+		if err := row.Column(i, &timeVal); err == nil {
+			if timeVal.Valid {
+				rowMap[col] = timeVal.Time.Format(time.RFC3339Nano)
+			} else {
+				rowMap[col] = nil
 			}
+			continue
+		}
 
-			// For any other types (BYTES, ARRAY, etc.), convert to string
-			var genericVal interface{}
-			if err := row.Column(i, &genericVal); err == nil {
-				rowMap[col] = fmt.Sprintf("%v", genericVal)
+		var jsonVal spanner.NullJSON
+		if err := row.Column(i, &jsonVal); err == nil {
+			if jsonVal.Valid {
+				// Convert JSON value to string representation
+				jsonBytes, _ := json.Marshal(jsonVal.Value)
+				rowMap[col] = string(jsonBytes)
 			} else {
-				rowMap[col] = "unsupported type"
+				rowMap[col] = nil
 			}
+			continue
 		}
 
-		rows = append(rows, rowMap)
-	}
+		// PostgreSQL-dialect databases annotate NUMERIC and JSONB columns,
+		// so they only decode into the PG types
+		var pgNumericVal spanner.PGNumeric
+		if err := row.Column(i, &pgNumericVal); err == nil {
+			if pgNumericVal.Valid {
+				rowMap[col] = pgNumericVal.Numeric
+			} else {
+				rowMap[col] = nil
+			}
+			continue
+		}
 
-	executionTime := time.Since(startTime).String()
+		var pgJSONVal spanner.PGJsonB
+		if err := row.Column(i, &pgJSONVal); err == nil {
+			if pgJSONVal.Valid {
+				jsonBytes, _ := json.Marshal(pgJSONVal.Value)
+				rowMap[col] = string(jsonBytes)
+			} else {
+				rowMap[col] = nil
+			}
+			continue
+		}
 
-	return types.QueryResponse{
-		Columns:       columns,
-		Rows:          rows,
-		RowCount:      len(rows),
-		ExecutionTime: executionTime,
+		// For any other types (BYTES, ARRAY, etc.), convert to string
+		var genericVal interface{}
+		if err := row.Column(i, &genericVal); err == nil {
+			rowMap[col] = fmt.Sprintf("%v", genericVal)
+		} else {
+			rowMap[col] = "unsupported type"
+		}
 	}
+
+	return rowMap
 }
@@ -0,0 +1,58 @@
+package spanner
+
+import (
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/spanner"
+	"cloudevents-explorer/internal/types"
+)
+
+// Timestamp bound modes accepted in types.TimestampBound
+const (
+	BoundStrong         = "strong"
+	BoundExactStaleness = "exactStaleness"
+	BoundMaxStaleness   = "maxStaleness"
+	BoundReadTimestamp  = "readTimestamp"
+)
+
+// isStrong reports whether a request leaves the timestamp bound at its default
+func isStrong(tb *types.TimestampBound) bool {
+	return tb == nil || tb.Mode == "" || tb.Mode == BoundStrong
+}
+
+// timestampBound converts the request's bound into the client's form.
+// Reads further back than the database's version_retention_period (one hour
+// by default) are rejected by Spanner with FAILED_PRECONDITION.
+func timestampBound(tb *types.TimestampBound) (spanner.TimestampBound, error) {
+	if isStrong(tb) {
+		return spanner.StrongRead(), nil
+	}
+
+	switch tb.Mode {
+	case BoundExactStaleness, BoundMaxStaleness:
+		d, err := time.ParseDuration(tb.Staleness)
+		if err != nil {
+			return spanner.TimestampBound{}, fmt.Errorf("invalid staleness %q: %w", tb.Staleness, err)
+		}
+		if d <= 0 {
+			return spanner.TimestampBound{}, fmt.Errorf("staleness must be positive, got %s", d)
+		}
+		if tb.Mode == BoundExactStaleness {
+			return spanner.ExactStaleness(d), nil
+		}
+		return spanner.MaxStaleness(d), nil
+
+	case BoundReadTimestamp:
+		ts, err := time.Parse(time.RFC3339Nano, tb.Timestamp)
+		if err != nil {
+			return spanner.TimestampBound{}, fmt.Errorf("invalid read timestamp %q: %w", tb.Timestamp, err)
+		}
+		if ts.After(time.Now()) {
+			return spanner.TimestampBound{}, fmt.Errorf("read timestamp %s is in the future", tb.Timestamp)
+		}
+		return spanner.ReadTimestamp(ts), nil
+	}
+
+	return spanner.TimestampBound{}, fmt.Errorf("unknown timestamp bound mode %q", tb.Mode)
+}
@@ -0,0 +1,142 @@
+package spanner
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/spanner"
+	"cloudevents-explorer/internal/types"
+	"google.golang.org/grpc/codes"
+)
+
+// Transactions left open this long without a statement are rolled back so
+// they do not hold locks (and the emulator's single transaction slot) forever
+const transactionIdleTimeout = 10 * time.Minute
+
+// openTransaction is an explicit read-write transaction kept between requests
+type openTransaction struct {
+	mu      sync.Mutex
+	client  *spanner.Client
+	dialect dialect
+	txn     *spanner.ReadWriteStmtBasedTransaction
+	timer   *time.Timer
+}
+
+var (
+	txnMu        sync.Mutex
+	transactions = map[string]*openTransaction{}
+)
+
+// BeginTransaction starts a read-write transaction that stays open until it
+// is committed, rolled back or left idle past the timeout
+func BeginTransaction(req types.ConnectionRequest) types.TransactionResponse {
+	ctx := context.Background()
+	client, d, err := openDatabase(ctx, req)
+	if err != nil {
+		return types.TransactionResponse{Status: "failed", Error: err.Error()}
+	}
+
+	txn, err := spanner.NewReadWriteStmtBasedTransaction(ctx, client)
+	if err != nil {
+		client.Close()
+		return types.TransactionResponse{Status: "failed", Error: err.Error()}
+	}
+
+	idBytes := make([]byte, 8)
+	rand.Read(idBytes)
+	id := hex.EncodeToString(idBytes)
+
+	t := &openTransaction{client: client, dialect: d, txn: txn}
+	t.timer = time.AfterFunc(transactionIdleTimeout, func() {
+		RollbackTransaction(id)
+	})
+
+	txnMu.Lock()
+	transactions[id] = t
+	txnMu.Unlock()
+
+	return types.TransactionResponse{TransactionID: id, Status: "open"}
+}
+
+// takeTransaction removes an open transaction from the registry
+func takeTransaction(id string) *openTransaction {
+	txnMu.Lock()
+	defer txnMu.Unlock()
+	t := transactions[id]
+	delete(transactions, id)
+	return t
+}
+
+// CommitTransaction commits an open transaction and releases its client
+func CommitTransaction(id string) types.TransactionResponse {
+	t := takeTransaction(id)
+	if t == nil {
+		return types.TransactionResponse{Status: "unknown", Error: fmt.Sprintf("transaction %s is not open", id)}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.timer.Stop()
+	defer t.client.Close()
+
+	ts, err := t.txn.Commit(context.Background())
+	if err != nil {
+		return types.TransactionResponse{TransactionID: id, Status: "failed", Error: err.Error()}
+	}
+
+	return types.TransactionResponse{
+		TransactionID:   id,
+		Status:          "committed",
+		CommitTimestamp: ts.UTC().Format(time.RFC3339Nano),
+	}
+}
+
+// RollbackTransaction discards an open transaction and releases its client
+func RollbackTransaction(id string) types.TransactionResponse {
+	t := takeTransaction(id)
+	if t == nil {
+		return types.TransactionResponse{Status: "unknown", Error: fmt.Sprintf("transaction %s is not open", id)}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.timer.Stop()
+	defer t.client.Close()
+
+	t.txn.Rollback(context.Background())
+
+	return types.TransactionResponse{TransactionID: id, Status: "rolled back"}
+}
+
+// executeInTransaction runs statements in an open transaction without
+// committing. If Spanner aborts the transaction it is rolled back here and
+// the caller has to begin a new one.
+func executeInTransaction(id string, statements []string) types.QueryResponse {
+	txnMu.Lock()
+	t := transactions[id]
+	txnMu.Unlock()
+
+	if t == nil {
+		return types.QueryResponse{Error: fmt.Sprintf("Transaction %s is not open; it may have been committed, rolled back or timed out", id)}
+	}
+
+	t.mu.Lock()
+	t.timer.Reset(transactionIdleTimeout)
+	startTime := time.Now()
+	results, err := runStatements(context.Background(), t.txn, t.dialect, statements)
+	t.mu.Unlock()
+
+	if err != nil && spanner.ErrCode(err) == codes.Aborted {
+		RollbackTransaction(id)
+		return types.QueryResponse{
+			Error:         fmt.Sprintf("Transaction was aborted by Spanner and has been rolled back: %v", err),
+			ExecutionTime: time.Since(startTime).String(),
+		}
+	}
+
+	return scriptResponse(results, err, time.Since(startTime))
+}
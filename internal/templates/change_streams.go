@@ -0,0 +1,210 @@
+package templates
+
+const ChangeStreamContent = `<div style="background: #e8f0fe; border: 1px solid #d2e3fc; border-radius: 4px; padding: 10px 16px; margin-bottom: 16px; font-size: 13px; color: #1967d2;">
+    💡 <strong>Tip:</strong> Connections are shared with the <a href="/spanner" style="color: #1a73e8; text-decoration: underline; font-weight: 500;">Spanner Explorer</a>. Each mod of a data change record is shown as one message.
+</div>
+
+<div class="panel">
+    <div class="panel-header">
+        <div class="panel-title">Change Stream Settings</div>
+    </div>
+    <div class="panel-body">
+        <div class="form-row">
+            <div class="form-group">
+                <label>Saved Configurations</label>
+                <select id="configSelect" onchange="loadSelectedConfig()">
+                    <option value="">-- Select Configuration --</option>
+                </select>
+            </div>
+            <div class="form-group">
+                <label>Emulator Host</label>
+                <input type="text" id="emulatorHost" placeholder="localhost:9010">
+            </div>
+            <div class="form-group">
+                <label>Project ID</label>
+                <input type="text" id="projectId" placeholder="project-id">
+            </div>
+            <div class="form-group">
+                <label>Instance ID</label>
+                <input type="text" id="instanceId" placeholder="instance-id">
+            </div>
+            <div class="form-group">
+                <label>Database ID</label>
+                <input type="text" id="databaseId" placeholder="database-id">
+            </div>
+        </div>
+        <div class="form-row">
+            <div class="form-group">
+                <label>Change Stream</label>
+                <select id="streamSelect">
+                    <option value="">-- Load Streams --</option>
+                </select>
+            </div>
+            <div class="form-group">
+                <label>Start Timestamp</label>
+                <input type="text" id="startTimestamp" placeholder="2024-01-01T00:00:00Z">
+            </div>
+            <div class="form-group">
+                <label>End Timestamp (empty = now)</label>
+                <input type="text" id="endTimestamp" placeholder="2024-01-01T01:00:00Z">
+            </div>
+            <div class="form-group">
+                <label>Partition Token (empty = initial)</label>
+                <input type="text" id="partitionToken" placeholder="">
+            </div>
+            <div class="form-group">
+                <label>Heartbeat (ms)</label>
+                <input type="number" id="heartbeatMs" value="10000" min="1000">
+            </div>
+        </div>
+        <label style="display: flex; align-items: center; gap: 6px; font-size: 13px; color: #202124; margin-bottom: 12px;">
+            <input type="checkbox" id="followChildren" checked> Follow child partitions up to the end timestamp
+        </label>
+        <div class="button-group">
+            <button class="btn-primary" onclick="readChangeStream()">Read Changes</button>
+            <button class="btn-secondary" onclick="loadStreams()">Load Streams</button>
+            <button class="btn-secondary" onclick="resetWindow()">Last Hour</button>
+            <button class="btn-danger" onclick="clearAllMessages()">Clear All</button>
+        </div>
+        <div id="partitionList" style="margin-top: 16px; display: none;"></div>
+    </div>
+</div>`
+
+const ChangeStreamJS = `let spannerConfigs = [];
+
+async function refreshConfigs() {
+    const response = await fetch('/api/configs');
+    const data = await response.json();
+    spannerConfigs = data.spannerConfigs || [];
+    const select = document.getElementById('configSelect');
+    select.innerHTML = '<option value="">-- Select Configuration --</option>';
+    spannerConfigs.forEach((config, index) => {
+        const option = document.createElement('option');
+        option.value = index;
+        option.textContent = config.name;
+        select.appendChild(option);
+    });
+}
+
+function loadSelectedConfig() {
+    const select = document.getElementById('configSelect');
+    if (select.value === '') return;
+    const config = spannerConfigs[parseInt(select.value)];
+    document.getElementById('emulatorHost').value = config.emulatorHost;
+    document.getElementById('projectId').value = config.projectId;
+    document.getElementById('instanceId').value = config.instanceId;
+    document.getElementById('databaseId').value = config.databaseId;
+    loadStreams();
+}
+
+function getConnectionRequest() {
+    return {
+        emulatorHost: document.getElementById('emulatorHost').value,
+        projectId: document.getElementById('projectId').value,
+        instanceId: document.getElementById('instanceId').value,
+        databaseId: document.getElementById('databaseId').value
+    };
+}
+
+function resetWindow() {
+    document.getElementById('startTimestamp').value = new Date(Date.now() - 3600 * 1000).toISOString();
+    document.getElementById('endTimestamp').value = '';
+    document.getElementById('partitionToken').value = '';
+}
+
+async function loadStreams() {
+    const select = document.getElementById('streamSelect');
+    try {
+        const response = await fetch('/api/spanner/database-schema', {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify(getConnectionRequest())
+        });
+        const schema = await response.json();
+        if (!response.ok) throw new Error(schema.error || 'Failed to load change streams');
+
+        const streams = schema.changeStreams || [];
+        select.innerHTML = streams.length === 0
+            ? '<option value="">-- No change streams --</option>'
+            : streams.map(cs => {
+                const watches = cs.all ? 'all tables' : (cs.tables || []).map(t => t.table).join(', ');
+                return '<option value="' + cs.name + '">' + cs.name + ' (' + watches + ')</option>';
+            }).join('');
+        showStatus('Loaded ' + streams.length + ' change stream(s)');
+    } catch (error) {
+        showStatus('Failed to load change streams: ' + error.message, true);
+    }
+}
+
+async function readChangeStream() {
+    const req = getConnectionRequest();
+    req.stream = document.getElementById('streamSelect').value;
+    req.startTimestamp = document.getElementById('startTimestamp').value.trim();
+    req.endTimestamp = document.getElementById('endTimestamp').value.trim();
+    req.partitionToken = document.getElementById('partitionToken').value.trim();
+    req.heartbeatMilliseconds = parseInt(document.getElementById('heartbeatMs').value) || 0;
+    req.followChildren = document.getElementById('followChildren').checked;
+
+    if (!req.projectId || !req.instanceId || !req.databaseId || !req.stream) {
+        showStatus('Please fill in the connection fields and pick a change stream', true);
+        return;
+    }
+
+    const messagesDiv = document.getElementById('messages');
+    messagesDiv.innerHTML = '<div class="loading"><div class="spinner"></div>Reading change stream...</div>';
+    try {
+        const response = await fetch('/api/spanner/change-streams/read', {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify(req)
+        });
+        const data = await response.json();
+        messagesData = (data.messages || []).concat(messagesData);
+        renderMessages();
+        renderPartitions(data.partitions || []);
+        if (!response.ok) throw new Error(data.error || 'Failed to read change stream');
+        showStatus('Read ' + data.messages.length + ' mod(s) and ' + data.heartbeats + ' heartbeat(s) in ' + data.executionTime);
+    } catch (error) {
+        showStatus('Failed to read change stream: ' + error.message, true);
+    }
+}
+
+// renderPartitions lists the partitions read and the child partitions left
+// to read, which can be picked to read next
+function renderPartitions(partitions) {
+    const div = document.getElementById('partitionList');
+    if (partitions.length === 0) {
+        div.style.display = 'none';
+        return;
+    }
+
+    let html = '<div class="panel-title" style="margin-bottom: 8px;">PARTITIONS</div>';
+    html += '<div style="display: flex; flex-direction: column; gap: 4px; font-size: 12px;">';
+    partitions.forEach((p, i) => {
+        const label = p.token ? p.token : '(initial partition query)';
+        html += '<div style="display: flex; align-items: center; gap: 8px; padding: 6px 10px; border: 1px solid #dadce0; border-radius: 4px;">';
+        html += '<span style="flex: 1; min-width: 0; font-family: monospace; overflow: hidden; text-overflow: ellipsis; white-space: nowrap;" title="' + label + '">' + label + '</span>';
+        html += '<span style="color: #5f6368;">from ' + p.startTimestamp + '</span>';
+        if (p.read) {
+            html += '<span style="color: #188038;">read, ' + p.records + ' mod(s)</span>';
+        } else {
+            html += '<button class="btn-secondary" style="padding: 4px 10px; font-size: 12px;" onclick="pickPartition(' + i + ')">Read</button>';
+        }
+        html += '</div>';
+    });
+    html += '</div>';
+
+    div.innerHTML = html;
+    div.style.display = 'block';
+    div.partitions = partitions;
+}
+
+function pickPartition(index) {
+    const p = document.getElementById('partitionList').partitions[index];
+    document.getElementById('partitionToken').value = p.token;
+    document.getElementById('startTimestamp').value = p.startTimestamp;
+    readChangeStream();
+}
+
+refreshConfigs();
+resetWindow();`
@@ -369,7 +369,7 @@ const ConfigEditor = `<!DOCTYPE html>
                     <input type="text" class="form-input" id="newGCSName" placeholder="e.g., TMS GCS Local">
                 </div>
                 <div class="form-group">
-                    <label class="form-label">Emulator Host</label>
+                    <label class="form-label">Emulator Host (leave empty for Google Cloud via ADC)</label>
                     <input type="text" class="form-input" id="newGCSHost" placeholder="e.g., localhost:4443">
                 </div>
                 <div class="form-group">
@@ -620,7 +620,9 @@ const ConfigEditor = `<!DOCTYPE html>
         }
 
         async function saveSpannerConfig(index) {
+            // Keep the profile's saved queries, which this form does not edit
             const updatedConfig = {
+                ...configs.spannerConfigs[index],
                 name: document.getElementById(` + "`edit-spanner-name-${index}`" + `).value,
                 emulatorHost: document.getElementById(` + "`edit-spanner-host-${index}`" + `).value,
                 projectId: document.getElementById(` + "`edit-spanner-project-${index}`" + `).value,
@@ -758,7 +760,7 @@ const ConfigEditor = `<!DOCTYPE html>
                         </div>
                     </div>
                     <div class="config-details" id="gcs-details-${index}">
-                        <div><strong>Emulator Host:</strong> ${config.emulatorHost}</div>
+                        <div><strong>Emulator Host:</strong> ${config.emulatorHost || 'Google Cloud (ADC)'}</div>
                         <div><strong>Project ID:</strong> ${config.projectId}</div>
                     </div>
                     <div class="hidden" id="gcs-form-${index}">
@@ -818,8 +820,8 @@ const ConfigEditor = `<!DOCTYPE html>
                 projectId: document.getElementById('newGCSProject').value
             };
 
-            if (!newConfig.name || !newConfig.emulatorHost || !newConfig.projectId) {
-                alert('Please fill in all fields');
+            if (!newConfig.name || !newConfig.projectId) {
+                alert('Please fill in the name and project ID');
                 return;
             }
 
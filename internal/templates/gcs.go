@@ -39,6 +39,28 @@ const GCS = `<!DOCTYPE html>
             margin: 0 auto;
             padding: 24px;
         }
+        .connection-bar {
+            background: white;
+            border: 1px solid #dadce0;
+            border-radius: 8px;
+            padding: 12px 16px;
+            margin-bottom: 16px;
+            display: flex;
+            align-items: center;
+            gap: 8px;
+            flex-wrap: wrap;
+            font-size: 13px;
+        }
+        .connection-bar select, .connection-bar input {
+            padding: 6px 10px;
+            border: 1px solid #dadce0;
+            border-radius: 4px;
+            font-size: 13px;
+        }
+        .connection-target {
+            color: #5f6368;
+            font-size: 12px;
+        }
         .breadcrumb {
             background: white;
             border: 1px solid #dadce0;
@@ -241,6 +263,262 @@ const GCS = `<!DOCTYPE html>
             max-height: 500px;
             overflow-y: auto;
         }
+        .preview-toolbar {
+            display: flex;
+            justify-content: space-between;
+            align-items: center;
+            margin-bottom: 12px;
+            font-size: 12px;
+            color: #5f6368;
+        }
+        .btn.active {
+            background: #e8f0fe;
+            border-color: #1a73e8;
+            color: #1967d2;
+        }
+        .table-wrap {
+            overflow: auto;
+            max-height: 50vh;
+            border: 1px solid #dadce0;
+            border-radius: 4px;
+        }
+        .data-table {
+            border-collapse: collapse;
+            font-size: 12px;
+            font-family: 'Monaco', 'Menlo', monospace;
+            width: 100%;
+        }
+        .data-table th, .data-table td {
+            border-bottom: 1px solid #f1f3f4;
+            padding: 4px 8px;
+            text-align: left;
+            white-space: nowrap;
+            max-width: 320px;
+            overflow: hidden;
+            text-overflow: ellipsis;
+        }
+        .data-table thead th {
+            position: sticky;
+            background: #f8f9fa;
+            font-weight: 500;
+        }
+        .data-table thead tr:first-child th {
+            top: 0;
+        }
+        .data-table thead tr:last-child th {
+            top: 38px;
+        }
+        .data-table .column-type {
+            display: block;
+            color: #5f6368;
+            font-weight: normal;
+            font-size: 11px;
+        }
+        .data-table input {
+            width: 100%;
+            min-width: 60px;
+            padding: 2px 4px;
+            border: 1px solid #dadce0;
+            border-radius: 3px;
+            font-size: 11px;
+        }
+        .data-table .null-cell {
+            color: #9aa0a6;
+            font-style: italic;
+        }
+        .column-toggles {
+            display: flex;
+            flex-wrap: wrap;
+            gap: 4px 12px;
+            margin: 8px 0;
+            font-size: 12px;
+        }
+        .search-panel {
+            display: none;
+            flex-wrap: wrap;
+            align-items: center;
+            gap: 8px;
+            padding-bottom: 12px;
+            margin-bottom: 8px;
+            border-bottom: 1px solid #f1f3f4;
+            font-size: 12px;
+            color: #5f6368;
+        }
+        .search-panel input, .search-panel select {
+            padding: 5px 8px;
+            border: 1px solid #dadce0;
+            border-radius: 4px;
+            font-size: 12px;
+        }
+        .result-bar {
+            display: flex;
+            align-items: center;
+            gap: 8px;
+            padding: 8px 12px;
+            font-size: 13px;
+            color: #5f6368;
+            border-bottom: 1px solid #f1f3f4;
+        }
+        .object-toolbar {
+            display: none;
+            align-items: center;
+            gap: 8px;
+            padding-bottom: 12px;
+            margin-bottom: 8px;
+            border-bottom: 1px solid #f1f3f4;
+            font-size: 12px;
+            color: #5f6368;
+        }
+        .object-toolbar select {
+            padding: 5px 8px;
+            border: 1px solid #dadce0;
+            border-radius: 4px;
+            font-size: 12px;
+        }
+        .content-area.drag-over {
+            border: 2px dashed #1a73e8;
+            background: #f8fbff;
+        }
+        .btn-danger {
+            color: #d93025;
+        }
+        .status-message {
+            display: none;
+            padding: 8px 12px;
+            margin-bottom: 12px;
+            border-radius: 4px;
+            font-size: 13px;
+        }
+        .form-field {
+            margin-bottom: 12px;
+        }
+        .form-field label {
+            display: block;
+            font-size: 12px;
+            color: #5f6368;
+            margin-bottom: 4px;
+        }
+        .form-field input, .form-field select, .form-field textarea {
+            width: 100%;
+            padding: 6px 10px;
+            border: 1px solid #dadce0;
+            border-radius: 4px;
+            font-size: 13px;
+        }
+        .details-table {
+            width: 100%;
+            border-collapse: collapse;
+            font-size: 13px;
+            margin-bottom: 20px;
+        }
+        .details-table td {
+            padding: 6px 8px;
+            border-bottom: 1px solid #f1f3f4;
+            vertical-align: top;
+            word-break: break-all;
+        }
+        .details-table td:first-child {
+            width: 160px;
+            color: #5f6368;
+            word-break: normal;
+        }
+        .notification-item {
+            border: 1px solid #dadce0;
+            border-radius: 4px;
+            padding: 8px 12px;
+            margin-bottom: 8px;
+            font-size: 13px;
+            display: flex;
+            justify-content: space-between;
+            align-items: flex-start;
+            gap: 12px;
+        }
+        .notification-item .notification-detail {
+            color: #5f6368;
+            font-size: 12px;
+            word-break: break-all;
+        }
+        .event-types {
+            display: flex;
+            flex-wrap: wrap;
+            gap: 12px;
+            font-size: 13px;
+        }
+        .details-section {
+            font-size: 14px;
+            font-weight: 500;
+            margin: 4px 0 10px;
+            display: flex;
+            justify-content: space-between;
+            align-items: center;
+        }
+        .metadata-row {
+            display: flex;
+            gap: 8px;
+            margin-bottom: 6px;
+        }
+        .metadata-row input {
+            flex: 1;
+            padding: 6px 10px;
+            border: 1px solid #dadce0;
+            border-radius: 4px;
+            font-size: 13px;
+            font-family: monospace;
+        }
+        .version-live {
+            color: #188038;
+            font-weight: 500;
+        }
+        .diff-form {
+            display: grid;
+            grid-template-columns: 60px 1fr 2fr 1fr;
+            gap: 6px 8px;
+            align-items: center;
+            font-size: 13px;
+            margin-bottom: 10px;
+        }
+        .diff-form input, .diff-form select, .diff-options input, .diff-options select {
+            padding: 5px 8px;
+            border: 1px solid #dadce0;
+            border-radius: 4px;
+            font-size: 13px;
+        }
+        .diff-options {
+            display: flex;
+            flex-wrap: wrap;
+            gap: 8px;
+            align-items: center;
+            font-size: 13px;
+            margin-bottom: 12px;
+        }
+        .diff-table {
+            border-collapse: collapse;
+            width: 100%;
+            font-family: monospace;
+            font-size: 12px;
+        }
+        .diff-table td {
+            padding: 1px 6px;
+            vertical-align: top;
+            white-space: pre-wrap;
+            word-break: break-all;
+        }
+        .diff-table td.diff-num {
+            color: #80868b;
+            text-align: right;
+            width: 44px;
+            user-select: none;
+        }
+        .diff-table tr.diff-hunk td {
+            background: #f1f3f4;
+            color: #5f6368;
+        }
+        .diff-table tr.diff-removed td {
+            background: #fce8e6;
+        }
+        .diff-table tr.diff-added td {
+            background: #e6f4ea;
+        }
         .loading {
             text-align: center;
             padding: 32px;
@@ -259,35 +537,299 @@ const GCS = `<!DOCTYPE html>
             💡 <strong>Tip:</strong> You can manage GCS connection settings in <a href="/config-editor" style="color: #1a73e8; text-decoration: underline; font-weight: 500;">Global Settings</a>
         </div>
 
+        <div class="connection-bar">
+            <select id="gcsConfigSelect" onchange="selectGCSConfig()">
+                <option value="">-- Custom Connection --</option>
+            </select>
+            <input type="text" id="gcsEmulatorHost" placeholder="Emulator host, empty = Google Cloud (ADC)" style="width: 280px;" oninput="customizeConnection()">
+            <input type="text" id="gcsProjectId" placeholder="Project ID" oninput="customizeConnection()">
+            <button class="btn btn-primary" onclick="connectGCS()">Connect</button>
+            <span class="connection-target" id="gcsTarget"></span>
+        </div>
+
         <div class="breadcrumb" id="breadcrumb">
             <span class="breadcrumb-item" onclick="navigateToRoot()">Buckets</span>
         </div>
 
         <div class="main-content">
             <div class="sidebar">
-                <div class="sidebar-title">Buckets</div>
+                <div class="sidebar-title" style="display: flex; justify-content: space-between; align-items: center;">
+                    <span>Buckets</span>
+                    <button class="btn" onclick="openBucketModal()">+ New</button>
+                </div>
                 <ul class="bucket-list" id="bucketList">
                     <li class="loading">Loading buckets...</li>
                 </ul>
             </div>
 
-            <div class="content-area" id="contentArea">
-                <div class="empty-state">
-                    <div class="empty-state-icon">📦</div>
-                    <div>Select a bucket to view its contents</div>
+            <div class="content-area" id="contentPane" ondragover="onDragOver(event)" ondragleave="onDragLeave(event)" ondrop="onDrop(event)">
+                <div class="status-message" id="gcsStatus"></div>
+                <div class="object-toolbar" id="objectToolbar">
+                    <button class="btn btn-primary" onclick="document.getElementById('uploadInput').click()">Upload Files</button>
+                    <input type="file" id="uploadInput" multiple style="display: none;" onchange="uploadFiles(this.files); this.value = '';">
+                    <select id="uploadType" title="How files are sent to GCS">
+                        <option value="auto">Auto (resumable above 8 MB)</option>
+                        <option value="multipart">Multipart</option>
+                        <option value="resumable">Resumable</option>
+                    </select>
+                    <button class="btn" onclick="createFolder()">New Folder</button>
+                    <button class="btn" onclick="toggleSearch()">Search</button>
+                    <button class="btn" onclick="downloadPrefixZip(currentPrefix)">Download as ZIP</button>
+                    <button class="btn" onclick="openNotifications()">Notifications</button>
+                    <span>or drop files here</span>
+                </div>
+                <div class="search-panel" id="searchPanel">
+                    <input type="text" id="searchPattern" placeholder="*.csv or reports/**/2026-*.json" style="width: 240px;" onkeydown="if (event.key === 'Enter') searchObjects()">
+                    <select id="searchMode">
+                        <option value="glob">Glob</option>
+                        <option value="regex">Regex</option>
+                    </select>
+                    <input type="text" id="searchMinSize" placeholder="Min size, e.g. 10 KB" style="width: 130px;">
+                    <input type="text" id="searchMaxSize" placeholder="Max size" style="width: 100px;">
+                    <label>Updated after <input type="datetime-local" id="searchAfter"></label>
+                    <label>before <input type="datetime-local" id="searchBefore"></label>
+                    <button class="btn btn-primary" onclick="searchObjects()">Search</button>
+                    <button class="btn" onclick="closeSearch()">Close</button>
+                </div>
+                <div id="contentArea">
+                    <div class="empty-state">
+                        <div class="empty-state-icon">📦</div>
+                        <div>Select a bucket to view its contents</div>
+                    </div>
                 </div>
             </div>
         </div>
     </div>
 
-    <div class="modal" id="previewModal">
+    <div class="modal" id="previewModal" style="z-index: 1001;">
         <div class="modal-content">
             <div class="modal-header">
-                <div class="modal-title" id="previewTitle">File Preview</div>
+                <div class="modal-title" id="previewTitle" style="word-break: break-all;">File Preview</div>
                 <button class="modal-close" onclick="closePreview()">×</button>
             </div>
             <div class="modal-body">
+                <div class="preview-toolbar">
+                    <span id="previewInfo"></span>
+                    <span id="previewViews">
+                        <button class="btn" id="previewTableBtn" onclick="setPreviewView('table')">Table</button>
+                        <button class="btn" id="previewTextBtn" onclick="setPreviewView('text')">Text</button>
+                        <button class="btn" id="previewHexBtn" onclick="setPreviewView('hex')">Hex</button>
+                    </span>
+                </div>
                 <div class="file-preview" id="previewContent">Loading...</div>
+                <img id="previewImage" alt="" style="display: none; max-width: 100%; max-height: 60vh;">
+                <div id="previewTable" style="display: none;">
+                    <div class="preview-toolbar">
+                        <span>
+                            Format
+                            <select id="tableFormat" onchange="loadPreviewTable()">
+                                <option value="">Auto</option>
+                                <option value="avro">Avro</option>
+                                <option value="parquet">Parquet</option>
+                                <option value="csv">CSV</option>
+                                <option value="ndjson">NDJSON</option>
+                                <option value="json">JSON</option>
+                            </select>
+                            Records
+                            <select id="tableLimit" onchange="loadPreviewTable()">
+                                <option value="100">100</option>
+                                <option value="500">500</option>
+                                <option value="1000">1000</option>
+                            </select>
+                        </span>
+                        <span id="tableSummary"></span>
+                    </div>
+                    <details style="margin-bottom: 8px; font-size: 12px;">
+                        <summary style="cursor: pointer;">Schema</summary>
+                        <pre class="file-preview" id="tableRawSchema" style="margin-top: 8px; max-height: 200px;"></pre>
+                    </details>
+                    <div class="column-toggles" id="tableColumns"></div>
+                    <div class="table-wrap">
+                        <table class="data-table" id="tableData"></table>
+                    </div>
+                </div>
+                <div class="preview-toolbar" style="margin: 12px 0 0;">
+                    <span id="previewProgress"></span>
+                    <button class="btn" id="previewMore" style="display: none;" onclick="loadPreviewChunk()">Load more</button>
+                </div>
+            </div>
+        </div>
+    </div>
+
+    <div class="modal" id="detailsModal">
+        <div class="modal-content">
+            <div class="modal-header">
+                <div class="modal-title" id="detailsTitle">Object Details</div>
+                <button class="modal-close" onclick="closeDetails()">×</button>
+            </div>
+            <div class="modal-body">
+                <div class="status-message" id="detailsStatus"></div>
+                <table class="details-table" id="detailsTable"></table>
+                <div class="details-section">
+                    <span>Custom Metadata</span>
+                    <span>
+                        <button class="btn" onclick="addMetadataRow('', '')">+ Add</button>
+                        <button class="btn btn-primary" onclick="saveMetadata()">Save</button>
+                    </span>
+                </div>
+                <div id="metadataRows" style="margin-bottom: 20px;"></div>
+                <div class="details-section"><span>Versions</span></div>
+                <div id="versionsList" style="font-size: 13px;"></div>
+            </div>
+        </div>
+    </div>
+
+    <div class="modal" id="diffModal" style="z-index: 1001;">
+        <div class="modal-content">
+            <div class="modal-header">
+                <div class="modal-title">Compare Objects</div>
+                <button class="modal-close" onclick="closeDiff()">×</button>
+            </div>
+            <div class="modal-body">
+                <div class="diff-form">
+                    <span></span><span>Bucket</span><span>Object</span><span>Generation</span>
+                    <strong>Left</strong>
+                    <select id="diffLeftBucket"></select>
+                    <input type="text" id="diffLeftObject">
+                    <input type="text" id="diffLeftGeneration" placeholder="live">
+                    <strong>Right</strong>
+                    <select id="diffRightBucket"></select>
+                    <input type="text" id="diffRightObject" placeholder="same as left">
+                    <input type="text" id="diffRightGeneration" placeholder="live">
+                </div>
+                <div class="diff-options">
+                    <select id="diffMode" title="How to compare the objects">
+                        <option value="">Auto</option>
+                        <option value="text">Text lines</option>
+                        <option value="json">JSON structure</option>
+                        <option value="csv">CSV rows</option>
+                    </select>
+                    <input type="text" id="diffKeyColumns" placeholder="CSV key columns, e.g. id" style="width: 180px;">
+                    <label><input type="checkbox" id="diffIgnoreWhitespace"> Ignore whitespace</label>
+                    <label>Context <input type="number" id="diffContext" value="3" min="0" style="width: 60px;"></label>
+                    <button class="btn" onclick="swapDiff()">Swap</button>
+                    <button class="btn btn-primary" onclick="runDiff()">Compare</button>
+                </div>
+                <div class="status-message" id="diffStatus"></div>
+                <div id="diffSummary" style="font-size: 13px; margin-bottom: 8px;"></div>
+                <div id="diffResult" style="overflow: auto; max-height: 60vh;"></div>
+            </div>
+        </div>
+    </div>
+
+    <div class="modal" id="copyModal">
+        <div class="modal-content" style="max-width: 520px;">
+            <div class="modal-header">
+                <div class="modal-title">Copy or Move</div>
+                <button class="modal-close" onclick="closeCopyModal()">×</button>
+            </div>
+            <div class="modal-body">
+                <div class="form-field">
+                    <label>Source</label>
+                    <div id="copySource" style="font-family: monospace; font-size: 13px; word-break: break-all;"></div>
+                </div>
+                <div class="form-field">
+                    <label for="copyBucket">Destination Bucket</label>
+                    <select id="copyBucket"></select>
+                </div>
+                <div class="form-field">
+                    <label for="copyName" id="copyNameLabel">Destination Name</label>
+                    <input type="text" id="copyName">
+                </div>
+                <label style="display: flex; align-items: center; gap: 6px; font-size: 13px; margin-bottom: 16px;">
+                    <input type="checkbox" id="copyMove"> Move (delete the source once copied)
+                </label>
+                <div style="display: flex; justify-content: flex-end; gap: 8px;">
+                    <button class="btn" onclick="closeCopyModal()">Cancel</button>
+                    <button class="btn btn-primary" onclick="runCopy()">Copy</button>
+                </div>
+            </div>
+        </div>
+    </div>
+
+    <div class="modal" id="bucketModal">
+        <div class="modal-content" style="max-width: 420px;">
+            <div class="modal-header">
+                <div class="modal-title">New Bucket</div>
+                <button class="modal-close" onclick="closeBucketModal()">×</button>
+            </div>
+            <div class="modal-body">
+                <div class="form-field">
+                    <label for="newBucketName">Bucket Name</label>
+                    <input type="text" id="newBucketName" placeholder="test-input-files">
+                </div>
+                <div class="form-field">
+                    <label for="newBucketLocation">Location (optional)</label>
+                    <input type="text" id="newBucketLocation" placeholder="australia-southeast1">
+                </div>
+                <div style="display: flex; justify-content: flex-end; gap: 8px;">
+                    <button class="btn" onclick="closeBucketModal()">Cancel</button>
+                    <button class="btn btn-primary" onclick="createBucket()">Create</button>
+                </div>
+            </div>
+        </div>
+    </div>
+
+    <div class="modal" id="notificationsModal">
+        <div class="modal-content" style="max-width: 640px;">
+            <div class="modal-header">
+                <div class="modal-title" id="notificationsTitle">Bucket Notifications</div>
+                <button class="modal-close" onclick="closeNotifications()">×</button>
+            </div>
+            <div class="modal-body">
+                <div class="status-message" id="notificationsStatus"></div>
+                <div id="notificationsList" style="margin-bottom: 16px;"></div>
+                <div class="details-section"><span>New Notification</span></div>
+                <div class="form-field">
+                    <label for="notifyPubSubConfig">Pub/Sub Configuration</label>
+                    <select id="notifyPubSubConfig" onchange="selectNotifyPubSubConfig()"></select>
+                </div>
+                <div style="display: flex; gap: 8px;">
+                    <div class="form-field" style="flex: 1;">
+                        <label for="notifyPubSubHost">Pub/Sub Emulator Host</label>
+                        <input type="text" id="notifyPubSubHost" placeholder="localhost:8085">
+                    </div>
+                    <div class="form-field" style="flex: 1;">
+                        <label for="notifyPubSubProject">Project ID</label>
+                        <input type="text" id="notifyPubSubProject" placeholder="test-project">
+                    </div>
+                </div>
+                <div class="form-field">
+                    <label for="notifyTopic">Topic</label>
+                    <input type="text" id="notifyTopic" placeholder="gcs-notifications">
+                </div>
+                <div class="form-field">
+                    <label>Event Types (none selected means all)</label>
+                    <div class="event-types">
+                        <label><input type="checkbox" class="notify-event" value="OBJECT_FINALIZE" checked> OBJECT_FINALIZE</label>
+                        <label><input type="checkbox" class="notify-event" value="OBJECT_DELETE"> OBJECT_DELETE</label>
+                        <label><input type="checkbox" class="notify-event" value="OBJECT_METADATA_UPDATE"> OBJECT_METADATA_UPDATE</label>
+                    </div>
+                </div>
+                <div style="display: flex; gap: 8px;">
+                    <div class="form-field" style="flex: 1;">
+                        <label for="notifyPrefix">Object Name Prefix</label>
+                        <input type="text" id="notifyPrefix" placeholder="incoming/">
+                    </div>
+                    <div class="form-field" style="flex: 1;">
+                        <label for="notifyPayloadFormat">Payload Format</label>
+                        <select id="notifyPayloadFormat">
+                            <option value="JSON_API_V1">JSON_API_V1</option>
+                            <option value="NONE">NONE</option>
+                        </select>
+                    </div>
+                </div>
+                <div class="form-field">
+                    <label for="notifyAttributes">Custom Attributes (one key=value per line)</label>
+                    <textarea id="notifyAttributes" rows="3" style="font-family: monospace;"></textarea>
+                </div>
+                <label style="display: flex; align-items: center; gap: 6px; font-size: 13px; margin-bottom: 16px;">
+                    <input type="checkbox" id="notifyWatch"> Watch the bucket, so changes made outside the studio are published too
+                </label>
+                <div style="display: flex; justify-content: flex-end; gap: 8px;">
+                    <button class="btn" onclick="closeNotifications()">Close</button>
+                    <button class="btn btn-primary" onclick="saveNotification()">Add Notification</button>
+                </div>
             </div>
         </div>
     </div>
@@ -295,6 +837,65 @@ const GCS = `<!DOCTYPE html>
     <script>
         let currentBucket = null;
         let currentPrefix = '';
+        let gcsConfigs = [];
+        let bucketNames = [];
+        let copySource = null;
+        let detailsObject = null;
+        // preview holds the object being previewed and the bytes loaded so
+        // far; each "Load more" appends the next chunk
+        let preview = null;
+
+        // jsArg quotes a value for use as a string argument in an inline
+        // onclick handler
+        function jsArg(value) {
+            return JSON.stringify(value).replace(/&/g, '&amp;').replace(/"/g, '&quot;');
+        }
+
+        function escapeHtml(value) {
+            return String(value).replace(/&/g, '&amp;').replace(/</g, '&lt;').replace(/>/g, '&gt;');
+        }
+
+        function showGCSStatus(message, isError) {
+            const status = document.getElementById('gcsStatus');
+            status.style.display = 'block';
+            status.style.background = isError ? '#fce8e6' : '#e8f5e9';
+            status.style.color = isError ? '#d93025' : '#188038';
+            status.textContent = message;
+        }
+
+        // gcsRequest sends a write request and returns its GCSOperationResponse,
+        // throwing with the server's message when it failed
+        async function gcsRequest(path, method, body) {
+            const options = { method: method };
+            if (body instanceof FormData) {
+                options.body = body;
+            } else if (body) {
+                options.headers = { 'Content-Type': 'application/json' };
+                options.body = JSON.stringify(body);
+            }
+            const response = await fetch(path + (path.indexOf('?') === -1 ? '?' : '&') + connectionQuery(), options);
+            const text = await response.text();
+            let result;
+            try {
+                result = JSON.parse(text);
+            } catch (e) {
+                throw new Error(text);
+            }
+            if (!response.ok) throw new Error(result.error || text);
+            return result;
+        }
+
+        // showOperation reports a write, with the bucket notifications it
+        // published or failed to publish
+        function showOperation(message, result) {
+            if (result.notificationError) {
+                showGCSStatus(message + '; notification failed: ' + result.notificationError, true);
+            } else if (result.notified) {
+                showGCSStatus(message + '; published ' + result.notified + ' notification(s)', false);
+            } else {
+                showGCSStatus(message, false);
+            }
+        }
 
         function formatFileSize(bytes) {
             if (bytes === 0) return '0 B';
@@ -303,22 +904,98 @@ const GCS = `<!DOCTYPE html>
             return (bytes / (1024 * 1024)).toFixed(1) + ' MB';
         }
 
+        async function loadGCSConfigs() {
+            try {
+                const response = await fetch('/api/configs');
+                const data = await response.json();
+                gcsConfigs = data.gcsConfigs || [];
+            } catch (error) {
+                console.error('Failed to load GCS configurations:', error);
+            }
+
+            const select = document.getElementById('gcsConfigSelect');
+            gcsConfigs.forEach(cfg => {
+                const option = document.createElement('option');
+                option.value = cfg.name;
+                option.textContent = cfg.name;
+                select.appendChild(option);
+            });
+
+            if (gcsConfigs.length > 0) {
+                select.value = gcsConfigs[0].name;
+                selectGCSConfig();
+            } else {
+                document.getElementById('gcsEmulatorHost').value = 'localhost:4443';
+                connectGCS();
+            }
+        }
+
+        function selectGCSConfig() {
+            const cfg = gcsConfigs.find(c => c.name === document.getElementById('gcsConfigSelect').value);
+            if (cfg) {
+                document.getElementById('gcsEmulatorHost').value = cfg.emulatorHost || '';
+                document.getElementById('gcsProjectId').value = cfg.projectId || '';
+                connectGCS();
+            }
+        }
+
+        // customizeConnection switches to a custom connection once the saved
+        // profile's fields are edited
+        function customizeConnection() {
+            document.getElementById('gcsConfigSelect').value = '';
+        }
+
+        // connectionQuery returns the query parameters naming the connection,
+        // appended to every GCS API call
+        function connectionQuery() {
+            const configName = document.getElementById('gcsConfigSelect').value;
+            if (configName) {
+                return 'config=' + encodeURIComponent(configName);
+            }
+            return 'emulatorHost=' + encodeURIComponent(document.getElementById('gcsEmulatorHost').value.trim()) +
+                '&projectId=' + encodeURIComponent(document.getElementById('gcsProjectId').value.trim());
+        }
+
+        function connectGCS() {
+            const host = document.getElementById('gcsEmulatorHost').value.trim();
+            const project = document.getElementById('gcsProjectId').value.trim();
+            document.getElementById('gcsTarget').textContent = (host ? 'fake-gcs-server at ' + host : 'Google Cloud Storage via Application Default Credentials') +
+                (project ? ', project ' + project : '');
+
+            currentBucket = null;
+            currentPrefix = '';
+            updateBreadcrumb();
+            document.getElementById('objectToolbar').style.display = 'none';
+            document.getElementById('searchPanel').style.display = 'none';
+            document.getElementById('gcsStatus').style.display = 'none';
+            document.getElementById('contentArea').innerHTML =
+                '<div class="empty-state"><div class="empty-state-icon">📦</div><div>Select a bucket to view its contents</div></div>';
+            loadBuckets();
+        }
+
         async function loadBuckets() {
+            document.getElementById('bucketList').innerHTML = '<li class="loading">Loading buckets...</li>';
             try {
-                const response = await fetch('/api/gcs/buckets');
+                const response = await fetch('/api/gcs/buckets?' + connectionQuery());
+                if (!response.ok) throw new Error(await response.text());
                 const data = await response.json();
 
                 const bucketList = document.getElementById('bucketList');
                 if (data.buckets && data.buckets.length > 0) {
+                    bucketNames = data.buckets.map(bucket => bucket.name);
                     bucketList.innerHTML = data.buckets.map(bucket =>
                         '<li class="bucket-item" onclick="selectBucket(\'' + bucket.name + '\')">' + bucket.name + '</li>'
                     ).join('');
                 } else {
+                    bucketNames = [];
                     bucketList.innerHTML = '<li style="color: #5f6368; padding: 8px;">No buckets found</li>';
                 }
             } catch (error) {
                 console.error('Failed to load buckets:', error);
-                document.getElementById('bucketList').innerHTML = '<li style="color: #ea4335; padding: 8px;">Error loading buckets</li>';
+                const item = document.createElement('li');
+                item.style.cssText = 'color: #ea4335; padding: 8px; font-size: 13px;';
+                item.textContent = 'Error loading buckets: ' + error.message;
+                document.getElementById('bucketList').replaceChildren(item);
             }
         }
 
@@ -341,15 +1018,18 @@ const GCS = `<!DOCTYPE html>
         async function loadObjects() {
             if (!currentBucket) return;
 
+            document.getElementById('objectToolbar').style.display = 'flex';
             document.getElementById('contentArea').innerHTML = '<div class="loading">Loading objects...</div>';
 
             try {
-                const url = '/api/gcs/objects?bucket=' + encodeURIComponent(currentBucket) +
+                const url = '/api/gcs/objects?' + connectionQuery() + '&bucket=' + encodeURIComponent(currentBucket) +
                            (currentPrefix ? '&prefix=' + encodeURIComponent(currentPrefix) : '');
                 const response = await fetch(url);
+                if (!response.ok) throw new Error(await response.text());
                 const data = await response.json();
 
-                if (data.prefixes || (data.items && data.items.length > 0)) {
+                const items = (data.items || []).filter(item => item.name !== currentPrefix);
+                if (data.prefixes || items.length > 0) {
                     let html = '<ul class="file-list">';
 
                     // Show prefixes (folders)
@@ -362,6 +1042,11 @@ const GCS = `<!DOCTYPE html>
                             html += '<div class="file-name">' + folderName + '</div>';
                             html += '<div class="file-meta">Folder</div>';
                             html += '</div>';
+                            html += '<div class="file-actions" onclick="event.stopPropagation()">';
+                            html += '<button class="btn" onclick="downloadPrefixZip(' + jsArg(prefix) + ')">ZIP</button>';
+                            html += '<button class="btn" onclick="openCopyModal(' + jsArg(prefix) + ')">Copy / Move</button>';
+                            html += '<button class="btn btn-danger" onclick="deletePrefix(' + jsArg(prefix) + ')">Delete</button>';
+                            html += '</div>';
                             html += '</li>';
                         });
                     }
@@ -371,16 +1056,22 @@ const GCS = `<!DOCTYPE html>
                         data.items.forEach(item => {
                             const fileName = item.name.replace(currentPrefix, '');
                             if (fileName) { // Skip if it's the prefix itself
-                                const fileSize = formatFileSize(item.size);
+                                const meta = [formatFileSize(item.size)];
+                                if (item.contentType) meta.push(escapeHtml(item.contentType));
+                                if (item.updated) meta.push('updated ' + new Date(item.updated).toLocaleString());
                                 html += '<li class="file-item">';
                                 html += '<div class="file-icon">📄</div>';
                                 html += '<div class="file-info">';
                                 html += '<div class="file-name">' + fileName + '</div>';
-                                html += '<div class="file-meta">' + fileSize + '</div>';
+                                html += '<div class="file-meta">' + meta.join(' · ') + '</div>';
                                 html += '</div>';
                                 html += '<div class="file-actions">';
                                 html += '<button class="btn" onclick="previewFile(\'' + item.name + '\')">Preview</button>';
+                                html += '<button class="btn" onclick="openDetails(' + jsArg(item.name) + ')">Details</button>';
+                                html += '<button class="btn" onclick="openDiff(' + jsArg(item.name) + ')">Compare</button>';
                                 html += '<button class="btn btn-primary" onclick="downloadFile(\'' + item.name + '\')">Download</button>';
+                                html += '<button class="btn" onclick="openCopyModal(' + jsArg(item.name) + ')">Copy / Move</button>';
+                                html += '<button class="btn btn-danger" onclick="deleteObject(' + jsArg(item.name) + ')">Delete</button>';
                                 html += '</div>';
                                 html += '</li>';
                             }
@@ -391,7 +1082,8 @@ const GCS = `<!DOCTYPE html>
                     document.getElementById('contentArea').innerHTML = html;
                 } else {
                     document.getElementById('contentArea').innerHTML =
-                        '<div class="empty-state"><div class="empty-state-icon">📭</div><div>This bucket is empty</div></div>';
+                        '<div class="empty-state"><div class="empty-state-icon">📭</div><div>' +
+                        (currentPrefix ? 'This folder is empty' : 'This bucket is empty') + '</div></div>';
                 }
             } catch (error) {
                 console.error('Failed to load objects:', error);
@@ -435,39 +1127,977 @@ const GCS = `<!DOCTYPE html>
             document.getElementById('breadcrumb').innerHTML = breadcrumb;
         }
 
-        async function previewFile(objectName) {
+        // generationQuery selects a noncurrent version; without a generation
+        // the live object is read
+        function generationQuery(generation) {
+            return generation ? '&generation=' + encodeURIComponent(generation) : '';
+        }
+
+        async function previewFile(objectName, generation) {
             document.getElementById('previewModal').classList.add('active');
-            document.getElementById('previewTitle').textContent = objectName;
+            document.getElementById('previewTitle').textContent = objectName + (generation ? ' #' + generation : '');
+            document.getElementById('previewContent').style.display = 'block';
             document.getElementById('previewContent').textContent = 'Loading...';
+            document.getElementById('previewImage').style.display = 'none';
+            document.getElementById('previewImage').removeAttribute('src');
+            document.getElementById('previewViews').style.display = 'none';
+            document.getElementById('previewInfo').textContent = '';
+            document.getElementById('previewProgress').textContent = '';
+            document.getElementById('previewMore').style.display = 'none';
 
-            try {
-                const url = '/api/gcs/object/content?bucket=' + encodeURIComponent(currentBucket) +
-                           '&object=' + encodeURIComponent(objectName);
-                const response = await fetch(url);
-                const data = await response.json();
+            document.getElementById('previewTable').style.display = 'none';
+            document.getElementById('tableFormat').value = '';
 
-                document.getElementById('previewContent').textContent = data.content;
-            } catch (error) {
-                console.error('Failed to preview file:', error);
-                document.getElementById('previewContent').textContent = 'Error loading file content';
+            preview = { name: objectName, generation: generation, bytes: new Uint8Array(0), nextOffset: 0, view: '', chunkLoaded: false };
+            if (tableFormat(objectName)) {
+                preview.view = 'table';
+                document.getElementById('previewViews').style.display = 'inline';
+                renderPreview();
+                return;
             }
+            await loadPreviewChunk();
         }
 
-        function closePreview() {
-            document.getElementById('previewModal').classList.remove('active');
+        // tableFormat mirrors the server's guess of a structured format from
+        // an object's name; those objects open in the table view
+        function tableFormat(name) {
+            const n = name.toLowerCase().replace(/\.gz$/, '');
+            if (/\.avro$/.test(n)) return 'avro';
+            if (/\.(parquet|parq)$/.test(n)) return 'parquet';
+            if (/\.(csv|tsv)$/.test(n)) return 'csv';
+            if (/\.(ndjson|jsonl)$/.test(n)) return 'ndjson';
+            if (/\.json$/.test(n)) return 'json';
+            return '';
         }
 
-        async function downloadFile(objectName) {
+        async function loadPreviewChunk() {
+            const current = preview;
+            const more = document.getElementById('previewMore');
+            more.disabled = true;
+
             try {
-                const url = '/api/gcs/object/download?bucket=' + encodeURIComponent(currentBucket) +
-                           '&object=' + encodeURIComponent(objectName);
-                window.location.href = url;
+                const query = connectionQuery() + '&bucket=' + encodeURIComponent(currentBucket) +
+                    '&object=' + encodeURIComponent(current.name) + generationQuery(current.generation);
+                const response = await fetch('/api/gcs/object/content?' + query + '&offset=' + current.nextOffset);
+                if (!response.ok) throw new Error(await response.text());
+                const data = await response.json();
+                if (preview !== current) return;
+
+                const info = [data.contentType || 'unknown type', formatFileSize(data.size) + (data.gunzipped ? ' compressed' : '')];
+                if (data.gunzipped) info.push('gunzipped');
+                document.getElementById('previewInfo').textContent = info.join(' · ');
+
+                if (data.kind === 'image') {
+                    document.getElementById('previewContent').style.display = 'none';
+                    const image = document.getElementById('previewImage');
+                    image.src = '/api/gcs/object/download?' + query + '&inline=1&contentType=' + encodeURIComponent(data.contentType);
+                    image.style.display = 'block';
+                    return;
+                }
+
+                const chunk = data.kind === 'text' ? new TextEncoder().encode(data.content || '') : base64ToBytes(data.data || '');
+                // Without a chosen view, binary objects open as hex
+                if (!current.view) {
+                    current.view = data.kind === 'binary' ? 'hex' : 'text';
+                }
+                const bytes = new Uint8Array(current.bytes.length + chunk.length);
+                bytes.set(current.bytes);
+                bytes.set(chunk, current.bytes.length);
+                current.bytes = bytes;
+                current.nextOffset = data.nextOffset;
+                current.eof = data.eof;
+                current.gunzipped = data.gunzipped;
+                current.size = data.size;
+                current.chunkLoaded = true;
+
+                document.getElementById('previewViews').style.display = 'inline';
+                renderPreview();
+            } catch (error) {
+                console.error('Failed to preview file:', error);
+                if (preview === current) {
+                    document.getElementById('previewContent').textContent = 'Error loading file content: ' + error.message;
+                }
+            } finally {
+                more.disabled = false;
+            }
+        }
+
+        function setPreviewView(view) {
+            if (!preview) return;
+            preview.view = view;
+            renderPreview();
+            if (view !== 'table' && !preview.chunkLoaded) {
+                loadPreviewChunk();
+            }
+        }
+
+        function renderPreview() {
+            document.getElementById('previewTableBtn').classList.toggle('active', preview.view === 'table');
+            document.getElementById('previewTextBtn').classList.toggle('active', preview.view === 'text');
+            document.getElementById('previewHexBtn').classList.toggle('active', preview.view === 'hex');
+
+            const isTable = preview.view === 'table';
+            document.getElementById('previewTable').style.display = isTable ? 'block' : 'none';
+            document.getElementById('previewContent').style.display = isTable ? 'none' : 'block';
+            if (isTable) {
+                document.getElementById('previewProgress').textContent = '';
+                document.getElementById('previewMore').style.display = 'none';
+                if (!preview.table && !preview.tableLoading) {
+                    loadPreviewTable();
+                }
+                return;
+            }
+            if (!preview.chunkLoaded) {
+                document.getElementById('previewContent').textContent = 'Loading...';
+                document.getElementById('previewProgress').textContent = '';
+                document.getElementById('previewMore').style.display = 'none';
+                return;
+            }
+
+            document.getElementById('previewContent').textContent = preview.view === 'hex' ?
+                hexDump(preview.bytes) : new TextDecoder().decode(preview.bytes);
+
+            const loaded = formatFileSize(preview.bytes.length);
+            document.getElementById('previewProgress').textContent = preview.gunzipped ?
+                'Showing ' + loaded + ' decompressed' + (preview.eof ? ' (complete)' : '') :
+                'Showing ' + loaded + ' of ' + formatFileSize(preview.size);
+            document.getElementById('previewMore').style.display = preview.eof ? 'none' : 'inline-block';
+        }
+
+        async function loadPreviewTable() {
+            const current = preview;
+            current.tableLoading = true;
+            document.getElementById('tableSummary').textContent = 'Loading records...';
+            document.getElementById('tableData').innerHTML = '';
+            document.getElementById('tableColumns').innerHTML = '';
+            document.getElementById('tableRawSchema').textContent = '';
+
+            try {
+                const url = '/api/gcs/object/table?' + connectionQuery() + '&bucket=' + encodeURIComponent(currentBucket) +
+                    '&object=' + encodeURIComponent(current.name) + generationQuery(current.generation) +
+                    '&format=' + encodeURIComponent(document.getElementById('tableFormat').value) +
+                    '&limit=' + document.getElementById('tableLimit').value;
+                const response = await fetch(url);
+                if (!response.ok) throw new Error(await response.text());
+                const data = await response.json();
+                if (preview !== current) return;
+
+                current.table = data;
+                current.filters = {};
+                current.hidden = {};
+                document.getElementById('previewInfo').textContent = data.format.toUpperCase() + ' · ' + data.schema.length + ' column(s)';
+                document.getElementById('tableRawSchema').textContent = data.rawSchema ||
+                    data.schema.map(column => column.name + ': ' + column.type).join('\n');
+                renderTable();
+            } catch (error) {
+                if (preview === current) {
+                    current.table = null;
+                    document.getElementById('tableSummary').textContent = 'Error: ' + error.message;
+                }
+            } finally {
+                current.tableLoading = false;
+            }
+        }
+
+        // renderTable draws the column toggles and the table head, whose
+        // filter inputs are kept while filtering redraws only the body
+        function renderTable() {
+            const table = preview.table;
+            document.getElementById('tableColumns').innerHTML = table.schema.map((column, i) =>
+                '<label><input type="checkbox"' + (preview.hidden[i] ? '' : ' checked') +
+                ' onchange="toggleTableColumn(' + i + ', this.checked)"> ' + escapeHtml(column.name) + '</label>'
+            ).join('');
+
+            const visible = table.schema.map((column, i) => i).filter(i => !preview.hidden[i]);
+            let head = '<thead><tr>' + visible.map(i =>
+                '<th title="' + escapeHtml(table.schema[i].name) + '">' + escapeHtml(table.schema[i].name) +
+                '<span class="column-type">' + escapeHtml(table.schema[i].type) + '</span></th>'
+            ).join('') + '</tr><tr>' + visible.map(i =>
+                '<th><input type="text" placeholder="filter" value="' + escapeHtml(preview.filters[i] || '').replace(/"/g, '&quot;') +
+                '" oninput="filterTable(' + i + ', this.value)"></th>'
+            ).join('') + '</tr></thead>';
+            document.getElementById('tableData').innerHTML = head + '<tbody id="tableBody"></tbody>';
+            renderTableRows();
+        }
+
+        function renderTableRows() {
+            const table = preview.table;
+            const visible = table.schema.map((column, i) => i).filter(i => !preview.hidden[i]);
+            const filters = Object.keys(preview.filters)
+                .filter(i => preview.filters[i])
+                .map(i => [Number(i), preview.filters[i].toLowerCase()]);
+
+            const rows = table.rows.filter(row => filters.every(([i, text]) =>
+                String(row[i] === null ? 'null' : row[i]).toLowerCase().includes(text)));
+
+            document.getElementById('tableBody').innerHTML = rows.map(row => '<tr>' + visible.map(i =>
+                row[i] === null ?
+                    '<td class="null-cell">null</td>' :
+                    '<td title="' + escapeHtml(row[i]).replace(/"/g, '&quot;') + '">' + escapeHtml(row[i]) + '</td>'
+            ).join('') + '</tr>').join('');
+
+            let summary = (filters.length ? rows.length + ' of ' : '') + table.rows.length + ' record(s)';
+            if (table.totalRows) summary += ' of ' + table.totalRows + ' in the file';
+            else if (table.truncated) summary += ', more in the file';
+            document.getElementById('tableSummary').textContent = summary;
+        }
+
+        function filterTable(column, text) {
+            preview.filters[column] = text;
+            renderTableRows();
+        }
+
+        function toggleTableColumn(column, shown) {
+            preview.hidden[column] = !shown;
+            renderTable();
+        }
+
+        function base64ToBytes(value) {
+            return Uint8Array.from(atob(value), c => c.charCodeAt(0));
+        }
+
+        // hexDump renders bytes as offset, sixteen hex bytes and their
+        // printable ASCII, like hexdump -C
+        function hexDump(bytes) {
+            const lines = [];
+            for (let offset = 0; offset < bytes.length; offset += 16) {
+                const row = bytes.subarray(offset, offset + 16);
+                let hex = '';
+                let ascii = '';
+                for (let i = 0; i < 16; i++) {
+                    if (i === 8) hex += ' ';
+                    if (i < row.length) {
+                        hex += row[i].toString(16).padStart(2, '0') + ' ';
+                        ascii += row[i] >= 0x20 && row[i] < 0x7f ? String.fromCharCode(row[i]) : '.';
+                    } else {
+                        hex += '   ';
+                    }
+                }
+                lines.push(offset.toString(16).padStart(8, '0') + '  ' + hex + ' |' + ascii + '|');
+            }
+            return lines.join('\n');
+        }
+
+        function closePreview() {
+            preview = null;
+            document.getElementById('previewModal').classList.remove('active');
+        }
+
+        async function downloadFile(objectName, generation) {
+            try {
+                const url = '/api/gcs/object/download?' + connectionQuery() + '&bucket=' + encodeURIComponent(currentBucket) +
+                           '&object=' + encodeURIComponent(objectName) + generationQuery(generation);
+                window.location.href = url;
             } catch (error) {
                 console.error('Failed to download file:', error);
                 alert('Error downloading file');
             }
         }
 
+        // base64ToHex shows an md5Hash, which the API encodes in base64, the
+        // way md5sum prints it
+        function base64ToHex(value) {
+            try {
+                return Array.from(atob(value), c => c.charCodeAt(0).toString(16).padStart(2, '0')).join('');
+            } catch (e) {
+                return '';
+            }
+        }
+
+        function showDetailsStatus(message, isError) {
+            const status = document.getElementById('detailsStatus');
+            status.style.display = message ? 'block' : 'none';
+            status.style.background = isError ? '#fce8e6' : '#e8f5e9';
+            status.style.color = isError ? '#d93025' : '#188038';
+            status.textContent = message;
+        }
+
+        async function openDetails(objectName) {
+            detailsObject = objectName;
+            document.getElementById('detailsTitle').textContent = objectName;
+            document.getElementById('detailsTable').innerHTML = '<tr><td colspan="2" class="loading">Loading...</td></tr>';
+            document.getElementById('metadataRows').innerHTML = '';
+            document.getElementById('versionsList').innerHTML = '';
+            showDetailsStatus('', false);
+            document.getElementById('detailsModal').classList.add('active');
+
+            const query = connectionQuery() + '&bucket=' + encodeURIComponent(currentBucket) + '&object=' + encodeURIComponent(objectName);
+            try {
+                const response = await fetch('/api/gcs/object/metadata?' + query);
+                if (!response.ok) throw new Error(await response.text());
+                renderDetails(await response.json());
+            } catch (error) {
+                document.getElementById('detailsTable').innerHTML = '';
+                showDetailsStatus('Failed to load metadata: ' + error.message, true);
+                return;
+            }
+
+            loadVersions(query);
+        }
+
+        function renderDetails(obj) {
+            const md5 = obj.md5Hash ? obj.md5Hash + ' (hex ' + base64ToHex(obj.md5Hash) + ')' : '';
+            const rows = [
+                ['Name', obj.name],
+                ['Size', formatFileSize(obj.size) + ' (' + obj.size + ' bytes)'],
+                ['Content Type', obj.contentType],
+                ['Storage Class', obj.storageClass],
+                ['MD5', md5],
+                ['CRC32C', obj.crc32c],
+                ['Generation', obj.generation],
+                ['Metageneration', obj.metageneration],
+                ['Created', obj.timeCreated ? new Date(obj.timeCreated).toLocaleString() : ''],
+                ['Updated', obj.updated ? new Date(obj.updated).toLocaleString() : '']
+            ];
+            document.getElementById('detailsTable').innerHTML = rows.map(row =>
+                '<tr><td>' + row[0] + '</td><td>' + escapeHtml(row[1] || '—') + '</td></tr>'
+            ).join('');
+
+            document.getElementById('metadataRows').innerHTML = '';
+            const metadata = obj.metadata || {};
+            Object.keys(metadata).sort().forEach(key => addMetadataRow(key, metadata[key]));
+            if (Object.keys(metadata).length === 0) addMetadataRow('', '');
+        }
+
+        function addMetadataRow(key, value) {
+            const row = document.createElement('div');
+            row.className = 'metadata-row';
+            row.innerHTML = '<input type="text" class="metadata-key" placeholder="key">' +
+                '<input type="text" class="metadata-value" placeholder="value">' +
+                '<button class="btn btn-danger" onclick="this.parentElement.remove()">×</button>';
+            row.querySelector('.metadata-key').value = key;
+            row.querySelector('.metadata-value').value = value;
+            document.getElementById('metadataRows').appendChild(row);
+        }
+
+        async function saveMetadata() {
+            const metadata = {};
+            document.querySelectorAll('#metadataRows .metadata-row').forEach(row => {
+                const key = row.querySelector('.metadata-key').value.trim();
+                if (key) metadata[key] = row.querySelector('.metadata-value').value;
+            });
+
+            try {
+                const obj = await gcsRequest('/api/gcs/object/metadata/update', 'POST', {
+                    bucket: currentBucket,
+                    object: detailsObject,
+                    metadata: metadata
+                });
+                renderDetails(obj);
+                showDetailsStatus('Metadata saved', false);
+            } catch (error) {
+                showDetailsStatus('Failed to save metadata: ' + error.message, true);
+            }
+        }
+
+        async function loadVersions(query) {
+            const list = document.getElementById('versionsList');
+            list.innerHTML = '<div class="loading">Loading versions...</div>';
+            try {
+                const response = await fetch('/api/gcs/object/versions?' + query);
+                if (!response.ok) throw new Error(await response.text());
+                const data = await response.json();
+
+                let html = '';
+                if (!data.versioningEnabled) {
+                    html += '<div style="color: #5f6368; margin-bottom: 8px;">Versioning is not enabled on this bucket; only the live generation is kept.</div>';
+                }
+                html += '<table class="details-table">';
+                data.versions.forEach(version => {
+                    const live = !version.timeDeleted;
+                    html += '<tr><td>' + escapeHtml(version.generation) +
+                        (live ? ' <span class="version-live">live</span>' : '') + '</td><td>' +
+                        formatFileSize(version.size) + ' · ' +
+                        (version.updated ? new Date(version.updated).toLocaleString() : '') +
+                        (live ? '' : ' · noncurrent since ' + new Date(version.timeDeleted).toLocaleString()) +
+                        '<div style="margin-top: 4px;">' +
+                        '<button class="btn" onclick="previewFile(' + jsArg(detailsObject) + ', ' + jsArg(version.generation) + ')">Preview</button> ' +
+                        '<button class="btn" onclick="downloadFile(' + jsArg(detailsObject) + ', ' + jsArg(version.generation) + ')">Download</button>' +
+                        (live ? '' : ' <button class="btn" onclick="openDiff(' + jsArg(detailsObject) + ', ' + jsArg(version.generation) + ')">Compare with live</button>') +
+                        '</div></td></tr>';
+                });
+                html += '</table>';
+                list.innerHTML = html;
+            } catch (error) {
+                list.textContent = 'Failed to load versions: ' + error.message;
+            }
+        }
+
+        function closeDetails() {
+            document.getElementById('detailsModal').classList.remove('active');
+        }
+
+        // openDiff compares an object, or one generation of it, with the
+        // object or generation chosen on the right; from a noncurrent
+        // version that is the live generation
+        function openDiff(objectName, generation) {
+            const options = bucketNames.map(b => '<option value="' + escapeHtml(b) + '">' + escapeHtml(b) + '</option>').join('');
+            document.getElementById('diffLeftBucket').innerHTML = options;
+            document.getElementById('diffRightBucket').innerHTML = options;
+            document.getElementById('diffLeftBucket').value = currentBucket;
+            document.getElementById('diffRightBucket').value = currentBucket;
+            document.getElementById('diffLeftObject').value = objectName;
+            document.getElementById('diffLeftGeneration').value = generation || '';
+            document.getElementById('diffRightObject').value = '';
+            document.getElementById('diffRightGeneration').value = '';
+            document.getElementById('diffStatus').style.display = 'none';
+            document.getElementById('diffSummary').innerHTML = '';
+            document.getElementById('diffResult').innerHTML = '';
+            document.getElementById('diffModal').classList.add('active');
+
+            if (generation) {
+                runDiff();
+            } else {
+                document.getElementById('diffRightObject').focus();
+            }
+        }
+
+        function closeDiff() {
+            document.getElementById('diffModal').classList.remove('active');
+        }
+
+        function swapDiff() {
+            for (const field of ['Bucket', 'Object', 'Generation']) {
+                const left = document.getElementById('diffLeft' + field);
+                const right = document.getElementById('diffRight' + field);
+                const value = left.value;
+                left.value = right.value || (field === 'Object' ? value : '');
+                right.value = value;
+            }
+        }
+
+        function showDiffStatus(message, isError) {
+            const status = document.getElementById('diffStatus');
+            status.style.display = 'block';
+            status.style.background = isError ? '#fce8e6' : '#e8f5e9';
+            status.style.color = isError ? '#d93025' : '#188038';
+            status.textContent = message;
+        }
+
+        async function runDiff() {
+            const params = new URLSearchParams({
+                bucket: document.getElementById('diffLeftBucket').value,
+                object: document.getElementById('diffLeftObject').value.trim(),
+                generation: document.getElementById('diffLeftGeneration').value.trim(),
+                otherBucket: document.getElementById('diffRightBucket').value,
+                otherObject: document.getElementById('diffRightObject').value.trim(),
+                otherGeneration: document.getElementById('diffRightGeneration').value.trim(),
+                mode: document.getElementById('diffMode').value,
+                keyColumns: document.getElementById('diffKeyColumns').value,
+                ignoreWhitespace: document.getElementById('diffIgnoreWhitespace').checked ? '1' : '',
+                context: document.getElementById('diffContext').value
+            });
+
+            document.getElementById('diffStatus').style.display = 'none';
+            document.getElementById('diffSummary').innerHTML = '';
+            document.getElementById('diffResult').innerHTML = '<div class="loading">Comparing...</div>';
+            try {
+                const response = await fetch('/api/gcs/object/diff?' + params.toString() + '&' + connectionQuery());
+                if (!response.ok) throw new Error(await response.text());
+                renderDiff(await response.json());
+            } catch (error) {
+                document.getElementById('diffResult').innerHTML = '';
+                showDiffStatus(error.message, true);
+            }
+        }
+
+        function diffSide(obj) {
+            return escapeHtml(obj.name) + (obj.generation ? ' <span style="color: #5f6368;">#' + escapeHtml(obj.generation) + '</span>' : '');
+        }
+
+        function renderDiff(diff) {
+            const labels = { text: 'lines', json: 'paths', csv: 'rows' };
+            let summary = '<strong>' + diffSide(diff.left) + '</strong> → <strong>' + diffSide(diff.right) + '</strong> · ' + escapeHtml(diff.mode) + ' diff · ';
+            if (diff.identical) {
+                summary += '<span style="color: #188038;">identical</span>';
+            } else {
+                summary += '<span style="color: #188038;">+' + diff.added + ' added</span> ' +
+                    '<span style="color: #d93025;">−' + diff.removed + ' removed</span> ' +
+                    (diff.mode === 'text' ? '' : '<span style="color: #b06000;">~' + diff.changed + ' changed</span> ') +
+                    '<span style="color: #5f6368;">' + labels[diff.mode] + '</span>';
+            }
+            if (diff.truncated) {
+                summary += ' · <span style="color: #b06000;">only the first ' + labels[diff.mode] + ' are shown</span>';
+            }
+            if (diff.note) {
+                summary += '<div style="color: #5f6368; font-size: 12px; margin-top: 4px;">' + escapeHtml(diff.note) + '</div>';
+            }
+            document.getElementById('diffSummary').innerHTML = summary;
+
+            const result = document.getElementById('diffResult');
+            if (diff.identical) {
+                result.innerHTML = '';
+            } else if (diff.mode === 'json') {
+                result.innerHTML = renderJSONDiff(diff.json || []);
+            } else if (diff.mode === 'csv') {
+                result.innerHTML = renderRowDiff(diff.rows);
+            } else {
+                result.innerHTML = renderTextDiff(diff.hunks || []);
+            }
+        }
+
+        function renderTextDiff(hunks) {
+            let html = '<table class="diff-table">';
+            hunks.forEach(hunk => {
+                html += '<tr class="diff-hunk"><td class="diff-num"></td><td class="diff-num"></td><td colspan="2">@@ -' + hunk.leftStart + ' +' + hunk.rightStart + ' @@</td></tr>';
+                hunk.lines.forEach(line => {
+                    const cls = line.op === '-' ? 'diff-removed' : line.op === '+' ? 'diff-added' : '';
+                    html += '<tr class="' + cls + '"><td class="diff-num">' + (line.left || '') + '</td><td class="diff-num">' + (line.right || '') + '</td>' +
+                        '<td style="width: 12px;">' + (line.op === '=' ? ' ' : line.op) + '</td><td>' + escapeHtml(line.text) + '</td></tr>';
+                });
+            });
+            return html + '</table>';
+        }
+
+        function renderJSONDiff(changes) {
+            const cell = 'padding: 4px 8px; border: 1px solid #e0e0e0; text-align: left; vertical-align: top;';
+            const value = v => '<code style="white-space: pre-wrap; word-break: break-all;">' + escapeHtml(JSON.stringify(v)) + '</code>';
+            let html = '<table style="border-collapse: collapse; font-size: 12px; width: 100%;"><thead><tr style="background: #f8f9fa;">' +
+                '<th style="' + cell + '">Path</th><th style="' + cell + '">Change</th><th style="' + cell + '">Left</th><th style="' + cell + '">Right</th></tr></thead><tbody>';
+            changes.forEach(change => {
+                const color = change.op === 'added' ? '#188038' : change.op === 'removed' ? '#d93025' : '#b06000';
+                html += '<tr><td style="' + cell + ' font-family: monospace;">' + escapeHtml(change.path) + '</td>' +
+                    '<td style="' + cell + ' color: ' + color + ';">' + escapeHtml(change.op) + '</td>' +
+                    '<td style="' + cell + '">' + (change.op === 'added' ? '' : value(change.before)) + '</td>' +
+                    '<td style="' + cell + '">' + (change.op === 'removed' ? '' : value(change.after)) + '</td></tr>';
+            });
+            return html + '</tbody></table>';
+        }
+
+        function renderRowDiff(t) {
+            const cell = 'padding: 4px 8px; border: 1px solid #e0e0e0; text-align: left; vertical-align: top; white-space: nowrap;';
+            let html = '<div style="color: #5f6368; font-size: 12px; margin-bottom: 8px;">' + t.unchanged + ' unchanged' +
+                (t.keyColumns && t.keyColumns.length ? ', matched on ' + escapeHtml(t.keyColumns.join(', ')) : '') +
+                (t.note ? '. ' + escapeHtml(t.note) : '') + '</div>';
+
+            const columns = [];
+            t.added.concat(t.removed).forEach(row => Object.keys(row).forEach(c => { if (columns.indexOf(c) === -1) columns.push(c); }));
+            t.changed.forEach(c => Object.keys(c.after).forEach(col => { if (columns.indexOf(col) === -1) columns.push(col); }));
+
+            html += '<table style="border-collapse: collapse; font-size: 12px; width: 100%;"><thead><tr style="background: #f8f9fa;">' +
+                '<th style="' + cell + ' width: 30px;"></th>' + columns.map(c => '<th style="' + cell + '">' + escapeHtml(c) + '</th>').join('') + '</tr></thead><tbody>';
+            const text = v => v === undefined ? '' : escapeHtml(v);
+            t.removed.forEach(row => {
+                html += '<tr style="background: #fce8e6;"><td style="' + cell + ' color: #d93025;">−</td>' +
+                    columns.map(c => '<td style="' + cell + '">' + text(row[c]) + '</td>').join('') + '</tr>';
+            });
+            t.added.forEach(row => {
+                html += '<tr style="background: #e6f4ea;"><td style="' + cell + ' color: #188038;">+</td>' +
+                    columns.map(c => '<td style="' + cell + '">' + text(row[c]) + '</td>').join('') + '</tr>';
+            });
+            t.changed.forEach(change => {
+                html += '<tr><td style="' + cell + ' color: #b06000;">~</td>' + columns.map(c => {
+                    if (change.changedColumns.indexOf(c) === -1) {
+                        return '<td style="' + cell + '">' + text(change.after[c]) + '</td>';
+                    }
+                    return '<td style="' + cell + ' background: #fef7e0;"><span style="color: #d93025; text-decoration: line-through;">' + text(change.before[c]) +
+                        '</span> → <span style="color: #188038;">' + text(change.after[c]) + '</span></td>';
+                }).join('') + '</tr>';
+            });
+            return html + '</tbody></table>';
+        }
+
+        function toggleSearch() {
+            const panel = document.getElementById('searchPanel');
+            panel.style.display = panel.style.display === 'flex' ? 'none' : 'flex';
+            if (panel.style.display === 'flex') {
+                document.getElementById('searchPattern').focus();
+            }
+        }
+
+        function closeSearch() {
+            document.getElementById('searchPanel').style.display = 'none';
+            loadObjects();
+        }
+
+        // parseSize reads sizes such as "512", "10 KB" or "1.5G" as bytes
+        function parseSize(text) {
+            const match = text.trim().match(/^(\d+(?:\.\d+)?)\s*([kmg]?)b?$/i);
+            if (!match) return null;
+            const units = { '': 1, k: 1024, m: 1024 * 1024, g: 1024 * 1024 * 1024 };
+            return Math.round(parseFloat(match[1]) * units[match[2].toLowerCase()]);
+        }
+
+        // searchObjects searches recursively under the current prefix and
+        // lists the matches with checkboxes for a ZIP download
+        async function searchObjects() {
+            if (!currentBucket) return;
+
+            let query = connectionQuery() + '&bucket=' + encodeURIComponent(currentBucket) +
+                '&prefix=' + encodeURIComponent(currentPrefix) +
+                '&pattern=' + encodeURIComponent(document.getElementById('searchPattern').value.trim()) +
+                '&mode=' + document.getElementById('searchMode').value;
+            for (const [id, param] of [['searchMinSize', 'minSize'], ['searchMaxSize', 'maxSize']]) {
+                const text = document.getElementById(id).value;
+                if (!text.trim()) continue;
+                const size = parseSize(text);
+                if (size === null) {
+                    showGCSStatus('Invalid size: ' + text, true);
+                    return;
+                }
+                query += '&' + param + '=' + size;
+            }
+            for (const [id, param] of [['searchAfter', 'updatedAfter'], ['searchBefore', 'updatedBefore']]) {
+                const value = document.getElementById(id).value;
+                if (value) query += '&' + param + '=' + encodeURIComponent(new Date(value).toISOString());
+            }
+
+            document.getElementById('gcsStatus').style.display = 'none';
+            document.getElementById('contentArea').innerHTML = '<div class="loading">Searching...</div>';
+            try {
+                const response = await fetch('/api/gcs/search?' + query);
+                if (!response.ok) throw new Error(await response.text());
+                renderSearchResults(await response.json());
+            } catch (error) {
+                document.getElementById('contentArea').innerHTML = '';
+                showGCSStatus('Search failed: ' + error.message, true);
+            }
+        }
+
+        function renderSearchResults(data) {
+            let html = '<div class="result-bar">';
+            html += '<input type="checkbox" id="selectAllResults" onchange="document.querySelectorAll(\'.result-check\').forEach(c => c.checked = this.checked)">';
+            html += '<span style="flex: 1;">' + data.items.length + (data.truncated ? '+' : '') + ' match(es) under ' +
+                escapeHtml(currentBucket + '/' + currentPrefix) + ' (' + data.scanned + ' objects scanned)</span>';
+            html += '<button class="btn btn-primary" onclick="downloadSelectedZip()">Download selected as ZIP</button>';
+            html += '</div>';
+
+            if (data.items.length === 0) {
+                html += '<div class="empty-state"><div class="empty-state-icon">🔍</div><div>No objects match</div></div>';
+                document.getElementById('contentArea').innerHTML = html;
+                return;
+            }
+
+            html += '<ul class="file-list">';
+            data.items.forEach(item => {
+                const meta = [formatFileSize(item.size)];
+                if (item.updated) meta.push('updated ' + new Date(item.updated).toLocaleString());
+                html += '<li class="file-item">';
+                html += '<input type="checkbox" class="result-check" value="' + escapeHtml(item.name).replace(/"/g, '&quot;') + '" style="margin-right: 12px;">';
+                html += '<div class="file-icon">📄</div>';
+                html += '<div class="file-info">';
+                html += '<div class="file-name">' + escapeHtml(item.name) + '</div>';
+                html += '<div class="file-meta">' + meta.join(' · ') + '</div>';
+                html += '</div>';
+                html += '<div class="file-actions">';
+                html += '<button class="btn" onclick="previewFile(' + jsArg(item.name) + ')">Preview</button>';
+                html += '<button class="btn" onclick="openDetails(' + jsArg(item.name) + ')">Details</button>';
+                html += '<button class="btn btn-primary" onclick="downloadFile(' + jsArg(item.name) + ')">Download</button>';
+                html += '</div>';
+                html += '</li>';
+            });
+            html += '</ul>';
+            document.getElementById('contentArea').innerHTML = html;
+        }
+
+        function downloadPrefixZip(prefix) {
+            if (!currentBucket) return;
+            window.location.href = '/api/gcs/zip?' + connectionQuery() + '&bucket=' + encodeURIComponent(currentBucket) +
+                '&prefix=' + encodeURIComponent(prefix);
+        }
+
+        // downloadSelectedZip posts the selected names as a form, so the
+        // browser saves the streamed archive itself
+        function downloadSelectedZip() {
+            const names = Array.from(document.querySelectorAll('.result-check:checked')).map(c => c.value);
+            if (names.length === 0) {
+                showGCSStatus('Select the objects to download first', true);
+                return;
+            }
+
+            const form = document.createElement('form');
+            form.method = 'POST';
+            form.action = '/api/gcs/zip?' + connectionQuery();
+            [['bucket', currentBucket]].concat(names.map(name => ['object', name])).forEach(([key, value]) => {
+                const input = document.createElement('input');
+                input.type = 'hidden';
+                input.name = key;
+                input.value = value;
+                form.appendChild(input);
+            });
+            document.body.appendChild(form);
+            form.submit();
+            form.remove();
+        }
+
+        function onDragOver(event) {
+            if (!currentBucket || !event.dataTransfer.types.includes('Files')) return;
+            event.preventDefault();
+            document.getElementById('contentPane').classList.add('drag-over');
+        }
+
+        function onDragLeave(event) {
+            if (!event.currentTarget.contains(event.relatedTarget)) {
+                document.getElementById('contentPane').classList.remove('drag-over');
+            }
+        }
+
+        function onDrop(event) {
+            document.getElementById('contentPane').classList.remove('drag-over');
+            if (!currentBucket) return;
+            event.preventDefault();
+            uploadFiles(event.dataTransfer.files);
+        }
+
+        async function uploadFiles(files) {
+            if (!currentBucket || files.length === 0) return;
+
+            const form = new FormData();
+            form.append('bucket', currentBucket);
+            form.append('prefix', currentPrefix);
+            form.append('uploadType', document.getElementById('uploadType').value);
+            for (const file of files) {
+                form.append('files', file);
+            }
+
+            showGCSStatus('Uploading ' + files.length + ' file(s) to ' + currentBucket + '/' + currentPrefix + '...', false);
+            try {
+                const result = await gcsRequest('/api/gcs/upload', 'POST', form);
+                const types = result.uploaded.map(u => u.uploadType).filter((t, i, all) => all.indexOf(t) === i);
+                showOperation(result.message + ' (' + types.join(', ') + ')', result);
+            } catch (error) {
+                showGCSStatus('Upload failed: ' + error.message, true);
+            }
+            loadObjects();
+        }
+
+        async function createFolder() {
+            const name = prompt('Folder name');
+            if (!name || !name.replace(/\/+$/, '')) return;
+
+            try {
+                const result = await gcsRequest('/api/gcs/folder/create', 'POST', { bucket: currentBucket, prefix: currentPrefix + name.replace(/\/+$/, '') + '/' });
+                showOperation(result.message, result);
+            } catch (error) {
+                showGCSStatus('Failed to create folder: ' + error.message, true);
+            }
+            loadObjects();
+        }
+
+        async function deleteObject(name) {
+            if (!confirm('Delete ' + name + '?')) return;
+
+            try {
+                const result = await gcsRequest('/api/gcs/object/delete?bucket=' + encodeURIComponent(currentBucket) + '&object=' + encodeURIComponent(name), 'DELETE');
+                showOperation(result.message, result);
+            } catch (error) {
+                showGCSStatus('Failed to delete ' + name + ': ' + error.message, true);
+            }
+            loadObjects();
+        }
+
+        async function deletePrefix(prefix) {
+            if (!confirm('Delete every object under ' + prefix + '?')) return;
+
+            try {
+                const result = await gcsRequest('/api/gcs/object/delete?bucket=' + encodeURIComponent(currentBucket) + '&prefix=' + encodeURIComponent(prefix), 'DELETE');
+                showOperation(result.message, result);
+            } catch (error) {
+                showGCSStatus('Failed to delete ' + prefix + ': ' + error.message, true);
+            }
+            loadObjects();
+        }
+
+        function openCopyModal(name) {
+            copySource = name;
+            const isPrefix = name.endsWith('/');
+            document.getElementById('copySource').textContent = currentBucket + '/' + name;
+            document.getElementById('copyNameLabel').textContent = isPrefix ? 'Destination Folder' : 'Destination Name';
+            document.getElementById('copyName').value = name;
+            document.getElementById('copyMove').checked = false;
+            document.getElementById('copyBucket').innerHTML = bucketNames.map(b =>
+                '<option value="' + escapeHtml(b) + '"' + (b === currentBucket ? ' selected' : '') + '>' + escapeHtml(b) + '</option>'
+            ).join('');
+            document.getElementById('copyModal').classList.add('active');
+        }
+
+        function closeCopyModal() {
+            document.getElementById('copyModal').classList.remove('active');
+        }
+
+        async function runCopy() {
+            const req = {
+                sourceBucket: currentBucket,
+                sourceObject: copySource,
+                destinationBucket: document.getElementById('copyBucket').value,
+                destinationObject: document.getElementById('copyName').value.trim(),
+                move: document.getElementById('copyMove').checked
+            };
+
+            closeCopyModal();
+            showGCSStatus((req.move ? 'Moving ' : 'Copying ') + copySource + '...', false);
+            try {
+                const result = await gcsRequest('/api/gcs/object/copy', 'POST', req);
+                showOperation(result.message + ' to ' + req.destinationBucket + '/' + req.destinationObject, result);
+            } catch (error) {
+                showGCSStatus('Copy failed: ' + error.message, true);
+            }
+            loadObjects();
+        }
+
+        function openBucketModal() {
+            document.getElementById('newBucketName').value = '';
+            document.getElementById('newBucketLocation').value = '';
+            document.getElementById('bucketModal').classList.add('active');
+        }
+
+        function closeBucketModal() {
+            document.getElementById('bucketModal').classList.remove('active');
+        }
+
+        async function createBucket() {
+            const name = document.getElementById('newBucketName').value.trim();
+            if (!name) return;
+
+            closeBucketModal();
+            try {
+                const result = await gcsRequest('/api/gcs/buckets/create', 'POST', {
+                    name: name,
+                    location: document.getElementById('newBucketLocation').value.trim()
+                });
+                showGCSStatus(result.message, false);
+                await loadBuckets();
+                selectBucket(name);
+            } catch (error) {
+                showGCSStatus('Failed to create bucket: ' + error.message, true);
+            }
+        }
+
+        function showNotificationsStatus(message, isError) {
+            const status = document.getElementById('notificationsStatus');
+            status.style.display = message ? 'block' : 'none';
+            status.style.background = isError ? '#fce8e6' : '#e8f5e9';
+            status.style.color = isError ? '#d93025' : '#188038';
+            status.textContent = message;
+        }
+
+        async function openNotifications() {
+            if (!currentBucket) return;
+
+            document.getElementById('notificationsTitle').textContent = 'Notifications of ' + currentBucket;
+            showNotificationsStatus('', false);
+            document.getElementById('notifyTopic').value = '';
+            document.getElementById('notifyPrefix').value = currentPrefix;
+            document.getElementById('notifyAttributes').value = '';
+            document.getElementById('notifyWatch').checked = false;
+
+            const select = document.getElementById('notifyPubSubConfig');
+            select.innerHTML = '<option value="">Custom</option>';
+            try {
+                const response = await fetch('/api/configs');
+                const data = await response.json();
+                (data.pubsubConfigs || []).forEach(cfg => {
+                    const option = document.createElement('option');
+                    option.value = JSON.stringify({ emulatorHost: cfg.emulatorHost, projectId: cfg.projectId });
+                    option.textContent = cfg.name;
+                    select.appendChild(option);
+                });
+            } catch (error) {
+                console.error('Failed to load Pub/Sub configurations:', error);
+            }
+            if (select.options.length > 1) {
+                select.selectedIndex = 1;
+                selectNotifyPubSubConfig();
+            }
+
+            document.getElementById('notificationsModal').classList.add('active');
+            loadNotifications();
+        }
+
+        function closeNotifications() {
+            document.getElementById('notificationsModal').classList.remove('active');
+        }
+
+        function selectNotifyPubSubConfig() {
+            const value = document.getElementById('notifyPubSubConfig').value;
+            if (!value) return;
+            const cfg = JSON.parse(value);
+            document.getElementById('notifyPubSubHost').value = cfg.emulatorHost || '';
+            document.getElementById('notifyPubSubProject').value = cfg.projectId || '';
+        }
+
+        async function loadNotifications() {
+            const list = document.getElementById('notificationsList');
+            try {
+                const response = await fetch('/api/gcs/notifications?bucket=' + encodeURIComponent(currentBucket) + '&' + connectionQuery());
+                if (!response.ok) throw new Error(await response.text());
+                const notifications = await response.json();
+
+                if (notifications.length === 0) {
+                    list.innerHTML = '<div style="color: #5f6368; font-size: 13px;">No notifications publish this bucket\'s changes yet</div>';
+                    return;
+                }
+                list.innerHTML = notifications.map(n => {
+                    const attributes = Object.entries(n.customAttributes || {}).map(([k, v]) => k + '=' + v).join(', ');
+                    return '<div class="notification-item"><div>' +
+                        '<div><strong>#' + escapeHtml(n.id) + '</strong> → ' + escapeHtml(n.topicId) + ' on ' + escapeHtml(n.pubsubEmulatorHost) + ' (' + escapeHtml(n.pubsubProjectId) + ')</div>' +
+                        '<div class="notification-detail">' +
+                        escapeHtml((n.eventTypes && n.eventTypes.length ? n.eventTypes.join(', ') : 'All events') + ', ' + n.payloadFormat) +
+                        (n.objectNamePrefix ? ', prefix ' + escapeHtml(n.objectNamePrefix) : '') +
+                        (attributes ? ', attributes ' + escapeHtml(attributes) : '') +
+                        (n.watch ? ', watched' : '') +
+                        '</div></div>' +
+                        '<button class="btn btn-danger" onclick="deleteNotification(' + jsArg(n.id) + ')">Delete</button></div>';
+                }).join('');
+            } catch (error) {
+                list.innerHTML = '';
+                showNotificationsStatus('Failed to load notifications: ' + error.message, true);
+            }
+        }
+
+        async function saveNotification() {
+            const attributes = {};
+            for (const line of document.getElementById('notifyAttributes').value.split('\n')) {
+                if (!line.trim()) continue;
+                const eq = line.indexOf('=');
+                if (eq <= 0) {
+                    showNotificationsStatus('Custom attributes must be key=value: ' + line, true);
+                    return;
+                }
+                attributes[line.slice(0, eq).trim()] = line.slice(eq + 1).trim();
+            }
+
+            const notification = {
+                bucket: currentBucket,
+                pubsubEmulatorHost: document.getElementById('notifyPubSubHost').value.trim(),
+                pubsubProjectId: document.getElementById('notifyPubSubProject').value.trim(),
+                topicId: document.getElementById('notifyTopic').value.trim(),
+                eventTypes: Array.from(document.querySelectorAll('.notify-event:checked')).map(box => box.value),
+                objectNamePrefix: document.getElementById('notifyPrefix').value,
+                payloadFormat: document.getElementById('notifyPayloadFormat').value,
+                customAttributes: attributes,
+                watch: document.getElementById('notifyWatch').checked
+            };
+
+            try {
+                const response = await fetch('/api/gcs/notifications/save?' + connectionQuery(), {
+                    method: 'POST',
+                    headers: { 'Content-Type': 'application/json' },
+                    body: JSON.stringify(notification)
+                });
+                if (!response.ok) throw new Error(await response.text());
+                const saved = await response.json();
+                showNotificationsStatus('Added notification #' + saved.id + ' publishing to ' + saved.topicId, false);
+                document.getElementById('notifyTopic').value = '';
+            } catch (error) {
+                showNotificationsStatus('Failed to add notification: ' + error.message, true);
+            }
+            loadNotifications();
+        }
+
+        async function deleteNotification(id) {
+            if (!confirm('Delete notification #' + id + '?')) return;
+
+            try {
+                const response = await fetch('/api/gcs/notifications/delete?id=' + encodeURIComponent(id), { method: 'DELETE' });
+                if (!response.ok) throw new Error(await response.text());
+                showNotificationsStatus('Deleted notification #' + id, false);
+            } catch (error) {
+                showNotificationsStatus('Failed to delete notification: ' + error.message, true);
+            }
+            loadNotifications();
+        }
+
         // Close modal when clicking outside
         document.getElementById('previewModal').addEventListener('click', function(e) {
             if (e.target === this) {
@@ -475,8 +2105,8 @@ const GCS = `<!DOCTYPE html>
             }
         });
 
-        // Load buckets on page load
-        loadBuckets();
+        // Load the saved connections, then the buckets of the first one
+        loadGCSConfigs();
     </script>
 </body>
 </html>`
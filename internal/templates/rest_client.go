@@ -47,6 +47,15 @@ const RestClient = `
         <input type="text" id="requestUrl" value="http://localhost:8888/api/configs" placeholder="http://localhost:8888/api/configs"
                style="flex: 1; padding: 10px 16px; border: 1px solid #dadce0; border-radius: 4px; font-size: 14px; font-family: 'Monaco', monospace;" />
 
+        <select id="environmentSelect" onchange="selectEnvironment()" title="Environment whose variables fill in {{name}} references"
+                style="padding: 10px 12px; border: 1px solid #dadce0; border-radius: 4px; font-size: 13px; color: #202124; background: white; cursor: pointer; max-width: 180px;">
+            <option value="">No environment</option>
+        </select>
+        <button onclick="openEnvironments()" title="Manage environments and collection variables"
+                style="padding: 10px 12px; background: white; border: 1px solid #dadce0; border-radius: 4px; cursor: pointer; font-size: 13px; color: #5f6368;">
+            Variables
+        </button>
+
         <button onclick="sendRequest()" id="sendBtn"
                 style="padding: 10px 32px; background: #7c3aed; color: white; border: none; border-radius: 4px; cursor: pointer; font-size: 14px; font-weight: 500; transition: background 0.2s;">
             Send
@@ -67,6 +76,12 @@ const RestClient = `
         <div class="rest-tab" data-tab="auth" onclick="switchRestTab('auth')" style="padding: 12px 24px; cursor: pointer; font-size: 13px; font-weight: 500; color: #5f6368; border-bottom: 2px solid transparent; transition: all 0.2s;">
             Authorization
         </div>
+        <div class="rest-tab" data-tab="settings" onclick="switchRestTab('settings')" style="padding: 12px 24px; cursor: pointer; font-size: 13px; font-weight: 500; color: #5f6368; border-bottom: 2px solid transparent; transition: all 0.2s;">
+            Settings
+        </div>
+        <div class="rest-tab" data-tab="tests" onclick="switchRestTab('tests')" style="padding: 12px 24px; cursor: pointer; font-size: 13px; font-weight: 500; color: #5f6368; border-bottom: 2px solid transparent; transition: all 0.2s;">
+            Tests
+        </div>
     </div>
 
     <!-- Tab Content -->
@@ -77,29 +92,18 @@ const RestClient = `
             <table style="width: 100%; border-collapse: collapse; font-size: 13px;">
                 <thead>
                     <tr style="background: #f8f9fa;">
-                        <th style="padding: 8px 12px; text-align: left; font-weight: 500; color: #5f6368; border: 1px solid #e8eaed; width: 35%;">Key</th>
+                        <th style="padding: 8px 12px; text-align: center; font-weight: 500; color: #5f6368; border: 1px solid #e8eaed; width: 5%;" title="Send this parameter"></th>
+                        <th style="padding: 8px 12px; text-align: left; font-weight: 500; color: #5f6368; border: 1px solid #e8eaed; width: 30%;">Key</th>
                         <th style="padding: 8px 12px; text-align: left; font-weight: 500; color: #5f6368; border: 1px solid #e8eaed; width: 35%;">Value</th>
                         <th style="padding: 8px 12px; text-align: left; font-weight: 500; color: #5f6368; border: 1px solid #e8eaed; width: 25%;">Description</th>
                         <th style="padding: 8px 12px; text-align: center; font-weight: 500; color: #5f6368; border: 1px solid #e8eaed; width: 5%;"></th>
                     </tr>
                 </thead>
-                <tbody id="paramsTable">
-                    <tr>
-                        <td style="padding: 8px 12px; border: 1px solid #e8eaed;">
-                            <input type="text" placeholder="key" style="width: 100%; border: none; padding: 4px; font-size: 13px; font-family: Monaco, monospace;" />
-                        </td>
-                        <td style="padding: 8px 12px; border: 1px solid #e8eaed;">
-                            <input type="text" placeholder="value" style="width: 100%; border: none; padding: 4px; font-size: 13px; font-family: Monaco, monospace;" />
-                        </td>
-                        <td style="padding: 8px 12px; border: 1px solid #e8eaed;">
-                            <input type="text" placeholder="description" style="width: 100%; border: none; padding: 4px; font-size: 13px;" />
-                        </td>
-                        <td style="padding: 8px 12px; border: 1px solid #e8eaed; text-align: center;">
-                            <button onclick="removeParamRow(this)" style="background: none; border: none; cursor: pointer; color: #d93025; font-size: 16px;">×</button>
-                        </td>
-                    </tr>
-                </tbody>
+                <tbody id="paramsTable"></tbody>
             </table>
+            <div style="margin-top: 8px; font-size: 12px; color: #5f6368;">
+                Keys may repeat. Parameters are encoded and added to the URL in this order; unchecked rows are not sent.
+            </div>
             <button onclick="addParamRow()" style="margin-top: 12px; padding: 6px 16px; background: white; border: 1px solid #dadce0; border-radius: 4px; cursor: pointer; font-size: 13px; color: #5f6368;">
                 + Add Parameter
             </button>
@@ -107,11 +111,66 @@ const RestClient = `
 
         <!-- Body Tab -->
         <div id="tab-body" class="rest-tab-content" style="padding: 20px; display: none;">
-            <div style="margin-bottom: 12px; color: #5f6368; font-size: 13px; font-weight: 500;">Request Body (JSON)</div>
-            <textarea id="requestBody" rows="15" placeholder='{\n  "key": "value"\n}'
-                      style="width: 100%; padding: 12px; border: 1px solid #dadce0; border-radius: 4px; font-family: 'Monaco', monospace; font-size: 13px; resize: vertical;"></textarea>
-            <div style="margin-top: 8px; font-size: 12px; color: #5f6368;">
-                Format: JSON only. Body is sent for POST, PUT, PATCH requests.
+            <div style="display: flex; gap: 16px; align-items: center; margin-bottom: 12px; font-size: 13px; color: #5f6368;">
+                <label><input type="radio" name="bodyType" value="none" onchange="switchBodyType('none')" /> none</label>
+                <label><input type="radio" name="bodyType" value="raw" onchange="switchBodyType('raw')" checked /> raw</label>
+                <label><input type="radio" name="bodyType" value="form" onchange="switchBodyType('form')" /> x-www-form-urlencoded</label>
+                <label><input type="radio" name="bodyType" value="multipart" onchange="switchBodyType('multipart')" /> form-data</label>
+                <label><input type="radio" name="bodyType" value="file" onchange="switchBodyType('file')" /> binary</label>
+            </div>
+
+            <div id="body-none" class="body-type-content" style="display: none; padding: 24px; text-align: center; color: #5f6368; font-size: 13px;">
+                This request has no body.
+            </div>
+
+            <div id="body-raw" class="body-type-content">
+                <div style="display: flex; gap: 8px; align-items: center; margin-bottom: 8px; font-size: 13px; color: #5f6368;">
+                    <span>Content-Type</span>
+                    <input type="text" id="bodyContentType" list="bodyContentTypes" value="application/json"
+                           style="width: 280px; padding: 4px 8px; border: 1px solid #dadce0; border-radius: 4px; font-size: 13px; font-family: Monaco, monospace;" />
+                    <datalist id="bodyContentTypes">
+                        <option value="application/json"></option>
+                        <option value="text/plain"></option>
+                        <option value="application/xml"></option>
+                        <option value="text/xml"></option>
+                        <option value="text/html"></option>
+                        <option value="application/javascript"></option>
+                        <option value="application/x-ndjson"></option>
+                    </datalist>
+                </div>
+                <textarea id="requestBody" rows="15" placeholder='{\n  "key": "value"\n}'
+                          style="width: 100%; padding: 12px; border: 1px solid #dadce0; border-radius: 4px; font-family: 'Monaco', monospace; font-size: 13px; resize: vertical;"></textarea>
+                <div style="margin-top: 8px; font-size: 12px; color: #5f6368;">
+                    Sent exactly as written, with this Content-Type.
+                </div>
+            </div>
+
+            <div id="body-fields" class="body-type-content" style="display: none;">
+                <table style="width: 100%; border-collapse: collapse; font-size: 13px;">
+                    <thead>
+                        <tr style="background: #f8f9fa;">
+                            <th style="padding: 8px 12px; text-align: left; font-weight: 500; color: #5f6368; border: 1px solid #e8eaed; width: 30%;">Key</th>
+                            <th class="multipart-only" style="padding: 8px 12px; text-align: left; font-weight: 500; color: #5f6368; border: 1px solid #e8eaed; width: 10%;">Type</th>
+                            <th style="padding: 8px 12px; text-align: left; font-weight: 500; color: #5f6368; border: 1px solid #e8eaed;">Value</th>
+                            <th style="padding: 8px 12px; text-align: center; font-weight: 500; color: #5f6368; border: 1px solid #e8eaed; width: 5%;"></th>
+                        </tr>
+                    </thead>
+                    <tbody id="bodyFieldsTable"></tbody>
+                </table>
+                <button onclick="addBodyFieldRow()" style="margin-top: 12px; padding: 6px 16px; background: white; border: 1px solid #dadce0; border-radius: 4px; cursor: pointer; font-size: 13px; color: #5f6368;">
+                    + Add Field
+                </button>
+            </div>
+
+            <div id="body-file" class="body-type-content" style="display: none;">
+                <input type="file" id="bodyFileInput" style="display: none;" onchange="handleBodyFileUpload(this)" />
+                <button onclick="document.getElementById('bodyFileInput').click()"
+                        style="padding: 10px 16px; background: white; border: 1px solid #dadce0; border-radius: 4px; cursor: pointer; font-size: 13px; color: #5f6368;">
+                    <span id="bodyFileName">Choose File</span>
+                </button>
+                <div style="margin-top: 8px; font-size: 12px; color: #5f6368;">
+                    Sent byte for byte, with the file's content type.
+                </div>
             </div>
         </div>
 
@@ -181,6 +240,82 @@ const RestClient = `
                 </div>
             </div>
         </div>
+
+        <!-- Settings Tab -->
+        <div id="tab-settings" class="rest-tab-content" style="padding: 20px; display: none; font-size: 13px; color: #5f6368;">
+            <div style="margin-bottom: 12px; font-weight: 500;">Name Resolution</div>
+            <div style="display: flex; gap: 8px; align-items: center; margin-bottom: 12px;">
+                <select id="optResolver" onchange="document.getElementById('optDnsServer').style.display = this.value === 'custom' ? '' : 'none'"
+                        style="padding: 6px 10px; border: 1px solid #dadce0; border-radius: 4px; font-size: 13px; background: white;">
+                    <option value="system">System resolver (/etc/hosts, Docker DNS)</option>
+                    <option value="custom">Custom DNS server</option>
+                </select>
+                <input type="text" id="optDnsServer" placeholder="8.8.8.8:53" style="display: none; width: 200px; padding: 6px 10px; border: 1px solid #dadce0; border-radius: 4px; font-size: 13px; font-family: Monaco, monospace;" />
+            </div>
+            <label style="display: block; margin-bottom: 6px;">Host overrides, one <code>host=address</code> per line; the address may have a port</label>
+            <textarea id="optHostOverrides" rows="3" placeholder="api.local=127.0.0.1&#10;payments=172.18.0.5:8080"
+                      style="width: 100%; padding: 8px; border: 1px solid #dadce0; border-radius: 4px; font-family: Monaco, monospace; font-size: 13px; resize: vertical; margin-bottom: 20px;"></textarea>
+
+            <div style="margin-bottom: 12px; font-weight: 500;">Proxy</div>
+            <input type="text" id="optProxy" placeholder="http://proxy:3128 or socks5://localhost:1080 (empty connects directly)"
+                   style="width: 100%; padding: 6px 10px; border: 1px solid #dadce0; border-radius: 4px; font-size: 13px; font-family: Monaco, monospace; margin-bottom: 20px;" />
+
+            <div style="margin-bottom: 12px; font-weight: 500;">Redirects and Timeout</div>
+            <div style="display: flex; gap: 20px; align-items: center; margin-bottom: 12px;">
+                <label><input type="checkbox" id="optFollowRedirects" checked /> Follow redirects</label>
+                <label>at most <input type="number" id="optMaxRedirects" min="1" placeholder="10" style="width: 70px; padding: 4px 8px; border: 1px solid #dadce0; border-radius: 4px; font-size: 13px;" /></label>
+                <label>Timeout <input type="number" id="optTimeout" min="1" placeholder="30000" style="width: 100px; padding: 4px 8px; border: 1px solid #dadce0; border-radius: 4px; font-size: 13px;" /> ms</label>
+            </div>
+
+            <div style="margin: 20px 0 12px; font-weight: 500;">TLS</div>
+            <label><input type="checkbox" id="optVerifyTls" /> Verify server certificates (against the system roots, or the CA certificate in Authorization)</label>
+        </div>
+
+        <!-- Tests Tab -->
+        <div id="tab-tests" class="rest-tab-content" style="padding: 20px; display: none;">
+            <div style="margin-bottom: 12px; color: #5f6368; font-size: 13px; font-weight: 500;">Assertions</div>
+            <table style="width: 100%; border-collapse: collapse; font-size: 13px;">
+                <thead>
+                    <tr style="background: #f8f9fa;">
+                        <th style="padding: 8px 12px; text-align: center; font-weight: 500; color: #5f6368; border: 1px solid #e8eaed; width: 5%;" title="Check this assertion"></th>
+                        <th style="padding: 8px 12px; text-align: left; font-weight: 500; color: #5f6368; border: 1px solid #e8eaed; width: 15%;">Check</th>
+                        <th style="padding: 8px 12px; text-align: left; font-weight: 500; color: #5f6368; border: 1px solid #e8eaed; width: 25%;">Target</th>
+                        <th style="padding: 8px 12px; text-align: left; font-weight: 500; color: #5f6368; border: 1px solid #e8eaed; width: 15%;">Operator</th>
+                        <th style="padding: 8px 12px; text-align: left; font-weight: 500; color: #5f6368; border: 1px solid #e8eaed; width: 35%;">Expected</th>
+                        <th style="padding: 8px 12px; text-align: center; font-weight: 500; color: #5f6368; border: 1px solid #e8eaed; width: 5%;"></th>
+                    </tr>
+                </thead>
+                <tbody id="assertionsTable"></tbody>
+            </table>
+            <div style="margin-top: 8px; font-size: 12px; color: #5f6368;">
+                Assertions are checked by the server against each response. Targets are a header name or a JSONPath such as <code>$.items[0].id</code>;
+                expected values may use variables, are compared as JSON where they parse, and may be <code>2xx</code> for a status or milliseconds for the response time.
+            </div>
+            <button onclick="addAssertionRow()" style="margin-top: 12px; padding: 6px 16px; background: white; border: 1px solid #dadce0; border-radius: 4px; cursor: pointer; font-size: 13px; color: #5f6368;">
+                + Add Assertion
+            </button>
+
+            <div style="margin: 24px 0 12px; color: #5f6368; font-size: 13px; font-weight: 500;">Extract into Variables</div>
+            <table style="width: 100%; border-collapse: collapse; font-size: 13px;">
+                <thead>
+                    <tr style="background: #f8f9fa;">
+                        <th style="padding: 8px 12px; text-align: center; font-weight: 500; color: #5f6368; border: 1px solid #e8eaed; width: 5%;" title="Extract this variable"></th>
+                        <th style="padding: 8px 12px; text-align: left; font-weight: 500; color: #5f6368; border: 1px solid #e8eaed; width: 25%;">Variable</th>
+                        <th style="padding: 8px 12px; text-align: left; font-weight: 500; color: #5f6368; border: 1px solid #e8eaed; width: 15%;">From</th>
+                        <th style="padding: 8px 12px; text-align: left; font-weight: 500; color: #5f6368; border: 1px solid #e8eaed; width: 40%;">JSONPath or Header</th>
+                        <th style="padding: 8px 12px; text-align: center; font-weight: 500; color: #5f6368; border: 1px solid #e8eaed; width: 10%;">Secret</th>
+                        <th style="padding: 8px 12px; text-align: center; font-weight: 500; color: #5f6368; border: 1px solid #e8eaed; width: 5%;"></th>
+                    </tr>
+                </thead>
+                <tbody id="extractionsTable"></tbody>
+            </table>
+            <div style="margin-top: 8px; font-size: 12px; color: #5f6368;">
+                Extracted values are saved to the selected environment, so later requests can use them as <code>{{variable}}</code>.
+            </div>
+            <button onclick="addExtractionRow()" style="margin-top: 12px; padding: 6px 16px; background: white; border: 1px solid #dadce0; border-radius: 4px; cursor: pointer; font-size: 13px; color: #5f6368;">
+                + Add Extraction
+            </button>
+        </div>
     </div>
 </div>
 
@@ -192,8 +327,10 @@ const RestClient = `
             <span>Status: <span id="responseStatus" style="font-weight: 500;"></span></span>
             <span>Time: <span id="responseTime" style="font-weight: 500;"></span></span>
             <span>Size: <span id="responseSize" style="font-weight: 500;"></span></span>
+            <span id="responseTestsSummary" style="display: none;">Tests: <span id="responseTests" style="font-weight: 500;"></span></span>
         </div>
     </div>
+    <div id="responseNotice" style="display: none; padding: 10px 20px; border-bottom: 1px solid #e8eaed; font-size: 12px; color: #5f6368; font-family: Monaco, monospace; word-break: break-all;"></div>
 
     <div style="display: flex; border-bottom: 1px solid #e8eaed; background: #f8f9fa;">
         <div class="resp-tab active" data-tab="resp-body" onclick="switchRespTab('resp-body')" style="padding: 12px 24px; cursor: pointer; font-size: 13px; font-weight: 500; color: #5f6368; border-bottom: 2px solid transparent;">
@@ -202,6 +339,12 @@ const RestClient = `
         <div class="resp-tab" data-tab="resp-headers" onclick="switchRespTab('resp-headers')" style="padding: 12px 24px; cursor: pointer; font-size: 13px; font-weight: 500; color: #5f6368; border-bottom: 2px solid transparent;">
             Headers
         </div>
+        <div class="resp-tab" data-tab="resp-timing" onclick="switchRespTab('resp-timing')" style="padding: 12px 24px; cursor: pointer; font-size: 13px; font-weight: 500; color: #5f6368; border-bottom: 2px solid transparent;">
+            Timing
+        </div>
+        <div class="resp-tab" data-tab="resp-tests" onclick="switchRespTab('resp-tests')" style="padding: 12px 24px; cursor: pointer; font-size: 13px; font-weight: 500; color: #5f6368; border-bottom: 2px solid transparent;">
+            Tests
+        </div>
     </div>
 
     <div style="min-height: 200px;">
@@ -211,6 +354,17 @@ const RestClient = `
         <div id="tab-resp-headers" class="resp-tab-content" style="padding: 20px; display: none;">
             <table id="responseHeaders" style="width: 100%; border-collapse: collapse; font-size: 13px;"></table>
         </div>
+        <div id="tab-resp-timing" class="resp-tab-content" style="padding: 20px; display: none;">
+            <table id="responseTiming" style="width: 100%; border-collapse: collapse; font-size: 13px;"></table>
+            <div style="margin: 20px 0 8px; color: #5f6368; font-size: 13px; font-weight: 500;">Connection</div>
+            <table id="responseConnection" style="width: 100%; border-collapse: collapse; font-size: 13px;"></table>
+            <div id="responseCertificates"></div>
+        </div>
+        <div id="tab-resp-tests" class="resp-tab-content" style="padding: 20px; display: none;">
+            <table id="responseAssertions" style="width: 100%; border-collapse: collapse; font-size: 13px;"></table>
+            <div id="responseExtractionsTitle" style="display: none; margin: 20px 0 8px; color: #5f6368; font-size: 13px; font-weight: 500;">Extracted Variables</div>
+            <table id="responseExtractions" style="width: 100%; border-collapse: collapse; font-size: 13px;"></table>
+        </div>
     </div>
 </div>
             </div>
@@ -218,6 +372,65 @@ const RestClient = `
     </div>
 </div>
 
+<!-- Environments Modal -->
+<div id="environmentsModal" style="display: none; position: fixed; top: 0; left: 0; right: 0; bottom: 0; background: rgba(0,0,0,0.5); z-index: 10000; align-items: center; justify-content: center;">
+    <div style="background: white; border-radius: 8px; width: 760px; max-width: 95vw; max-height: 85vh; display: flex; flex-direction: column; overflow: hidden;">
+        <div style="padding: 16px 20px; border-bottom: 1px solid #e8eaed; display: flex; justify-content: space-between; align-items: center;">
+            <div style="font-size: 16px; font-weight: 500; color: #202124;">Variables</div>
+            <button onclick="closeEnvironments()" style="background: none; border: none; cursor: pointer; font-size: 20px; color: #5f6368;">×</button>
+        </div>
+        <div style="flex: 1; overflow-y: auto; padding: 20px;">
+            <div style="margin-bottom: 12px; font-size: 12px; color: #5f6368;">
+                Write <code>{{name}}</code> in the URL, parameters, headers, body or certificates. Environment variables override collection variables. Secret values are never shown again once saved; leave them empty to keep them.
+            </div>
+
+            <div style="margin-bottom: 12px; color: #5f6368; font-size: 13px; font-weight: 500;">Environment</div>
+            <div style="display: flex; gap: 8px; margin-bottom: 12px;">
+                <select id="envEditorSelect" onchange="editEnvironment(this.value)"
+                        style="padding: 6px 10px; border: 1px solid #dadce0; border-radius: 4px; font-size: 13px; background: white;"></select>
+                <input type="text" id="envEditorName" placeholder="Environment name, e.g. local"
+                       style="flex: 1; padding: 6px 10px; border: 1px solid #dadce0; border-radius: 4px; font-size: 13px;" />
+            </div>
+            <table style="width: 100%; border-collapse: collapse; font-size: 13px;">
+                <thead>
+                    <tr style="background: #f8f9fa;">
+                        <th style="padding: 8px 12px; text-align: left; font-weight: 500; color: #5f6368; border: 1px solid #e8eaed; width: 35%;">Key</th>
+                        <th style="padding: 8px 12px; text-align: left; font-weight: 500; color: #5f6368; border: 1px solid #e8eaed; width: 45%;">Value</th>
+                        <th style="padding: 8px 12px; text-align: center; font-weight: 500; color: #5f6368; border: 1px solid #e8eaed; width: 12%;">Secret</th>
+                        <th style="padding: 8px 12px; text-align: center; font-weight: 500; color: #5f6368; border: 1px solid #e8eaed; width: 8%;"></th>
+                    </tr>
+                </thead>
+                <tbody id="envVariablesTable"></tbody>
+            </table>
+            <div style="display: flex; gap: 8px; margin-top: 12px; margin-bottom: 28px;">
+                <button onclick="addVariableRow('envVariablesTable')" style="padding: 6px 16px; background: white; border: 1px solid #dadce0; border-radius: 4px; cursor: pointer; font-size: 13px; color: #5f6368;">+ Add Variable</button>
+                <button onclick="deleteEnvironment()" style="margin-left: auto; padding: 6px 16px; background: white; border: 1px solid #dadce0; border-radius: 4px; cursor: pointer; font-size: 13px; color: #d93025;">Delete</button>
+                <button onclick="saveEnvironment()" style="padding: 6px 16px; background: #7c3aed; color: white; border: none; border-radius: 4px; cursor: pointer; font-size: 13px; font-weight: 500;">Save Environment</button>
+            </div>
+
+            <div style="margin-bottom: 12px; color: #5f6368; font-size: 13px; font-weight: 500;">Collection Variables <span id="collVariablesName" style="color: #202124;"></span></div>
+            <div id="collVariablesEmpty" style="font-size: 12px; color: #5f6368; display: none;">Open or save a request in a collection to edit its variables.</div>
+            <div id="collVariablesEditor">
+                <table style="width: 100%; border-collapse: collapse; font-size: 13px;">
+                    <thead>
+                        <tr style="background: #f8f9fa;">
+                            <th style="padding: 8px 12px; text-align: left; font-weight: 500; color: #5f6368; border: 1px solid #e8eaed; width: 35%;">Key</th>
+                            <th style="padding: 8px 12px; text-align: left; font-weight: 500; color: #5f6368; border: 1px solid #e8eaed; width: 45%;">Value</th>
+                            <th style="padding: 8px 12px; text-align: center; font-weight: 500; color: #5f6368; border: 1px solid #e8eaed; width: 12%;">Secret</th>
+                            <th style="padding: 8px 12px; text-align: center; font-weight: 500; color: #5f6368; border: 1px solid #e8eaed; width: 8%;"></th>
+                        </tr>
+                    </thead>
+                    <tbody id="collVariablesTable"></tbody>
+                </table>
+                <div style="display: flex; gap: 8px; margin-top: 12px;">
+                    <button onclick="addVariableRow('collVariablesTable')" style="padding: 6px 16px; background: white; border: 1px solid #dadce0; border-radius: 4px; cursor: pointer; font-size: 13px; color: #5f6368;">+ Add Variable</button>
+                    <button onclick="saveCollectionVariables()" style="margin-left: auto; padding: 6px 16px; background: #7c3aed; color: white; border: none; border-radius: 4px; cursor: pointer; font-size: 13px; font-weight: 500;">Save Collection Variables</button>
+                </div>
+            </div>
+        </div>
+    </div>
+</div>
+
 <style>
 .collection {
     margin-bottom: 4px;
@@ -355,6 +568,151 @@ function handleKeyFileUpload(input) {
     reader.readAsText(file);
 }
 
+// Request body: the type chosen and, for binary bodies, the file read
+let bodyType = 'raw';
+let bodyFile = null;
+
+// readUploadedFile reads a file into the form the server keeps uploads in
+function readUploadedFile(file, done) {
+    const reader = new FileReader();
+    reader.onload = function(e) {
+        done({
+            name: file.name,
+            contentType: file.type,
+            data: e.target.result.substring(e.target.result.indexOf(',') + 1)
+        });
+    };
+    reader.readAsDataURL(file);
+}
+
+function handleBodyFileUpload(input) {
+    const file = input.files[0];
+    if (!file) return;
+
+    readUploadedFile(file, uploaded => {
+        bodyFile = uploaded;
+        document.getElementById('bodyFileName').textContent = file.name + ' (' + file.size + ' bytes)';
+    });
+}
+
+function switchBodyType(type) {
+    bodyType = type;
+    document.querySelectorAll('input[name="bodyType"]').forEach(el => el.checked = el.value === type);
+    document.querySelectorAll('.body-type-content').forEach(el => el.style.display = 'none');
+    const fields = type === 'form' || type === 'multipart';
+    document.getElementById(fields ? 'body-fields' : 'body-' + type).style.display = 'block';
+    document.querySelectorAll('.multipart-only').forEach(el => el.style.display = type === 'multipart' ? '' : 'none');
+}
+
+function addBodyFieldRow(field) {
+    field = field || { name: '', value: '' };
+    const row = document.getElementById('bodyFieldsTable').insertRow();
+    row.innerHTML = '<td style="padding: 8px 12px; border: 1px solid #e8eaed;"><input type="text" placeholder="key" style="width: 100%; border: none; padding: 4px; font-size: 13px; font-family: Monaco, monospace;" /></td>' +
+                    '<td class="multipart-only" style="padding: 8px 12px; border: 1px solid #e8eaed;"><select onchange="switchBodyFieldKind(this)" style="border: none; font-size: 13px; background: white;"><option value="text">Text</option><option value="file">File</option></select></td>' +
+                    '<td style="padding: 8px 12px; border: 1px solid #e8eaed;"><input type="text" placeholder="value" style="width: 100%; border: none; padding: 4px; font-size: 13px; font-family: Monaco, monospace;" />' +
+                    '<input type="file" onchange="handleBodyFieldFile(this)" style="display: none; font-size: 12px;" /><span class="body-field-file" style="font-size: 12px; color: #5f6368;"></span></td>' +
+                    '<td style="padding: 8px 12px; border: 1px solid #e8eaed; text-align: center;"><button onclick="removeParamRow(this)" style="background: none; border: none; cursor: pointer; color: #d93025; font-size: 16px;">×</button></td>';
+    const inputs = row.querySelectorAll('input');
+    inputs[0].value = field.name;
+    inputs[1].value = field.value || '';
+    row.querySelector('.multipart-only').style.display = bodyType === 'multipart' ? '' : 'none';
+    if (field.file) {
+        row.bodyFile = field.file;
+        row.querySelector('select').value = 'file';
+        switchBodyFieldKind(row.querySelector('select'));
+        row.querySelector('.body-field-file').textContent = field.file.name;
+    }
+}
+
+function switchBodyFieldKind(select) {
+    const inputs = select.closest('tr').querySelectorAll('input');
+    inputs[1].style.display = select.value === 'file' ? 'none' : '';
+    inputs[2].style.display = select.value === 'file' ? '' : 'none';
+}
+
+function handleBodyFieldFile(input) {
+    const file = input.files[0];
+    if (!file) return;
+
+    const row = input.closest('tr');
+    readUploadedFile(file, uploaded => {
+        row.bodyFile = uploaded;
+        row.querySelector('.body-field-file').textContent = '';
+    });
+}
+
+// readOptions returns the Settings tab as request options
+function readOptions() {
+    const hostOverrides = [];
+    document.getElementById('optHostOverrides').value.split('\n').forEach(line => {
+        const i = line.indexOf('=');
+        if (i > 0) {
+            hostOverrides.push({ host: line.substring(0, i).trim(), address: line.substring(i + 1).trim() });
+        }
+    });
+    return {
+        resolver: document.getElementById('optResolver').value,
+        dnsServer: document.getElementById('optDnsServer').value.trim(),
+        hostOverrides: hostOverrides,
+        proxy: document.getElementById('optProxy').value.trim(),
+        disableRedirects: !document.getElementById('optFollowRedirects').checked,
+        maxRedirects: parseInt(document.getElementById('optMaxRedirects').value) || 0,
+        timeoutMs: parseInt(document.getElementById('optTimeout').value) || 0,
+        verifyTls: document.getElementById('optVerifyTls').checked
+    };
+}
+
+function renderOptions(options) {
+    options = options || {};
+    document.getElementById('optResolver').value = options.resolver || 'system';
+    document.getElementById('optDnsServer').value = options.dnsServer || '';
+    document.getElementById('optDnsServer').style.display = options.resolver === 'custom' ? '' : 'none';
+    document.getElementById('optHostOverrides').value = (options.hostOverrides || []).map(o => o.host + '=' + o.address).join('\n');
+    document.getElementById('optProxy').value = options.proxy || '';
+    document.getElementById('optFollowRedirects').checked = !options.disableRedirects;
+    document.getElementById('optMaxRedirects').value = options.maxRedirects || '';
+    document.getElementById('optTimeout').value = options.timeoutMs || '';
+    document.getElementById('optVerifyTls').checked = !!options.verifyTls;
+}
+
+// readBody returns the body as the server takes it
+function readBody() {
+    const body = { type: bodyType };
+    if (bodyType === 'raw') {
+        body.raw = document.getElementById('requestBody').value;
+        body.contentType = document.getElementById('bodyContentType').value.trim();
+    } else if (bodyType === 'form' || bodyType === 'multipart') {
+        body.fields = [];
+        document.querySelectorAll('#bodyFieldsTable tr').forEach(row => {
+            const inputs = row.querySelectorAll('input');
+            const name = inputs[0].value.trim();
+            if (!name) return;
+            if (bodyType === 'multipart' && row.querySelector('select').value === 'file') {
+                if (row.bodyFile) {
+                    body.fields.push({ name: name, file: row.bodyFile });
+                }
+            } else {
+                body.fields.push({ name: name, value: inputs[1].value });
+            }
+        });
+    } else if (bodyType === 'file') {
+        body.file = bodyFile;
+    }
+    return body;
+}
+
+function renderBody(body) {
+    body = body || { type: 'none' };
+    document.getElementById('requestBody').value = body.raw || '';
+    document.getElementById('bodyContentType').value = body.type === 'raw' ? (body.contentType || '') : 'application/json';
+    bodyFile = body.type === 'file' ? body.file : null;
+    document.getElementById('bodyFileName').textContent = bodyFile ? bodyFile.name : 'Choose File';
+    switchBodyType(body.type || 'none');
+    document.getElementById('bodyFieldsTable').innerHTML = '';
+    (body.fields || []).forEach(field => addBodyFieldRow(field));
+    addBodyFieldRow();
+}
+
 function syntaxHighlightJSON(json) {
     if (typeof json !== 'string') {
         json = JSON.stringify(json, null, 2);
@@ -399,13 +757,38 @@ function switchRespTab(tabName) {
     document.querySelector('[data-tab="' + tabName + '"]').classList.add('active');
 }
 
-function addParamRow() {
+function addParamRow(param) {
+    param = param || { key: '', value: '' };
     const table = document.getElementById('paramsTable');
     const row = table.insertRow();
-    row.innerHTML = '<td style="padding: 8px 12px; border: 1px solid #e8eaed;"><input type="text" placeholder="key" style="width: 100%; border: none; padding: 4px; font-size: 13px; font-family: Monaco, monospace;" /></td>' +
+    row.innerHTML = '<td style="padding: 8px 12px; border: 1px solid #e8eaed; text-align: center;"><input type="checkbox" /></td>' +
+                    '<td style="padding: 8px 12px; border: 1px solid #e8eaed;"><input type="text" placeholder="key" style="width: 100%; border: none; padding: 4px; font-size: 13px; font-family: Monaco, monospace;" /></td>' +
                     '<td style="padding: 8px 12px; border: 1px solid #e8eaed;"><input type="text" placeholder="value" style="width: 100%; border: none; padding: 4px; font-size: 13px; font-family: Monaco, monospace;" /></td>' +
                     '<td style="padding: 8px 12px; border: 1px solid #e8eaed;"><input type="text" placeholder="description" style="width: 100%; border: none; padding: 4px; font-size: 13px;" /></td>' +
                     '<td style="padding: 8px 12px; border: 1px solid #e8eaed; text-align: center;"><button onclick="removeParamRow(this)" style="background: none; border: none; cursor: pointer; color: #d93025; font-size: 16px;">×</button></td>';
+    const inputs = row.querySelectorAll('input');
+    inputs[0].checked = !param.disabled;
+    inputs[1].value = param.key;
+    inputs[2].value = param.value || '';
+}
+
+function renderParams(params) {
+    document.getElementById('paramsTable').innerHTML = '';
+    (params || []).forEach(param => addParamRow(param));
+    addParamRow();
+}
+
+// readParams returns the parameter rows with a key, unchecked ones included
+function readParams() {
+    const params = [];
+    document.querySelectorAll('#paramsTable tr').forEach(row => {
+        const inputs = row.querySelectorAll('input');
+        const key = inputs[1].value.trim();
+        if (key) {
+            params.push({ key: key, value: inputs[2].value, disabled: !inputs[0].checked });
+        }
+    });
+    return params;
 }
 
 function removeParamRow(btn) {
@@ -424,15 +807,155 @@ function removeHeaderRow(btn) {
     btn.closest('tr').remove();
 }
 
+// assertionOperators lists the operators each assertion type offers, its
+// default first
+const assertionOperators = {
+    status: ['equals', 'notEquals', 'lessThan', 'greaterThan'],
+    header: ['exists', 'notExists', 'equals', 'notEquals', 'contains', 'matches'],
+    jsonPath: ['exists', 'notExists', 'equals', 'notEquals', 'contains', 'matches', 'lessThan', 'greaterThan'],
+    jsonSchema: [],
+    responseTime: ['lessThan', 'greaterThan']
+};
+
+const assertionPlaceholders = {
+    status: ['', '200 or 2xx'],
+    header: ['Content-Type', 'value or regular expression'],
+    jsonPath: ['$.data.id', 'JSON value, text or regular expression'],
+    jsonSchema: ['', '{"type": "object", "required": ["id"]}'],
+    responseTime: ['', 'milliseconds']
+};
+
+function addAssertionRow(assertion) {
+    assertion = assertion || { type: 'status', value: '' };
+    const table = document.getElementById('assertionsTable');
+    const row = table.insertRow();
+    row.innerHTML = '<td style="padding: 8px 12px; border: 1px solid #e8eaed; text-align: center;"><input type="checkbox" class="assertion-enabled" /></td>' +
+                    '<td style="padding: 8px 12px; border: 1px solid #e8eaed;"><select class="assertion-type" onchange="switchAssertionType(this)" style="width: 100%; border: none; padding: 4px; font-size: 13px; background: white;">' +
+                    '<option value="status">Status code</option><option value="header">Header</option><option value="jsonPath">JSONPath</option>' +
+                    '<option value="jsonSchema">JSON Schema</option><option value="responseTime">Response time</option></select></td>' +
+                    '<td style="padding: 8px 12px; border: 1px solid #e8eaed;"><input type="text" class="assertion-target" style="width: 100%; border: none; padding: 4px; font-size: 13px; font-family: Monaco, monospace;" /></td>' +
+                    '<td style="padding: 8px 12px; border: 1px solid #e8eaed;"><select class="assertion-operator" style="width: 100%; border: none; padding: 4px; font-size: 13px; background: white;"></select></td>' +
+                    '<td style="padding: 8px 12px; border: 1px solid #e8eaed;"><textarea class="assertion-value" rows="1" style="width: 100%; border: none; padding: 4px; font-size: 13px; font-family: Monaco, monospace; resize: vertical;"></textarea></td>' +
+                    '<td style="padding: 8px 12px; border: 1px solid #e8eaed; text-align: center;"><button onclick="removeAssertionRow(this)" style="background: none; border: none; cursor: pointer; color: #d93025; font-size: 16px;">×</button></td>';
+    row.querySelector('.assertion-enabled').checked = !assertion.disabled;
+    row.querySelector('.assertion-type').value = assertion.type;
+    row.querySelector('.assertion-target').value = assertion.target || '';
+    row.querySelector('.assertion-value').value = assertion.value || '';
+    switchAssertionType(row.querySelector('.assertion-type'), assertion.operator);
+}
+
+// switchAssertionType offers the operators and hints of the chosen type
+function switchAssertionType(select, operator) {
+    const row = select.closest('tr');
+    const type = select.value;
+    const operators = assertionOperators[type] || [];
+    const operatorSelect = row.querySelector('.assertion-operator');
+    operatorSelect.innerHTML = '';
+    operators.forEach(op => {
+        const option = document.createElement('option');
+        option.value = op;
+        option.textContent = op;
+        operatorSelect.appendChild(option);
+    });
+    operatorSelect.value = operators.includes(operator) ? operator : (operators[0] || '');
+    operatorSelect.style.visibility = operators.length > 0 ? 'visible' : 'hidden';
+
+    const target = row.querySelector('.assertion-target');
+    const hasTarget = type === 'header' || type === 'jsonPath';
+    target.style.visibility = hasTarget ? 'visible' : 'hidden';
+    target.placeholder = assertionPlaceholders[type][0];
+    row.querySelector('.assertion-value').placeholder = assertionPlaceholders[type][1];
+    row.querySelector('.assertion-value').rows = type === 'jsonSchema' ? 4 : 1;
+}
+
+function renderAssertions(assertions) {
+    document.getElementById('assertionsTable').innerHTML = '';
+    (assertions || []).forEach(assertion => addAssertionRow(assertion));
+}
+
+// readAssertions returns the assertion rows, unchecked ones included
+function readAssertions() {
+    const assertions = [];
+    document.querySelectorAll('#assertionsTable tr').forEach(row => {
+        const type = row.querySelector('.assertion-type').value;
+        const assertion = {
+            type: type,
+            operator: row.querySelector('.assertion-operator').value,
+            value: row.querySelector('.assertion-value').value.trim(),
+            disabled: !row.querySelector('.assertion-enabled').checked
+        };
+        if (type === 'header' || type === 'jsonPath') {
+            assertion.target = row.querySelector('.assertion-target').value.trim();
+            if (!assertion.target) return;
+        }
+        assertions.push(assertion);
+    });
+    return assertions;
+}
+
+function removeAssertionRow(btn) {
+    btn.closest('tr').remove();
+}
+
+function addExtractionRow(extraction) {
+    extraction = extraction || { variable: '', source: 'jsonPath', path: '' };
+    const table = document.getElementById('extractionsTable');
+    const row = table.insertRow();
+    row.innerHTML = '<td style="padding: 8px 12px; border: 1px solid #e8eaed; text-align: center;"><input type="checkbox" class="extraction-enabled" /></td>' +
+                    '<td style="padding: 8px 12px; border: 1px solid #e8eaed;"><input type="text" class="extraction-variable" placeholder="variable" style="width: 100%; border: none; padding: 4px; font-size: 13px; font-family: Monaco, monospace;" /></td>' +
+                    '<td style="padding: 8px 12px; border: 1px solid #e8eaed;"><select class="extraction-source" style="width: 100%; border: none; padding: 4px; font-size: 13px; background: white;">' +
+                    '<option value="jsonPath">JSONPath</option><option value="header">Header</option></select></td>' +
+                    '<td style="padding: 8px 12px; border: 1px solid #e8eaed;"><input type="text" class="extraction-path" placeholder="$.token or Location" style="width: 100%; border: none; padding: 4px; font-size: 13px; font-family: Monaco, monospace;" /></td>' +
+                    '<td style="padding: 8px 12px; border: 1px solid #e8eaed; text-align: center;"><input type="checkbox" class="extraction-secret" /></td>' +
+                    '<td style="padding: 8px 12px; border: 1px solid #e8eaed; text-align: center;"><button onclick="removeExtractionRow(this)" style="background: none; border: none; cursor: pointer; color: #d93025; font-size: 16px;">×</button></td>';
+    row.querySelector('.extraction-enabled').checked = !extraction.disabled;
+    row.querySelector('.extraction-variable').value = extraction.variable;
+    row.querySelector('.extraction-source').value = extraction.source || 'jsonPath';
+    row.querySelector('.extraction-path').value = extraction.path || '';
+    row.querySelector('.extraction-secret').checked = !!extraction.secret;
+}
+
+function renderExtractions(extractions) {
+    document.getElementById('extractionsTable').innerHTML = '';
+    (extractions || []).forEach(extraction => addExtractionRow(extraction));
+}
+
+// readExtractions returns the extraction rows with a variable and a path,
+// unchecked ones included
+function readExtractions() {
+    const extractions = [];
+    document.querySelectorAll('#extractionsTable tr').forEach(row => {
+        const variable = row.querySelector('.extraction-variable').value.trim();
+        const path = row.querySelector('.extraction-path').value.trim();
+        if (variable && path) {
+            extractions.push({
+                variable: variable,
+                source: row.querySelector('.extraction-source').value,
+                path: path,
+                secret: row.querySelector('.extraction-secret').checked,
+                disabled: !row.querySelector('.extraction-enabled').checked
+            });
+        }
+    });
+    return extractions;
+}
+
+function removeExtractionRow(btn) {
+    btn.closest('tr').remove();
+}
+
 // Track current collection and request
 let currentCollection = '';
 let currentRequest = '';
+let collectionsData = [];
+let environments = [];
 
 // Load collections sidebar
 async function loadCollections() {
     try {
         const response = await fetch('/api/rest/collections');
         const collections = await response.json();
+        collectionsData = collections || [];
 
         const container = document.getElementById('collectionsContainer');
         container.innerHTML = '';
@@ -496,17 +1019,7 @@ async function loadRequest(collectionName, requestName) {
         document.getElementById('requestUrl').value = req.url;
 
         // Load parameters
-        const paramsTable = document.getElementById('paramsTable');
-        paramsTable.innerHTML = '';
-        if (req.parameters) {
-            Object.entries(req.parameters).forEach(([key, value]) => {
-                const row = paramsTable.insertRow();
-                row.innerHTML = '<td style="padding: 8px 12px; border: 1px solid #e8eaed;"><input type="text" value="' + key + '" style="width: 100%; border: none; padding: 4px; font-size: 13px; font-family: Monaco, monospace;" /></td>' +
-                    '<td style="padding: 8px 12px; border: 1px solid #e8eaed;"><input type="text" value="' + value + '" style="width: 100%; border: none; padding: 4px; font-size: 13px; font-family: Monaco, monospace;" /></td>' +
-                    '<td style="padding: 8px 12px; border: 1px solid #e8eaed;"><input type="text" placeholder="description" style="width: 100%; border: none; padding: 4px; font-size: 13px;" /></td>' +
-                    '<td style="padding: 8px 12px; border: 1px solid #e8eaed; text-align: center;"><button onclick="removeParamRow(this)" style="background: none; border: none; cursor: pointer; color: #d93025; font-size: 16px;">×</button></td>';
-            });
-        }
+        renderParams(req.parameters);
 
         // Load headers
         const headersTable = document.getElementById('headersTable');
@@ -521,7 +1034,14 @@ async function loadRequest(collectionName, requestName) {
         }
 
         // Load body
-        document.getElementById('requestBody').value = req.body || '';
+        renderBody(req.body);
+
+        // Load settings
+        renderOptions(req.options);
+
+        // Load tests
+        renderAssertions(req.assertions);
+        renderExtractions(req.extractions);
 
         // Load TLS certs
         tlsCertContent = req.tlsCert || '';
@@ -568,16 +1088,7 @@ async function saveRequest() {
     const method = document.getElementById('httpMethod').value;
     const url = document.getElementById('requestUrl').value.trim();
 
-    // Build parameters
-    const parameters = {};
-    document.querySelectorAll('#paramsTable tr').forEach(row => {
-        const inputs = row.querySelectorAll('input');
-        const key = inputs[0]?.value.trim();
-        const value = inputs[1]?.value.trim();
-        if (key && value) {
-            parameters[key] = value;
-        }
-    });
+    const parameters = readParams();
 
     // Build headers
     const headers = {};
@@ -590,7 +1101,7 @@ async function saveRequest() {
         }
     });
 
-    const body = document.getElementById('requestBody').value.trim();
+    const body = readBody();
 
     const requestData = {
         collection: collection,
@@ -601,7 +1112,10 @@ async function saveRequest() {
         parameters: parameters,
         body: body,
         tlsCert: tlsCertContent || '',
-        tlsKey: tlsKeyContent || ''
+        tlsKey: tlsKeyContent || '',
+        options: readOptions(),
+        assertions: readAssertions(),
+        extractions: readExtractions()
     };
 
     try {
@@ -658,32 +1172,317 @@ async function deleteCurrentRequest() {
     }
 }
 
-// Load collections when page loads
+// Load environments into the selector next to the URL, keeping the choice
+// made on an earlier visit
+async function loadEnvironments() {
+    try {
+        const response = await fetch('/api/rest/environments');
+        environments = (await response.json()) || [];
+    } catch (error) {
+        console.error('Failed to load environments:', error);
+        return;
+    }
+
+    const select = document.getElementById('environmentSelect');
+    const selected = localStorage.getItem('restEnvironment') || '';
+    select.innerHTML = '<option value="">No environment</option>';
+    environments.forEach(env => {
+        const option = document.createElement('option');
+        option.value = env.name;
+        option.textContent = env.name;
+        select.appendChild(option);
+    });
+    select.value = environments.some(env => env.name === selected) ? selected : '';
+}
+
+function selectEnvironment() {
+    localStorage.setItem('restEnvironment', document.getElementById('environmentSelect').value);
+}
+
+function addVariableRow(tableId, variable) {
+    variable = variable || { key: '', value: '', secret: false };
+    const row = document.getElementById(tableId).insertRow();
+    row.innerHTML = '<td style="padding: 8px 12px; border: 1px solid #e8eaed;"><input type="text" placeholder="baseUrl" style="width: 100%; border: none; padding: 4px; font-size: 13px; font-family: Monaco, monospace;" /></td>' +
+                    '<td style="padding: 8px 12px; border: 1px solid #e8eaed;"><input type="text" placeholder="value" style="width: 100%; border: none; padding: 4px; font-size: 13px; font-family: Monaco, monospace;" /></td>' +
+                    '<td style="padding: 8px 12px; border: 1px solid #e8eaed; text-align: center;"><input type="checkbox" onchange="toggleSecret(this)" /></td>' +
+                    '<td style="padding: 8px 12px; border: 1px solid #e8eaed; text-align: center;"><button onclick="this.closest(\'tr\').remove()" style="background: none; border: none; cursor: pointer; color: #d93025; font-size: 16px;">×</button></td>';
+    const inputs = row.querySelectorAll('input');
+    inputs[0].value = variable.key;
+    inputs[1].value = variable.value || '';
+    inputs[2].checked = !!variable.secret;
+    if (variable.secret) {
+        inputs[1].type = 'password';
+        // The server never sends secret values back; an empty one keeps what is stored
+        inputs[1].placeholder = 'unchanged';
+    }
+}
+
+function toggleSecret(checkbox) {
+    checkbox.closest('tr').querySelectorAll('input')[1].type = checkbox.checked ? 'password' : 'text';
+}
+
+function renderVariables(tableId, variables) {
+    document.getElementById(tableId).innerHTML = '';
+    (variables || []).forEach(v => addVariableRow(tableId, v));
+    addVariableRow(tableId);
+}
+
+function readVariables(tableId) {
+    const variables = [];
+    document.querySelectorAll('#' + tableId + ' tr').forEach(row => {
+        const inputs = row.querySelectorAll('input');
+        const key = inputs[0].value.trim();
+        if (key) {
+            variables.push({ key: key, value: inputs[1].value, secret: inputs[2].checked });
+        }
+    });
+    return variables;
+}
+
+async function openEnvironments() {
+    await Promise.all([loadEnvironments(), loadCollections()]);
+
+    const select = document.getElementById('envEditorSelect');
+    select.innerHTML = '<option value="">New environment</option>';
+    environments.forEach(env => {
+        const option = document.createElement('option');
+        option.value = env.name;
+        option.textContent = env.name;
+        select.appendChild(option);
+    });
+    editEnvironment(document.getElementById('environmentSelect').value);
+
+    const collection = collectionsData.find(c => c.name === currentCollection);
+    document.getElementById('collVariablesName').textContent = currentCollection ? '(' + currentCollection + ')' : '';
+    document.getElementById('collVariablesEmpty').style.display = currentCollection ? 'none' : 'block';
+    document.getElementById('collVariablesEditor').style.display = currentCollection ? 'block' : 'none';
+    renderVariables('collVariablesTable', collection ? collection.variables : []);
+
+    document.getElementById('environmentsModal').style.display = 'flex';
+}
+
+function closeEnvironments() {
+    document.getElementById('environmentsModal').style.display = 'none';
+}
+
+function editEnvironment(name) {
+    const env = environments.find(e => e.name === name);
+    document.getElementById('envEditorSelect').value = env ? env.name : '';
+    document.getElementById('envEditorName').value = env ? env.name : '';
+    document.getElementById('envEditorName').disabled = !!env;
+    renderVariables('envVariablesTable', env ? env.variables : []);
+}
+
+async function saveEnvironment() {
+    const name = document.getElementById('envEditorName').value.trim();
+    if (!name) {
+        alert('Please enter an environment name');
+        return;
+    }
+
+    try {
+        const response = await fetch('/api/rest/environments/save', {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({ name: name, variables: readVariables('envVariablesTable') })
+        });
+        if (!response.ok) {
+            alert('Failed to save environment: ' + await response.text());
+            return;
+        }
+        localStorage.setItem('restEnvironment', name);
+        await openEnvironments();
+    } catch (error) {
+        alert('Failed to save environment: ' + error.message);
+    }
+}
+
+async function deleteEnvironment() {
+    const name = document.getElementById('envEditorSelect').value;
+    if (!name || !confirm('Are you sure you want to delete environment "' + name + '"?')) {
+        return;
+    }
+
+    try {
+        const response = await fetch('/api/rest/environments/delete', {
+            method: 'DELETE',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({ name: name })
+        });
+        if (!response.ok) {
+            alert('Failed to delete environment: ' + await response.text());
+            return;
+        }
+        await openEnvironments();
+    } catch (error) {
+        alert('Failed to delete environment: ' + error.message);
+    }
+}
+
+async function saveCollectionVariables() {
+    if (!currentCollection) return;
+
+    try {
+        const response = await fetch('/api/rest/collection/variables', {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({ collection: currentCollection, variables: readVariables('collVariablesTable') })
+        });
+        if (!response.ok) {
+            alert('Failed to save collection variables: ' + await response.text());
+            return;
+        }
+        await openEnvironments();
+    } catch (error) {
+        alert('Failed to save collection variables: ' + error.message);
+    }
+}
+
+// Load collections and environments when page loads
 document.addEventListener('DOMContentLoaded', loadCollections);
+document.addEventListener('DOMContentLoaded', loadEnvironments);
+document.addEventListener('DOMContentLoaded', () => {
+    renderParams([]);
+    renderBody({ type: 'raw', contentType: 'application/json' });
+});
+
+// renderTiming fills the Timing tab: each phase as a bar on one time axis,
+// then the connection and the server's certificate chain
+function renderTiming(timing) {
+    const timingTable = document.getElementById('responseTiming');
+    const connectionTable = document.getElementById('responseConnection');
+    const certificates = document.getElementById('responseCertificates');
+    timingTable.innerHTML = '';
+    connectionTable.innerHTML = '';
+    certificates.innerHTML = '';
+    if (!timing) return;
+
+    const phases = [
+        ['DNS lookup', timing.dns],
+        ['TCP connect', timing.connect],
+        ['TLS handshake', timing.tlsHandshake],
+        ['Waiting (TTFB)', timing.ttfb],
+        ['Content transfer', timing.transfer]
+    ];
+    const total = timing.total || 1;
+    let offset = Math.max(0, total - phases.reduce((sum, p) => sum + p[1], 0));
+    phases.forEach(([name, ms]) => {
+        const row = timingTable.insertRow();
+        const left = (offset / total * 100).toFixed(2);
+        const width = Math.max(ms / total * 100, ms > 0 ? 0.5 : 0).toFixed(2);
+        row.innerHTML = '<td style="padding: 6px 12px; border: 1px solid #e8eaed; width: 20%; color: #5f6368;">' + name + '</td>' +
+                        '<td style="padding: 6px 12px; border: 1px solid #e8eaed;"><div style="position: relative; height: 12px; background: #f1f3f4; border-radius: 2px;">' +
+                        '<div style="position: absolute; top: 0; bottom: 0; left: ' + left + '%; width: ' + width + '%; background: #7c3aed; border-radius: 2px;"></div></div></td>' +
+                        '<td style="padding: 6px 12px; border: 1px solid #e8eaed; width: 12%; text-align: right; font-family: Monaco, monospace; font-size: 12px;">' + ms.toFixed(2) + ' ms</td>';
+        offset += ms;
+    });
+    const totalRow = timingTable.insertRow();
+    totalRow.innerHTML = '<td style="padding: 6px 12px; border: 1px solid #e8eaed; font-weight: 500;">Total</td><td style="padding: 6px 12px; border: 1px solid #e8eaed; font-size: 12px; color: #5f6368;">' +
+                         (timing.reusedConnection ? 'Reused an open connection, so no DNS, connect or TLS time' : '') + '</td>' +
+                         '<td style="padding: 6px 12px; border: 1px solid #e8eaed; text-align: right; font-family: Monaco, monospace; font-size: 12px; font-weight: 500;">' + timing.total.toFixed(2) + ' ms</td>';
+
+    const addRow = (table, key, value) => {
+        if (!value) return;
+        const row = table.insertRow();
+        row.innerHTML = '<td style="padding: 6px 12px; border: 1px solid #e8eaed; width: 20%; color: #5f6368;"></td><td style="padding: 6px 12px; border: 1px solid #e8eaed; font-family: Monaco, monospace; font-size: 12px; word-break: break-all;"></td>';
+        row.cells[0].textContent = key;
+        row.cells[1].textContent = value;
+    };
+    addRow(connectionTable, 'Protocol', timing.protocol);
+    addRow(connectionTable, 'Remote address', timing.remoteAddr);
+    if (timing.tls) {
+        addRow(connectionTable, 'TLS version', timing.tls.version);
+        addRow(connectionTable, 'Cipher suite', timing.tls.cipherSuite);
+        addRow(connectionTable, 'Server name', timing.tls.serverName);
+        addRow(connectionTable, 'ALPN', timing.tls.negotiatedProtocol);
+
+        timing.tls.certificates.forEach((cert, i) => {
+            const title = document.createElement('div');
+            title.style.cssText = 'margin: 20px 0 8px; color: #5f6368; font-size: 13px; font-weight: 500;';
+            title.textContent = 'Certificate ' + (i + 1) + (i === 0 ? ' (server)' : cert.isCA ? ' (CA)' : '');
+            certificates.appendChild(title);
+            const table = document.createElement('table');
+            table.style.cssText = 'width: 100%; border-collapse: collapse; font-size: 13px;';
+            addRow(table, 'Subject', cert.subject);
+            addRow(table, 'Issuer', cert.issuer);
+            addRow(table, 'Valid', cert.notBefore + ' to ' + cert.notAfter);
+            addRow(table, 'DNS names', (cert.dnsNames || []).join(', '));
+            addRow(table, 'Serial number', cert.serialNumber);
+            addRow(table, 'SHA-256', cert.sha256);
+            certificates.appendChild(table);
+        });
+    }
+}
+
+// renderTests fills the Tests tab with the assertion results and the
+// variables extracted, and counts the passes beside the status
+function renderTests(assertions, extractions) {
+    const assertionsTable = document.getElementById('responseAssertions');
+    const extractionsTable = document.getElementById('responseExtractions');
+    assertionsTable.innerHTML = '';
+    extractionsTable.innerHTML = '';
+    assertions = assertions || [];
+    extractions = extractions || [];
+
+    const summary = document.getElementById('responseTestsSummary');
+    summary.style.display = assertions.length > 0 ? '' : 'none';
+    const passed = assertions.filter(result => result.passed).length;
+    const count = document.getElementById('responseTests');
+    count.textContent = passed + '/' + assertions.length;
+    count.style.color = passed === assertions.length ? '#188038' : '#d93025';
+
+    assertions.forEach(result => {
+        const a = result.assertion;
+        const row = assertionsTable.insertRow();
+        row.innerHTML = '<td style="padding: 6px 12px; border: 1px solid #e8eaed; width: 4%; text-align: center; font-weight: 500;"></td>' +
+                        '<td style="padding: 6px 12px; border: 1px solid #e8eaed; width: 40%; font-family: Monaco, monospace; font-size: 12px; word-break: break-all;"></td>' +
+                        '<td style="padding: 6px 12px; border: 1px solid #e8eaed; font-family: Monaco, monospace; font-size: 12px; word-break: break-all;"></td>';
+        row.cells[0].textContent = result.passed ? '✓' : '✗';
+        row.cells[0].style.color = result.passed ? '#188038' : '#d93025';
+        const check = a.type === 'jsonSchema' ? 'matches JSON Schema' :
+                      [a.type, a.target, a.operator, a.value].filter(part => part).join(' ');
+        row.cells[1].textContent = check;
+        row.cells[1].title = check;
+        if (result.actual !== undefined) {
+            const actual = document.createElement('div');
+            actual.textContent = 'actual: ' + result.actual;
+            row.cells[2].appendChild(actual);
+        }
+        if (result.message) {
+            const message = document.createElement('div');
+            message.style.color = '#d93025';
+            message.textContent = result.message;
+            row.cells[2].appendChild(message);
+        }
+    });
+
+    document.getElementById('responseExtractionsTitle').style.display = extractions.length > 0 ? 'block' : 'none';
+    extractions.forEach(result => {
+        const row = extractionsTable.insertRow();
+        row.innerHTML = '<td style="padding: 6px 12px; border: 1px solid #e8eaed; width: 20%; font-family: Monaco, monospace; font-size: 12px;"></td>' +
+                        '<td style="padding: 6px 12px; border: 1px solid #e8eaed; font-family: Monaco, monospace; font-size: 12px; word-break: break-all;"></td>';
+        row.cells[0].textContent = result.variable;
+        if (result.error) {
+            row.cells[1].style.color = '#d93025';
+            row.cells[1].textContent = result.error;
+        } else {
+            row.cells[1].textContent = result.value;
+        }
+    });
+}
 
 async function sendRequest() {
     const method = document.getElementById('httpMethod').value;
-    let url = document.getElementById('requestUrl').value.trim();
+    const url = document.getElementById('requestUrl').value.trim();
 
     if (!url) {
         alert('Please enter a URL');
         return;
     }
 
-    // Build query parameters
-    const params = [];
-    document.querySelectorAll('#paramsTable tr').forEach(row => {
-        const inputs = row.querySelectorAll('input');
-        const key = inputs[0]?.value.trim();
-        const value = inputs[1]?.value.trim();
-        if (key && value) {
-            params.push(encodeURIComponent(key) + '=' + encodeURIComponent(value));
-        }
-    });
-
-    if (params.length > 0) {
-        url += (url.includes('?') ? '&' : '?') + params.join('&');
-    }
+    // Query parameters are encoded and added to the URL by the server, once
+    // variables are substituted
+    const params = readParams();
 
     // Build headers
     const headers = {};
@@ -697,17 +1496,10 @@ async function sendRequest() {
     });
 
     // Get body
-    let body = null;
-    if (['POST', 'PUT', 'PATCH'].includes(method)) {
-        const bodyText = document.getElementById('requestBody').value.trim();
-        if (bodyText) {
-            try {
-                body = JSON.parse(bodyText);
-            } catch (e) {
-                alert('Invalid JSON in body: ' + e.message);
-                return;
-            }
-        }
+    const body = readBody();
+    if (bodyType === 'file' && !bodyFile) {
+        alert('Please choose a file for the body');
+        return;
     }
 
     // Get TLS certs from uploaded files
@@ -717,10 +1509,16 @@ async function sendRequest() {
     const requestData = {
         method: method,
         url: url,
+        params: params,
         headers: headers,
         body: body,
         tlsCert: tlsCert || null,
-        tlsKey: tlsKey || null
+        tlsKey: tlsKey || null,
+        collection: currentCollection,
+        environment: document.getElementById('environmentSelect').value,
+        options: readOptions(),
+        assertions: readAssertions(),
+        extractions: readExtractions()
     };
 
     const startTime = Date.now();
@@ -750,9 +1548,31 @@ async function sendRequest() {
         document.getElementById('responseStatus').innerHTML = '<span style="color: ' + statusColor + ';">' + result.statusCode + '</span>';
         document.getElementById('responseTime').textContent = duration + ' ms';
 
+        // Show what the variables resolved to
+        const notice = document.getElementById('responseNotice');
+        notice.innerHTML = '';
+        if (result.resolvedUrl) {
+            const line = document.createElement('div');
+            line.textContent = result.resolvedUrl;
+            notice.appendChild(line);
+        }
+        (result.redirects || []).forEach(redirect => {
+            const line = document.createElement('div');
+            line.textContent = '↪ ' + redirect.statusCode + ' ' + redirect.url + ' → ' + redirect.location;
+            notice.appendChild(line);
+        });
+        if (result.unresolvedVariables && result.unresolvedVariables.length > 0) {
+            const line = document.createElement('div');
+            line.style.color = '#b06000';
+            line.textContent = '⚠ Unresolved variables sent as written: ' + result.unresolvedVariables.join(', ');
+            notice.appendChild(line);
+        }
+        notice.style.display = notice.children.length > 0 ? 'block' : 'none';
+
         if (result.error) {
             document.getElementById('responseBody').textContent = 'Error: ' + result.error;
             document.getElementById('responseSize').textContent = '-';
+            renderTests([], []);
         } else {
             const bodyStr = typeof result.body === 'string' ? result.body : JSON.stringify(result.body, null, 2);
 
@@ -767,14 +1587,27 @@ async function sendRequest() {
 
             document.getElementById('responseSize').textContent = (bodyStr.length / 1024).toFixed(2) + ' KB';
 
+            renderTiming(result.timing);
+            renderTests(result.assertions, result.extractions);
+
+            // Extracted variables are saved to the environment
+            if (result.extractions && result.extractions.some(e => !e.error)) {
+                loadEnvironments();
+            }
+
             // Update response headers
             const headersTable = document.getElementById('responseHeaders');
             headersTable.innerHTML = '';
             if (result.headers) {
-                Object.keys(result.headers).forEach(key => {
-                    const row = headersTable.insertRow();
-                    row.innerHTML = '<td style="padding: 8px 12px; border: 1px solid #e8eaed; font-weight: 500; color: #5f6368; font-family: Monaco, monospace; font-size: 12px;">' + key + '</td>' +
-                                   '<td style="padding: 8px 12px; border: 1px solid #e8eaed; font-family: Monaco, monospace; font-size: 12px;">' + result.headers[key] + '</td>';
+                // A header sent several times, such as Set-Cookie, gets a row per value
+                Object.keys(result.headers).sort().forEach(key => {
+                    result.headers[key].forEach(value => {
+                        const row = headersTable.insertRow();
+                        row.innerHTML = '<td style="padding: 8px 12px; border: 1px solid #e8eaed; font-weight: 500; color: #5f6368; font-family: Monaco, monospace; font-size: 12px;"></td>' +
+                                       '<td style="padding: 8px 12px; border: 1px solid #e8eaed; font-family: Monaco, monospace; font-size: 12px;"></td>';
+                        row.cells[0].textContent = key;
+                        row.cells[1].textContent = value;
+                    });
                 });
             }
         }
@@ -784,10 +1617,12 @@ async function sendRequest() {
 
     } catch (error) {
         document.getElementById('responsePanel').style.display = 'block';
+        document.getElementById('responseNotice').style.display = 'none';
         document.getElementById('responseStatus').innerHTML = '<span style="color: #d93025;">Error</span>';
         document.getElementById('responseTime').textContent = '-';
         document.getElementById('responseSize').textContent = '-';
         document.getElementById('responseBody').textContent = 'Request failed: ' + error.message;
+        renderTests([], []);
     } finally {
         document.getElementById('sendBtn').textContent = 'Send';
         document.getElementById('sendBtn').disabled = false;
@@ -46,6 +46,7 @@ const SpannerContent = `
         <div class="button-group">
             <button class="btn-primary" onclick="testConnection()">Connect</button>
             <button class="btn-secondary" onclick="saveConfig()">Save Configuration</button>
+            <button class="btn-secondary" onclick="openProvisionModal()" title="Create or drop emulator instances and databases">Provision...</button>
         </div>
         <div id="connectionStatus" style="margin-top: 12px; padding: 8px; border-radius: 4px; display: none;"></div>
     </div>
@@ -62,6 +63,8 @@ const SpannerContent = `
             <input type="text" id="tableSearch" placeholder="Search tables..."
                    onkeyup="filterTables()"
                    style="width: 100%; padding: 6px 8px; font-size: 13px;">
+            <button class="btn-secondary" onclick="showErDiagram()" style="width: 100%; margin-top: 8px; padding: 6px 8px; font-size: 12px;">ER Diagram</button>
+            <button class="btn-secondary" onclick="window.location.href = '/spanner/change-streams'" style="width: 100%; margin-top: 8px; padding: 6px 8px; font-size: 12px;">Change Streams</button>
         </div>
         <div id="tablesListContainer" style="flex: 1; overflow-y: auto; padding: 0 12px 12px 12px;">
             <div id="tableList" style="display: flex; flex-direction: column; gap: 4px;">
@@ -87,12 +90,35 @@ const SpannerContent = `
                               placeholder="-- Enter SQL query here&#10;SELECT * FROM TableName LIMIT 10;"></textarea>
                     <div class="button-group">
                         <button class="btn-primary" onclick="executeQuery()">Run Query</button>
+                        <button class="btn-secondary" onclick="openQueryLibrary()" title="Search saved queries and query history">Saved &amp; History</button>
+                        <button class="btn-secondary" id="beginTxnBtn" onclick="beginTransaction()" title="Run the following queries in one read-write transaction">Begin Transaction</button>
+                        <button class="btn-primary" id="commitTxnBtn" onclick="endTransaction('commit')" style="display: none; background: #188038; border-color: #188038;">Commit</button>
+                        <button class="btn-secondary" id="rollbackTxnBtn" onclick="endTransaction('rollback')" style="display: none; color: #d93025;">Rollback</button>
+                        <span id="txnBadge" style="display: none; align-self: center; font-size: 12px; color: #b06000; background: #fef7e0; border: 1px solid #fdd663; border-radius: 12px; padding: 3px 10px;"></span>
                         <select id="exampleQueries" onchange="loadExampleQuery()" style="padding: 6px 10px;">
                             <option value="">-- Example Queries --</option>
                             <option value="SHOW_TABLES">Show all tables</option>
                             <option value="SELECT_ALL">SELECT * FROM (selected table)</option>
                             <option value="COUNT">Count rows in (selected table)</option>
                         </select>
+                        <select id="readMode" onchange="updateReadBoundInput()" style="padding: 6px 10px;" title="Snapshot used by read-only queries. Spanner keeps old versions for the database's version retention period (1 hour by default).">
+                            <option value="strong">Strong read</option>
+                            <option value="exactStaleness">Exact staleness</option>
+                            <option value="maxStaleness">Max staleness</option>
+                            <option value="readTimestamp">Read at timestamp</option>
+                        </select>
+                        <input type="text" id="readBoundValue" style="display: none; width: 240px;">
+                        <div style="margin-left: auto; display: flex; gap: 8px;">
+                            <select id="exportFormat" style="padding: 6px 10px;">
+                                <option value="csv">CSV</option>
+                                <option value="json">JSON</option>
+                                <option value="ndjson">NDJSON</option>
+                                <option value="sql">SQL INSERTs</option>
+                            </select>
+                            <button class="btn-secondary" onclick="exportResults()">Export</button>
+                            <button class="btn-secondary" onclick="openDataModal()">Import / Seeds</button>
+                            <button class="btn-secondary" onclick="openSnapshotModal()">Snapshots</button>
+                        </div>
                     </div>
                 </div>
             </div>
@@ -113,6 +139,222 @@ const SpannerContent = `
         </div>
     </div>
 </div>
+
+<!-- Schema & ER Diagram Modal -->
+<div id="schemaModal" style="display: none; position: fixed; top: 0; left: 0; right: 0; bottom: 0; background: rgba(0,0,0,0.5); z-index: 10000; align-items: center; justify-content: center;" onclick="if (event.target === this) closeSchemaModal()">
+    <div style="background: white; border-radius: 8px; width: 95%; max-width: 1200px; max-height: 90vh; display: flex; flex-direction: column; box-shadow: 0 4px 16px rgba(0,0,0,0.2);">
+        <div style="padding: 16px 24px; border-bottom: 1px solid #dadce0; display: flex; justify-content: space-between; align-items: center; flex-shrink: 0;">
+            <div id="schemaModalTitle" style="font-size: 16px; font-weight: 500; color: #202124;"></div>
+            <button onclick="closeSchemaModal()" style="background: none; border: none; font-size: 24px; color: #5f6368; cursor: pointer; padding: 0; width: 32px; height: 32px;">&times;</button>
+        </div>
+        <div id="schemaModalBody" style="padding: 16px 24px; overflow: auto; font-size: 13px; color: #202124;"></div>
+    </div>
+</div>
+
+<!-- Row Editor Mutation Review Modal -->
+<div id="mutationModal" style="display: none; position: fixed; top: 0; left: 0; right: 0; bottom: 0; background: rgba(0,0,0,0.5); z-index: 10000; align-items: center; justify-content: center;" onclick="if (event.target === this) closeMutationModal()">
+    <div style="background: white; border-radius: 8px; width: 90%; max-width: 900px; max-height: 85vh; display: flex; flex-direction: column; box-shadow: 0 4px 16px rgba(0,0,0,0.2);">
+        <div style="padding: 16px 24px; border-bottom: 1px solid #dadce0; display: flex; justify-content: space-between; align-items: center; flex-shrink: 0;">
+            <div id="mutationModalTitle" style="font-size: 16px; font-weight: 500; color: #202124;">Pending Mutations</div>
+            <button onclick="closeMutationModal()" style="background: none; border: none; font-size: 24px; color: #5f6368; cursor: pointer; padding: 0; width: 32px; height: 32px;">&times;</button>
+        </div>
+        <div id="mutationDiff" style="padding: 16px 24px; overflow: auto; font-size: 13px;"></div>
+        <div style="padding: 12px 24px; border-top: 1px solid #dadce0; display: flex; gap: 8px; align-items: center; flex-shrink: 0;">
+            <button class="btn-primary" id="applyMutationsBtn" onclick="applyRowChanges()">Apply Atomically</button>
+            <button class="btn-secondary" onclick="closeMutationModal()">Keep Editing</button>
+            <span id="mutationStatus" style="font-size: 13px; color: #d93025;"></span>
+        </div>
+    </div>
+</div>
+
+<!-- Snapshots & Diff Modal -->
+<div id="snapshotModal" style="display: none; position: fixed; top: 0; left: 0; right: 0; bottom: 0; background: rgba(0,0,0,0.5); z-index: 10000; align-items: center; justify-content: center;" onclick="if (event.target === this) closeSnapshotModal()">
+    <div style="background: white; border-radius: 8px; width: 95%; max-width: 1100px; max-height: 90vh; overflow: auto; box-shadow: 0 4px 16px rgba(0,0,0,0.2);">
+        <div style="padding: 16px 24px; border-bottom: 1px solid #dadce0; display: flex; justify-content: space-between; align-items: center;">
+            <div style="font-size: 16px; font-weight: 500; color: #202124;">Snapshots &amp; Diff</div>
+            <button onclick="closeSnapshotModal()" style="background: none; border: none; font-size: 24px; color: #5f6368; cursor: pointer; padding: 0; width: 32px; height: 32px;">&times;</button>
+        </div>
+        <div style="padding: 16px 24px;">
+            <div class="panel-title" style="margin-bottom: 8px;">CAPTURE SNAPSHOT</div>
+            <div class="form-row">
+                <div class="form-group">
+                    <label for="snapshotName">Snapshot Name</label>
+                    <input type="text" id="snapshotName" placeholder="before-checkout-scenario">
+                </div>
+            </div>
+            <label style="display: block; margin-bottom: 4px;">Tables (rows are matched by primary key)</label>
+            <div id="snapshotTables" style="max-height: 120px; overflow-y: auto; border: 1px solid #dadce0; border-radius: 3px; padding: 6px 10px; margin-bottom: 12px; font-size: 13px;"></div>
+            <div class="form-row">
+                <div class="form-group" style="flex: 2;">
+                    <label for="snapshotQuery">Query (optional, read-only)</label>
+                    <textarea id="snapshotQuery" rows="2" style="font-family: Monaco, monospace; font-size: 12px; padding: 6px 10px; border: 1px solid #dadce0; border-radius: 3px;" placeholder="SELECT OrderId, Status FROM Orders WHERE CustomerId = 42"></textarea>
+                </div>
+                <div class="form-group">
+                    <label for="snapshotKeyColumns">Query Key Columns</label>
+                    <input type="text" id="snapshotKeyColumns" placeholder="OrderId">
+                </div>
+            </div>
+            <div class="button-group">
+                <button class="btn-primary" onclick="captureSnapshot()">Capture Snapshot</button>
+            </div>
+            <div id="snapshotStatus" style="margin-top: 12px; padding: 8px; border-radius: 4px; display: none; font-size: 13px;"></div>
+
+            <div class="panel-title" style="margin: 24px 0 8px 0;">SAVED SNAPSHOTS</div>
+            <div id="snapshotList" style="display: flex; flex-direction: column; gap: 6px; margin-bottom: 16px;"></div>
+            <div class="form-row" style="align-items: flex-end;">
+                <div class="form-group">
+                    <label for="diffBefore">Before</label>
+                    <select id="diffBefore"></select>
+                </div>
+                <div class="form-group">
+                    <label for="diffAfter">After</label>
+                    <select id="diffAfter"></select>
+                </div>
+                <div class="form-group" style="flex: 0;">
+                    <button class="btn-primary" onclick="compareSnapshots()">Compare</button>
+                </div>
+            </div>
+            <div id="snapshotDiff" style="margin-top: 12px; font-size: 13px;"></div>
+        </div>
+    </div>
+</div>
+
+<!-- Saved Queries & History Modal -->
+<div id="queryLibraryModal" style="display: none; position: fixed; top: 0; left: 0; right: 0; bottom: 0; background: rgba(0,0,0,0.5); z-index: 10000; align-items: center; justify-content: center;" onclick="if (event.target === this) closeQueryLibrary()">
+    <div style="background: white; border-radius: 8px; width: 90%; max-width: 900px; max-height: 90vh; overflow: auto; box-shadow: 0 4px 16px rgba(0,0,0,0.2);">
+        <div style="padding: 16px 24px; border-bottom: 1px solid #dadce0; display: flex; justify-content: space-between; align-items: center;">
+            <div style="font-size: 16px; font-weight: 500; color: #202124;">Saved Queries &amp; History</div>
+            <button onclick="closeQueryLibrary()" style="background: none; border: none; font-size: 24px; color: #5f6368; cursor: pointer; padding: 0; width: 32px; height: 32px;">&times;</button>
+        </div>
+        <div style="padding: 16px 24px;">
+            <input type="text" id="queryLibrarySearch" placeholder="Search by name, SQL or connection..." oninput="renderQueryLibrary()" style="width: 100%; margin-bottom: 16px;">
+
+            <div class="panel-title" style="margin-bottom: 8px;">SAVED QUERIES FOR <span id="savedQueriesConfig"></span></div>
+            <div class="form-row" style="align-items: flex-end;">
+                <div class="form-group">
+                    <label for="savedQueryName">Save the editor's SQL as</label>
+                    <input type="text" id="savedQueryName" placeholder="Orders by customer">
+                </div>
+                <div class="form-group" style="flex: 0;">
+                    <button class="btn-primary" onclick="saveQuery()">Save Query</button>
+                </div>
+            </div>
+            <div id="queryLibraryStatus" style="margin-bottom: 12px; padding: 8px; border-radius: 4px; display: none; font-size: 13px;"></div>
+            <div id="savedQueryList" style="display: flex; flex-direction: column; gap: 6px;"></div>
+
+            <div style="display: flex; justify-content: space-between; align-items: center; margin: 24px 0 8px 0;">
+                <div class="panel-title">HISTORY</div>
+                <button class="btn-secondary" style="color: #d93025;" onclick="clearQueryHistory()">Clear History</button>
+            </div>
+            <div id="queryHistoryList" style="display: flex; flex-direction: column; gap: 6px;"></div>
+        </div>
+    </div>
+</div>
+
+<!-- Provision Emulator Modal -->
+<div id="provisionModal" style="display: none; position: fixed; top: 0; left: 0; right: 0; bottom: 0; background: rgba(0,0,0,0.5); z-index: 10000; align-items: center; justify-content: center;" onclick="if (event.target === this) closeProvisionModal()">
+    <div style="background: white; border-radius: 8px; width: 90%; max-width: 800px; max-height: 90vh; overflow: auto; box-shadow: 0 4px 16px rgba(0,0,0,0.2);">
+        <div style="padding: 16px 24px; border-bottom: 1px solid #dadce0; display: flex; justify-content: space-between; align-items: center;">
+            <div style="font-size: 16px; font-weight: 500; color: #202124;">Provision Emulator</div>
+            <button onclick="closeProvisionModal()" style="background: none; border: none; font-size: 24px; color: #5f6368; cursor: pointer; padding: 0; width: 32px; height: 32px;">&times;</button>
+        </div>
+        <div style="padding: 16px 24px;">
+            <div class="panel-title" style="margin-bottom: 8px;">INSTANCES IN <span id="provisionProject"></span></div>
+            <div id="instanceList" style="display: flex; flex-direction: column; gap: 6px; margin-bottom: 12px;"></div>
+            <div class="button-group">
+                <button class="btn-secondary" onclick="createInstance()">Create Instance <span id="provisionInstanceName"></span></button>
+            </div>
+
+            <div class="panel-title" style="margin: 24px 0 8px 0;">DATABASES IN <span id="provisionInstance"></span></div>
+            <div id="databaseList" style="display: flex; flex-direction: column; gap: 6px; margin-bottom: 12px;"></div>
+            <div class="form-row">
+                <div class="form-group">
+                    <label for="provisionDatabaseId">Database ID</label>
+                    <input type="text" id="provisionDatabaseId" placeholder="tms-suncorp-db">
+                </div>
+                <div class="form-group">
+                    <label for="provisionDialect">Dialect</label>
+                    <select id="provisionDialect">
+                        <option value="GOOGLE_STANDARD_SQL">GoogleSQL</option>
+                        <option value="POSTGRESQL">PostgreSQL</option>
+                    </select>
+                </div>
+                <div class="form-group">
+                    <label for="provisionDdlFile">DDL File (optional)</label>
+                    <input type="file" id="provisionDdlFile" accept=".sql,.ddl,.txt">
+                </div>
+            </div>
+            <label style="display: flex; align-items: center; gap: 6px; font-weight: normal; color: #202124; font-size: 13px; margin-bottom: 12px;">
+                <input type="checkbox" id="provisionCreateInstance" checked> Create the instance first if it does not exist
+            </label>
+            <div class="button-group">
+                <button class="btn-primary" onclick="createDatabase()">Create Database</button>
+            </div>
+            <div id="provisionStatus" style="margin-top: 12px; padding: 8px; border-radius: 4px; display: none; font-size: 13px;"></div>
+        </div>
+    </div>
+</div>
+
+<!-- Import & Seed Sets Modal -->
+<div id="dataModal" style="display: none; position: fixed; top: 0; left: 0; right: 0; bottom: 0; background: rgba(0,0,0,0.5); z-index: 10000; align-items: center; justify-content: center;" onclick="if (event.target === this) closeDataModal()">
+    <div style="background: white; border-radius: 8px; width: 90%; max-width: 760px; max-height: 85vh; overflow: auto; box-shadow: 0 4px 16px rgba(0,0,0,0.2);">
+        <div style="padding: 16px 24px; border-bottom: 1px solid #dadce0; display: flex; justify-content: space-between; align-items: center;">
+            <div style="font-size: 16px; font-weight: 500; color: #202124;">Import Data &amp; Seed Sets</div>
+            <button onclick="closeDataModal()" style="background: none; border: none; font-size: 24px; color: #5f6368; cursor: pointer; padding: 0; width: 32px; height: 32px;">&times;</button>
+        </div>
+        <div style="padding: 16px 24px;">
+            <div class="panel-title" style="margin-bottom: 8px;">IMPORT FILE</div>
+            <div class="form-row">
+                <div class="form-group">
+                    <label for="importTable">Target Table</label>
+                    <select id="importTable"></select>
+                </div>
+                <div class="form-group">
+                    <label for="importMode">Mode</label>
+                    <select id="importMode">
+                        <option value="upsert">Insert or update</option>
+                        <option value="insert">Insert only</option>
+                        <option value="replace">Replace</option>
+                    </select>
+                </div>
+                <div class="form-group">
+                    <label for="importBatchSize">Batch Size</label>
+                    <input type="number" id="importBatchSize" value="500" min="1">
+                </div>
+            </div>
+            <div class="form-group" style="margin-bottom: 12px;">
+                <label for="importFile">File (CSV, JSON or NDJSON)</label>
+                <input type="file" id="importFile" accept=".csv,.json,.ndjson,.jsonl">
+            </div>
+            <div class="form-group" style="margin-bottom: 12px;">
+                <label for="importMapping">Column Mapping (optional, one "source=column" per line; leave column empty to skip a field)</label>
+                <textarea id="importMapping" rows="3" style="font-family: Monaco, monospace; font-size: 12px; padding: 6px 10px; border: 1px solid #dadce0; border-radius: 3px;" placeholder="customer_id=CustomerId&#10;ignored_field="></textarea>
+            </div>
+            <div class="button-group">
+                <button class="btn-primary" onclick="importFile()">Import</button>
+            </div>
+            <div id="importStatus" style="margin-top: 12px; padding: 8px; border-radius: 4px; display: none; font-size: 13px;"></div>
+
+            <div class="panel-title" style="margin: 24px 0 8px 0;">SEED SETS</div>
+            <div id="seedList" style="display: flex; flex-direction: column; gap: 6px; margin-bottom: 16px;"></div>
+            <div class="form-row">
+                <div class="form-group">
+                    <label for="seedName">New Seed Set Name</label>
+                    <input type="text" id="seedName" placeholder="baseline-customers">
+                </div>
+                <div class="form-group">
+                    <label for="seedDescription">Description</label>
+                    <input type="text" id="seedDescription" placeholder="Optional">
+                </div>
+            </div>
+            <label style="display: block; margin-bottom: 4px;">Tables to capture (in load order; parents before children)</label>
+            <div id="seedTables" style="max-height: 140px; overflow-y: auto; border: 1px solid #dadce0; border-radius: 3px; padding: 6px 10px; margin-bottom: 12px; font-size: 13px;"></div>
+            <div class="button-group">
+                <button class="btn-secondary" onclick="saveSeedSet()">Save Current Rows as Seed Set</button>
+            </div>
+        </div>
+    </div>
+</div>
 `
 
 const SpannerJS = `
@@ -121,6 +363,10 @@ let allTables = [];
 let selectedTable = '';
 let currentTableColumns = [];
 let currentTableRows = [];
+let activeTransactionId = '';
+let currentDialect = 'GOOGLE_STANDARD_SQL';
+let rowEditor = null;
+let pendingRowChanges = [];
 
 // Toggle connection settings panel
 function toggleConnectionSettings() {
@@ -306,7 +552,8 @@ async function testConnection() {
         if (result.success) {
             statusDiv.style.background = '#e8f5e9';
             statusDiv.style.color = '#188038';
-            statusDiv.textContent = '✓ ' + result.message;
+            currentDialect = result.dialect || 'GOOGLE_STANDARD_SQL';
+            statusDiv.textContent = '✓ ' + result.message + (currentDialect === 'POSTGRESQL' ? ' (PostgreSQL dialect)' : '');
             showStatus('Connection successful!');
             // Auto-load tables on successful connection
             loadTables();
@@ -402,8 +649,14 @@ function renderTables(tables) {
     tableList.innerHTML = '';
     tables.forEach(table => {
         const div = document.createElement('div');
-        div.textContent = table.name;
-        div.style.cssText = 'padding: 8px 12px; cursor: pointer; border-radius: 4px; font-size: 13px; transition: all 0.2s; border: 1px solid #dadce0; margin-bottom: 4px; background: white;';
+        // Counting scans the table, so it waits until asked for
+        const count = table.type === 'VIEW' ? 'view' : table.rowCount !== undefined ? table.rowCount.toLocaleString() :
+            '<span title="Count rows (scans the table)" style="color: #1a73e8; cursor: pointer;" onclick="event.stopPropagation(); countTableRows(\'' + table.name.replace(/'/g, "\\'") + '\')">#</span>';
+        div.innerHTML = '<span style="flex: 1; overflow: hidden; text-overflow: ellipsis;">' + escapeHtml(table.name) + '</span>' +
+            '<span style="color: #5f6368; font-size: 11px;">' + count + '</span>' +
+            '<span title="Show schema" style="color: #1a73e8; font-size: 13px; padding: 0 2px;" onclick="event.stopPropagation(); showTableSchema(\'' + table.name.replace(/'/g, "\\'") + '\')">ⓘ</span>' +
+            '<span title="Edit rows" style="color: #1a73e8; font-size: 13px; padding: 0 2px;" onclick="event.stopPropagation(); openRowEditor(\'' + table.name.replace(/'/g, "\\'") + '\', 0)">✎</span>';
+        div.style.cssText = 'display: flex; align-items: center; gap: 6px; padding: 8px 12px; cursor: pointer; border-radius: 4px; font-size: 13px; transition: all 0.2s; border: 1px solid #dadce0; margin-bottom: 4px; background: white; white-space: nowrap;';
         div.onmouseover = () => {
             div.style.background = '#f1f3f4';
             div.style.borderColor = '#1a73e8';
@@ -423,18 +676,50 @@ function renderTables(tables) {
     });
 }
 
+async function countTableRows(tableName) {
+    try {
+        const req = getConnectionRequest();
+        req.tableName = tableName;
+        const response = await fetch('/api/spanner/tables/count', {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify(req)
+        });
+        const result = await response.json();
+        if (result.error) {
+            showStatus('Failed to count rows: ' + result.error, true);
+            return;
+        }
+        const table = allTables.find(t => t.name === tableName);
+        if (table) {
+            table.rowCount = result.rowCount;
+        }
+        filterTables();
+    } catch (error) {
+        showStatus('Failed to count rows: ' + error.message, true);
+    }
+}
+
 function filterTables() {
     const searchText = document.getElementById('tableSearch').value.toLowerCase();
     const filtered = allTables.filter(t => t.name.toLowerCase().includes(searchText));
     renderTables(filtered);
 }
 
+// quoteTableName quotes names PostgreSQL would otherwise fold to lower case
+function quoteTableName(name) {
+    if (currentDialect === 'POSTGRESQL' && !/^[a-z_][a-z0-9_]*$/.test(name)) {
+        return '"' + name.replace(/"/g, '""') + '"';
+    }
+    return name;
+}
+
 function selectTable(tableName) {
     selectedTable = tableName;
     renderTables(allTables);
 
     // Auto-fill query
-    document.getElementById('sqlQuery').value = 'SELECT * FROM ' + tableName + ' LIMIT 10;';
+    document.getElementById('sqlQuery').value = 'SELECT * FROM ' + quoteTableName(tableName) + ' LIMIT 10;';
 }
 
 function loadExampleQuery() {
@@ -443,11 +728,12 @@ function loadExampleQuery() {
     const queryArea = document.getElementById('sqlQuery');
 
     if (value === 'SHOW_TABLES') {
-        queryArea.value = "SELECT table_name FROM information_schema.tables WHERE table_schema = '' ORDER BY table_name;";
+        const schema = currentDialect === 'POSTGRESQL' ? 'public' : '';
+        queryArea.value = "SELECT table_name FROM information_schema.tables WHERE table_schema = '" + schema + "' ORDER BY table_name;";
     } else if (value === 'SELECT_ALL' && selectedTable) {
-        queryArea.value = 'SELECT * FROM ' + selectedTable + ' LIMIT 10;';
+        queryArea.value = 'SELECT * FROM ' + quoteTableName(selectedTable) + ' LIMIT 10;';
     } else if (value === 'COUNT' && selectedTable) {
-        queryArea.value = 'SELECT COUNT(*) as row_count FROM ' + selectedTable + ';';
+        queryArea.value = 'SELECT COUNT(*) as row_count FROM ' + quoteTableName(selectedTable) + ';';
     }
 
     select.value = '';
@@ -466,7 +752,10 @@ async function executeQuery() {
         projectId: document.getElementById('projectId').value,
         instanceId: document.getElementById('instanceId').value,
         databaseId: document.getElementById('databaseId').value,
-        query: query
+        query: query,
+        transactionId: activeTransactionId,
+        timestampBound: getTimestampBound(),
+        configName: document.getElementById('configSelect').value
     };
 
     // Hide previous results/errors
@@ -488,13 +777,27 @@ async function executeQuery() {
             document.getElementById('queryError').textContent = 'Error: ' + result.error;
             document.getElementById('queryResults').innerHTML = '';
             showStatus('Query failed', true);
+            // An aborted transaction is rolled back on the server
+            if (activeTransactionId && result.error.indexOf('Transaction') === 0) {
+                setActiveTransaction('');
+            }
             return;
         }
 
         // Show success stats
         const statsDiv = document.getElementById('queryStats');
         statsDiv.style.display = 'block';
-        statsDiv.textContent = '✓ Query executed successfully. Rows: ' + result.rowCount + ' | Time: ' + result.executionTime;
+        if (result.statements && result.statements.length > 0) {
+            const affected = result.statements.reduce((sum, st) => sum + st.rowsAffected, 0);
+            statsDiv.textContent = '✓ ' + result.statements.length + ' statement(s) executed ' +
+                (activeTransactionId ? 'in open transaction (not committed)' : 'and committed') +
+                '. Rows affected: ' + affected + ' | Time: ' + result.executionTime;
+        } else {
+            statsDiv.textContent = '✓ Query executed successfully. Rows: ' + result.rowCount + ' | Time: ' + result.executionTime;
+            if (result.readTimestamp && getTimestampBound()) {
+                statsDiv.textContent += ' | Read at: ' + result.readTimestamp;
+            }
+        }
 
         // Render results table
         if (result.rows && result.rows.length > 0) {
@@ -503,6 +806,10 @@ async function executeQuery() {
             document.getElementById('queryResults').innerHTML = '<div style="padding: 20px; color: #5f6368; font-size: 13px;">Query returned no rows</div>';
         }
 
+        if (result.statements && result.statements.length > 1) {
+            renderStatementSummary(result.statements);
+        }
+
         showStatus('Query executed successfully');
     } catch (error) {
         document.getElementById('queryError').style.display = 'block';
@@ -512,8 +819,1528 @@ async function executeQuery() {
     }
 }
 
+function updateReadBoundInput() {
+    const mode = document.getElementById('readMode').value;
+    const input = document.getElementById('readBoundValue');
+
+    if (mode === 'strong') {
+        input.style.display = 'none';
+        return;
+    }
+
+    input.style.display = '';
+    if (mode === 'readTimestamp') {
+        input.placeholder = '2024-01-31T09:30:00.000Z';
+        input.title = 'RFC 3339 timestamp within the version retention period';
+        input.value = new Date(Date.now() - 60000).toISOString();
+    } else {
+        input.placeholder = '30s, 5m, 1h';
+        input.title = 'How far in the past to read, e.g. 30s or 5m';
+        input.value = '30s';
+    }
+}
+
+function getTimestampBound() {
+    const mode = document.getElementById('readMode').value;
+    if (mode === 'strong') return null;
+
+    const value = document.getElementById('readBoundValue').value.trim();
+    if (mode === 'readTimestamp') {
+        return { mode: mode, timestamp: value };
+    }
+    return { mode: mode, staleness: value };
+}
+
+function renderStatementSummary(statements) {
+    const summary = document.createElement('div');
+    summary.style.cssText = 'padding: 8px 0 12px 0; font-size: 12px; color: #5f6368;';
+    statements.forEach((st, idx) => {
+        const line = document.createElement('div');
+        line.style.cssText = 'padding: 2px 0; white-space: nowrap; overflow: hidden; text-overflow: ellipsis; font-family: Monaco, monospace;';
+        line.textContent = (idx + 1) + '. ' + st.sql.replace(/\s+/g, ' ') + '  →  ' + st.rowsAffected + ' row(s)';
+        line.title = st.sql;
+        summary.appendChild(line);
+    });
+    const resultsDiv = document.getElementById('queryResults');
+    resultsDiv.insertBefore(summary, resultsDiv.firstChild);
+}
+
+function setActiveTransaction(id) {
+    activeTransactionId = id;
+    document.getElementById('beginTxnBtn').style.display = id ? 'none' : '';
+    document.getElementById('commitTxnBtn').style.display = id ? '' : 'none';
+    document.getElementById('rollbackTxnBtn').style.display = id ? '' : 'none';
+    const badge = document.getElementById('txnBadge');
+    badge.style.display = id ? '' : 'none';
+    badge.textContent = id ? 'Transaction open · ' + id.substring(0, 8) : '';
+}
+
+async function beginTransaction() {
+    try {
+        const response = await fetch('/api/spanner/transaction/begin', {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify(getConnectionRequest())
+        });
+        const result = await response.json();
+
+        if (result.error) {
+            showStatus('Failed to begin transaction: ' + result.error, true);
+            return;
+        }
+        setActiveTransaction(result.transactionId);
+        showStatus('Transaction started. Queries now run inside it until you commit or roll back.');
+    } catch (error) {
+        showStatus('Failed to begin transaction: ' + error.message, true);
+    }
+}
+
+async function endTransaction(action) {
+    if (!activeTransactionId) return;
+
+    try {
+        const response = await fetch('/api/spanner/transaction/' + action, {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({ transactionId: activeTransactionId })
+        });
+        const result = await response.json();
+        setActiveTransaction('');
+
+        if (result.error) {
+            showStatus(action + ' failed: ' + result.error, true);
+            return;
+        }
+        showStatus(action === 'commit'
+            ? 'Transaction committed at ' + result.commitTimestamp
+            : 'Transaction rolled back');
+    } catch (error) {
+        showStatus(action + ' failed: ' + error.message, true);
+    }
+}
+
+async function exportResults() {
+    const query = document.getElementById('sqlQuery').value.trim();
+
+    if (!query) {
+        showStatus('Please enter a SQL query', true);
+        return;
+    }
+
+    const exportReq = {
+        emulatorHost: document.getElementById('emulatorHost').value,
+        projectId: document.getElementById('projectId').value,
+        instanceId: document.getElementById('instanceId').value,
+        databaseId: document.getElementById('databaseId').value,
+        query: query,
+        format: document.getElementById('exportFormat').value,
+        timestampBound: getTimestampBound()
+    };
+
+    showStatus('Exporting...');
+
+    try {
+        const response = await fetch('/api/spanner/export', {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify(exportReq)
+        });
+
+        if (!response.ok) {
+            const text = await response.text();
+            let message = text;
+            try { message = JSON.parse(text).error || text; } catch (e) {}
+            showStatus('Export failed: ' + message, true);
+            return;
+        }
+
+        // Use the filename suggested by the server
+        let filename = 'query-results.' + exportReq.format;
+        const disposition = response.headers.get('Content-Disposition') || '';
+        const match = disposition.match(/filename="([^"]+)"/);
+        if (match) filename = match[1];
+
+        const blob = await response.blob();
+        const link = document.createElement('a');
+        link.href = URL.createObjectURL(blob);
+        link.download = filename;
+        document.body.appendChild(link);
+        link.click();
+        link.remove();
+        URL.revokeObjectURL(link.href);
+
+        showStatus('Exported ' + filename);
+    } catch (error) {
+        showStatus('Export error: ' + error.message, true);
+    }
+}
+
+function escapeHtml(value) {
+    return String(value)
+        .replace(/&/g, '&amp;')
+        .replace(/</g, '&lt;')
+        .replace(/>/g, '&gt;')
+        .replace(/"/g, '&quot;');
+}
+
+function openSchemaModal(title, html) {
+    document.getElementById('schemaModalTitle').textContent = title;
+    document.getElementById('schemaModalBody').innerHTML = html;
+    document.getElementById('schemaModal').style.display = 'flex';
+}
+
+function closeSchemaModal() {
+    document.getElementById('schemaModal').style.display = 'none';
+}
+
+async function showTableSchema(tableName) {
+    openSchemaModal(tableName, '<div style="color: #5f6368;">Loading schema...</div>');
+
+    try {
+        const req = getConnectionRequest();
+        req.tableName = tableName;
+        const response = await fetch('/api/spanner/schema', {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify(req)
+        });
+        const table = await response.json();
+
+        if (table.error) {
+            openSchemaModal(tableName, '<div style="color: #d93025;">Error: ' + escapeHtml(table.error) + '</div>');
+            return;
+        }
+        openSchemaModal(tableName, renderTableSchema(table));
+    } catch (error) {
+        openSchemaModal(tableName, '<div style="color: #d93025;">Error: ' + escapeHtml(error.message) + '</div>');
+    }
+}
+
+function schemaSection(title, body) {
+    return '<div class="panel-title" style="margin: 16px 0 6px 0;">' + title + '</div>' + body;
+}
+
+function schemaTable(headers, rows) {
+    const cell = 'padding: 6px 10px; border: 1px solid #e0e0e0; text-align: left; vertical-align: top;';
+    let html = '<table style="width: 100%; border-collapse: collapse; font-size: 12px;"><thead><tr style="background: #f8f9fa;">';
+    headers.forEach(h => { html += '<th style="' + cell + ' font-weight: 500; color: #5f6368;">' + h + '</th>'; });
+    html += '</tr></thead><tbody>';
+    rows.forEach(row => {
+        html += '<tr>' + row.map(v => '<td style="' + cell + '">' + v + '</td>').join('') + '</tr>';
+    });
+    return html + '</tbody></table>';
+}
+
+function renderTableSchema(table) {
+    const code = v => '<code style="font-family: Monaco, monospace; font-size: 12px;">' + escapeHtml(v) + '</code>';
+    const summary = [];
+    if (table.type === 'VIEW') {
+        summary.push('View');
+    } else if (table.rowCount !== undefined) {
+        summary.push(table.rowCount.toLocaleString() + ' rows');
+    }
+    if (table.parentTable) {
+        summary.push('Interleaved in ' + escapeHtml(table.parentTable) + (table.onDeleteAction ? ' ON DELETE ' + escapeHtml(table.onDeleteAction) : ''));
+    }
+    if (table.primaryKey && table.primaryKey.length > 0) {
+        summary.push('Primary key (' + table.primaryKey.map(escapeHtml).join(', ') + ')');
+    }
+    if (table.changeStreams && table.changeStreams.length > 0) {
+        summary.push('Change streams: ' + table.changeStreams.map(escapeHtml).join(', '));
+    }
+    let html = '<div style="color: #5f6368;">' + summary.join(' &middot; ') + '</div>';
+
+    html += schemaSection('COLUMNS', schemaTable(['Name', 'Type', 'Nullable', 'Default / Generated'],
+        (table.columns || []).map(c => {
+            let extra = '';
+            if (c.generationExpression) {
+                extra = 'AS (' + code(c.generationExpression) + ')' + (c.isStored ? ' STORED' : '');
+            } else if (c.defaultValue) {
+                extra = 'DEFAULT (' + code(c.defaultValue) + ')';
+            }
+            return [(c.isPrimaryKey ? '🔑 ' : '') + escapeHtml(c.name), code(c.type), c.isNullable ? 'YES' : 'NO', extra];
+        })));
+
+    if (table.indexes && table.indexes.length > 0) {
+        html += schemaSection('INDEXES', schemaTable(['Name', 'Columns', 'Storing', 'Options'],
+            table.indexes.map(idx => {
+                const options = [];
+                if (idx.isUnique) options.push('UNIQUE');
+                if (idx.isNullFiltered) options.push('NULL_FILTERED');
+                if (idx.parentTable) options.push('INTERLEAVE IN ' + escapeHtml(idx.parentTable));
+                return [
+                    escapeHtml(idx.name),
+                    idx.columns.map(c => escapeHtml(c.name) + (c.ordering === 'DESC' ? ' DESC' : '')).join(', '),
+                    (idx.storing || []).map(escapeHtml).join(', '),
+                    options.join(', ')
+                ];
+            })));
+    }
+
+    if (table.foreignKeys && table.foreignKeys.length > 0) {
+        html += schemaSection('FOREIGN KEYS', schemaTable(['Name', 'Columns', 'References', 'On Delete'],
+            table.foreignKeys.map(fk => [
+                escapeHtml(fk.name),
+                fk.columns.map(escapeHtml).join(', '),
+                escapeHtml(fk.referencedTable) + ' (' + fk.referencedColumns.map(escapeHtml).join(', ') + ')',
+                escapeHtml(fk.onDelete || '')
+            ])));
+    }
+
+    if (table.checkConstraints && table.checkConstraints.length > 0) {
+        html += schemaSection('CHECK CONSTRAINTS', schemaTable(['Name', 'Expression'],
+            table.checkConstraints.map(cc => [escapeHtml(cc.name), code(cc.expression)])));
+    }
+
+    return html;
+}
+
+async function showErDiagram() {
+    openSchemaModal('ER Diagram', '<div style="color: #5f6368;">Loading schema...</div>');
+
+    try {
+        const response = await fetch('/api/spanner/database-schema', {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify(getConnectionRequest())
+        });
+        const schema = await response.json();
+
+        if (schema.error) {
+            openSchemaModal('ER Diagram', '<div style="color: #d93025;">Error: ' + escapeHtml(schema.error) + '</div>');
+            return;
+        }
+        openSchemaModal('ER Diagram', renderErDiagram(schema));
+    } catch (error) {
+        openSchemaModal('ER Diagram', '<div style="color: #d93025;">Error: ' + escapeHtml(error.message) + '</div>');
+    }
+}
+
+// Lays tables out on a grid, parents before their interleaved children, and
+// draws interleaving as dashed lines and foreign keys as solid arrows
+function renderErDiagram(schema) {
+    const tables = (schema.tables || []).filter(t => t.type !== 'VIEW');
+    if (tables.length === 0) {
+        return '<div style="color: #5f6368;">No tables found</div>';
+    }
+
+    const byName = {};
+    tables.forEach(t => { byName[t.name] = t; });
+    const ordered = [];
+    const visit = t => {
+        if (ordered.indexOf(t) !== -1) return;
+        ordered.push(t);
+        tables.filter(c => c.parentTable === t.name).forEach(visit);
+    };
+    tables.filter(t => !t.parentTable || !byName[t.parentTable]).forEach(visit);
+
+    const boxWidth = 230, headerHeight = 26, lineHeight = 18, gap = 60;
+    const perRow = Math.ceil(Math.sqrt(ordered.length));
+    const boxes = {};
+    let y = 20, rowHeight = 0;
+    ordered.forEach((t, i) => {
+        if (i > 0 && i % perRow === 0) {
+            y += rowHeight + gap;
+            rowHeight = 0;
+        }
+        const height = headerHeight + Math.max(1, (t.columns || []).length) * lineHeight + 8;
+        boxes[t.name] = { x: 20 + (i % perRow) * (boxWidth + gap), y: y, w: boxWidth, h: height };
+        rowHeight = Math.max(rowHeight, height);
+    });
+    const width = 20 + perRow * (boxWidth + gap);
+    const height = y + rowHeight + 20;
+
+    // Connects the facing sides of two boxes
+    const edge = (from, to) => {
+        const a = boxes[from], b = boxes[to];
+        let x1, y1, x2, y2;
+        if (a.x + a.w < b.x || b.x + b.w < a.x) {
+            const right = a.x < b.x;
+            x1 = right ? a.x + a.w : a.x;
+            x2 = right ? b.x : b.x + b.w;
+            y1 = a.y + headerHeight / 2;
+            y2 = b.y + headerHeight / 2;
+        } else {
+            const down = a.y < b.y;
+            x1 = a.x + a.w / 2;
+            x2 = b.x + b.w / 2;
+            y1 = down ? a.y + a.h : a.y;
+            y2 = down ? b.y : b.y + b.h;
+        }
+        return 'x1="' + x1 + '" y1="' + y1 + '" x2="' + x2 + '" y2="' + y2 + '"';
+    };
+
+    let svg = '<svg xmlns="http://www.w3.org/2000/svg" width="' + width + '" height="' + height + '" style="font-family: Roboto, Arial, sans-serif; font-size: 12px;">';
+    svg += '<defs><marker id="erArrow" viewBox="0 0 10 10" refX="10" refY="5" markerWidth="8" markerHeight="8" orient="auto-start-reverse"><path d="M 0 0 L 10 5 L 0 10 z" fill="#1a73e8"/></marker>' +
+        '<marker id="erParent" viewBox="0 0 10 10" refX="10" refY="5" markerWidth="8" markerHeight="8" orient="auto-start-reverse"><path d="M 0 0 L 10 5 L 0 10 z" fill="#b06000"/></marker></defs>';
+
+    ordered.forEach(t => {
+        if (t.parentTable && boxes[t.parentTable]) {
+            svg += '<line ' + edge(t.name, t.parentTable) + ' stroke="#b06000" stroke-width="1.5" stroke-dasharray="6 4" marker-end="url(#erParent)">' +
+                '<title>' + escapeHtml(t.name) + ' interleaved in ' + escapeHtml(t.parentTable) + (t.onDeleteAction ? ' ON DELETE ' + escapeHtml(t.onDeleteAction) : '') + '</title></line>';
+        }
+        (t.foreignKeys || []).forEach(fk => {
+            if (!boxes[fk.referencedTable] || fk.referencedTable === t.name) return;
+            svg += '<line ' + edge(t.name, fk.referencedTable) + ' stroke="#1a73e8" stroke-width="1.5" marker-end="url(#erArrow)">' +
+                '<title>' + escapeHtml(fk.name) + ': ' + escapeHtml(t.name) + ' (' + fk.columns.map(escapeHtml).join(', ') + ') → ' +
+                escapeHtml(fk.referencedTable) + ' (' + fk.referencedColumns.map(escapeHtml).join(', ') + ')</title></line>';
+        });
+    });
+
+    ordered.forEach(t => {
+        const b = boxes[t.name];
+        const fkColumns = [];
+        (t.foreignKeys || []).forEach(fk => fk.columns.forEach(c => fkColumns.push(c)));
+        const clickName = t.name.replace(/'/g, "\\'");
+
+        svg += '<g style="cursor: pointer;" onclick="showTableSchema(\'' + escapeHtml(clickName) + '\')">';
+        svg += '<rect x="' + b.x + '" y="' + b.y + '" width="' + b.w + '" height="' + b.h + '" rx="4" fill="white" stroke="#dadce0"/>';
+        svg += '<rect x="' + b.x + '" y="' + b.y + '" width="' + b.w + '" height="' + headerHeight + '" rx="4" fill="#e8f0fe" stroke="#dadce0"/>';
+        svg += '<text x="' + (b.x + 8) + '" y="' + (b.y + 17) + '" font-weight="500" fill="#1967d2">' + escapeHtml(t.name) + '</text>';
+        if (t.rowCount !== undefined) {
+            svg += '<text x="' + (b.x + b.w - 8) + '" y="' + (b.y + 17) + '" text-anchor="end" fill="#5f6368" font-size="11">' + t.rowCount.toLocaleString() + ' rows</text>';
+        }
+        (t.columns || []).forEach((c, i) => {
+            const cy = b.y + headerHeight + 14 + i * lineHeight;
+            const marker = c.isPrimaryKey ? '🔑 ' : (fkColumns.indexOf(c.name) !== -1 ? '↗ ' : '');
+            svg += '<text x="' + (b.x + 8) + '" y="' + cy + '" fill="#202124"' + (c.isPrimaryKey ? ' font-weight="500"' : '') + '>' + marker + escapeHtml(c.name) + '</text>';
+            svg += '<text x="' + (b.x + b.w - 8) + '" y="' + cy + '" text-anchor="end" fill="#5f6368" font-size="11">' + escapeHtml(c.type) + (c.isNullable ? '' : ' !') + '</text>';
+        });
+        svg += '</g>';
+    });
+    svg += '</svg>';
+
+    let legend = '<div style="display: flex; gap: 16px; color: #5f6368; font-size: 12px; margin-bottom: 12px;">' +
+        '<span>🔑 primary key</span><span>↗ foreign key column</span><span>! NOT NULL</span>' +
+        '<span style="color: #b06000;">- - → interleaved in parent</span><span style="color: #1a73e8;">—→ foreign key</span>' +
+        '<span>Click a table for details</span></div>';
+
+    if (schema.changeStreams && schema.changeStreams.length > 0) {
+        legend += '<div style="color: #5f6368; font-size: 12px; margin-bottom: 12px;">Change streams: ' +
+            schema.changeStreams.map(cs => '<strong>' + escapeHtml(cs.name) + '</strong> (' +
+                (cs.all ? 'all tables' : (cs.tables || []).map(t => escapeHtml(t.table) +
+                    (t.allColumns ? '' : '[' + (t.columns || []).map(escapeHtml).join(', ') + ']')).join(', ')) + ')').join('; ') +
+            '</div>';
+    }
+
+    return legend + svg;
+}
+
+async function openRowEditor(tableName, offset) {
+    if (rowEditor && countPendingRowChanges() > 0 && !confirm('Discard the pending changes to ' + rowEditor.table + '?')) {
+        return;
+    }
+
+    const resultsDiv = document.getElementById('queryResults');
+    document.getElementById('queryStats').style.display = 'none';
+    document.getElementById('queryError').style.display = 'none';
+    resultsDiv.innerHTML = '<div style="padding: 20px; text-align: center;">Loading rows...</div>';
+
+    // Counting scans the table, so paging keeps the count it already has
+    const knownCount = rowEditor && rowEditor.table === tableName ? rowEditor.rowCount : null;
+
+    try {
+        const req = getConnectionRequest();
+        req.table = tableName;
+        req.offset = offset;
+        req.limit = 100;
+        req.count = knownCount === null;
+        const response = await fetch('/api/spanner/rows', {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify(req)
+        });
+        const data = await response.json();
+
+        if (data.error) {
+            rowEditor = null;
+            resultsDiv.innerHTML = '';
+            document.getElementById('queryError').style.display = 'block';
+            document.getElementById('queryError').textContent = 'Error: ' + data.error;
+            return;
+        }
+
+        rowEditor = {
+            table: tableName,
+            columns: data.columns || [],
+            primaryKey: data.primaryKey || [],
+            rows: data.rows || [],
+            rowCount: data.rowCount !== undefined ? data.rowCount : knownCount,
+            editable: data.editable,
+            offset: offset,
+            limit: 100,
+            edits: {},
+            deleted: {},
+            inserts: []
+        };
+        renderRowEditor();
+    } catch (error) {
+        resultsDiv.innerHTML = '';
+        document.getElementById('queryError').style.display = 'block';
+        document.getElementById('queryError').textContent = 'Error: ' + error.message;
+    }
+}
+
+// Cells show NULL as an empty input, so an empty STRING is written as ''
+function editorText(value) {
+    if (value === null || value === undefined) return '';
+    if (value === '') return "''";
+    return value;
+}
+
+function editorValue(col, text) {
+    if (text === '') return null;
+    if (text === "''" && col.type.toUpperCase().indexOf('STRING') === 0) return '';
+    return text;
+}
+
+function isReadOnlyColumn(col, isNewRow) {
+    return !rowEditor.editable || !!col.generationExpression || (col.isPrimaryKey && !isNewRow);
+}
+
+function editorCell(col, text, changed, readOnly, onchange) {
+    const cell = 'padding: 2px 4px; border: 1px solid #e0e0e0; min-width: 120px;' + (changed ? ' background: #fef7e0;' : '');
+    if (readOnly) {
+        const shown = text === '' ? '<span style="color: #9e9e9e; font-style: italic;">NULL</span>' : escapeHtml(text);
+        return '<td style="' + cell + ' color: #5f6368; padding: 6px 8px; white-space: nowrap;">' + shown + '</td>';
+    }
+    return '<td style="' + cell + '"><input type="text" value="' + escapeHtml(text) + '" placeholder="NULL" onchange="' + onchange + '" ' +
+        'style="width: 100%; border: none; background: transparent; font-size: 13px; padding: 4px; box-sizing: border-box;"></td>';
+}
+
+function renderRowEditor() {
+    const ed = rowEditor;
+    const resultsDiv = document.getElementById('queryResults');
+    const first = ed.rows.length === 0 ? 0 : ed.offset + 1;
+    const last = ed.offset + ed.rows.length;
+
+    let html = '<div style="display: flex; gap: 8px; align-items: center; margin-bottom: 8px; flex-wrap: wrap;">' +
+        '<strong style="color: #202124;">' + escapeHtml(ed.table) + '</strong>' +
+        '<span>rows ' + first + '–' + last + ' of ' + ed.rowCount.toLocaleString() + '</span>' +
+        '<button class="btn-secondary" onclick="openRowEditor(rowEditor.table, Math.max(0, rowEditor.offset - rowEditor.limit))"' + (ed.offset === 0 ? ' disabled' : '') + '>◀ Prev</button>' +
+        '<button class="btn-secondary" onclick="openRowEditor(rowEditor.table, rowEditor.offset + rowEditor.limit)"' + (last >= ed.rowCount ? ' disabled' : '') + '>Next ▶</button>';
+    if (ed.editable) {
+        html += '<button class="btn-secondary" onclick="addEditorRow()">+ Add Row</button>' +
+            '<button class="btn-primary" id="reviewChangesBtn" onclick="reviewRowChanges()">Review Changes (' + countPendingRowChanges() + ')</button>' +
+            '<button class="btn-secondary" onclick="discardRowChanges()">Discard</button>';
+    }
+    html += '</div>';
+
+    if (ed.editable) {
+        html += '<div style="color: #5f6368; font-size: 12px; margin-bottom: 8px;">Leave a cell empty for NULL; type \'\' for an empty string. Primary key and generated columns of existing rows are read-only.</div>';
+    } else {
+        html += '<div style="color: #b06000; font-size: 12px; margin-bottom: 8px;">' + escapeHtml(ed.table) + ' has no primary key, so its rows are read-only.</div>';
+    }
+
+    html += '<table style="border-collapse: collapse; font-size: 13px; background: white;"><thead><tr style="background: #f8f9fa;">';
+    html += '<th style="padding: 6px 8px; border: 1px solid #dadce0; width: 40px;"></th>';
+    ed.columns.forEach(col => {
+        html += '<th style="padding: 6px 8px; border: 1px solid #dadce0; text-align: left; font-weight: 500; color: #5f6368; font-size: 12px; white-space: nowrap;">' +
+            (col.isPrimaryKey ? '🔑 ' : '') + escapeHtml(col.name) + ' <span style="font-weight: normal; font-size: 11px;">' + escapeHtml(col.type) + '</span></th>';
+    });
+    html += '</tr></thead><tbody>';
+
+    ed.rows.forEach((row, idx) => {
+        const deleted = !!ed.deleted[idx];
+        const edits = ed.edits[idx] || {};
+        html += '<tr style="' + (deleted ? 'background: #fce8e6; text-decoration: line-through;' : '') + '">';
+        html += '<td style="padding: 2px; border: 1px solid #e0e0e0; text-align: center;">' +
+            (ed.editable ? '<button title="' + (deleted ? 'Restore row' : 'Delete row') + '" onclick="toggleEditorDelete(' + idx + ')" style="background: none; border: none; cursor: pointer; font-size: 13px;">' + (deleted ? '↺' : '🗑') + '</button>' : '') + '</td>';
+        ed.columns.forEach((col, colIdx) => {
+            const changed = edits.hasOwnProperty(col.name);
+            const text = changed ? edits[col.name] : editorText(row[col.name]);
+            html += editorCell(col, text, changed, deleted || isReadOnlyColumn(col, false), 'editRowCell(' + idx + ', ' + colIdx + ', this)');
+        });
+        html += '</tr>';
+    });
+
+    ed.inserts.forEach((values, idx) => {
+        html += '<tr style="background: #e6f4ea;">';
+        html += '<td style="padding: 2px; border: 1px solid #e0e0e0; text-align: center;"><button title="Remove new row" onclick="removeEditorRow(' + idx + ')" style="background: none; border: none; cursor: pointer; font-size: 13px;">✕</button></td>';
+        ed.columns.forEach((col, colIdx) => {
+            html += editorCell(col, values[col.name] || '', false, isReadOnlyColumn(col, true), 'editNewRowCell(' + idx + ', ' + colIdx + ', this)');
+        });
+        html += '</tr>';
+    });
+    html += '</tbody></table>';
+
+    resultsDiv.innerHTML = html;
+}
+
+function updateReviewButton() {
+    const btn = document.getElementById('reviewChangesBtn');
+    if (btn) {
+        btn.textContent = 'Review Changes (' + countPendingRowChanges() + ')';
+    }
+}
+
+function editRowCell(idx, colIdx, input) {
+    const col = rowEditor.columns[colIdx];
+    const original = editorText(rowEditor.rows[idx][col.name]);
+    const edits = rowEditor.edits[idx] || {};
+
+    if (input.value === original) {
+        delete edits[col.name];
+    } else {
+        edits[col.name] = input.value;
+    }
+    if (Object.keys(edits).length > 0) {
+        rowEditor.edits[idx] = edits;
+    } else {
+        delete rowEditor.edits[idx];
+    }
+
+    input.parentElement.style.background = edits.hasOwnProperty(col.name) ? '#fef7e0' : '';
+    updateReviewButton();
+}
+
+function editNewRowCell(idx, colIdx, input) {
+    rowEditor.inserts[idx][rowEditor.columns[colIdx].name] = input.value;
+    updateReviewButton();
+}
+
+function toggleEditorDelete(idx) {
+    if (rowEditor.deleted[idx]) {
+        delete rowEditor.deleted[idx];
+    } else {
+        rowEditor.deleted[idx] = true;
+    }
+    renderRowEditor();
+}
+
+function addEditorRow() {
+    rowEditor.inserts.push({});
+    renderRowEditor();
+}
+
+function removeEditorRow(idx) {
+    rowEditor.inserts.splice(idx, 1);
+    renderRowEditor();
+}
+
+function discardRowChanges() {
+    rowEditor.edits = {};
+    rowEditor.deleted = {};
+    rowEditor.inserts = [];
+    renderRowEditor();
+}
+
+function rowKey(row) {
+    const key = {};
+    rowEditor.primaryKey.forEach(name => { key[name] = row[name]; });
+    return key;
+}
+
+// Collects the edits as row changes, deletes first so a deleted key can be
+// inserted again, each paired with the row as it was loaded
+function collectRowChanges() {
+    const ed = rowEditor;
+    const changes = [];
+
+    Object.keys(ed.deleted).forEach(idx => {
+        changes.push({ change: { op: 'delete', key: rowKey(ed.rows[idx]) }, before: ed.rows[idx] });
+    });
+
+    Object.keys(ed.edits).forEach(idx => {
+        if (ed.deleted[idx]) return;
+        const values = {};
+        ed.columns.forEach(col => {
+            if (ed.edits[idx].hasOwnProperty(col.name)) {
+                values[col.name] = editorValue(col, ed.edits[idx][col.name]);
+            }
+        });
+        changes.push({ change: { op: 'update', key: rowKey(ed.rows[idx]), values: values }, before: ed.rows[idx] });
+    });
+
+    ed.inserts.forEach(inserted => {
+        // Empty cells are left out so column defaults apply
+        const values = {};
+        ed.columns.forEach(col => {
+            if (inserted[col.name]) {
+                values[col.name] = editorValue(col, inserted[col.name]);
+            }
+        });
+        if (Object.keys(values).length > 0) {
+            changes.push({ change: { op: 'insert', values: values }, before: null });
+        }
+    });
+
+    return changes;
+}
+
+function countPendingRowChanges() {
+    return rowEditor ? collectRowChanges().length : 0;
+}
+
+async function sendRowChanges(dryRun) {
+    const req = getConnectionRequest();
+    req.table = rowEditor.table;
+    req.changes = pendingRowChanges.map(p => p.change);
+    req.dryRun = dryRun;
+
+    const response = await fetch('/api/spanner/mutations', {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify(req)
+    });
+    return response.json();
+}
+
+async function reviewRowChanges() {
+    pendingRowChanges = collectRowChanges();
+    if (pendingRowChanges.length === 0) {
+        showStatus('No pending changes');
+        return;
+    }
+
+    document.getElementById('mutationModalTitle').textContent = 'Pending Mutations for ' + rowEditor.table;
+    document.getElementById('mutationDiff').innerHTML = '<div style="color: #5f6368;">Building mutations...</div>';
+    document.getElementById('mutationStatus').textContent = '';
+    document.getElementById('applyMutationsBtn').disabled = true;
+    document.getElementById('mutationModal').style.display = 'flex';
+
+    try {
+        const result = await sendRowChanges(true);
+        if (result.error) {
+            document.getElementById('mutationDiff').innerHTML = renderMutationDiff(result.mutations || []);
+            document.getElementById('mutationStatus').textContent = result.error;
+            return;
+        }
+        document.getElementById('mutationDiff').innerHTML = renderMutationDiff(result.mutations);
+        document.getElementById('applyMutationsBtn').disabled = false;
+    } catch (error) {
+        document.getElementById('mutationStatus').textContent = error.message;
+    }
+}
+
+function renderMutationDiff(mutations) {
+    const shown = v => v === null || v === undefined
+        ? '<span style="color: #9e9e9e; font-style: italic;">NULL</span>'
+        : escapeHtml(v);
+    const labels = {
+        insert: { text: 'INSERT', call: 'spanner.InsertMap', color: '#188038', background: '#e6f4ea' },
+        update: { text: 'UPDATE', call: 'spanner.UpdateMap', color: '#b06000', background: '#fef7e0' },
+        delete: { text: 'DELETE', call: 'spanner.Delete', color: '#d93025', background: '#fce8e6' }
+    };
+    const cell = 'padding: 4px 8px; border: 1px solid #e0e0e0; text-align: left; vertical-align: top;';
+
+    return mutations.map((m, i) => {
+        const label = labels[m.op];
+        const before = pendingRowChanges[i] ? pendingRowChanges[i].before : null;
+        const key = Object.keys(m.key || {}).map(k => escapeHtml(k) + '=' + shown(m.key[k])).join(', ');
+
+        let html = '<div style="border: 1px solid #dadce0; border-radius: 4px; margin-bottom: 10px;">' +
+            '<div style="padding: 6px 10px; background: ' + label.background + '; display: flex; gap: 8px; align-items: center;">' +
+            '<strong style="color: ' + label.color + ';">' + label.text + '</strong>' +
+            '<span>' + escapeHtml(m.table) + (key ? ' (' + key + ')' : '') + '</span>' +
+            '<code style="margin-left: auto; font-size: 11px; color: #5f6368;">' + label.call + '</code></div>';
+
+        html += '<table style="width: 100%; border-collapse: collapse; font-size: 12px;"><thead><tr style="background: #f8f9fa;">' +
+            '<th style="' + cell + ' width: 30%;">Column</th><th style="' + cell + '">Before</th><th style="' + cell + '">After</th></tr></thead><tbody>';
+        if (m.op === 'delete') {
+            rowEditor.columns.forEach(col => {
+                html += '<tr><td style="' + cell + '">' + escapeHtml(col.name) + '</td>' +
+                    '<td style="' + cell + ' color: #d93025; text-decoration: line-through;">' + shown(before ? before[col.name] : null) + '</td>' +
+                    '<td style="' + cell + '"></td></tr>';
+            });
+        } else {
+            (m.columns || []).forEach(name => {
+                html += '<tr><td style="' + cell + '">' + escapeHtml(name) + '</td>' +
+                    '<td style="' + cell + ' color: #d93025;' + (before ? ' text-decoration: line-through;' : '') + '">' + (before ? shown(before[name]) : '') + '</td>' +
+                    '<td style="' + cell + ' color: #188038;">' + shown(m.values[name]) + '</td></tr>';
+            });
+        }
+        return html + '</tbody></table></div>';
+    }).join('');
+}
+
+async function applyRowChanges() {
+    document.getElementById('applyMutationsBtn').disabled = true;
+    document.getElementById('mutationStatus').textContent = '';
+
+    try {
+        const result = await sendRowChanges(false);
+        if (result.error) {
+            document.getElementById('mutationStatus').textContent = result.error;
+            document.getElementById('applyMutationsBtn').disabled = false;
+            return;
+        }
+
+        closeMutationModal();
+        showStatus('Applied ' + result.mutations.length + ' mutations at ' + result.commitTimestamp);
+        rowEditor.edits = {};
+        rowEditor.deleted = {};
+        rowEditor.inserts = [];
+        rowEditor.rowCount = null;
+        openRowEditor(rowEditor.table, rowEditor.offset);
+    } catch (error) {
+        document.getElementById('mutationStatus').textContent = error.message;
+        document.getElementById('applyMutationsBtn').disabled = false;
+    }
+}
+
+function closeMutationModal() {
+    document.getElementById('mutationModal').style.display = 'none';
+}
+
+function openSnapshotModal() {
+    document.getElementById('snapshotTables').innerHTML = allTables.length === 0
+        ? '<div style="color: #5f6368;">Connect to load tables</div>'
+        : allTables.map(t =>
+            '<label style="display: flex; align-items: center; gap: 6px; font-weight: normal; color: #202124; font-size: 13px; padding: 2px 0;">' +
+            '<input type="checkbox" class="snapshot-table" value="' + escapeHtml(t.name) + '"' + (t.name === selectedTable ? ' checked' : '') + '> ' + escapeHtml(t.name) + '</label>'
+        ).join('');
+
+    document.getElementById('snapshotStatus').style.display = 'none';
+    document.getElementById('snapshotDiff').innerHTML = '';
+    document.getElementById('snapshotModal').style.display = 'flex';
+    loadSnapshots();
+}
+
+function closeSnapshotModal() {
+    document.getElementById('snapshotModal').style.display = 'none';
+}
+
+function showSnapshotStatus(message, isError) {
+    const statusDiv = document.getElementById('snapshotStatus');
+    statusDiv.style.display = 'block';
+    statusDiv.style.background = isError ? '#fce8e6' : '#e8f5e9';
+    statusDiv.style.color = isError ? '#d93025' : '#188038';
+    statusDiv.textContent = message;
+}
+
+async function loadSnapshots() {
+    const list = document.getElementById('snapshotList');
+    try {
+        const response = await fetch('/api/spanner/snapshots');
+        const snapshots = await response.json();
+
+        if (snapshots.length === 0) {
+            list.innerHTML = '<div style="color: #5f6368; font-size: 13px;">No snapshots captured yet</div>';
+        } else {
+            list.innerHTML = snapshots.map(snap => {
+                const tables = snap.tables.map(t => escapeHtml(t.name) + ' (' + t.rowCount + ')').join(', ');
+                const name = escapeHtml(snap.name.replace(/'/g, "\\'"));
+                return '<div style="display: flex; align-items: center; gap: 8px; padding: 8px 12px; border: 1px solid #dadce0; border-radius: 4px;">' +
+                    '<div style="flex: 1; min-width: 0;"><div style="font-weight: 500;">' + escapeHtml(snap.name) + '</div>' +
+                    '<div style="font-size: 12px; color: #5f6368;">' + escapeHtml(snap.createdAt) + ' &middot; ' + tables + '</div></div>' +
+                    '<button class="btn-secondary" onclick="compareSnapshots(\'' + name + '\', \'\')">Diff vs Current</button>' +
+                    '<button class="btn-secondary" style="color: #d93025;" onclick="deleteSnapshot(\'' + name + '\')">Delete</button></div>';
+            }).join('');
+        }
+
+        const options = snapshots.map(snap => '<option value="' + escapeHtml(snap.name) + '">' + escapeHtml(snap.name) + '</option>').join('');
+        document.getElementById('diffBefore').innerHTML = options;
+        document.getElementById('diffAfter').innerHTML = '<option value="">Current database</option>' + options;
+    } catch (error) {
+        list.innerHTML = '<div style="color: #d93025; font-size: 13px;">Error: ' + escapeHtml(error.message) + '</div>';
+    }
+}
+
+async function captureSnapshot() {
+    const name = document.getElementById('snapshotName').value.trim();
+    const tables = Array.from(document.querySelectorAll('.snapshot-table:checked')).map(cb => cb.value);
+    const query = document.getElementById('snapshotQuery').value.trim();
+    const keyColumns = document.getElementById('snapshotKeyColumns').value.split(',').map(k => k.trim()).filter(k => k);
+
+    if (!name || (tables.length === 0 && !query)) {
+        showSnapshotStatus('Enter a name and select at least one table or enter a query', true);
+        return;
+    }
+
+    const req = getConnectionRequest();
+    req.name = name;
+    req.tables = tables;
+    if (query) {
+        req.queries = [{ name: 'Query', sql: query, keyColumns: keyColumns }];
+    }
+
+    showSnapshotStatus('Capturing...', false);
+    try {
+        const response = await fetch('/api/spanner/snapshots/capture', {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify(req)
+        });
+
+        if (!response.ok) {
+            const text = await response.text();
+            let message = text;
+            try { message = JSON.parse(text).error || text; } catch (e) {}
+            showSnapshotStatus('✗ ' + message, true);
+            return;
+        }
+
+        showSnapshotStatus('✓ Snapshot "' + name + '" captured', false);
+        loadSnapshots();
+    } catch (error) {
+        showSnapshotStatus('✗ Error: ' + error.message, true);
+    }
+}
+
+async function deleteSnapshot(name) {
+    if (!confirm('Delete snapshot "' + name + '"?')) {
+        return;
+    }
+
+    try {
+        const response = await fetch('/api/spanner/snapshots/delete', {
+            method: 'DELETE',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({ name: name })
+        });
+        if (!response.ok) {
+            showSnapshotStatus('✗ ' + await response.text(), true);
+            return;
+        }
+        loadSnapshots();
+    } catch (error) {
+        showSnapshotStatus('✗ Error: ' + error.message, true);
+    }
+}
+
+async function compareSnapshots(before, after) {
+    if (before === undefined) {
+        before = document.getElementById('diffBefore').value;
+        after = document.getElementById('diffAfter').value;
+    }
+    if (!before) {
+        showSnapshotStatus('Capture a snapshot first', true);
+        return;
+    }
+
+    const diffDiv = document.getElementById('snapshotDiff');
+    diffDiv.innerHTML = '<div style="color: #5f6368;">Comparing...</div>';
+
+    try {
+        const req = getConnectionRequest();
+        req.before = before;
+        req.after = after;
+        const response = await fetch('/api/spanner/snapshots/diff', {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify(req)
+        });
+
+        const text = await response.text();
+        let diff;
+        try { diff = JSON.parse(text); } catch (e) { diff = { error: text }; }
+        if (diff.error) {
+            diffDiv.innerHTML = '<div style="color: #d93025;">Error: ' + escapeHtml(diff.error) + '</div>';
+            return;
+        }
+        diffDiv.innerHTML = renderSnapshotDiff(diff);
+    } catch (error) {
+        diffDiv.innerHTML = '<div style="color: #d93025;">Error: ' + escapeHtml(error.message) + '</div>';
+    }
+}
+
+function diffValue(value) {
+    if (value === null || value === undefined) {
+        return '<span style="color: #9e9e9e; font-style: italic;">NULL</span>';
+    }
+    return escapeHtml(typeof value === 'object' ? JSON.stringify(value) : value);
+}
+
+function renderSnapshotDiff(diff) {
+    const cell = 'padding: 4px 8px; border: 1px solid #e0e0e0; text-align: left; vertical-align: top; white-space: nowrap;';
+    let html = '<div style="color: #5f6368; margin-bottom: 8px;">' + escapeHtml(diff.before) + ' → ' + escapeHtml(diff.after) + '</div>';
+
+    diff.tables.forEach(t => {
+        const total = t.added.length + t.removed.length + t.changed.length;
+        html += '<div style="border: 1px solid #dadce0; border-radius: 4px; margin-bottom: 12px;">' +
+            '<div style="padding: 8px 12px; background: #f8f9fa; display: flex; gap: 12px; align-items: center; flex-wrap: wrap;">' +
+            '<strong>' + escapeHtml(t.name) + '</strong>' +
+            '<span style="color: #188038;">+' + t.added.length + ' added</span>' +
+            '<span style="color: #d93025;">−' + t.removed.length + ' removed</span>' +
+            '<span style="color: #b06000;">~' + t.changed.length + ' changed</span>' +
+            '<span style="color: #5f6368;">' + t.unchanged + ' unchanged</span>' +
+            (t.note ? '<span style="color: #5f6368; font-size: 12px;">' + escapeHtml(t.note) + '</span>' : '') + '</div>';
+
+        if (total === 0) {
+            html += '</div>';
+            return;
+        }
+
+        const columns = [];
+        t.added.concat(t.removed).forEach(row => Object.keys(row).forEach(c => { if (columns.indexOf(c) === -1) columns.push(c); }));
+        t.changed.forEach(c => Object.keys(c.after).forEach(col => { if (columns.indexOf(col) === -1) columns.push(col); }));
+
+        html += '<div style="overflow-x: auto;"><table style="border-collapse: collapse; font-size: 12px; width: 100%;"><thead><tr style="background: #f8f9fa;">' +
+            '<th style="' + cell + ' width: 30px;"></th>' + columns.map(c => '<th style="' + cell + '">' + escapeHtml(c) + '</th>').join('') + '</tr></thead><tbody>';
+        t.removed.forEach(row => {
+            html += '<tr style="background: #fce8e6;"><td style="' + cell + ' color: #d93025;">−</td>' +
+                columns.map(c => '<td style="' + cell + '">' + diffValue(row[c]) + '</td>').join('') + '</tr>';
+        });
+        t.added.forEach(row => {
+            html += '<tr style="background: #e6f4ea;"><td style="' + cell + ' color: #188038;">+</td>' +
+                columns.map(c => '<td style="' + cell + '">' + diffValue(row[c]) + '</td>').join('') + '</tr>';
+        });
+        t.changed.forEach(change => {
+            html += '<tr><td style="' + cell + ' color: #b06000;">~</td>' + columns.map(c => {
+                if (change.changedColumns.indexOf(c) === -1) {
+                    return '<td style="' + cell + '">' + diffValue(change.after[c]) + '</td>';
+                }
+                return '<td style="' + cell + ' background: #fef7e0;"><span style="color: #d93025; text-decoration: line-through;">' + diffValue(change.before[c]) +
+                    '</span> → <span style="color: #188038;">' + diffValue(change.after[c]) + '</span></td>';
+            }).join('') + '</tr>';
+        });
+        html += '</tbody></table></div></div>';
+    });
+
+    return html;
+}
+
+let savedQueries = [];
+let queryHistory = [];
+
+async function openQueryLibrary() {
+    document.getElementById('queryLibraryStatus').style.display = 'none';
+    document.getElementById('queryLibraryModal').style.display = 'flex';
+    document.getElementById('queryLibrarySearch').focus();
+    await loadQueryLibrary();
+}
+
+function closeQueryLibrary() {
+    document.getElementById('queryLibraryModal').style.display = 'none';
+}
+
+function showQueryLibraryStatus(message, isError) {
+    const statusDiv = document.getElementById('queryLibraryStatus');
+    statusDiv.style.display = 'block';
+    statusDiv.style.background = isError ? '#fce8e6' : '#e8f5e9';
+    statusDiv.style.color = isError ? '#d93025' : '#188038';
+    statusDiv.textContent = message;
+}
+
+// loadQueryLibrary fetches the saved queries of the selected profile and the
+// query history of every connection
+async function loadQueryLibrary() {
+    const configName = document.getElementById('configSelect').value;
+    document.getElementById('savedQueriesConfig').textContent = configName || '(no profile selected)';
+
+    try {
+        const [configsResponse, historyResponse] = await Promise.all([
+            fetch('/api/configs'),
+            fetch('/api/spanner/history')
+        ]);
+        const data = await configsResponse.json();
+        const config = (data.spannerConfigs || []).find(c => c.name === configName);
+        savedQueries = config ? (config.savedQueries || []) : [];
+        queryHistory = await historyResponse.json();
+        renderQueryLibrary();
+    } catch (error) {
+        showQueryLibraryStatus('Failed to load queries: ' + error.message, true);
+    }
+}
+
+function matchesQuerySearch(search, values) {
+    return !search || values.some(v => v && v.toLowerCase().indexOf(search) !== -1);
+}
+
+function renderQueryLibrary() {
+    const search = document.getElementById('queryLibrarySearch').value.trim().toLowerCase();
+
+    const saved = savedQueries
+        .map((q, idx) => ({ q: q, idx: idx }))
+        .filter(item => matchesQuerySearch(search, [item.q.name, item.q.sql]));
+    document.getElementById('savedQueryList').innerHTML = saved.length === 0
+        ? '<div style="color: #5f6368; font-size: 13px;">' + (savedQueries.length === 0 ? 'No saved queries for this profile' : 'No saved queries match') + '</div>'
+        : saved.map(item =>
+            '<div style="display: flex; align-items: center; gap: 8px; padding: 8px 12px; border: 1px solid #dadce0; border-radius: 4px;">' +
+            '<div style="flex: 1; min-width: 0;"><div style="font-weight: 500;">' + escapeHtml(item.q.name) + '</div>' +
+            '<div style="font-size: 12px; color: #5f6368; font-family: monospace; white-space: nowrap; overflow: hidden; text-overflow: ellipsis;">' + escapeHtml(item.q.sql) + '</div></div>' +
+            '<button class="btn-secondary" onclick="useSavedQuery(' + item.idx + ')">Open</button>' +
+            '<button class="btn-secondary" style="color: #d93025;" onclick="deleteSavedQuery(' + item.idx + ')">Delete</button></div>'
+        ).join('');
+
+    const history = queryHistory
+        .map((h, idx) => ({ h: h, idx: idx }))
+        .filter(item => matchesQuerySearch(search, [item.h.sql, item.h.connection, item.h.configName]));
+    document.getElementById('queryHistoryList').innerHTML = history.length === 0
+        ? '<div style="color: #5f6368; font-size: 13px;">' + (queryHistory.length === 0 ? 'No queries run yet' : 'No history entries match') + '</div>'
+        : history.map(item => {
+            const h = item.h;
+            return '<div style="display: flex; align-items: center; gap: 8px; padding: 8px 12px; border: 1px solid #dadce0; border-radius: 4px; cursor: pointer;" onclick="useHistoryQuery(' + item.idx + ')" title="Open in the editor">' +
+                '<div style="flex: 1; min-width: 0;"><div style="font-size: 12px; font-family: monospace; white-space: nowrap; overflow: hidden; text-overflow: ellipsis;">' + escapeHtml(h.sql) + '</div>' +
+                '<div style="font-size: 12px; color: #5f6368;">' + escapeHtml(new Date(h.timestamp).toLocaleString()) + ' &middot; ' +
+                escapeHtml(h.configName ? h.configName + ' (' + h.connection + ')' : h.connection) + ' &middot; ' +
+                escapeHtml(h.duration || '-') + ' &middot; ' + h.rowCount + ' row(s)</div></div></div>';
+        }).join('');
+}
+
+function useSavedQuery(idx) {
+    document.getElementById('sqlQuery').value = savedQueries[idx].sql;
+    document.getElementById('savedQueryName').value = savedQueries[idx].name;
+    closeQueryLibrary();
+}
+
+function useHistoryQuery(idx) {
+    document.getElementById('sqlQuery').value = queryHistory[idx].sql;
+    closeQueryLibrary();
+}
+
+async function saveQuery() {
+    const configName = document.getElementById('configSelect').value;
+    const name = document.getElementById('savedQueryName').value.trim();
+    const sql = document.getElementById('sqlQuery').value.trim();
+
+    if (!configName) {
+        showQueryLibraryStatus('Select or save a configuration profile first', true);
+        return;
+    }
+    if (!name || !sql) {
+        showQueryLibraryStatus('Enter a name and a query in the SQL editor', true);
+        return;
+    }
+
+    try {
+        const response = await fetch('/api/spanner/queries/save', {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({ configName: configName, name: name, sql: sql })
+        });
+        if (!response.ok) throw new Error(await response.text());
+        showQueryLibraryStatus('Saved "' + name + '" to ' + configName, false);
+        await loadQueryLibrary();
+    } catch (error) {
+        showQueryLibraryStatus('Failed to save query: ' + error.message, true);
+    }
+}
+
+async function deleteSavedQuery(idx) {
+    const configName = document.getElementById('configSelect').value;
+    const name = savedQueries[idx].name;
+    if (!confirm('Delete saved query "' + name + '"?')) return;
+
+    try {
+        const response = await fetch('/api/spanner/queries/delete', {
+            method: 'DELETE',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({ configName: configName, name: name })
+        });
+        if (!response.ok) throw new Error(await response.text());
+        await loadQueryLibrary();
+    } catch (error) {
+        showQueryLibraryStatus('Failed to delete query: ' + error.message, true);
+    }
+}
+
+async function clearQueryHistory() {
+    if (!confirm('Clear the query history of every connection?')) return;
+
+    try {
+        const response = await fetch('/api/spanner/history/clear', { method: 'DELETE' });
+        if (!response.ok) throw new Error(await response.text());
+        await loadQueryLibrary();
+    } catch (error) {
+        showQueryLibraryStatus('Failed to clear history: ' + error.message, true);
+    }
+}
+
+let provisionInstances = [];
+
+function openProvisionModal() {
+    const conn = getConnectionRequest();
+    document.getElementById('provisionProject').textContent = conn.projectId;
+    document.getElementById('provisionInstanceName').textContent = conn.instanceId;
+    document.getElementById('provisionInstance').textContent = conn.instanceId;
+    document.getElementById('provisionDatabaseId').value = conn.databaseId;
+    document.getElementById('provisionStatus').style.display = 'none';
+    document.getElementById('provisionModal').style.display = 'flex';
+    loadInstances();
+}
+
+function closeProvisionModal() {
+    document.getElementById('provisionModal').style.display = 'none';
+}
+
+function showProvisionStatus(message, isError) {
+    const statusDiv = document.getElementById('provisionStatus');
+    statusDiv.style.display = 'block';
+    statusDiv.style.background = isError ? '#fce8e6' : '#e8f5e9';
+    statusDiv.style.color = isError ? '#d93025' : '#188038';
+    statusDiv.textContent = message;
+}
+
+// provisionRequest posts to an admin endpoint and reports the outcome,
+// returning whether it succeeded
+async function provisionRequest(url, method, body, pending) {
+    showProvisionStatus(pending, false);
+    try {
+        const response = await fetch(url, {
+            method: method,
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify(body)
+        });
+        const text = await response.text();
+        let result = {};
+        try { result = JSON.parse(text); } catch (e) { result = { error: text }; }
+
+        if (!response.ok || !result.success) {
+            showProvisionStatus('✗ ' + (result.message ? result.message + ': ' : '') + (result.error || text), true);
+            return false;
+        }
+        showProvisionStatus('✓ ' + result.message, false);
+        return true;
+    } catch (error) {
+        showProvisionStatus('✗ Error: ' + error.message, true);
+        return false;
+    }
+}
+
+async function loadInstances() {
+    const list = document.getElementById('instanceList');
+    const current = document.getElementById('instanceId').value;
+    try {
+        const response = await fetch('/api/spanner/instances', {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify(getConnectionRequest())
+        });
+        const result = await response.json();
+        if (!response.ok) {
+            throw new Error(result.error || 'Failed to list instances');
+        }
+        provisionInstances = result;
+
+        list.innerHTML = result.length === 0
+            ? '<div style="color: #5f6368; font-size: 13px;">No instances yet</div>'
+            : result.map(inst => {
+                const id = escapeHtml(inst.instanceId.replace(/'/g, "\\'"));
+                const isCurrent = inst.instanceId === current;
+                return '<div style="display: flex; align-items: center; gap: 8px; padding: 8px 12px; border: 1px solid ' + (isCurrent ? '#1a73e8' : '#dadce0') + '; border-radius: 4px;">' +
+                    '<div style="flex: 1; min-width: 0;"><div style="font-weight: 500;">' + escapeHtml(inst.instanceId) + '</div>' +
+                    '<div style="font-size: 12px; color: #5f6368;">' + escapeHtml(inst.config) + ' &middot; ' + inst.nodeCount + ' node(s) &middot; ' + escapeHtml(inst.state) + '</div></div>' +
+                    (isCurrent ? '' : '<button class="btn-secondary" onclick="useInstance(\'' + id + '\')">Use</button>') +
+                    '<button class="btn-secondary" style="color: #d93025;" onclick="deleteInstance(\'' + id + '\')">Delete</button></div>';
+            }).join('');
+    } catch (error) {
+        provisionInstances = [];
+        list.innerHTML = '<div style="color: #d93025; font-size: 13px;">Error: ' + escapeHtml(error.message) + '</div>';
+    }
+    loadDatabases();
+}
+
+async function loadDatabases() {
+    const list = document.getElementById('databaseList');
+    const conn = getConnectionRequest();
+    if (!provisionInstances.some(inst => inst.instanceId === conn.instanceId)) {
+        list.innerHTML = '<div style="color: #5f6368; font-size: 13px;">Instance ' + escapeHtml(conn.instanceId) + ' does not exist yet</div>';
+        return;
+    }
+
+    try {
+        const response = await fetch('/api/spanner/databases', {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify(conn)
+        });
+        const result = await response.json();
+        if (!response.ok) {
+            throw new Error(result.error || 'Failed to list databases');
+        }
+
+        list.innerHTML = result.length === 0
+            ? '<div style="color: #5f6368; font-size: 13px;">No databases yet</div>'
+            : result.map(db => {
+                const id = escapeHtml(db.databaseId.replace(/'/g, "\\'"));
+                const isCurrent = db.databaseId === conn.databaseId;
+                return '<div style="display: flex; align-items: center; gap: 8px; padding: 8px 12px; border: 1px solid ' + (isCurrent ? '#1a73e8' : '#dadce0') + '; border-radius: 4px;">' +
+                    '<div style="flex: 1; min-width: 0;"><div style="font-weight: 500;">' + escapeHtml(db.databaseId) + '</div>' +
+                    '<div style="font-size: 12px; color: #5f6368;">' + (db.dialect === 'POSTGRESQL' ? 'PostgreSQL' : 'GoogleSQL') + ' &middot; ' + escapeHtml(db.state) + '</div></div>' +
+                    '<button class="btn-secondary" onclick="useDatabase(\'' + id + '\')">Connect</button>' +
+                    '<button class="btn-secondary" style="color: #d93025;" onclick="dropDatabase(\'' + id + '\')">Drop</button></div>';
+            }).join('');
+    } catch (error) {
+        list.innerHTML = '<div style="color: #d93025; font-size: 13px;">Error: ' + escapeHtml(error.message) + '</div>';
+    }
+}
+
+function useInstance(instanceId) {
+    document.getElementById('instanceId').value = instanceId;
+    openProvisionModal();
+}
+
+function useDatabase(databaseId) {
+    document.getElementById('databaseId').value = databaseId;
+    closeProvisionModal();
+    testConnection();
+}
+
+async function createInstance() {
+    const req = getConnectionRequest();
+    if (await provisionRequest('/api/spanner/instances/create', 'POST', req, 'Creating instance ' + req.instanceId + '...')) {
+        loadInstances();
+    }
+}
+
+async function deleteInstance(instanceId) {
+    if (!confirm('Delete instance "' + instanceId + '" and all of its databases?')) {
+        return;
+    }
+    const req = getConnectionRequest();
+    req.instanceId = instanceId;
+    if (await provisionRequest('/api/spanner/instances/delete', 'DELETE', req, 'Deleting instance ' + instanceId + '...')) {
+        loadInstances();
+    }
+}
+
+async function createDatabase() {
+    const req = getConnectionRequest();
+    req.databaseId = document.getElementById('provisionDatabaseId').value.trim();
+    req.dialect = document.getElementById('provisionDialect').value;
+    if (!req.databaseId) {
+        showProvisionStatus('Enter a database ID', true);
+        return;
+    }
+
+    const file = document.getElementById('provisionDdlFile').files[0];
+    if (file) {
+        req.ddl = await file.text();
+    }
+
+    if (document.getElementById('provisionCreateInstance').checked &&
+        !provisionInstances.some(inst => inst.instanceId === req.instanceId)) {
+        if (!await provisionRequest('/api/spanner/instances/create', 'POST', req, 'Creating instance ' + req.instanceId + '...')) {
+            return;
+        }
+    }
+
+    if (await provisionRequest('/api/spanner/databases/create', 'POST', req, 'Creating database ' + req.databaseId + '...')) {
+        document.getElementById('databaseId').value = req.databaseId;
+        loadInstances();
+        testConnection();
+    }
+}
+
+async function dropDatabase(databaseId) {
+    if (!confirm('Drop database "' + databaseId + '"? All of its data is lost.')) {
+        return;
+    }
+    const req = getConnectionRequest();
+    req.databaseId = databaseId;
+    if (await provisionRequest('/api/spanner/databases/drop', 'DELETE', req, 'Dropping database ' + databaseId + '...')) {
+        loadDatabases();
+    }
+}
+
+function getConnectionRequest() {
+    return {
+        emulatorHost: document.getElementById('emulatorHost').value,
+        projectId: document.getElementById('projectId').value,
+        instanceId: document.getElementById('instanceId').value,
+        databaseId: document.getElementById('databaseId').value
+    };
+}
+
+function openDataModal() {
+    const options = allTables.map(t => '<option value="' + t.name + '">' + t.name + '</option>').join('');
+    document.getElementById('importTable').innerHTML = options;
+    if (selectedTable) {
+        document.getElementById('importTable').value = selectedTable;
+    }
+
+    document.getElementById('seedTables').innerHTML = allTables.length === 0
+        ? '<div style="color: #5f6368;">Connect to load tables</div>'
+        : allTables.map(t =>
+            '<label style="display: flex; align-items: center; gap: 6px; font-weight: normal; color: #202124; font-size: 13px; padding: 2px 0;">' +
+            '<input type="checkbox" class="seed-table" value="' + t.name + '"> ' + t.name + '</label>'
+        ).join('');
+
+    document.getElementById('importStatus').style.display = 'none';
+    document.getElementById('dataModal').style.display = 'flex';
+    loadSeedSets();
+}
+
+function closeDataModal() {
+    document.getElementById('dataModal').style.display = 'none';
+}
+
+function showImportStatus(message, isError) {
+    const statusDiv = document.getElementById('importStatus');
+    statusDiv.style.display = 'block';
+    statusDiv.style.background = isError ? '#fce8e6' : '#e8f5e9';
+    statusDiv.style.color = isError ? '#d93025' : '#188038';
+    statusDiv.textContent = message;
+}
+
+async function importFile() {
+    const fileInput = document.getElementById('importFile');
+    if (!fileInput.files.length) {
+        showImportStatus('Please choose a file to import', true);
+        return;
+    }
+
+    const mapping = {};
+    document.getElementById('importMapping').value.split('\n').forEach(line => {
+        const idx = line.indexOf('=');
+        if (idx > 0) {
+            mapping[line.substring(0, idx).trim()] = line.substring(idx + 1).trim();
+        }
+    });
+
+    const options = Object.assign(getConnectionRequest(), {
+        table: document.getElementById('importTable').value,
+        mode: document.getElementById('importMode').value,
+        batchSize: parseInt(document.getElementById('importBatchSize').value, 10) || 0,
+        mapping: mapping
+    });
+
+    const form = new FormData();
+    form.append('options', JSON.stringify(options));
+    form.append('file', fileInput.files[0]);
+
+    showImportStatus('Importing ' + fileInput.files[0].name + '...', false);
+
+    try {
+        const response = await fetch('/api/spanner/import', { method: 'POST', body: form });
+        const text = await response.text();
+        let result;
+        try { result = JSON.parse(text); } catch (e) { result = { error: text }; }
+
+        if (result.error) {
+            showImportStatus('✗ ' + result.error + (result.rowsImported ? ' (' + result.rowsImported + ' rows written before the failure)' : ''), true);
+            return;
+        }
+        showImportStatus('✓ Imported ' + result.rowsImported + ' rows in ' + result.batches + ' batch(es) | Time: ' + result.executionTime, false);
+        showStatus('Import complete');
+    } catch (error) {
+        showImportStatus('✗ Error: ' + error.message, true);
+    }
+}
+
+async function loadSeedSets() {
+    const list = document.getElementById('seedList');
+    try {
+        const response = await fetch('/api/spanner/seeds');
+        const seeds = await response.json();
+
+        if (seeds.length === 0) {
+            list.innerHTML = '<div style="color: #5f6368; font-size: 13px;">No seed sets saved yet</div>';
+            return;
+        }
+
+        list.innerHTML = '';
+        seeds.forEach(seed => {
+            const rows = seed.tables.reduce((sum, t) => sum + t.rowCount, 0);
+            const item = document.createElement('div');
+            item.style.cssText = 'display: flex; align-items: center; gap: 8px; padding: 8px 12px; border: 1px solid #dadce0; border-radius: 4px; font-size: 13px;';
+
+            const info = document.createElement('div');
+            info.style.flex = '1';
+            info.innerHTML = '<div style="font-weight: 500; color: #202124;"></div><div style="color: #5f6368; font-size: 12px;"></div>';
+            info.children[0].textContent = seed.name + (seed.description ? ' — ' + seed.description : '');
+            info.children[1].textContent = seed.tables.map(t => t.table + ' (' + t.rowCount + ')').join(', ') + ' · ' + rows + ' rows';
+            item.appendChild(info);
+
+            const button = (label, cls, handler) => {
+                const b = document.createElement('button');
+                b.className = cls;
+                b.textContent = label;
+                b.onclick = handler;
+                return b;
+            };
+            item.appendChild(button('Apply', 'btn-primary', () => applySeedSet(seed.name, false)));
+            item.appendChild(button('Reset & Apply', 'btn-secondary', () => applySeedSet(seed.name, true)));
+            item.appendChild(button('Delete', 'btn-secondary', () => deleteSeedSet(seed.name)));
+            list.appendChild(item);
+        });
+    } catch (error) {
+        list.innerHTML = '<div style="color: #d93025; font-size: 13px;">Error loading seed sets: ' + error.message + '</div>';
+    }
+}
+
+async function applySeedSet(name, reset) {
+    if (reset && !confirm('Delete all rows from the tables in "' + name + '" before seeding?')) {
+        return;
+    }
+
+    showImportStatus((reset ? 'Resetting and applying ' : 'Applying ') + name + '...', false);
+
+    try {
+        const response = await fetch('/api/spanner/seeds/apply', {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify(Object.assign(getConnectionRequest(), { name: name, reset: reset }))
+        });
+        const text = await response.text();
+        let result;
+        try { result = JSON.parse(text); } catch (e) { result = { error: text }; }
+
+        if (result.error) {
+            showImportStatus('✗ ' + result.error, true);
+            return;
+        }
+        showImportStatus('✓ Seeded ' + result.rowsImported + ' rows from "' + name + '" | Time: ' + result.executionTime, false);
+        showStatus('Seed set applied');
+    } catch (error) {
+        showImportStatus('✗ Error: ' + error.message, true);
+    }
+}
+
+async function saveSeedSet() {
+    const name = document.getElementById('seedName').value.trim();
+    const tables = Array.from(document.querySelectorAll('.seed-table:checked')).map(cb => cb.value);
+
+    if (!name || tables.length === 0) {
+        showImportStatus('Enter a seed set name and pick at least one table', true);
+        return;
+    }
+
+    showImportStatus('Capturing rows from ' + tables.join(', ') + '...', false);
+
+    try {
+        const response = await fetch('/api/spanner/seeds/save', {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify(Object.assign(getConnectionRequest(), {
+                name: name,
+                description: document.getElementById('seedDescription').value.trim(),
+                tables: tables
+            }))
+        });
+        const text = await response.text();
+        let result;
+        try { result = JSON.parse(text); } catch (e) { result = { error: text }; }
+
+        if (!response.ok) {
+            showImportStatus('✗ ' + (result.error || text), true);
+            return;
+        }
+        showImportStatus('✓ ' + result.message, false);
+        document.getElementById('seedName').value = '';
+        document.getElementById('seedDescription').value = '';
+        loadSeedSets();
+    } catch (error) {
+        showImportStatus('✗ Error: ' + error.message, true);
+    }
+}
+
+async function deleteSeedSet(name) {
+    if (!confirm('Delete seed set "' + name + '"?')) {
+        return;
+    }
+
+    try {
+        const response = await fetch('/api/spanner/seeds/delete', {
+            method: 'DELETE',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({ name: name })
+        });
+        if (!response.ok) {
+            showImportStatus('✗ ' + await response.text(), true);
+            return;
+        }
+        loadSeedSets();
+    } catch (error) {
+        showImportStatus('✗ Error: ' + error.message, true);
+    }
+}
+
 function renderResultsTable(columns, rows) {
     const resultsDiv = document.getElementById('queryResults');
+    rowEditor = null;
 
     // Store current table data for copying
     currentTableColumns = columns;
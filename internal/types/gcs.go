@@ -0,0 +1,180 @@
+package types
+
+// GCSConnection identifies the storage service to talk to. An empty
+// EmulatorHost means real GCS, authenticated with Application Default
+// Credentials.
+type GCSConnection struct {
+	EmulatorHost string `json:"emulatorHost"`
+	ProjectID    string `json:"projectId"`
+}
+
+type BucketsResponse struct {
+	Buckets []Bucket `json:"buckets"`
+}
+
+type Bucket struct {
+	Name         string `json:"name"`
+	Location     string `json:"location,omitempty"`
+	StorageClass string `json:"storageClass,omitempty"`
+}
+
+type ObjectsResponse struct {
+	Items    []Object `json:"items,omitempty"`
+	Prefixes []string `json:"prefixes,omitempty"`
+}
+
+// Object is an object resource of the GCS JSON API, which spells sizes and
+// generations as strings. Metadata is the custom metadata; TimeDeleted is
+// set on noncurrent versions.
+type Object struct {
+	Name           string            `json:"name"`
+	Size           int64             `json:"size,string"`
+	ContentType    string            `json:"contentType,omitempty"`
+	MD5Hash        string            `json:"md5Hash,omitempty"`
+	CRC32C         string            `json:"crc32c,omitempty"`
+	Generation     string            `json:"generation,omitempty"`
+	Metageneration string            `json:"metageneration,omitempty"`
+	StorageClass   string            `json:"storageClass,omitempty"`
+	TimeCreated    string            `json:"timeCreated,omitempty"`
+	Updated        string            `json:"updated,omitempty"`
+	TimeDeleted    string            `json:"timeDeleted,omitempty"`
+	Metadata       map[string]string `json:"metadata,omitempty"`
+}
+
+// ObjectSearchResponse lists the objects matching a search. Scanned counts
+// the objects listed to find them; Truncated is set when the search stopped
+// at its limit.
+type ObjectSearchResponse struct {
+	Items     []Object `json:"items"`
+	Scanned   int      `json:"scanned"`
+	Truncated bool     `json:"truncated"`
+}
+
+type ObjectVersionsResponse struct {
+	VersioningEnabled bool     `json:"versioningEnabled"`
+	Versions          []Object `json:"versions"`
+}
+
+type GCSMetadataRequest struct {
+	Bucket   string            `json:"bucket"`
+	Object   string            `json:"object"`
+	Metadata map[string]string `json:"metadata"`
+}
+
+// ContentResponse is one chunk of an object preview. Kind is text, binary
+// or image: text chunks come in Content, binary ones in Data, and images are
+// left to the download endpoint. Offsets count decompressed bytes when the
+// object was gunzipped; Size is always the stored size.
+type ContentResponse struct {
+	Content     string `json:"content,omitempty"`
+	Data        []byte `json:"data,omitempty"`
+	Kind        string `json:"kind"`
+	ContentType string `json:"contentType"`
+	Size        int64  `json:"size"`
+	Offset      int64  `json:"offset"`
+	NextOffset  int64  `json:"nextOffset"`
+	EOF         bool   `json:"eof"`
+	Gunzipped   bool   `json:"gunzipped,omitempty"`
+}
+
+// TablePreview is the first rows of a structured object: Avro, Parquet, CSV
+// or NDJSON. Cells are strings, or null when the record has no value; nested
+// values are rendered as JSON. RawSchema is the schema as the format writes
+// it, when it has one.
+type TablePreview struct {
+	Format    string          `json:"format"`
+	Schema    []TableColumn   `json:"schema"`
+	RawSchema string          `json:"rawSchema,omitempty"`
+	Rows      [][]interface{} `json:"rows"`
+	Truncated bool            `json:"truncated"`
+	TotalRows int64           `json:"totalRows,omitempty"`
+}
+
+type TableColumn struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// ObjectDiff compares two objects in one of three modes. Text diffs come as
+// hunks of lines around each change; JSON diffs as the paths whose values
+// differ, whatever the order of keys; CSV diffs as rows matched by key
+// columns. Added, Removed and Changed count lines, paths or rows, including
+// those left out when Truncated is set.
+type ObjectDiff struct {
+	Mode      string       `json:"mode"`
+	Left      Object       `json:"left"`
+	Right     Object       `json:"right"`
+	Identical bool         `json:"identical"`
+	Added     int          `json:"added"`
+	Removed   int          `json:"removed"`
+	Changed   int          `json:"changed"`
+	Hunks     []DiffHunk   `json:"hunks,omitempty"`
+	JSON      []JSONChange `json:"json,omitempty"`
+	Rows      *TableDiff   `json:"rows,omitempty"`
+	Truncated bool         `json:"truncated"`
+	Note      string       `json:"note,omitempty"`
+}
+
+// DiffHunk is a run of changed lines with the unchanged lines around them.
+// Line numbers count from 1.
+type DiffHunk struct {
+	LeftStart  int        `json:"leftStart"`
+	RightStart int        `json:"rightStart"`
+	Lines      []DiffLine `json:"lines"`
+}
+
+// DiffLine is an unchanged ("="), removed ("-") or added ("+") line, with
+// its number on the sides it is on
+type DiffLine struct {
+	Op    string `json:"op"`
+	Left  int    `json:"left,omitempty"`
+	Right int    `json:"right,omitempty"`
+	Text  string `json:"text"`
+}
+
+// JSONChange is a value added, removed or changed at a JSONPath-like path
+type JSONChange struct {
+	Path   string      `json:"path"`
+	Op     string      `json:"op"`
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// GCSCopyRequest copies or moves an object, or every object under a prefix
+// when SourceObject ends with "/"
+type GCSCopyRequest struct {
+	SourceBucket      string `json:"sourceBucket"`
+	SourceObject      string `json:"sourceObject"`
+	DestinationBucket string `json:"destinationBucket"`
+	DestinationObject string `json:"destinationObject"`
+	Move              bool   `json:"move"`
+}
+
+type GCSBucketRequest struct {
+	Name     string `json:"name"`
+	Location string `json:"location,omitempty"`
+}
+
+type GCSFolderRequest struct {
+	Bucket string `json:"bucket"`
+	Prefix string `json:"prefix"`
+}
+
+type GCSUploadedObject struct {
+	Name       string `json:"name"`
+	Size       int64  `json:"size"`
+	UploadType string `json:"uploadType"`
+}
+
+// GCSOperationResponse reports the outcome of a write. Count is the number
+// of objects deleted or copied, which on error is how far it got. Notified
+// is the number of bucket notifications published for the write; failing to
+// publish them does not fail the write.
+type GCSOperationResponse struct {
+	Message           string              `json:"message,omitempty"`
+	Count             int                 `json:"count"`
+	Uploaded          []GCSUploadedObject `json:"uploaded,omitempty"`
+	Notified          int                 `json:"notified,omitempty"`
+	NotificationError string              `json:"notificationError,omitempty"`
+	Error             string              `json:"error,omitempty"`
+}
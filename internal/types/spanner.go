@@ -7,30 +7,159 @@ type QueryRequest struct {
 	InstanceID   string `json:"instanceId"`
 	DatabaseID   string `json:"databaseId"`
 	Query        string `json:"query"`
+	// TransactionID runs the query inside an open read-write transaction
+	TransactionID string `json:"transactionId,omitempty"`
+	// TimestampBound selects the snapshot read-only queries see; nil means strong
+	TimestampBound *TimestampBound `json:"timestampBound,omitempty"`
+	// ConfigName is the selected configuration profile, kept in the query history
+	ConfigName string `json:"configName,omitempty"`
+}
+
+// TimestampBound selects which version of the data a read-only query sees.
+// Mode is one of strong, exactStaleness, maxStaleness or readTimestamp.
+// Staleness is a Go duration such as "30s"; Timestamp is RFC 3339.
+type TimestampBound struct {
+	Mode      string `json:"mode"`
+	Staleness string `json:"staleness,omitempty"`
+	Timestamp string `json:"timestamp,omitempty"`
+}
+
+// Connection returns the database the query runs against
+func (q QueryRequest) Connection() ConnectionRequest {
+	return ConnectionRequest{
+		EmulatorHost: q.EmulatorHost,
+		ProjectID:    q.ProjectID,
+		InstanceID:   q.InstanceID,
+		DatabaseID:   q.DatabaseID,
+	}
 }
 
 // QueryResponse represents the result of a SQL query
 type QueryResponse struct {
-	Columns      []string                 `json:"columns"`
-	Rows         []map[string]interface{} `json:"rows"`
-	RowCount     int                      `json:"rowCount"`
+	Columns       []string                 `json:"columns"`
+	Rows          []map[string]interface{} `json:"rows"`
+	RowCount      int                      `json:"rowCount"`
 	ExecutionTime string                   `json:"executionTime"`
-	Error        string                   `json:"error,omitempty"`
+	Error         string                   `json:"error,omitempty"`
+	// Statements holds one result per statement when a script was executed
+	Statements []StatementResult `json:"statements,omitempty"`
+	// ReadTimestamp is the snapshot time a read-only query was served at
+	ReadTimestamp string `json:"readTimestamp,omitempty"`
 }
 
-// TableInfo represents metadata about a table
+// StatementResult represents the outcome of one statement in a script
+type StatementResult struct {
+	SQL          string                   `json:"sql"`
+	RowsAffected int64                    `json:"rowsAffected"`
+	Columns      []string                 `json:"columns,omitempty"`
+	Rows         []map[string]interface{} `json:"rows,omitempty"`
+}
+
+// TransactionRequest identifies an open read-write transaction
+type TransactionRequest struct {
+	TransactionID string `json:"transactionId"`
+}
+
+// TransactionResponse represents the state of a read-write transaction
+type TransactionResponse struct {
+	TransactionID   string `json:"transactionId,omitempty"`
+	Status          string `json:"status"`
+	CommitTimestamp string `json:"commitTimestamp,omitempty"`
+	Error           string `json:"error,omitempty"`
+}
+
+// ExportRequest represents a request to export query results to a file.
+// Table names the target of SQL inserts by its parts, such as a schema and
+// a table, and is read from the query when left out.
+type ExportRequest struct {
+	QueryRequest
+	Format string   `json:"format"`
+	Table  []string `json:"table,omitempty"`
+}
+
+// TableInfo represents metadata about a table. RowCount is only set when the
+// table's rows were counted, which scans the table.
 type TableInfo struct {
-	Name       string       `json:"name"`
-	RowCount   int64        `json:"rowCount,omitempty"`
-	Columns    []ColumnInfo `json:"columns,omitempty"`
+	Name     string       `json:"name"`
+	RowCount *int64       `json:"rowCount,omitempty"`
+	Columns  []ColumnInfo `json:"columns,omitempty"`
+	// Type is BASE TABLE or VIEW
+	Type       string   `json:"type,omitempty"`
+	PrimaryKey []string `json:"primaryKey,omitempty"`
+	// ParentTable is set for tables interleaved in another table
+	ParentTable      string                `json:"parentTable,omitempty"`
+	OnDeleteAction   string                `json:"onDeleteAction,omitempty"`
+	Indexes          []IndexInfo           `json:"indexes,omitempty"`
+	ForeignKeys      []ForeignKeyInfo      `json:"foreignKeys,omitempty"`
+	CheckConstraints []CheckConstraintInfo `json:"checkConstraints,omitempty"`
+	// ChangeStreams names the change streams that watch this table
+	ChangeStreams []string `json:"changeStreams,omitempty"`
 }
 
 // ColumnInfo represents metadata about a column
 type ColumnInfo struct {
+	Name         string `json:"name"`
+	Type         string `json:"type"`
+	IsNullable   bool   `json:"isNullable"`
+	IsPrimaryKey bool   `json:"isPrimaryKey"`
+	// GenerationExpression is set for generated columns
+	GenerationExpression string `json:"generationExpression,omitempty"`
+	IsStored             bool   `json:"isStored,omitempty"`
+	DefaultValue         string `json:"defaultValue,omitempty"`
+}
+
+// IndexInfo represents a secondary index. Column ordering is ASC or DESC.
+type IndexInfo struct {
+	Name           string            `json:"name"`
+	Columns        []IndexColumnInfo `json:"columns"`
+	Storing        []string          `json:"storing,omitempty"`
+	IsUnique       bool              `json:"isUnique"`
+	IsNullFiltered bool              `json:"isNullFiltered"`
+	ParentTable    string            `json:"parentTable,omitempty"`
+}
+
+// IndexColumnInfo represents one key column of an index
+type IndexColumnInfo struct {
+	Name     string `json:"name"`
+	Ordering string `json:"ordering"`
+}
+
+// ForeignKeyInfo represents a foreign key from this table to another
+type ForeignKeyInfo struct {
+	Name              string   `json:"name"`
+	Columns           []string `json:"columns"`
+	ReferencedTable   string   `json:"referencedTable"`
+	ReferencedColumns []string `json:"referencedColumns"`
+	OnDelete          string   `json:"onDelete,omitempty"`
+}
+
+// CheckConstraintInfo represents a CHECK constraint
+type CheckConstraintInfo struct {
 	Name       string `json:"name"`
-	Type       string `json:"type"`
-	IsNullable bool   `json:"isNullable"`
-	IsPrimaryKey bool `json:"isPrimaryKey"`
+	Expression string `json:"expression"`
+}
+
+// ChangeStreamInfo represents a change stream and what it watches. All is
+// set when the stream watches every table in the database.
+type ChangeStreamInfo struct {
+	Name   string                  `json:"name"`
+	All    bool                    `json:"all"`
+	Tables []ChangeStreamTableInfo `json:"tables,omitempty"`
+}
+
+// ChangeStreamTableInfo represents a table watched by a change stream. An
+// empty Columns list with AllColumns unset means only key changes are tracked.
+type ChangeStreamTableInfo struct {
+	Table      string   `json:"table"`
+	AllColumns bool     `json:"allColumns"`
+	Columns    []string `json:"columns,omitempty"`
+}
+
+// DatabaseSchema represents the full schema of a database
+type DatabaseSchema struct {
+	Dialect       string             `json:"dialect"`
+	Tables        []TableInfo        `json:"tables"`
+	ChangeStreams []ChangeStreamInfo `json:"changeStreams,omitempty"`
 }
 
 // ConnectionRequest represents a connection test request
@@ -45,5 +174,206 @@ type ConnectionRequest struct {
 type ConnectionResponse struct {
 	Success bool   `json:"success"`
 	Message string `json:"message"`
+	Dialect string `json:"dialect,omitempty"`
 	Error   string `json:"error,omitempty"`
 }
+
+// ImportRequest represents a request to load a CSV, JSON or NDJSON file into a table
+type ImportRequest struct {
+	ConnectionRequest
+	Table     string            `json:"table"`
+	Format    string            `json:"format"`
+	Mode      string            `json:"mode"`
+	Mapping   map[string]string `json:"mapping,omitempty"`
+	BatchSize int               `json:"batchSize,omitempty"`
+}
+
+// ImportResponse represents the result of an import or seed run
+type ImportResponse struct {
+	RowsImported  int    `json:"rowsImported"`
+	Batches       int    `json:"batches"`
+	ExecutionTime string `json:"executionTime"`
+	Error         string `json:"error,omitempty"`
+}
+
+// SeedRequest represents a request to capture or apply a named seed set
+type SeedRequest struct {
+	ConnectionRequest
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Tables      []string `json:"tables,omitempty"`
+	Reset       bool     `json:"reset,omitempty"`
+}
+
+// TableRowsRequest represents a request to load a page of rows into the row
+// editor. Count asks for the table's row count too, which scans the table,
+// so it is only wanted when the editor opens or the rows change.
+type TableRowsRequest struct {
+	ConnectionRequest
+	Table  string `json:"table"`
+	Limit  int    `json:"limit,omitempty"`
+	Offset int    `json:"offset,omitempty"`
+	Count  bool   `json:"count,omitempty"`
+}
+
+// TableRowsResponse represents a page of rows for the row editor. Each value
+// is null or its text form, as in an exported CSV cell, which RowChange
+// accepts back unchanged and which keeps INT64 precision in JavaScript.
+type TableRowsResponse struct {
+	Columns    []ColumnInfo             `json:"columns"`
+	PrimaryKey []string                 `json:"primaryKey"`
+	Rows       []map[string]interface{} `json:"rows"`
+	RowCount   *int64                   `json:"rowCount,omitempty"`
+	Editable   bool                     `json:"editable"`
+	Error      string                   `json:"error,omitempty"`
+}
+
+// RowChange represents one edited row. Op is insert, update or delete. Key
+// holds the primary key of an existing row; Values holds every column of an
+// inserted row, or only the changed columns of an updated row.
+type RowChange struct {
+	Op     string                 `json:"op"`
+	Key    map[string]interface{} `json:"key,omitempty"`
+	Values map[string]interface{} `json:"values,omitempty"`
+}
+
+// RowChangesRequest represents a set of row edits to apply as mutations
+type RowChangesRequest struct {
+	ConnectionRequest
+	Table   string      `json:"table"`
+	Changes []RowChange `json:"changes"`
+	// DryRun builds the mutations without applying them
+	DryRun bool `json:"dryRun,omitempty"`
+}
+
+// MutationInfo describes one mutation built from a row change
+type MutationInfo struct {
+	Op      string                 `json:"op"`
+	Table   string                 `json:"table"`
+	Key     map[string]interface{} `json:"key"`
+	Columns []string               `json:"columns,omitempty"`
+	Values  map[string]interface{} `json:"values,omitempty"`
+}
+
+// RowChangesResponse represents the mutations built from row edits and,
+// unless it was a dry run, the result of applying them
+type RowChangesResponse struct {
+	Mutations       []MutationInfo `json:"mutations"`
+	Applied         bool           `json:"applied"`
+	CommitTimestamp string         `json:"commitTimestamp,omitempty"`
+	ExecutionTime   string         `json:"executionTime"`
+	Error           string         `json:"error,omitempty"`
+}
+
+// SnapshotQuery is a query whose result is captured in a snapshot. Rows are
+// matched by KeyColumns when snapshots are compared; without key columns a
+// row can only be added or removed, never changed.
+type SnapshotQuery struct {
+	Name       string   `json:"name"`
+	SQL        string   `json:"sql"`
+	KeyColumns []string `json:"keyColumns,omitempty"`
+}
+
+// SnapshotRequest represents a request to capture tables and query results
+type SnapshotRequest struct {
+	ConnectionRequest
+	Name    string          `json:"name"`
+	Tables  []string        `json:"tables,omitempty"`
+	Queries []SnapshotQuery `json:"queries,omitempty"`
+}
+
+// SnapshotDiffRequest names two saved snapshots to compare. An empty After
+// compares Before with the current contents of the database.
+type SnapshotDiffRequest struct {
+	ConnectionRequest
+	Before string `json:"before"`
+	After  string `json:"after,omitempty"`
+}
+
+// SnapshotDiff represents the row-level differences between two snapshots
+type SnapshotDiff struct {
+	Before string      `json:"before"`
+	After  string      `json:"after"`
+	Tables []TableDiff `json:"tables"`
+	Error  string      `json:"error,omitempty"`
+}
+
+// TableDiff represents the differences in one table or query result.
+// Note explains tables that could not be compared row by row.
+type TableDiff struct {
+	Name       string                   `json:"name"`
+	KeyColumns []string                 `json:"keyColumns,omitempty"`
+	Added      []map[string]interface{} `json:"added"`
+	Removed    []map[string]interface{} `json:"removed"`
+	Changed    []RowDiff                `json:"changed"`
+	Unchanged  int                      `json:"unchanged"`
+	Note       string                   `json:"note,omitempty"`
+}
+
+// RowDiff represents a row present in both snapshots with different values
+type RowDiff struct {
+	Key            map[string]interface{} `json:"key"`
+	Before         map[string]interface{} `json:"before"`
+	After          map[string]interface{} `json:"after"`
+	ChangedColumns []string               `json:"changedColumns"`
+}
+
+// InstanceInfo represents a Spanner instance
+type InstanceInfo struct {
+	InstanceID  string `json:"instanceId"`
+	DisplayName string `json:"displayName"`
+	Config      string `json:"config"`
+	NodeCount   int32  `json:"nodeCount"`
+	State       string `json:"state"`
+}
+
+// DatabaseInfo represents a database of a Spanner instance
+type DatabaseInfo struct {
+	DatabaseID string `json:"databaseId"`
+	State      string `json:"state"`
+	Dialect    string `json:"dialect"`
+}
+
+// DatabaseRequest represents a request to create a database. Dialect is
+// GOOGLE_STANDARD_SQL (the default) or POSTGRESQL, and DDL holds optional
+// semicolon-separated statements applied on create.
+type DatabaseRequest struct {
+	ConnectionRequest
+	Dialect string `json:"dialect,omitempty"`
+	DDL     string `json:"ddl,omitempty"`
+}
+
+// ChangeStreamRequest represents a request to read a change stream.
+// Timestamps are RFC 3339; the end defaults to now and the start to an hour
+// before it. An empty PartitionToken runs the initial partition query.
+type ChangeStreamRequest struct {
+	ConnectionRequest
+	Stream                string `json:"stream"`
+	StartTimestamp        string `json:"startTimestamp,omitempty"`
+	EndTimestamp          string `json:"endTimestamp,omitempty"`
+	PartitionToken        string `json:"partitionToken,omitempty"`
+	HeartbeatMilliseconds int64  `json:"heartbeatMilliseconds,omitempty"`
+	// FollowChildren reads the child partitions found, up to MaxPartitions
+	FollowChildren bool `json:"followChildren"`
+	MaxPartitions  int  `json:"maxPartitions,omitempty"`
+}
+
+// ChangeStreamPartition represents a change stream partition that was read,
+// or a child partition found that is left for the caller to read
+type ChangeStreamPartition struct {
+	Token          string   `json:"token"`
+	ParentTokens   []string `json:"parentTokens,omitempty"`
+	StartTimestamp string   `json:"startTimestamp"`
+	Read           bool     `json:"read"`
+	Records        int      `json:"records"`
+}
+
+// ChangeStreamResponse represents the mods read from a change stream, one
+// event per mod in commit timestamp order
+type ChangeStreamResponse struct {
+	Messages      []CloudEvent            `json:"messages"`
+	Partitions    []ChangeStreamPartition `json:"partitions"`
+	Heartbeats    int                     `json:"heartbeats"`
+	ExecutionTime string                  `json:"executionTime,omitempty"`
+	Error         string                  `json:"error,omitempty"`
+}